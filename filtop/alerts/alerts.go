@@ -0,0 +1,162 @@
+// Package alerts evalúa reglas definidas con el lenguaje de expresiones y
+// lleva la cuenta de las alertas activas.
+package alerts
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/iTiagoCO/filtop/filtop/expr"
+)
+
+// Rule dispara una alerta mientras Condition sea verdadera. Value es el
+// valor que se muestra junto a la alerta; si es nil se usa la primera
+// métrica que aparece en la condición.
+type Rule struct {
+	Name      string
+	Severity  string
+	Condition *expr.Expr
+	Value     *expr.Expr
+}
+
+type Alert struct {
+	Rule     string
+	Severity string
+	Value    float64
+	Since    time.Time
+	// Silenced indica que un silencio la cubrió mientras estaba activa: ni
+	// su activación ni su resolución se notifican
+	Silenced bool
+}
+
+// Event es una transición de una alerta: se activa o se resuelve
+type Event struct {
+	Alert  Alert
+	Raised bool
+	At     time.Time
+}
+
+type Engine struct {
+	rules  []Rule
+	active map[string]*Alert
+	errors map[string]string
+	// silences y target marcan las alertas silenciadas; silences es nil si
+	// no se usan
+	silences *Silences
+	target   string
+}
+
+func NewEngine(rules []Rule) *Engine {
+	return &Engine{
+		rules:  rules,
+		active: make(map[string]*Alert),
+		errors: make(map[string]string),
+	}
+}
+
+// SilenceWith marca como silenciadas las alertas que silences cubre para
+// target
+func (e *Engine) SilenceWith(silences *Silences, target string) {
+	e.silences, e.target = silences, target
+}
+
+// Evaluate evalúa todas las reglas y devuelve las transiciones. Una regla
+// que no se puede evaluar (p. ej. una métrica que esta versión no expone)
+// no dispara; su error solo se devuelve la primera vez que aparece.
+func (e *Engine) Evaluate(env expr.Env, now time.Time) ([]Event, []error) {
+	var events []Event
+	var errs []error
+
+	for _, rule := range e.rules {
+		firing, err := rule.Condition.Bool(env)
+		if err != nil {
+			if e.errors[rule.Name] != err.Error() {
+				e.errors[rule.Name] = err.Error()
+				errs = append(errs, &RuleError{Rule: rule.Name, Err: err})
+			}
+			firing = false
+		} else {
+			delete(e.errors, rule.Name)
+		}
+
+		alert, active := e.active[rule.Name]
+		if active && !alert.Silenced {
+			alert.Silenced = e.silenced(rule, now)
+		}
+		switch {
+		case firing && !active:
+			alert = &Alert{Rule: rule.Name, Severity: rule.Severity, Since: now, Silenced: e.silenced(rule, now)}
+			alert.Value = ruleValue(rule, env)
+			e.active[rule.Name] = alert
+			events = append(events, Event{Alert: *alert, Raised: true, At: now})
+		case firing:
+			alert.Value = ruleValue(rule, env)
+		case active:
+			delete(e.active, rule.Name)
+			events = append(events, Event{Alert: *alert, Raised: false, At: now})
+		}
+	}
+	return events, errs
+}
+
+// SetRules reemplaza las reglas al recargar la configuración. Las alertas
+// activas de reglas que siguen existiendo se conservan; las de reglas
+// eliminadas se resuelven y se devuelven como eventos.
+func (e *Engine) SetRules(rules []Rule, now time.Time) []Event {
+	names := make(map[string]Rule, len(rules))
+	for _, rule := range rules {
+		names[rule.Name] = rule
+	}
+
+	var events []Event
+	for name, alert := range e.active {
+		rule, ok := names[name]
+		if !ok {
+			delete(e.active, name)
+			events = append(events, Event{Alert: *alert, Raised: false, At: now})
+			continue
+		}
+		alert.Severity = rule.Severity
+	}
+	e.rules = rules
+	e.errors = make(map[string]string)
+	return events
+}
+
+// Active devuelve las alertas activas ordenadas por nombre de regla
+func (e *Engine) Active() []Alert {
+	alerts := make([]Alert, 0, len(e.active))
+	for _, alert := range e.active {
+		alerts = append(alerts, *alert)
+	}
+	sort.Slice(alerts, func(i, j int) bool { return alerts[i].Rule < alerts[j].Rule })
+	return alerts
+}
+
+func (e *Engine) silenced(rule Rule, at time.Time) bool {
+	return e.silences != nil && e.silences.Silenced(e.target, rule, at)
+}
+
+func ruleValue(rule Rule, env expr.Env) float64 {
+	if rule.Value != nil {
+		if v, err := rule.Value.Eval(env); err == nil {
+			return v
+		}
+		return math.NaN()
+	}
+	for _, path := range rule.Condition.Metrics() {
+		if v, ok := env.Metric(path); ok {
+			return v
+		}
+	}
+	return math.NaN()
+}
+
+// RuleError indica que la condición de una regla no se pudo evaluar
+type RuleError struct {
+	Rule string
+	Err  error
+}
+
+func (e *RuleError) Error() string { return "alerta " + e.Rule + ": " + e.Err.Error() }
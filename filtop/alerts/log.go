@@ -0,0 +1,259 @@
+package alerts
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Log es el historial de las alertas que se activaron y resolvieron. Retiene
+// las últimas en memoria y, si tiene un archivo, las agrega a él como JSON
+// Lines para poder consultarlas después de reiniciar. El archivo se reescribe
+// con solo las retenidas al abrirlo y cada vez que duplica su tamaño, para
+// que no crezca sin límite. Es seguro usarlo desde varias goroutines.
+type Log struct {
+	mu      sync.Mutex
+	records []Record
+	size    int
+	// maxAge es la edad a partir de la que se descartan; 0 no tiene límite
+	maxAge time.Duration
+	path   string
+	file   *os.File
+	// lines son las líneas del archivo, retenidas o no
+	lines int
+	now   func() time.Time
+}
+
+// Record es una transición del historial. Target es el Filebeat de la
+// alerta cuando se monitorea más de uno y Value es nil si no se pudo
+// calcular.
+type Record struct {
+	At       time.Time `json:"at"`
+	Target   string    `json:"target,omitempty"`
+	Rule     string    `json:"rule"`
+	Severity string    `json:"severity"`
+	Raised   bool      `json:"raised"`
+	Value    *float64  `json:"value"`
+	// Since es cuándo se activó la alerta
+	Since time.Time `json:"since"`
+	// Silenced indica que la alerta estaba silenciada y no se notificó
+	Silenced bool `json:"silenced,omitempty"`
+}
+
+// NewLog crea un historial que retiene las últimas size transiciones que
+// no superan maxAge. Con path lee las que ya tenía el archivo y agrega las
+// nuevas al final.
+func NewLog(size int, maxAge time.Duration, path string) (*Log, error) {
+	l := &Log{size: size, maxAge: maxAge, path: path, now: time.Now}
+	if path == "" {
+		return l, nil
+	}
+	if err := l.load(); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	if l.lines > len(l.records) {
+		if err := l.rewrite(); err != nil {
+			return nil, err
+		}
+	}
+	if err := l.open(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+func (l *Log) load() error {
+	file, err := os.Open(l.path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		l.lines++
+		var record Record
+		// Una línea a medias, p. ej. de una escritura interrumpida, se saltea
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			continue
+		}
+		l.append(record)
+	}
+	l.prune()
+	return scanner.Err()
+}
+
+func (l *Log) open() error {
+	file, err := os.OpenFile(l.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	l.file = file
+	return nil
+}
+
+// rewrite reemplaza el archivo por uno con solo las transiciones retenidas.
+// Se escribe aparte y se renombra, para no perder el historial si filtop se
+// interrumpe a mitad de camino; el archivo queda cerrado.
+func (l *Log) rewrite() error {
+	var data []byte
+	for _, record := range l.records {
+		line, err := json.Marshal(record)
+		if err != nil {
+			return err
+		}
+		data = append(append(data, line...), '\n')
+	}
+	tmp := l.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	// En Windows no se puede reemplazar un archivo abierto
+	if l.file != nil {
+		l.file.Close()
+		l.file = nil
+	}
+	if err := os.Rename(tmp, l.path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	l.lines = len(l.records)
+	return nil
+}
+
+// compact reescribe el archivo y lo vuelve a abrir para agregar
+func (l *Log) compact() error {
+	err := l.rewrite()
+	if l.file == nil {
+		if openErr := l.open(); err == nil {
+			err = openErr
+		}
+	}
+	return err
+}
+
+func (l *Log) append(record Record) {
+	l.records = append(l.records, record)
+	if len(l.records) > l.size {
+		l.records = l.records[len(l.records)-l.size:]
+	}
+}
+
+// prune descarta de la memoria las transiciones que superan maxAge, que
+// están al principio; el archivo se recorta al reescribirlo
+func (l *Log) prune() {
+	if l.maxAge <= 0 {
+		return
+	}
+	cutoff := l.now().Add(-l.maxAge)
+	old := 0
+	for old < len(l.records) && l.records[old].At.Before(cutoff) {
+		old++
+	}
+	l.records = l.records[old:]
+}
+
+// Add registra las transiciones de las alertas de target. El error es el
+// de escribir en el archivo; las transiciones quedan en memoria igual.
+func (l *Log) Add(target string, events []Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var lines []byte
+	for _, event := range events {
+		record := Record{
+			At:       event.At,
+			Target:   target,
+			Rule:     event.Alert.Rule,
+			Severity: event.Alert.Severity,
+			Raised:   event.Raised,
+			Since:    event.Alert.Since,
+			Silenced: event.Alert.Silenced,
+		}
+		if v := event.Alert.Value; !math.IsNaN(v) && !math.IsInf(v, 0) {
+			record.Value = &v
+		}
+		l.append(record)
+		if l.file != nil {
+			line, err := json.Marshal(record)
+			if err != nil {
+				return err
+			}
+			lines = append(append(lines, line...), '\n')
+		}
+	}
+	l.prune()
+	if l.file == nil {
+		return nil
+	}
+	if _, err := l.file.Write(lines); err != nil {
+		return err
+	}
+	l.lines += len(events)
+	if l.lines >= 2*l.size {
+		return l.compact()
+	}
+	return nil
+}
+
+// Records devuelve las transiciones retenidas, de la más antigua a la más
+// reciente
+func (l *Log) Records() []Record {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.prune()
+	return append([]Record(nil), l.records...)
+}
+
+// Close cierra el archivo del historial, si tiene
+func (l *Log) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.file == nil {
+		return nil
+	}
+	err := l.file.Close()
+	l.file = nil
+	return err
+}
+
+// WriteCSV escribe las transiciones como CSV, con una fila de encabezado
+func WriteCSV(w io.Writer, records []Record) error {
+	out := csv.NewWriter(w)
+	out.Write([]string{"at", "target", "rule", "severity", "event", "value", "since", "silenced"})
+	for _, record := range records {
+		event := "resolved"
+		if record.Raised {
+			event = "raised"
+		}
+		value := ""
+		if record.Value != nil {
+			value = strconv.FormatFloat(*record.Value, 'g', -1, 64)
+		}
+		out.Write([]string{
+			record.At.Format(time.RFC3339),
+			record.Target,
+			record.Rule,
+			record.Severity,
+			event,
+			value,
+			record.Since.Format(time.RFC3339),
+			strconv.FormatBool(record.Silenced),
+		})
+	}
+	out.Flush()
+	return out.Error()
+}
@@ -0,0 +1,131 @@
+package alerts
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+var start = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// transitions devuelve n transiciones de rule, una por minuto, que se
+// activan y resuelven alternadamente
+func transitions(rule string, from time.Time, n int) []Event {
+	var events []Event
+	for i := 0; i < n; i++ {
+		at := from.Add(time.Duration(i) * time.Minute)
+		events = append(events, Event{Alert: Alert{Rule: rule, Severity: "warning", Value: float64(i), Since: at}, Raised: i%2 == 0, At: at})
+	}
+	return events
+}
+
+func countLines(t *testing.T, path string) int {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return bytes.Count(data, []byte("\n"))
+}
+
+func TestLogRewriteOnLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "alerts.jsonl")
+	log, err := NewLog(100, 0, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := log.Add("", transitions("queue", start, 50)); err != nil {
+		t.Fatal(err)
+	}
+	log.Close()
+	// Una línea a medio escribir al final
+	file, _ := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	file.WriteString(`{"at":"2024-01-01T01:00:00Z","ru`)
+	file.Close()
+
+	// Con un tamaño menor el archivo queda con solo las retenidas
+	log, err = NewLog(10, 0, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer log.Close()
+	records := log.Records()
+	if len(records) != 10 || records[0].Value == nil || *records[0].Value != 40 {
+		t.Fatalf("%d transiciones retenidas, la primera %+v", len(records), records[0])
+	}
+	if n := countLines(t, path); n != 10 {
+		t.Errorf("el archivo tiene %d líneas, se esperaban 10", n)
+	}
+	if _, err := os.Stat(path + ".tmp"); !os.IsNotExist(err) {
+		t.Errorf("quedó el archivo temporal: %v", err)
+	}
+
+	// Y se sigue agregando al final
+	if err := log.Add("", transitions("queue", start.Add(time.Hour), 1)); err != nil {
+		t.Fatal(err)
+	}
+	if n := countLines(t, path); n != 11 {
+		t.Errorf("el archivo tiene %d líneas, se esperaban 11", n)
+	}
+}
+
+func TestLogRewriteWhenGrowing(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "alerts.jsonl")
+	log, err := NewLog(10, 0, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer log.Close()
+	for i := 0; i < 100; i++ {
+		if err := log.Add("", transitions("queue", start.Add(time.Duration(i)*time.Minute), 1)); err != nil {
+			t.Fatal(err)
+		}
+		// Nunca supera el doble de lo retenido
+		if n := countLines(t, path); n >= 20 {
+			t.Fatalf("tras %d transiciones el archivo tiene %d líneas", i+1, n)
+		}
+	}
+
+	reopened, err := NewLog(10, 0, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reopened.Close()
+	records := reopened.Records()
+	if len(records) != 10 || !records[9].At.Equal(start.Add(99*time.Minute)) {
+		t.Errorf("%d transiciones, la última %v", len(records), records[len(records)-1].At)
+	}
+}
+
+func TestMaxAge(t *testing.T) {
+	now := start.Add(48 * time.Hour)
+	log, err := NewLog(1000, 24*time.Hour, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	log.now = func() time.Time { return now }
+	// Más vieja que un día: se descarta
+	if err := log.Add("", transitions("queue", now.Add(-30*time.Hour), 2)); err != nil {
+		t.Fatal(err)
+	}
+	if err := log.Add("", transitions("queue", now.Add(-10*time.Hour), 4)); err != nil {
+		t.Fatal(err)
+	}
+	records := log.Records()
+	if len(records) != 4 || !records[0].At.Equal(now.Add(-10*time.Hour)) {
+		t.Fatalf("%d transiciones, la primera %v", len(records), records[0].At)
+	}
+	// Las activaciones y resoluciones quedan todas, en orden
+	for i, record := range records {
+		if record.Raised != (i%2 == 0) {
+			t.Errorf("transición %d: %+v", i, record)
+		}
+	}
+
+	log.now = func() time.Time { return now.Add(14*time.Hour + 90*time.Second) }
+	if records := log.Records(); len(records) != 2 || records[0].Raised != true {
+		t.Errorf("14 horas después: %+v", records)
+	}
+}
@@ -0,0 +1,126 @@
+package alerts
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Silence silencia alertas entre Start y End, p. ej. durante un reinicio
+// planificado: se siguen evaluando, mostrando y guardando en el historial,
+// pero no se notifican. Target, Rule y Metric vacíos no filtran: un
+// silencio sin ninguno silencia todo.
+type Silence struct {
+	// Target es el beat, como en Record
+	Target string
+	Rule   string
+	// Metric silencia las reglas cuya condición usa esa métrica
+	Metric string
+	Start  time.Time
+	End    time.Time
+	Reason string
+}
+
+// Matches indica si el silencio cubre la regla rule de target en at
+func (s Silence) Matches(target string, rule Rule, at time.Time) bool {
+	if at.Before(s.Start) || !at.Before(s.End) {
+		return false
+	}
+	if s.Target != "" && s.Target != target || s.Rule != "" && s.Rule != rule.Name {
+		return false
+	}
+	if s.Metric == "" {
+		return true
+	}
+	for _, path := range rule.Condition.Metrics() {
+		if path == s.Metric {
+			return true
+		}
+	}
+	return false
+}
+
+// Silences son los silencios de la configuración más los que se agregan
+// desde la interfaz. Es seguro usarlo desde varias goroutines.
+type Silences struct {
+	mu         sync.Mutex
+	configured []Silence
+	added      []Silence
+}
+
+func NewSilences() *Silences {
+	return &Silences{}
+}
+
+// SetConfigured reemplaza los silencios de la configuración, al cargarla o
+// recargarla; los agregados con Add se conservan
+func (s *Silences) SetConfigured(silences []Silence) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.configured = append([]Silence(nil), silences...)
+}
+
+// Add agrega un silencio
+func (s *Silences) Add(silence Silence) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.added = append(s.added, silence)
+}
+
+// Remove quita los silencios vigentes o programados en at cuyo Target es
+// target y devuelve cuántos eran. Uno de la configuración vuelve al
+// recargarla.
+func (s *Silences) Remove(target string, at time.Time) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	removed := 0
+	keep := func(list []Silence) []Silence {
+		var kept []Silence
+		for _, silence := range list {
+			if silence.End.After(at) && silence.Target == target {
+				removed++
+				continue
+			}
+			kept = append(kept, silence)
+		}
+		return kept
+	}
+	s.configured, s.added = keep(s.configured), keep(s.added)
+	return removed
+}
+
+// Silenced indica si algún silencio cubre la regla rule de target en at
+func (s *Silences) Silenced(target string, rule Rule, at time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, list := range [][]Silence{s.configured, s.added} {
+		for _, silence := range list {
+			if silence.Matches(target, rule, at) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Active devuelve los silencios vigentes o programados en at, del que
+// termina antes al último. Olvida los agregados que ya terminaron.
+func (s *Silences) Active(at time.Time) []Silence {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var active, added []Silence
+	for _, silence := range s.added {
+		if silence.End.After(at) {
+			added = append(added, silence)
+		}
+	}
+	s.added = added
+	for _, silence := range s.configured {
+		if silence.End.After(at) {
+			active = append(active, silence)
+		}
+	}
+	active = append(active, added...)
+	sort.SliceStable(active, func(i, j int) bool { return active[i].End.Before(active[j].End) })
+	return active
+}
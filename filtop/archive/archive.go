@@ -0,0 +1,257 @@
+// Package archive guarda en disco el historial de algunas métricas de cada
+// beat (history.path en la configuración), para consultarlo después de
+// reiniciar y más atrás de lo que retiene la memoria. Las muestras recientes
+// se guardan completas, las más viejas se promedian en intervalos y las que
+// superan la retención se descartan, así el archivo no crece sin límite.
+package archive
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Options es la política de retención
+type Options struct {
+	// Raw es la edad hasta la que se conservan todas las muestras
+	Raw time.Duration
+	// Rollup es el intervalo en el que se promedian las más viejas que
+	// Raw; con 0 no se promedian
+	Rollup time.Duration
+	// Retention es la edad a partir de la que se descartan; 0 no tiene
+	// límite
+	Retention time.Duration
+}
+
+// Point es el valor de una métrica en una muestra o un intervalo
+type Point struct {
+	Time  time.Time `json:"t"`
+	Value float64   `json:"v"`
+}
+
+// entry es una línea del archivo: una muestra de un beat o el promedio de
+// las de un intervalo, que empieza en Time
+type entry struct {
+	Time   time.Time `json:"t"`
+	Target string    `json:"target"`
+	// Samples es la cantidad de muestras que promedia; 0 es una sola
+	Samples int                `json:"n,omitempty"`
+	Values  map[string]float64 `json:"v"`
+}
+
+func (e entry) samples() int {
+	return max(e.Samples, 1)
+}
+
+type bucketKey struct {
+	target string
+	start  time.Time
+}
+
+// apply devuelve entries sin las muestras que superan Retention y con las
+// más viejas que Raw promediadas por beat en intervalos de Rollup. Como un
+// promedio ya hecho queda en el inicio de su intervalo, aplicarla otra vez
+// solo suma las muestras que envejecieron desde entonces.
+func (o Options) apply(entries []entry, now time.Time) []entry {
+	out := make([]entry, 0, len(entries))
+	buckets := make(map[bucketKey]int)
+	for _, e := range entries {
+		age := now.Sub(e.Time)
+		if o.Retention > 0 && age > o.Retention {
+			continue
+		}
+		if o.Rollup <= 0 || age <= o.Raw {
+			out = append(out, e)
+			continue
+		}
+		key := bucketKey{e.Target, e.Time.Truncate(o.Rollup)}
+		i, ok := buckets[key]
+		if !ok {
+			buckets[key] = len(out)
+			out = append(out, entry{Time: key.start, Target: e.Target, Samples: e.samples(), Values: e.Values})
+			continue
+		}
+		out[i] = merge(out[i], e)
+	}
+	return out
+}
+
+// merge promedia dos entradas según cuántas muestras resume cada una. Una
+// métrica que falta en una de ellas queda con el valor de la otra.
+func merge(a, b entry) entry {
+	wa, wb := float64(a.samples()), float64(b.samples())
+	values := make(map[string]float64, len(a.Values))
+	for name, v := range a.Values {
+		values[name] = v
+	}
+	for name, v := range b.Values {
+		if prev, ok := values[name]; ok {
+			values[name] = (prev*wa + v*wb) / (wa + wb)
+		} else {
+			values[name] = v
+		}
+	}
+	a.Samples = a.samples() + b.samples()
+	a.Values = values
+	return a
+}
+
+// Archive es el historial guardado. Las muestras se agregan al final del
+// archivo y, cada Rollup (o cada minuto si no se promedian), se aplica la
+// retención y se reescribe. Es seguro usarlo desde varias goroutines.
+type Archive struct {
+	mu      sync.Mutex
+	opts    Options
+	path    string
+	file    *os.File
+	entries []entry
+	// Próxima vez que se aplica la retención
+	next time.Time
+}
+
+// timeNow es el reloj de la retención; las pruebas lo reemplazan
+var timeNow = time.Now
+
+// Open abre el historial de path, lo crea si no existe y le aplica la
+// retención
+func Open(path string, opts Options) (*Archive, error) {
+	a := &Archive{opts: opts, path: path}
+	if err := a.load(); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	if err := a.compact(); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+func (a *Archive) load() error {
+	file, err := os.Open(a.path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var e entry
+		// Una línea a medias, p. ej. de una escritura interrumpida, se saltea
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			continue
+		}
+		a.entries = append(a.entries, e)
+	}
+	return scanner.Err()
+}
+
+// compact aplica la retención y reescribe el archivo aparte y lo renombra,
+// para no perder el historial si filtop se interrumpe a mitad de camino.
+// Después lo vuelve a abrir para agregar.
+func (a *Archive) compact() error {
+	now := timeNow()
+	a.entries = a.opts.apply(a.entries, now)
+	interval := a.opts.Rollup
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	a.next = now.Add(interval)
+
+	var data []byte
+	for _, e := range a.entries {
+		line, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		data = append(append(data, line...), '\n')
+	}
+	tmp := a.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	// En Windows no se puede reemplazar un archivo abierto
+	if a.file != nil {
+		a.file.Close()
+		a.file = nil
+	}
+	if err := os.Rename(tmp, a.path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	file, err := os.OpenFile(a.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	a.file = file
+	return nil
+}
+
+// Add guarda los valores de una muestra de target. Los NaN e infinitos, que
+// JSON no admite, se omiten. El error es el de escribir en el archivo; la
+// muestra queda en memoria igual.
+func (a *Archive) Add(target string, at time.Time, values map[string]float64) error {
+	e := entry{Time: at, Target: target, Values: make(map[string]float64, len(values))}
+	for name, v := range values {
+		if !math.IsNaN(v) && !math.IsInf(v, 0) {
+			e.Values[name] = v
+		}
+	}
+	line, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.entries = append(a.entries, e)
+	if !timeNow().Before(a.next) {
+		return a.compact()
+	}
+	if a.file == nil {
+		return nil
+	}
+	_, err = a.file.Write(append(line, '\n'))
+	return err
+}
+
+// Series devuelve los valores guardados de una métrica de target desde
+// from, del más antiguo al más reciente. found es false si la métrica no
+// se guarda.
+func (a *Archive) Series(target, name string, from time.Time) (points []Point, found bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for _, e := range a.entries {
+		if e.Target != target {
+			continue
+		}
+		v, ok := e.Values[name]
+		if !ok {
+			continue
+		}
+		found = true
+		if !e.Time.Before(from) {
+			points = append(points, Point{Time: e.Time, Value: v})
+		}
+	}
+	return points, found
+}
+
+// Close cierra el archivo
+func (a *Archive) Close() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.file == nil {
+		return nil
+	}
+	err := a.file.Close()
+	a.file = nil
+	return err
+}
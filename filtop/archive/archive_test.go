@@ -0,0 +1,123 @@
+package archive
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+var start = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+var policy = Options{Raw: 2 * time.Hour, Rollup: time.Minute, Retention: 24 * time.Hour}
+
+// samples devuelve n muestras de target cada 10s desde from, con v igual
+// a i
+func samples(target string, from time.Time, n int) []entry {
+	var entries []entry
+	for i := 0; i < n; i++ {
+		entries = append(entries, entry{Time: from.Add(time.Duration(i) * 10 * time.Second), Target: target, Values: map[string]float64{"v": float64(i)}})
+	}
+	return entries
+}
+
+func TestApply(t *testing.T) {
+	now := start.Add(48 * time.Hour)
+	var entries []entry
+	// Más vieja que la retención: se descarta
+	entries = append(entries, samples("a", now.Add(-30*time.Hour), 6)...)
+	// Dos minutos viejos de dos beats: se promedian por beat y minuto
+	entries = append(entries, samples("a", now.Add(-10*time.Hour), 12)...)
+	entries = append(entries, samples("b", now.Add(-10*time.Hour), 6)...)
+	// Reciente: se conserva completa
+	entries = append(entries, samples("a", now.Add(-time.Hour), 3)...)
+
+	got := policy.apply(entries, now)
+	type row struct {
+		target  string
+		age     time.Duration
+		samples int
+		v       float64
+	}
+	want := []row{
+		{"a", 10 * time.Hour, 6, 2.5},
+		{"a", 10*time.Hour - time.Minute, 6, 8.5},
+		{"b", 10 * time.Hour, 6, 2.5},
+		{"a", time.Hour, 1, 0},
+		{"a", time.Hour - 10*time.Second, 1, 1},
+		{"a", time.Hour - 20*time.Second, 1, 2},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("%d entradas, se esperaban %d: %+v", len(got), len(want), got)
+	}
+	for i, w := range want {
+		g := row{got[i].Target, now.Sub(got[i].Time), got[i].samples(), got[i].Values["v"]}
+		if g != w {
+			t.Errorf("entrada %d: %+v, se esperaba %+v", i, g, w)
+		}
+	}
+
+	// Aplicarla otra vez no cambia nada, y lo que envejece se suma a su
+	// intervalo con el peso de cada promedio
+	if again := policy.apply(got, now); len(again) != len(got) || again[0].Values["v"] != 2.5 {
+		t.Errorf("al volver a aplicarla: %+v", again)
+	}
+	later := policy.apply(got, now.Add(time.Hour+15*time.Second))
+	if len(later) != 5 || later[3].samples() != 2 || later[3].Values["v"] != 0.5 {
+		t.Errorf("una hora después: %+v", later)
+	}
+}
+
+func TestArchive(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	now := start
+	timeNow = func() time.Time { return now }
+	defer func() { timeNow = time.Now }()
+	a, err := Open(path, policy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 6*60*3; i++ {
+		now = start.Add(time.Duration(i) * 10 * time.Second)
+		if err := a.Add("a", now, map[string]float64{"v": float64(i % 6)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	a.Close()
+
+	// Al reabrir tres horas después, la primera hora queda por minuto
+	last := now
+	now = start.Add(3 * time.Hour)
+	reopened, err := Open(path, policy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reopened.Close()
+	points, found := reopened.Series("a", "v", time.Time{})
+	if !found || len(points) != 60+6*60*2 {
+		t.Fatalf("%d puntos (found %v)", len(points), found)
+	}
+	if !points[1].Time.Equal(start.Add(time.Minute)) || points[1].Value != 2.5 {
+		t.Errorf("segundo minuto: %+v", points[1])
+	}
+	if _, found := reopened.Series("a", "otra", time.Time{}); found {
+		t.Error("se encontró una métrica que no se guarda")
+	}
+	recent, _ := reopened.Series("a", "v", last.Add(-time.Minute))
+	if len(recent) != 7 {
+		t.Errorf("%d puntos en el último minuto", len(recent))
+	}
+
+	// El archivo tiene solo lo retenido, sin el temporal
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := bytes.Count(data, []byte("\n")); n != len(points) {
+		t.Errorf("el archivo tiene %d líneas, se esperaban %d", n, len(points))
+	}
+	if _, err := os.Stat(path + ".tmp"); !os.IsNotExist(err) {
+		t.Errorf("quedó el archivo temporal: %v", err)
+	}
+}
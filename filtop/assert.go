@@ -0,0 +1,199 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/iTiagoCO/filtop/filtop/expr"
+	"github.com/iTiagoCO/filtop/filtop/metrics"
+)
+
+// filtop assert comprueba una condición del lenguaje de expresiones en cada
+// intervalo durante -for, p. ej. como prueba de humo de la ingesta en un
+// pipeline de despliegue. Termina con código 0 si se cumplió en todas las
+// muestras de todos los beats y con 1 en cuanto deja de cumplirse. Con
+// -wait tolera que la condición tarde en cumplirse, p. ej. mientras
+// Filebeat arranca: la ventana de -for empieza cuando se cumple por primera
+// vez en cada beat. Si la condición no se puede evaluar, p. ej. porque usa
+// una métrica que el beat no publica, termina enseguida con código 2: no
+// es una falla de la ingesta sino de la condición.
+
+// assertOptions son los flags de filtop assert
+type assertOptions struct {
+	condition *expr.Expr
+	source    string
+	window    time.Duration
+	wait      time.Duration
+	// computed son las métricas calculadas de la configuración, que la
+	// condición puede usar
+	computed []metrics.Computed
+}
+
+// assertResult es el resultado de la condición en una muestra
+type assertResult int
+
+const (
+	// Una sola muestra todavía no alcanza para rate() y delta()
+	assertPending assertResult = iota
+	assertTrue
+	assertFalse
+	// Filebeat no respondió; cuenta como que no se cumple
+	assertDown
+	// La condición no se pudo evaluar
+	assertError
+)
+
+// Códigos de salida de filtop assert, además de 0; el 2 es también el de
+// los flags inválidos
+const (
+	assertExitFailed = 1
+	assertExitError  = 2
+)
+
+// assertion sigue la condición en un beat
+type assertion struct {
+	beat *beat
+	// since es cuándo empezó a cumplirse; cero mientras se espera
+	since   time.Time
+	samples int
+	passed  bool
+}
+
+// runAssert ejecuta filtop assert y termina con su código de salida
+func runAssert(args []string) {
+	fs := newFlagSet("assert", "filtop assert -expr condición [flags]")
+	flags := &beatFlags{}
+	flags.register(fs, "assert")
+	source := fs.String("expr", "", "Condición que se comprueba, p. ej. 'rate(pipeline.events.total) > 100'")
+	window := fs.Duration("for", time.Minute, "Tiempo durante el que la condición debe cumplirse")
+	wait := fs.Duration("wait", 0, "Tiempo que se espera a que la condición se cumpla por primera vez")
+	flags.parse(fs, args)
+	if *source == "" {
+		fatal("filtop assert necesita -expr")
+	}
+	condition, err := expr.Compile(*source, metrics.Functions)
+	if err != nil {
+		fmt.Printf("ERROR: condición inválida %s: %s\n", *source, err)
+		os.Exit(assertExitError)
+	}
+
+	s := newSession("assert", flags)
+	os.Exit(assertBeats(s.ctx, s.beats, assertOptions{condition: condition, source: *source, window: *window, wait: *wait, computed: s.cfg.computedMetrics()}))
+}
+
+// assertBeats comprueba la condición en los beats hasta que se cumple
+// durante toda la ventana, falla o se cancela ctx, y devuelve el código de
+// salida del programa.
+func assertBeats(ctx context.Context, beats []*beat, opts assertOptions) int {
+	fmt.Fprintf(os.Stderr, "Comprobando %s durante %s cada %s; -wait %s\n", opts.source, opts.window, refresh, opts.wait)
+	deadline := time.Now().Add(opts.wait)
+	assertions := make([]*assertion, len(beats))
+	for i, b := range beats {
+		assertions[i] = &assertion{beat: b}
+	}
+	ticker := time.NewTicker(refresh)
+	defer ticker.Stop()
+
+	for {
+		pending := 0
+		for _, a := range assertions {
+			if a.passed {
+				continue
+			}
+			result, detail := a.check(ctx, opts)
+			if ctx.Err() != nil {
+				fmt.Fprintln(os.Stderr, "Interrumpido antes de completar la ventana")
+				return assertExitFailed
+			}
+			now := time.Now()
+			switch {
+			case result == assertPending:
+			case result == assertError:
+				fmt.Printf("ERROR %s: no se pudo evaluar %s: %s\n", a.beat.name, opts.source, detail)
+				return assertExitError
+			case result == assertTrue:
+				if a.since.IsZero() {
+					a.since = now
+					fmt.Fprintf(os.Stderr, "%s: se cumple (%s)\n", a.beat.name, detail)
+				}
+				a.samples++
+				if now.Sub(a.since) >= opts.window {
+					a.passed = true
+					fmt.Printf("OK %s: %s se cumplió durante %s en %d muestras\n", a.beat.name, opts.source, now.Sub(a.since).Round(time.Second), a.samples)
+				}
+			case !a.since.IsZero():
+				fmt.Printf("FALLA %s a las %s, tras %s cumpliéndose: %s\n", a.beat.name, now.Format("15:04:05"), now.Sub(a.since).Round(time.Second), detail)
+				return assertExitFailed
+			case now.Before(deadline):
+				// Todavía se espera a que se cumpla
+			default:
+				fmt.Printf("FALLA %s: %s no se cumplió en %s: %s\n", a.beat.name, opts.source, opts.wait, detail)
+				return assertExitFailed
+			}
+			if !a.passed {
+				pending++
+			}
+		}
+		if pending == 0 {
+			return 0
+		}
+		select {
+		case <-ctx.Done():
+			fmt.Fprintln(os.Stderr, "Interrumpido antes de completar la ventana")
+			return assertExitFailed
+		case <-ticker.C:
+		}
+	}
+}
+
+// check toma una muestra del beat y evalúa la condición; detail describe
+// el error o los valores de las métricas de la condición. La primera
+// muestra no alcanza para rate() y delta(), pero sirve para comprobar antes
+// de empezar que el beat publica todas las métricas de la condición.
+func (a *assertion) check(ctx context.Context, opts assertOptions) (assertResult, string) {
+	stats, err := benchSample(ctx, a.beat.source)
+	if err != nil {
+		return assertDown, "error consultando Filebeat: " + err.Error()
+	}
+	a.beat.history.Add(stats)
+	env := metrics.NewEnv(a.beat.history)
+	computed := metrics.EvaluateComputed(env, opts.computed)
+	if a.beat.history.Len() < 2 {
+		for _, path := range opts.condition.Metrics() {
+			if _, found := env.Metric(path); found {
+				continue
+			}
+			for _, value := range computed {
+				if value.Name == path && value.Err != nil {
+					return assertError, path + ": " + value.Err.Error()
+				}
+			}
+			return assertError, "métrica desconocida: " + path
+		}
+		if _, err := opts.condition.Eval(env); err != nil {
+			return assertError, err.Error()
+		}
+		return assertPending, ""
+	}
+	ok, err := opts.condition.Bool(env)
+	if err != nil {
+		return assertError, err.Error()
+	}
+	var values []string
+	for _, path := range opts.condition.Metrics() {
+		if v, found := env.Metric(path); found {
+			values = append(values, fmt.Sprintf("%s=%.6g", path, v))
+		}
+	}
+	detail := strings.Join(values, " ")
+	if !ok {
+		if detail == "" {
+			return assertFalse, "la condición es falsa"
+		}
+		return assertFalse, "la condición es falsa con " + detail
+	}
+	return assertTrue, detail
+}
@@ -0,0 +1,98 @@
+// Package baseline guarda los valores de un período "conocido bueno" para
+// compararlos después de un cambio de configuración o una actualización de
+// Filebeat.
+package baseline
+
+import (
+	"encoding/json"
+	"math"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Baseline es el promedio de cada valor durante la captura. Las claves son
+// las de la interfaz, p. ej. cpu_percent o input.nginx-access.
+type Baseline struct {
+	CapturedAt time.Time `json:"captured_at"`
+	// Version es la de Filebeat durante la captura
+	Version string             `json:"version"`
+	Samples int                `json:"samples"`
+	Values  map[string]float64 `json:"values"`
+}
+
+// Load lee una línea base guardada con Save
+func Load(path string) (*Baseline, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	b := &Baseline{}
+	if err := json.Unmarshal(data, b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// Save escribe la línea base en path, creando su directorio. Se escribe en
+// un archivo temporal para no dejar una línea base a medias.
+func (b *Baseline) Save(path string) error {
+	data, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// Value devuelve el valor de key en la línea base
+func (b *Baseline) Value(key string) (float64, bool) {
+	v, ok := b.Values[key]
+	return v, ok
+}
+
+// Recorder promedia los valores de cada muestra durante una captura
+type Recorder struct {
+	sums    map[string]float64
+	counts  map[string]int
+	samples int
+}
+
+func NewRecorder() *Recorder {
+	return &Recorder{sums: make(map[string]float64), counts: make(map[string]int)}
+}
+
+// Add suma los valores de una muestra; los NaN (sin dato) se ignoran
+func (r *Recorder) Add(values map[string]float64) {
+	r.samples++
+	for key, v := range values {
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			continue
+		}
+		r.sums[key] += v
+		r.counts[key]++
+	}
+}
+
+// Samples devuelve cuántas muestras se sumaron
+func (r *Recorder) Samples() int { return r.samples }
+
+// Baseline devuelve el promedio de lo capturado
+func (r *Recorder) Baseline(version string, now time.Time) *Baseline {
+	b := &Baseline{
+		CapturedAt: now,
+		Version:    version,
+		Samples:    r.samples,
+		Values:     make(map[string]float64, len(r.sums)),
+	}
+	for key, sum := range r.sums {
+		b.Values[key] = sum / float64(r.counts[key])
+	}
+	return b
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/iTiagoCO/filtop/filtop/client"
+	"github.com/iTiagoCO/filtop/filtop/metrics"
+)
+
+// filtop bench registra una prueba de carga durante -duration: el caudal de
+// eventos y bytes que confirma la salida, el llenado de la cola y los
+// descartes en cada intervalo, y al terminar (o con Ctrl-C) imprime un
+// resumen con percentiles, para comparar configuraciones de bulk_max_size
+// y worker con la misma carga. Con targets se miden todos a la vez y se
+// imprime un resumen por beat.
+
+// benchProgress es cada cuánto se informa el avance en stderr
+const benchProgress = time.Minute
+
+// runBench ejecuta filtop bench y termina con su código de salida
+func runBench(args []string) {
+	fs := newFlagSet("bench", "filtop bench [flags]")
+	flags := &beatFlags{}
+	flags.register(fs, "bench")
+	duration := fs.Duration("duration", 5*time.Minute, "Duración de la prueba de carga")
+	flags.parse(fs, args)
+
+	s := newSession("bench", flags)
+	os.Exit(benchBeats(s.ctx, s.beats, *duration))
+}
+
+// benchBeats mide los beats a la vez hasta que pasa duration o se cancela
+// ctx, imprime sus resúmenes en stdout y devuelve el código de salida del
+// programa: 1 si alguno no tuvo muestras suficientes.
+func benchBeats(ctx context.Context, beats []*beat, duration time.Duration) int {
+	sums := make([]metrics.BenchSummary, len(beats))
+	var wg sync.WaitGroup
+	for i, b := range beats {
+		i, b := i, b
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sums[i] = benchBeat(ctx, b, duration)
+		}()
+	}
+	wg.Wait()
+
+	code, printed := 0, false
+	for i, b := range beats {
+		if sums[i].Intervals == 0 {
+			fmt.Fprintf(os.Stderr, "%s: sin muestras suficientes para el resumen, hacen falta al menos dos\n", b.name)
+			code = 1
+			continue
+		}
+		if printed {
+			fmt.Fprintln(os.Stdout)
+		}
+		printBenchSummary(os.Stdout, b.name, sums[i])
+		printed = true
+	}
+	return code
+}
+
+// benchBeat mide el beat hasta que pasa duration o se cancela ctx y
+// devuelve el resumen
+func benchBeat(ctx context.Context, b *beat, duration time.Duration) metrics.BenchSummary {
+	bench := &metrics.Bench{}
+	fmt.Fprintf(os.Stderr, "Midiendo %s durante %s cada %s; Ctrl-C termina antes\n", b.name, duration, refresh)
+	start := time.Now()
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+	ticker := time.NewTicker(refresh)
+	defer ticker.Stop()
+
+	progress := start
+	for finished := false; !finished; {
+		stats, err := benchSample(ctx, b.source)
+		switch {
+		case ctx.Err() != nil:
+			// Interrumpido: se resume lo medido hasta ahora
+		case err != nil:
+			bench.Failed()
+			slog.Warn("Error consultando Filebeat", "target", b.name, "err", err)
+		default:
+			bench.Add(stats)
+		}
+		if time.Since(progress) >= benchProgress {
+			progress = time.Now()
+			sum := bench.Summary()
+			fmt.Fprintf(os.Stderr, "%s: %s de %s: %s ev/s, %s/s (promedio)\n", b.name, time.Since(start).Round(time.Second), duration, humanCount(sum.Events.Mean), humanBytes(sum.ByteRate.Mean))
+		}
+		select {
+		case <-ctx.Done():
+			finished = true
+		case <-timer.C:
+			// Una última muestra para cubrir la duración completa
+			if stats, err := benchSample(ctx, b.source); err == nil {
+				bench.Add(stats)
+			}
+			finished = true
+		case <-ticker.C:
+		}
+	}
+
+	return bench.Summary()
+}
+
+// benchSample consulta el beat como filtop watch
+func benchSample(ctx context.Context, source *client.Client) (*client.FilebeatStats, error) {
+	if source.Info() == nil {
+		if err := source.DetectVersion(ctx); err != nil {
+			return nil, err
+		}
+	}
+	stats, _, err := source.Fetch(ctx)
+	if err != nil {
+		// Filebeat pudo reiniciarse con otra versión: se vuelve a detectar
+		source.Reset()
+		return nil, err
+	}
+	source.Normalize(stats)
+	return stats, nil
+}
+
+// printBenchSummary imprime el resumen de la prueba: una tabla de
+// percentiles y los totales, con una conclusión para ajustar la salida
+func printBenchSummary(out io.Writer, name string, sum metrics.BenchSummary) {
+	elapsed := sum.Last.Sub(sum.First).Round(time.Second)
+	fmt.Fprintf(out, "filtop bench: %s, %s en %d intervalos\n\n", name, elapsed, sum.Intervals)
+
+	w := tabwriter.NewWriter(out, 0, 0, 3, ' ', tabwriter.AlignRight)
+	// Las etiquetas con el mismo ancho quedan alineadas a la izquierda
+	fmt.Fprintf(w, "%-14s\tp50\tp90\tp95\tp99\tmáx\tpromedio\t\n", "")
+	row := func(label string, p metrics.Percentiles, format func(float64) string) {
+		fmt.Fprintf(w, "%-14s\t%s\t%s\t%s\t%s\t%s\t%s\t\n", label, format(p.P50), format(p.P90), format(p.P95), format(p.P99), format(p.Max), format(p.Mean))
+	}
+	row("Eventos/s", sum.Events, humanCount)
+	row("Bytes/s", sum.ByteRate, humanBytes)
+	row("Cola (eventos)", sum.Queue, func(v float64) string { return fmt.Sprintf("%.0f", v) })
+	w.Flush()
+	fmt.Fprintln(out)
+
+	percent := func(n int) float64 { return float64(n) / float64(sum.Intervals) * 100 }
+	fmt.Fprintf(out, "Confirmados:    %d eventos (%s)\n", sum.Acked, humanBytes(float64(sum.Bytes)))
+	fmt.Fprintf(out, "Fallidos:       %d eventos\n", sum.Failed)
+	fmt.Fprintf(out, "Descartados:    %d eventos en %d intervalos (%.0f%%)\n", sum.Dropped, sum.DropIntervals, percent(sum.DropIntervals))
+	if sum.QueueMax > 0 {
+		fmt.Fprintf(out, "Cola llena:     %d intervalos (%.0f%%) a partir del %d%% de %d eventos\n", sum.QueueFull, percent(sum.QueueFull), metrics.QueueFullPercent, sum.QueueMax)
+	}
+	if sum.Batch > 0 {
+		fmt.Fprintf(out, "Lote promedio:  %.0f eventos\n", sum.Batch)
+	}
+	fmt.Fprintf(out, "Reinicios:      %d · consultas fallidas: %d\n", sum.Restarts, sum.Errors)
+
+	if conclusion := benchConclusion(sum); conclusion != "" {
+		fmt.Fprintln(out)
+		fmt.Fprintln(out, conclusion)
+	}
+}
+
+// benchConclusion interpreta el resumen: si la cola estuvo llena el límite
+// es la salida; si no, está antes, en la lectura o los processors
+func benchConclusion(sum metrics.BenchSummary) string {
+	switch {
+	case sum.QueueMax == 0:
+		return ""
+	case sum.QueueFull*10 >= sum.Intervals:
+		return "La cola estuvo llena al menos el 10% del tiempo: la salida es el cuello de botella. Más worker o un bulk_max_size mayor pueden aumentar el caudal si el destino lo soporta."
+	case sum.Queue.P95 < float64(sum.QueueMax)/2:
+		return "La cola no pasó de la mitad: la salida sigue el ritmo y el límite está en la lectura o los processors, no en bulk_max_size ni worker."
+	}
+	return ""
+}
+
+// humanCount formatea una cantidad, p. ej. 950 o 12.3k
+func humanCount(v float64) string {
+	switch {
+	case v >= 1e6:
+		return fmt.Sprintf("%.1fM", v/1e6)
+	case v >= 1e3:
+		return fmt.Sprintf("%.1fk", v/1e3)
+	}
+	return fmt.Sprintf("%.0f", v)
+}
+
+// humanBytes formatea bytes, p. ej. 1.5 MiB
+func humanBytes(v float64) string {
+	const unit = 1024
+	if v < unit {
+		return fmt.Sprintf("%.0f B", v)
+	}
+	exp := 0
+	for v >= unit*unit && exp < 5 {
+		v /= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", v/unit, "KMGTPE"[exp])
+}
@@ -0,0 +1,87 @@
+package client
+
+import (
+	"context"
+	"time"
+)
+
+// Cada cuánto se vuelve a sondear un endpoint que respondió 404
+const probeInterval = time.Minute
+
+// endpointState lleva la disponibilidad de un endpoint secundario (/inputs/,
+// /state, /dataset). Si falla se marca como no disponible con el motivo; los
+// 404 no se vuelven a consultar hasta el siguiente sondeo.
+type endpointState struct {
+	path        string
+	unavailable bool
+	reason      string
+	nextProbe   time.Time
+	lastFetch   time.Time
+}
+
+// due indica si el endpoint se debe consultar en este ciclo. Mientras esté
+// disponible se consulta cada interval; se tolera un 10% de adelanto para
+// no saltear un ciclo por la demora de la consulta anterior.
+func (e *endpointState) due(now time.Time, interval time.Duration) bool {
+	if e.unavailable {
+		return !now.Before(e.nextProbe)
+	}
+	return now.Sub(e.lastFetch) >= interval-interval/10
+}
+
+// update registra el resultado de una consulta. Devuelve el error solo
+// cuando el endpoint deja de estar disponible o cambia el motivo, para no
+// repetirlo en cada ciclo.
+func (e *endpointState) update(err error, now time.Time) error {
+	e.lastFetch = now
+	if err == nil {
+		e.unavailable = false
+		e.reason = ""
+		return nil
+	}
+
+	changed := !e.unavailable || e.reason != err.Error()
+	e.unavailable = true
+	e.reason = err.Error()
+	e.nextProbe = now
+	if _, ok := err.(NotFoundError); ok {
+		e.nextProbe = now.Add(probeInterval)
+	}
+	if !changed {
+		return nil
+	}
+	return err
+}
+
+// InputsStatus indica si /inputs/ está disponible y, si no, por qué
+func (c *Client) InputsStatus() (available bool, reason string) {
+	return !c.inputsEndpoint.unavailable, c.inputsEndpoint.reason
+}
+
+// Probe comprueba qué endpoints secundarios ofrece este Filebeat, para no
+// esperar al primer ciclo ni registrar errores en cada uno.
+func (c *Client) Probe(ctx context.Context) {
+	// Las respuestas se conservan como las de un ciclo normal
+	now := time.Now()
+	var raw []map[string]interface{}
+	err := c.get(ctx, c.schema.InputsPath(), &raw)
+	if err == nil {
+		c.inputs, c.inputsAt = c.schema.NormalizeInputs(raw), now
+	}
+	c.inputsEndpoint.update(err, now)
+	if !c.StateEnabled {
+		return
+	}
+	var state BeatState
+	err = c.get(ctx, c.statusEndpoint.path, &state)
+	if err == nil {
+		c.state = &state
+	}
+	c.statusEndpoint.update(err, now)
+	var dataset interface{}
+	err = c.get(ctx, c.datasetEndpoint.path, &dataset)
+	if err == nil {
+		c.inputStates = parseInputStates(dataset)
+	}
+	c.datasetEndpoint.update(err, now)
+}
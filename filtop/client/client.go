@@ -0,0 +1,283 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Estructuras de datos mejoradas para mapear correctamente la respuesta JSON
+type FilebeatStats struct {
+	Timestamp time.Time `json:"timestamp"`
+	Beat      struct {
+		CPU struct {
+			System struct {
+				Ticks uint64 `json:"ticks"`
+				Time  struct {
+					MS uint64 `json:"ms"`
+				} `json:"time"`
+			} `json:"system"`
+			Total struct {
+				Ticks uint64 `json:"ticks"`
+				Time  struct {
+					MS uint64 `json:"ms"`
+				} `json:"time"`
+				Value uint64 `json:"value"`
+			} `json:"total"`
+			User struct {
+				Ticks uint64 `json:"ticks"`
+				Time  struct {
+					MS uint64 `json:"ms"`
+				} `json:"time"`
+			} `json:"user"`
+		} `json:"cpu"`
+		Memstats struct {
+			MemoryAlloc uint64 `json:"memory_alloc"`
+			RSS         uint64 `json:"rss"`
+		} `json:"memstats"`
+		Info struct {
+			Uptime struct {
+				MS uint64 `json:"ms"`
+			} `json:"uptime"`
+			Version string `json:"version"`
+		} `json:"info"`
+	} `json:"beat"`
+	Libbeat struct {
+		Pipeline struct {
+			Queue struct {
+				Filled struct {
+					Events uint64 `json:"events"`
+				} `json:"filled"`
+				MaxEvents uint64 `json:"max_events"`
+			} `json:"queue"`
+			Events struct {
+				Active   uint64 `json:"active"`
+				Total    uint64 `json:"total"`
+				Dropped  uint64 `json:"dropped"`
+				Failed   uint64 `json:"failed"`
+				Filtered uint64 `json:"filtered"`
+			} `json:"events"`
+		} `json:"pipeline"`
+	} `json:"libbeat"`
+	Filebeat struct {
+		Harvester struct {
+			Running    uint64 `json:"running"`
+			Open       uint64 `json:"open_files"`
+			Closed     uint64 `json:"closed"`
+			Started    uint64 `json:"started"`
+			Terminated uint64 `json:"skipped"`
+		} `json:"harvester"`
+		Inputs  []Input `json:"inputs"`
+		Modules struct {
+			List []Module `json:"list"`
+		} `json:"modules"`
+	} `json:"filebeat"`
+	System struct {
+		Load struct {
+			Norm struct {
+				Load1  float64 `json:"1"`
+				Load5  float64 `json:"5"`
+				Load15 float64 `json:"15"`
+			} `json:"norm"`
+		} `json:"load"`
+	} `json:"system"`
+	// Raw es el documento /stats completo, para consultar rutas arbitrarias
+	Raw map[string]interface{} `json:"-"`
+	// FetchDuration es lo que tardó la consulta de /stats e /inputs/
+	FetchDuration time.Duration `json:"-"`
+	// InputsError es el motivo por el que /inputs/ no está disponible
+	InputsError string `json:"-"`
+	// InputsAt es cuándo se obtuvieron los inputs. Si /inputs/ no tocaba en
+	// este ciclo se repiten los anteriores y es anterior a Timestamp.
+	InputsAt time.Time `json:"-"`
+}
+
+type Module struct {
+	Name    string `json:"name"`
+	Enabled bool   `json:"enabled"`
+	Errors  int    `json:"errors"`
+}
+
+type Input struct {
+	ID            string `json:"id"`
+	Type          string `json:"input"`
+	Device        string `json:"device"`
+	Packets       uint64 `json:"packets"`
+	Bytes         uint64 `json:"bytes"`
+	Events        uint64 `json:"events"`
+	Active        bool   `json:"active"`
+	ArrivalPeriod struct {
+		Histogram map[string]interface{} `json:"histogram"`
+	} `json:"arrival_period"`
+	ProcessingTime struct {
+		Histogram map[string]interface{} `json:"histogram"`
+	} `json:"processing_time"`
+	Throughput struct {
+		Bytes  float64 `json:"bytes"`
+		Events float64 `json:"events"`
+	} `json:"throughput"`
+	Files       uint64 `json:"files"`
+	FilesOpened uint64 `json:"files_opened"`
+	FilesClosed uint64 `json:"files_closed"`
+	Errors      uint64 `json:"errors"`
+	// Dropped son los eventos que el input descartó antes de procesarlos,
+	// p. ej. los paquetes que el sistema descartó en un input UDP
+	Dropped uint64 `json:"dropped"`
+	// Estado opcional obtenido de /dataset
+	State *InputState `json:"-"`
+}
+
+// Client consulta un beat concreto. Recuerda la versión detectada para
+// aplicar el esquema correcto en cada muestra.
+type Client struct {
+	BaseURL string
+	HTTP    *http.Client
+	// StateEnabled activa los endpoints opcionales /state y /dataset
+	StateEnabled bool
+	// Retries es la cantidad de reintentos ante fallos transitorios
+	Retries int
+	// InputsInterval y StateInterval espacian las consultas de /inputs/ y de
+	// /state y /dataset; con 0 se consultan en cada ciclo
+	InputsInterval time.Duration
+	StateInterval  time.Duration
+
+	info   *BeatInfo
+	schema Schema
+	state  *BeatState
+	// Últimos inputs y estados de /dataset, que se repiten mientras no
+	// toque volver a consultarlos
+	inputs          []Input
+	inputsAt        time.Time
+	inputStates     map[string]InputState
+	inputsEndpoint  endpointState
+	statusEndpoint  endpointState
+	datasetEndpoint endpointState
+}
+
+// New crea un cliente para la API de Filebeat en baseURL (http://host:puerto)
+func New(baseURL string) *Client {
+	return &Client{
+		BaseURL:         baseURL,
+		HTTP:            NewHTTPClient(DefaultHTTPOptions),
+		schema:          SchemaForVersion(""),
+		statusEndpoint:  endpointState{path: "/state"},
+		datasetEndpoint: endpointState{path: "/dataset"},
+	}
+}
+
+// Info devuelve la identidad del beat detectada, o nil si aún no se detectó
+func (c *Client) Info() *BeatInfo { return c.info }
+
+// Schema devuelve el esquema en uso según la versión detectada
+func (c *Client) Schema() Schema { return c.schema }
+
+// State devuelve la última respuesta de /state, o nil si no está disponible
+func (c *Client) State() *BeatState { return c.state }
+
+// DetectVersion consulta el endpoint raíz (/) y elige el esquema de métricas
+func (c *Client) DetectVersion(ctx context.Context) error {
+	var info BeatInfo
+	if err := c.get(ctx, "/", &info); err != nil {
+		return err
+	}
+	c.info = &info
+	c.setSchema(SchemaForVersion(info.Version))
+	return nil
+}
+
+// Reset olvida la versión detectada; tras un corte Filebeat pudo reiniciarse
+// con otra versión.
+func (c *Client) Reset() { c.info = nil }
+
+// Stats obtiene /stats. Si la versión no se pudo detectar por el endpoint
+// raíz se usa beat.info.version de la propia respuesta.
+func (c *Client) Stats(ctx context.Context) (*FilebeatStats, error) {
+	stats, err := c.fetchStats(ctx)
+	if err != nil {
+		return nil, err
+	}
+	c.adoptVersion(stats)
+	return stats, nil
+}
+
+// Inputs obtiene las métricas por input en el formato de la versión detectada
+func (c *Client) Inputs(ctx context.Context) ([]Input, error) {
+	return c.fetchInputs(ctx, c.schema)
+}
+
+func (c *Client) fetchStats(ctx context.Context) (*FilebeatStats, error) {
+	var body json.RawMessage
+	if err := c.get(ctx, "/stats", &body); err != nil {
+		return nil, err
+	}
+	var stats FilebeatStats
+	if err := json.Unmarshal(body, &stats); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(body, &stats.Raw); err != nil {
+		return nil, err
+	}
+	stats.Timestamp = time.Now()
+	return &stats, nil
+}
+
+func (c *Client) fetchInputs(ctx context.Context, schema Schema) ([]Input, error) {
+	// Los nombres de los campos cambian entre versiones, se decodifica en crudo
+	var raw []map[string]interface{}
+	if err := c.get(ctx, schema.InputsPath(), &raw); err != nil {
+		return nil, err
+	}
+	return schema.NormalizeInputs(raw), nil
+}
+
+func (c *Client) adoptVersion(stats *FilebeatStats) {
+	if c.info == nil && stats.Beat.Info.Version != "" {
+		c.setSchema(SchemaForVersion(stats.Beat.Info.Version))
+	}
+}
+
+func (c *Client) setSchema(schema Schema) {
+	if schema.InputsPath() != c.schema.InputsPath() {
+		// Otra versión publica los inputs en otra ruta
+		c.inputsEndpoint = endpointState{}
+		c.inputs = nil
+	}
+	c.schema = schema
+}
+
+// Normalize completa los campos de stats que dependen de la versión
+func (c *Client) Normalize(stats *FilebeatStats) {
+	c.schema.NormalizeStats(stats)
+}
+
+func getJSON(ctx context.Context, client *http.Client, url string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return NotFoundError{URL: url}
+	}
+	if resp.StatusCode != http.StatusOK {
+		return StatusError{Code: resp.StatusCode}
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// StatusError es una respuesta HTTP distinta de 200 y 404
+type StatusError struct{ Code int }
+
+func (e StatusError) Error() string { return fmt.Sprintf("error: código de estado %d", e.Code) }
+
+// NotFoundError indica que el endpoint no existe en esta versión de Filebeat
+type NotFoundError struct{ URL string }
+
+func (e NotFoundError) Error() string { return fmt.Sprintf("%s no disponible (404)", e.URL) }
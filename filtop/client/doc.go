@@ -0,0 +1,35 @@
+// Package client consulta la API HTTP de monitoreo de Filebeat (http.enabled
+// en filebeat.yml) y normaliza sus métricas entre versiones mayores, para
+// usarla desde otras herramientas sin la interfaz de filtop:
+//
+//	import "github.com/iTiagoCO/filtop/filtop/client"
+//
+//	c := client.New("http://localhost:5066")
+//	if err := c.DetectVersion(ctx); err != nil {
+//		return err
+//	}
+//	stats, inputsErr, err := c.Fetch(ctx)
+//
+// API pública:
+//
+//   - New crea un Client para la URL base de Filebeat; SocketURL e IsSocket
+//     la arman para un socket Unix o un named pipe de Windows.
+//   - DetectVersion consulta / y elige el Schema de la versión mayor (7.x,
+//     8.x o 9.x); Info y Schema devuelven lo detectado y Reset lo olvida, p.
+//     ej. tras un reinicio de Filebeat.
+//   - Fetch consulta /stats e /inputs/ en paralelo y normaliza el resultado
+//     en un FilebeatStats; Stats e Inputs consultan cada endpoint por
+//     separado. Si /inputs/ falla, Fetch devuelve igual las métricas y el
+//     error aparte; InputsStatus indica si el endpoint está disponible.
+//   - Con StateEnabled, AttachState agrega /state y /dataset (BeatState e
+//     InputState).
+//   - NewHTTPClient y HTTPOptions arman otro cliente HTTP para el campo HTTP,
+//     con timeouts, keep-alive, TLS y credenciales propios; Retries
+//     reintenta los fallos transitorios.
+//   - FetchJSON y Lookup leen un endpoint JSON cualquiera por ruta,
+//     FetchExpvar el documento de expvar y FetchPprof el resumen de pprof;
+//     TailErrorLines lee los errores del log de Filebeat.
+//
+// Los errores HTTP son StatusError o, para un 404, NotFoundError. Client no
+// es seguro para usarlo desde varias goroutines a la vez.
+package client
@@ -0,0 +1,47 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+// FetchExpvar obtiene la salida expvar (/debug/vars) de un proceso Go y
+// anida las claves con puntos para poder recorrerla como árbol.
+func FetchExpvar(ctx context.Context, client *http.Client, url string) (map[string]interface{}, error) {
+	var doc map[string]interface{}
+	if err := getJSON(ctx, client, url, &doc); err != nil {
+		return nil, err
+	}
+	return nestDottedKeys(doc), nil
+}
+
+// nestDottedKeys convierte claves planas como "libbeat.pipeline.events.total",
+// que es como los beats publican su registro en expvar, en un árbol.
+func nestDottedKeys(doc map[string]interface{}) map[string]interface{} {
+	result := make(map[string]interface{})
+	for key, value := range doc {
+		if m, ok := value.(map[string]interface{}); ok {
+			value = nestDottedKeys(m)
+		}
+
+		parts := strings.Split(key, ".")
+		node := result
+		for _, part := range parts[:len(parts)-1] {
+			child, ok := node[part].(map[string]interface{})
+			if !ok {
+				if _, exists := node[part]; exists {
+					// Colisión con una hoja: se conserva la clave sin dividir
+					node = result
+					parts = []string{key}
+					break
+				}
+				child = make(map[string]interface{})
+				node[part] = child
+			}
+			node = child
+		}
+		node[parts[len(parts)-1]] = value
+	}
+	return result
+}
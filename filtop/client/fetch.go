@@ -0,0 +1,60 @@
+package client
+
+import (
+	"context"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// Fetch consulta /stats e /inputs/ en paralelo con un mismo plazo, de modo
+// que ambas respuestas correspondan al mismo instante. Un error en /inputs/
+// no invalida la muestra: queda en stats.InputsError y se devuelve en
+// inputsErr solo cuando el endpoint deja de estar disponible. Si todavía no
+// pasó InputsInterval desde la última consulta de /inputs/ se repiten los
+// inputs anteriores.
+func (c *Client) Fetch(ctx context.Context) (stats *FilebeatStats, inputsErr error, err error) {
+	if timeout := c.timeout(); timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	// Las dos consultas usan el esquema vigente; si /stats revela otra
+	// versión el cambio se aplica al terminar
+	schema := c.schema
+	start := time.Now()
+	var inputs []Input
+	g, ctx := errgroup.WithContext(ctx)
+	g.Go(func() error {
+		var err error
+		stats, err = c.fetchStats(ctx)
+		return err
+	})
+	fetchInputs := c.inputsEndpoint.due(start, c.InputsInterval)
+	var rawInputsErr error
+	if fetchInputs {
+		g.Go(func() error {
+			inputs, rawInputsErr = c.fetchInputs(ctx, schema)
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, nil, err
+	}
+
+	c.adoptVersion(stats)
+	stats.Timestamp = start
+	stats.FetchDuration = time.Since(start)
+	if fetchInputs {
+		inputsErr = c.inputsEndpoint.update(rawInputsErr, start)
+		c.inputs, c.inputsAt = inputs, start
+	}
+	if !c.inputsEndpoint.unavailable {
+		// Copia: AttachState completa el estado de cada input de la muestra
+		stats.Filebeat.Inputs = append([]Input(nil), c.inputs...)
+		stats.InputsAt = c.inputsAt
+	}
+	stats.InputsError = c.inputsEndpoint.reason
+	return stats, inputsErr, nil
+}
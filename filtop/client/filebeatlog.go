@@ -0,0 +1,114 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"strings"
+)
+
+// Bytes leídos desde el final del log de Filebeat al buscar errores
+const logTailBytes = 512 * 1024
+
+// LogEntry es una línea del log de Filebeat
+type LogEntry struct {
+	Time    string
+	Level   string
+	Logger  string
+	Message string
+}
+
+// TailErrorLines devuelve las últimas líneas de nivel error/warn del log de
+// Filebeat que mencionan alguna de las palabras clave. Soporta el formato
+// ndjson de 8.x/9.x y el formato tabulado de 7.x.
+func TailErrorLines(path string, keywords []string, max int) ([]LogEntry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	offset := info.Size() - logTailBytes
+	if offset < 0 {
+		offset = 0
+	}
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return nil, err
+	}
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return nil, err
+	}
+	if offset > 0 {
+		// Descarta la primera línea, probablemente cortada
+		if i := bytes.IndexByte(data, '\n'); i >= 0 {
+			data = data[i+1:]
+		}
+	}
+
+	var entries []LogEntry
+	for _, line := range strings.Split(string(data), "\n") {
+		entry, ok := parseLogLine(line)
+		if !ok || (entry.Level != "error" && entry.Level != "warn") {
+			continue
+		}
+		if !containsAny(strings.ToLower(line), keywords) {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	if len(entries) > max {
+		entries = entries[len(entries)-max:]
+	}
+	return entries, nil
+}
+
+func parseLogLine(line string) (LogEntry, bool) {
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return LogEntry{}, false
+	}
+
+	if strings.HasPrefix(line, "{") {
+		var doc map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &doc); err != nil {
+			return LogEntry{}, false
+		}
+		entry := LogEntry{
+			Time:    stringField(doc, "@timestamp"),
+			Level:   strings.ToLower(stringField(doc, "log.level")),
+			Logger:  stringField(doc, "log.logger"),
+			Message: stringField(doc, "message"),
+		}
+		return entry, true
+	}
+
+	// 7.x: <timestamp>\t<LEVEL>\t[<logger>]\t<origen>\t<mensaje>
+	fields := strings.SplitN(line, "\t", 5)
+	if len(fields) < 3 {
+		return LogEntry{}, false
+	}
+	entry := LogEntry{
+		Time:    fields[0],
+		Level:   strings.ToLower(fields[1]),
+		Message: fields[len(fields)-1],
+	}
+	if len(fields) >= 4 {
+		entry.Logger = strings.Trim(fields[2], "[]")
+	}
+	return entry, true
+}
+
+func containsAny(s string, keywords []string) bool {
+	for _, keyword := range keywords {
+		if keyword != "" && strings.Contains(s, strings.ToLower(keyword)) {
+			return true
+		}
+	}
+	return false
+}
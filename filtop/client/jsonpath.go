@@ -0,0 +1,69 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// FetchJSON obtiene y decodifica un documento JSON arbitrario
+func FetchJSON(ctx context.Context, client *http.Client, url string) (interface{}, error) {
+	var doc interface{}
+	if err := getJSON(ctx, client, url, &doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// Lookup evalúa una ruta estilo JSONPath sobre un documento decodificado.
+// Acepta "$.a.b[0].c" o simplemente "a.b[0].c"; no soporta comodines.
+func Lookup(doc interface{}, path string) (interface{}, bool) {
+	path = strings.TrimPrefix(strings.TrimPrefix(path, "$"), ".")
+	if path == "" {
+		return doc, true
+	}
+
+	current := doc
+	for _, segment := range strings.Split(path, ".") {
+		key, indexes, ok := splitIndexes(segment)
+		if !ok {
+			return nil, false
+		}
+		if key != "" {
+			m, ok := current.(map[string]interface{})
+			if !ok {
+				return nil, false
+			}
+			if current, ok = m[key]; !ok {
+				return nil, false
+			}
+		}
+		for _, i := range indexes {
+			list, ok := current.([]interface{})
+			if !ok || i < 0 || i >= len(list) {
+				return nil, false
+			}
+			current = list[i]
+		}
+	}
+	return current, true
+}
+
+// splitIndexes separa "items[2][0]" en "items" y [2 0]
+func splitIndexes(segment string) (string, []int, bool) {
+	key, rest, found := strings.Cut(segment, "[")
+	if !found {
+		return segment, nil, true
+	}
+
+	var indexes []int
+	for _, part := range strings.Split("["+rest, "[")[1:] {
+		n, err := strconv.Atoi(strings.TrimSuffix(part, "]"))
+		if err != nil || !strings.HasSuffix(part, "]") {
+			return "", nil, false
+		}
+		indexes = append(indexes, n)
+	}
+	return key, indexes, true
+}
@@ -0,0 +1,14 @@
+//go:build !windows
+
+package client
+
+import (
+	"context"
+	"errors"
+	"net"
+)
+
+// dialPipe falla fuera de Windows: los named pipes no existen
+func dialPipe(context.Context, string) (net.Conn, error) {
+	return nil, errors.New("los named pipes (npipe://) solo existen en Windows")
+}
@@ -0,0 +1,73 @@
+//go:build windows
+
+package client
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"syscall"
+	"time"
+)
+
+// errPipeBusy (ERROR_PIPE_BUSY) indica que todas las instancias del named
+// pipe están atendiendo a otro cliente
+const errPipeBusy syscall.Errno = 231
+
+// Espera entre intentos mientras el named pipe está ocupado
+const pipeBusyRetry = 50 * time.Millisecond
+
+// dialPipe abre el named pipe path como una conexión
+func dialPipe(ctx context.Context, path string) (net.Conn, error) {
+	for {
+		file, err := os.OpenFile(path, os.O_RDWR, 0)
+		if err == nil {
+			return &pipeConn{File: file, addr: pipeAddr(path)}, nil
+		}
+		if !errors.Is(err, errPipeBusy) {
+			return nil, err
+		}
+		timer := time.NewTimer(pipeBusyRetry)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// pipeConn es un named pipe abierto. El archivo no admite plazos: el
+// timeout de la consulta la corta cerrando la conexión.
+type pipeConn struct {
+	*os.File
+	addr pipeAddr
+}
+
+func (c *pipeConn) LocalAddr() net.Addr  { return c.addr }
+func (c *pipeConn) RemoteAddr() net.Addr { return c.addr }
+
+func (c *pipeConn) SetDeadline(t time.Time) error {
+	return ignoreNoDeadline(c.File.SetDeadline(t))
+}
+
+func (c *pipeConn) SetReadDeadline(t time.Time) error {
+	return ignoreNoDeadline(c.File.SetReadDeadline(t))
+}
+
+func (c *pipeConn) SetWriteDeadline(t time.Time) error {
+	return ignoreNoDeadline(c.File.SetWriteDeadline(t))
+}
+
+func ignoreNoDeadline(err error) error {
+	if errors.Is(err, os.ErrNoDeadline) {
+		return nil
+	}
+	return err
+}
+
+type pipeAddr string
+
+func (a pipeAddr) Network() string { return "pipe" }
+func (a pipeAddr) String() string  { return string(a) }
@@ -0,0 +1,78 @@
+package client
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// PprofEntry es un registro de un perfil pprof: número de objetos o
+// goroutines, bytes en uso (solo heap) y las funciones del stack.
+type PprofEntry struct {
+	Count int64
+	Bytes int64
+	Stack []string
+}
+
+// FetchPprof obtiene un perfil de /debug/pprof en formato de texto (debug=1)
+func FetchPprof(client *http.Client, url string) ([]PprofEntry, error) {
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("error: código de estado %d", resp.StatusCode)
+	}
+	return parsePprofText(resp.Body)
+}
+
+// parsePprofText interpreta el formato legible de pprof. Cada registro empieza
+// con "<count>: <bytes> [...] @ <pcs>" (heap) o "<count> @ <pcs>" (goroutine)
+// seguido de líneas "#\t<pc>\t<función>+<off>\t<archivo>:<línea>".
+func parsePprofText(r io.Reader) ([]PprofEntry, error) {
+	var entries []PprofEntry
+	var current *PprofEntry
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "heap profile:"), strings.HasPrefix(line, "goroutine profile:"):
+			continue
+		case strings.HasPrefix(line, "# runtime.MemStats"):
+			// Fin de los registros del heap
+			return entries, scanner.Err()
+		case strings.HasPrefix(line, "#\t"):
+			if current == nil {
+				continue
+			}
+			fields := strings.Fields(strings.TrimPrefix(line, "#\t"))
+			if len(fields) >= 2 {
+				fn := fields[1]
+				if i := strings.LastIndex(fn, "+0x"); i > 0 {
+					fn = fn[:i]
+				}
+				current.Stack = append(current.Stack, fn)
+			}
+		case strings.Contains(line, " @ "):
+			head, _, _ := strings.Cut(line, " @ ")
+			entry := PprofEntry{}
+			if countStr, bytesStr, ok := strings.Cut(head, ":"); ok {
+				entry.Count, _ = strconv.ParseInt(strings.TrimSpace(countStr), 10, 64)
+				bytesStr, _, _ = strings.Cut(strings.TrimSpace(bytesStr), " ")
+				entry.Bytes, _ = strconv.ParseInt(bytesStr, 10, 64)
+			} else {
+				entry.Count, _ = strconv.ParseInt(strings.TrimSpace(head), 10, 64)
+			}
+			entries = append(entries, entry)
+			current = &entries[len(entries)-1]
+		}
+	}
+	return entries, scanner.Err()
+}
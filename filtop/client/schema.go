@@ -0,0 +1,161 @@
+package client
+
+import (
+	"log/slog"
+	"strconv"
+	"strings"
+)
+
+// BeatInfo es la respuesta del endpoint raíz (/) de la API HTTP de Filebeat
+type BeatInfo struct {
+	Beat     string `json:"beat"`
+	Hostname string `json:"hostname"`
+	Name     string `json:"name"`
+	UUID     string `json:"uuid"`
+	Version  string `json:"version"`
+}
+
+// Schema traduce las métricas de una versión mayor de Filebeat al modelo
+// que usa filtop. Los nombres y rutas cambiaron entre 7.x, 8.x y 9.x
+// (harvesters del input log vs métricas de filestream en /inputs/).
+type Schema interface {
+	Name() string
+	InputsPath() string
+	NormalizeInputs(raw []map[string]interface{}) []Input
+	NormalizeStats(stats *FilebeatStats)
+}
+
+// SchemaForVersion elige el esquema para una versión como "8.12.1"
+func SchemaForVersion(version string) Schema {
+	switch majorVersion(version) {
+	case 7:
+		return schemaV7{}
+	case 9:
+		return schemaV9{}
+	case 8:
+		return schemaV8{}
+	default:
+		// 8.x es el formato más extendido y sirve de base para versiones desconocidas
+		if version != "" {
+			slog.Debug("Versión de Filebeat desconocida, se usa el esquema 8.x", "version", version)
+		}
+		return schemaV8{}
+	}
+}
+
+func majorVersion(version string) int {
+	major, _, _ := strings.Cut(version, ".")
+	n, err := strconv.Atoi(major)
+	if err != nil {
+		return 0
+	}
+	return n
+}
+
+// schemaV7: el input log reporta sus harvesters en filebeat.harvester y la
+// cola no expone filled/max_events, solo los eventos activos del pipeline.
+type schemaV7 struct{}
+
+func (schemaV7) Name() string       { return "7.x" }
+func (schemaV7) InputsPath() string { return "/inputs" }
+
+func (schemaV7) NormalizeInputs(raw []map[string]interface{}) []Input {
+	return normalizeInputList(raw)
+}
+
+func (schemaV7) NormalizeStats(stats *FilebeatStats) {
+	queue := &stats.Libbeat.Pipeline.Queue
+	if queue.MaxEvents == 0 && queue.Filled.Events == 0 {
+		queue.Filled.Events = stats.Libbeat.Pipeline.Events.Active
+	}
+}
+
+// schemaV8: filestream publica sus métricas por input en /inputs/ y deja
+// filebeat.harvester en cero cuando no hay inputs de tipo log.
+type schemaV8 struct{}
+
+func (schemaV8) Name() string       { return "8.x" }
+func (schemaV8) InputsPath() string { return "/inputs/" }
+
+func (schemaV8) NormalizeInputs(raw []map[string]interface{}) []Input {
+	return normalizeInputList(raw)
+}
+
+func (schemaV8) NormalizeStats(stats *FilebeatStats) {
+	harvester := &stats.Filebeat.Harvester
+	if harvester.Running > 0 || harvester.Open > 0 {
+		return
+	}
+	for _, input := range stats.Filebeat.Inputs {
+		harvester.Running += input.Files
+		harvester.Open += input.Files
+		harvester.Started += input.FilesOpened
+		harvester.Closed += input.FilesClosed
+	}
+}
+
+// schemaV9: el input log ya no existe, todo se reporta como en 8.x vía filestream
+type schemaV9 struct{ schemaV8 }
+
+func (schemaV9) Name() string { return "9.x" }
+
+func normalizeInputList(raw []map[string]interface{}) []Input {
+	inputs := make([]Input, 0, len(raw))
+	for _, m := range raw {
+		input := Input{
+			ID:          stringField(m, "id"),
+			Type:        stringField(m, "input"),
+			Device:      stringField(m, "device"),
+			Packets:     uintField(m, "packets", "messages_read_total", "messages_read"),
+			Bytes:       uintField(m, "bytes", "bytes_processed_total", "bytes_processed", "received_bytes_total"),
+			Events:      uintField(m, "events", "events_processed_total", "events_processed", "received_events_total"),
+			Files:       uintField(m, "files", "files_active"),
+			FilesOpened: uintField(m, "files_opened_total", "files_opened"),
+			FilesClosed: uintField(m, "files_closed_total", "files_closed"),
+			Errors:      uintField(m, "errors", "processing_errors_total", "processing_errors"),
+			Dropped:     uintField(m, "dropped", "system_packet_drops"),
+			Active:      true,
+		}
+		if input.ID == "" {
+			slog.Debug("Input sin id en la respuesta de Filebeat", "type", input.Type)
+		}
+		if active, ok := m["active"].(bool); ok {
+			input.Active = active
+		}
+		if input.Device == "" {
+			input.Device = stringField(m, "path")
+		}
+		input.ArrivalPeriod.Histogram = histogramField(m, "arrival_period")
+		input.ProcessingTime.Histogram = histogramField(m, "processing_time")
+		if throughput, ok := m["throughput"].(map[string]interface{}); ok {
+			input.Throughput.Bytes, _ = throughput["bytes"].(float64)
+			input.Throughput.Events, _ = throughput["events"].(float64)
+		}
+		inputs = append(inputs, input)
+	}
+	return inputs
+}
+
+func stringField(m map[string]interface{}, key string) string {
+	s, _ := m[key].(string)
+	return s
+}
+
+// uintField devuelve el primer campo numérico presente entre las claves dadas
+func uintField(m map[string]interface{}, keys ...string) uint64 {
+	for _, key := range keys {
+		if v, ok := m[key].(float64); ok {
+			return uint64(v)
+		}
+	}
+	return 0
+}
+
+func histogramField(m map[string]interface{}, key string) map[string]interface{} {
+	metric, ok := m[key].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	histogram, _ := metric["histogram"].(map[string]interface{})
+	return histogram
+}
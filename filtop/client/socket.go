@@ -0,0 +1,88 @@
+package client
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// Filebeat puede escuchar en un socket Unix o, en Windows, en un named pipe
+// en lugar de un puerto TCP (http.host: unix:///var/run/filebeat.sock o
+// npipe:///filebeat). SocketURL traduce esas direcciones a una URL base que
+// el transporte de NewHTTPClient sabe consultar: el esquema indica el tipo y
+// el host es la ruta codificada en hexadecimal, porque una URL no admite
+// barras en el host.
+
+const (
+	unixScheme  = "http+unix"
+	npipeScheme = "http+npipe"
+)
+
+// IsSocket indica si host es un socket Unix o un named pipe
+func IsSocket(host string) bool {
+	_, ok := SocketURL(host)
+	return ok
+}
+
+// SocketURL devuelve la URL base para consultar el socket o named pipe de
+// address, con la misma sintaxis que http.host de Filebeat; ok es false si
+// address no es ninguno de los dos
+func SocketURL(address string) (url string, ok bool) {
+	var scheme, path string
+	switch {
+	case strings.HasPrefix(address, "unix://"):
+		scheme, path = unixScheme, strings.TrimPrefix(address, "unix://")
+	case strings.HasPrefix(address, "npipe://"):
+		// Como Filebeat: npipe:///filebeat es \\.\pipe\filebeat
+		name := strings.TrimPrefix(strings.TrimPrefix(address, "npipe://"), "/")
+		scheme, path = npipeScheme, `\\.\pipe\`+strings.ReplaceAll(name, "/", `\`)
+	default:
+		return "", false
+	}
+	return scheme + "://" + hex.EncodeToString([]byte(path)), true
+}
+
+// registerSockets permite a transport consultar las URLs de SocketURL
+func registerSockets(transport *http.Transport) {
+	unix := socketTransport(transport, func(ctx context.Context, path string) (net.Conn, error) {
+		var dialer net.Dialer
+		return dialer.DialContext(ctx, "unix", path)
+	})
+	npipe := socketTransport(transport, dialPipe)
+	transport.RegisterProtocol(unixScheme, unix)
+	transport.RegisterProtocol(npipeScheme, npipe)
+}
+
+// socketTransport copia base para que cada conexión se abra con dial en la
+// ruta codificada en el host de la URL
+func socketTransport(base *http.Transport, dial func(ctx context.Context, path string) (net.Conn, error)) http.RoundTripper {
+	transport := base.Clone()
+	// Un proxy de HTTP_PROXY no llega al socket
+	transport.Proxy = nil
+	transport.DialContext = func(ctx context.Context, _, addr string) (net.Conn, error) {
+		host, _, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+		path, err := hex.DecodeString(host)
+		if err != nil {
+			return nil, errors.New("dirección de socket inválida")
+		}
+		return dial(ctx, string(path))
+	}
+	return roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		req = req.Clone(req.Context())
+		req.URL.Scheme = "http"
+		req.Host = "localhost"
+		return transport.RoundTrip(req)
+	})
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
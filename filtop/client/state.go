@@ -0,0 +1,132 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"time"
+)
+
+// BeatState es la respuesta de /state: qué inputs, módulos, cola y output
+// tiene configurados el beat.
+type BeatState struct {
+	Input struct {
+		Count int      `json:"count"`
+		Names []string `json:"names"`
+	} `json:"input"`
+	Module struct {
+		Count int      `json:"count"`
+		Names []string `json:"names"`
+	} `json:"module"`
+	Output struct {
+		Name string `json:"name"`
+	} `json:"output"`
+	Queue struct {
+		Name string `json:"name"`
+	} `json:"queue"`
+}
+
+// InputState es el estado interno de un input (cursor y último evento
+// publicado) que algunas versiones exponen en /dataset.
+type InputState struct {
+	ID            string
+	Cursor        string
+	Offset        uint64
+	LastPublished time.Time
+}
+
+// AttachState consulta /state y /dataset si están habilitados y asocia el
+// estado de cada input a los inputs de stats. Solo devuelve un error cuando
+// uno de ellos deja de estar disponible.
+func (c *Client) AttachState(ctx context.Context, stats *FilebeatStats) error {
+	if !c.StateEnabled {
+		return nil
+	}
+	now := time.Now()
+
+	var errs []error
+	if c.statusEndpoint.due(now, c.StateInterval) {
+		var state BeatState
+		err := c.get(ctx, c.statusEndpoint.path, &state)
+		if err == nil {
+			c.state = &state
+		}
+		if err := c.statusEndpoint.update(err, now); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if c.datasetEndpoint.due(now, c.StateInterval) {
+		var raw interface{}
+		err := c.get(ctx, c.datasetEndpoint.path, &raw)
+		c.inputStates = nil
+		if err == nil {
+			c.inputStates = parseInputStates(raw)
+		}
+		if err := c.datasetEndpoint.update(err, now); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	attachInputStates(stats, c.inputStates)
+	return errors.Join(errs...)
+}
+
+// parseInputStates acepta tanto una lista de objetos con "id" como un
+// objeto indexado por id.
+func parseInputStates(raw interface{}) map[string]InputState {
+	states := make(map[string]InputState)
+	add := func(id string, m map[string]interface{}) {
+		if id == "" {
+			id = stringField(m, "id")
+		}
+		if id == "" {
+			slog.Debug("Estado de input sin id en /dataset, se ignora")
+			return
+		}
+		state := InputState{ID: id, Offset: uintField(m, "offset")}
+		switch cursor := m["cursor"].(type) {
+		case string:
+			state.Cursor = cursor
+		case map[string]interface{}:
+			if b, err := json.Marshal(cursor); err == nil {
+				state.Cursor = string(b)
+			}
+			if state.Offset == 0 {
+				state.Offset = uintField(cursor, "offset")
+			}
+		}
+		for _, key := range []string{"last_event_time", "last_published", "timestamp"} {
+			if ts, err := time.Parse(time.RFC3339Nano, stringField(m, key)); err == nil {
+				state.LastPublished = ts
+				break
+			}
+		}
+		states[id] = state
+	}
+
+	switch v := raw.(type) {
+	case []interface{}:
+		for _, item := range v {
+			if m, ok := item.(map[string]interface{}); ok {
+				add("", m)
+			}
+		}
+	case map[string]interface{}:
+		for id, item := range v {
+			if m, ok := item.(map[string]interface{}); ok {
+				add(id, m)
+			}
+		}
+	}
+	return states
+}
+
+func attachInputStates(stats *FilebeatStats, states map[string]InputState) {
+	for i := range stats.Filebeat.Inputs {
+		if state, ok := states[stats.Filebeat.Inputs[i].ID]; ok {
+			state := state
+			stats.Filebeat.Inputs[i].State = &state
+		}
+	}
+}
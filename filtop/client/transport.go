@@ -0,0 +1,125 @@
+package client
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+)
+
+// HTTPOptions ajusta el cliente HTTP para enlaces lentos o hosts cargados
+type HTTPOptions struct {
+	// Timeout de cada consulta
+	Timeout time.Duration
+	// DisableKeepAlives abre una conexión nueva por consulta
+	DisableKeepAlives bool
+	// IdleConnTimeout es cuánto se conserva una conexión ociosa para reusarla
+	IdleConnTimeout time.Duration
+	// MaxIdleConnsPerHost limita las conexiones ociosas por host
+	MaxIdleConnsPerHost int
+	// TLS configura HTTPS, p. ej. con Filebeat detrás de un proxy
+	TLS *tls.Config
+	// Credenciales que se envían en cada consulta; BearerToken reemplaza a
+	// Username y Password
+	Username    string
+	Password    string
+	BearerToken string
+}
+
+// DefaultHTTPOptions son los valores usados por New
+var DefaultHTTPOptions = HTTPOptions{
+	Timeout:             10 * time.Second,
+	IdleConnTimeout:     90 * time.Second,
+	MaxIdleConnsPerHost: 4,
+}
+
+// NewHTTPClient crea un cliente HTTP con las opciones dadas
+func NewHTTPClient(opts HTTPOptions) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DisableKeepAlives = opts.DisableKeepAlives
+	transport.IdleConnTimeout = opts.IdleConnTimeout
+	transport.MaxIdleConnsPerHost = opts.MaxIdleConnsPerHost
+	transport.TLSClientConfig = opts.TLS
+	registerSockets(transport)
+	if opts.Username == "" && opts.BearerToken == "" {
+		return &http.Client{Timeout: opts.Timeout, Transport: transport}
+	}
+	auth := &authTransport{base: transport, username: opts.Username, password: opts.Password, token: opts.BearerToken}
+	return &http.Client{Timeout: opts.Timeout, Transport: auth}
+}
+
+// authTransport agrega las credenciales a cada consulta, también a las de
+// expvar y pprof que comparten el transporte
+type authTransport struct {
+	base               http.RoundTripper
+	username, password string
+	token              string
+}
+
+func (t *authTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// Un RoundTripper no debe modificar la consulta original
+	req = req.Clone(req.Context())
+	if t.token != "" {
+		req.Header.Set("Authorization", "Bearer "+t.token)
+	} else {
+		req.SetBasicAuth(t.username, t.password)
+	}
+	return t.base.RoundTrip(req)
+}
+
+// Espera antes del primer reintento; se duplica en cada uno
+const retryBackoff = 200 * time.Millisecond
+
+// get consulta path en el beat reintentando los fallos transitorios (red,
+// timeouts y respuestas 5xx) hasta c.Retries veces.
+func (c *Client) get(ctx context.Context, path string, v interface{}) error {
+	backoff := retryBackoff
+	for attempt := 0; ; attempt++ {
+		start := time.Now()
+		err := getJSON(ctx, c.HTTP, c.BaseURL+path, v)
+		if err != nil {
+			slog.DebugContext(ctx, "Consulta HTTP fallida", "url", c.BaseURL+path, "attempt", attempt+1, "duration", time.Since(start), "err", err)
+		} else {
+			slog.DebugContext(ctx, "Consulta HTTP", "url", c.BaseURL+path, "attempt", attempt+1, "duration", time.Since(start))
+		}
+		if err == nil || attempt >= c.Retries || !retryable(err) {
+			return err
+		}
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+		backoff *= 2
+	}
+}
+
+func retryable(err error) bool {
+	var status StatusError
+	if errors.As(err, &status) {
+		return status.Code >= 500
+	}
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) || errors.Is(err, context.DeadlineExceeded)
+}
+
+// timeout es el plazo total de una consulta con todos sus reintentos
+func (c *Client) timeout() time.Duration {
+	if c.HTTP.Timeout <= 0 {
+		return 0
+	}
+	total := c.HTTP.Timeout * time.Duration(c.Retries+1)
+	for i, backoff := 0, retryBackoff; i < c.Retries; i, backoff = i+1, backoff*2 {
+		total += backoff
+	}
+	return total
+}
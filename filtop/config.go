@@ -0,0 +1,1408 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/iTiagoCO/filtop/filtop/alerts"
+	"github.com/iTiagoCO/filtop/filtop/archive"
+	"github.com/iTiagoCO/filtop/filtop/client"
+	"github.com/iTiagoCO/filtop/filtop/elastic"
+	"github.com/iTiagoCO/filtop/filtop/expr"
+	"github.com/iTiagoCO/filtop/filtop/kafka"
+	"github.com/iTiagoCO/filtop/filtop/metrics"
+	"github.com/iTiagoCO/filtop/filtop/notify"
+	"github.com/iTiagoCO/filtop/filtop/plugins"
+	"github.com/iTiagoCO/filtop/filtop/remotewrite"
+	"github.com/iTiagoCO/filtop/filtop/server"
+	"github.com/iTiagoCO/filtop/filtop/ui"
+)
+
+// Config es el archivo de configuración de filtop. Los flags de la línea de
+// comandos tienen prioridad sobre estos valores.
+type Config struct {
+	Host     string `yaml:"host"`
+	Port     int    `yaml:"port"`
+	Interval int    `yaml:"interval"`
+	// Varios Filebeats monitoreados a la vez, uno por pestaña; reemplazan a
+	// host y port
+	Targets []TargetConfig `yaml:"targets"`
+	// Conexión a los Filebeats por HTTPS y con credenciales, p. ej. detrás
+	// de un proxy
+	TLS  TLSConfig  `yaml:"tls"`
+	Auth AuthConfig `yaml:"auth"`
+	// Perfiles de conexión por entorno: cada uno puede redefinir cualquier
+	// clave de la configuración y se elige con -profile o con la tecla P
+	Profiles map[string]yaml.Node `yaml:"profiles"`
+	// Intervalos propios de los endpoints secundarios de Filebeat
+	Intervals IntervalsConfig `yaml:"intervals"`
+	// Métricas del host; solo tienen sentido si filtop corre en la misma
+	// máquina que Filebeat
+	System SystemConfig `yaml:"system"`
+	// Elasticsearch de destino, para comparar lo enviado con lo indexado
+	Elasticsearch ElasticsearchConfig `yaml:"elasticsearch"`
+	// Kafka de destino, para comparar lo enviado con lo escrito en el topic
+	// y seguir el lag de quien lo consume
+	Kafka KafkaConfig `yaml:"kafka"`
+	// Registry de Filebeat, para seguir las rotaciones de los archivos;
+	// también solo en la misma máquina
+	Registry RegistryConfig `yaml:"registry"`
+	// Sonda de latencia de ingesta con un log de prueba
+	Probe ProbeConfig `yaml:"probe"`
+	// Detección de cambios bruscos en el ritmo de eventos de cada input
+	Anomalies AnomaliesConfig `yaml:"anomalies"`
+	// Rutas de /stats del panel Watch
+	Watch []string `yaml:"watch"`
+	// Suavizado inicial de las tasas de la interfaz: instant (por defecto),
+	// 1m o 5m; la tecla v lo cambia
+	RateSmoothing string `yaml:"rate_smoothing"`
+	// Panel Inputs
+	Inputs InputsConfig `yaml:"inputs"`
+	// Endpoints JSON adicionales (exporters sidecar, beats propios)
+	Endpoints []EndpointConfig `yaml:"endpoints"`
+	// Métricas calculadas, en el orden del archivo
+	Computed ComputedConfig `yaml:"computed"`
+	Alerts   []AlertConfig  `yaml:"alerts"`
+	// Ventanas de mantenimiento en que las alertas no se notifican
+	Silences []SilenceConfig `yaml:"silences"`
+	// Historial de las alertas activadas y resueltas
+	AlertHistory AlertHistoryConfig `yaml:"alert_history"`
+	// Historial de métricas guardado en disco
+	History HistoryConfig `yaml:"history"`
+	// Envío de las alertas a Slack y por correo
+	Notifications NotificationsConfig `yaml:"notifications"`
+	// Publicación de las métricas por Prometheus remote_write
+	RemoteWrite RemoteWriteConfig `yaml:"remote_write"`
+	// Programas externos que reciben las muestras y las alertas
+	Plugins PluginsConfig `yaml:"plugins"`
+	// Paneles propios que se agregan al tablero
+	Panels []PanelConfig `yaml:"panels"`
+}
+
+// TargetConfig es un Filebeat de targets. Name por defecto es host:port y
+// port por defecto 5066. Con ports se monitorean varios beats del mismo
+// host (p. ej. Filebeat en 5066 y Metricbeat en 5067), cada uno en su
+// pestaña y con el nombre <name>:<port>; name por defecto es host.
+type TargetConfig struct {
+	Name  string `yaml:"name"`
+	Host  string `yaml:"host"`
+	Port  int    `yaml:"port"`
+	Ports []int  `yaml:"ports"`
+}
+
+// Pestañas que se pueden elegir con las teclas 1 a 9
+const maxTargets = 9
+
+// TLSConfig se usa con cualquiera de sus campos: la conexión pasa a HTTPS.
+// cert y key son el certificado de cliente, si el proxy lo pide.
+type TLSConfig struct {
+	Enabled  bool   `yaml:"enabled"`
+	CA       string `yaml:"ca"`
+	Cert     string `yaml:"cert"`
+	Key      string `yaml:"key"`
+	Insecure bool   `yaml:"insecure"`
+}
+
+func (c *TLSConfig) enabled() bool {
+	return c.Enabled || c.CA != "" || c.Cert != "" || c.Insecure
+}
+
+func (c *TLSConfig) config() (*tls.Config, error) {
+	if !c.enabled() {
+		return nil, nil
+	}
+	config := &tls.Config{InsecureSkipVerify: c.Insecure}
+	if c.CA != "" {
+		pem, err := os.ReadFile(c.CA)
+		if err != nil {
+			return nil, err
+		}
+		config.RootCAs = x509.NewCertPool()
+		if !config.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("%s: no contiene certificados PEM", c.CA)
+		}
+	}
+	if c.Cert != "" {
+		cert, err := tls.LoadX509KeyPair(c.Cert, c.Key)
+		if err != nil {
+			return nil, err
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	return config, nil
+}
+
+// AuthConfig son las credenciales de la conexión. La contraseña y el token
+// admiten variables de entorno.
+type AuthConfig struct {
+	Username    string `yaml:"username"`
+	Password    string `yaml:"password"`
+	BearerToken string `yaml:"bearer_token"`
+}
+
+// beatHTTPOptions agrega a opts el TLS y las credenciales de la conexión
+func (c *Config) beatHTTPOptions(opts client.HTTPOptions) (client.HTTPOptions, error) {
+	tlsConfig, err := c.TLS.config()
+	if err != nil {
+		return opts, fmt.Errorf("tls: %w", err)
+	}
+	opts.TLS = tlsConfig
+	opts.Username = c.Auth.Username
+	opts.Password = os.ExpandEnv(c.Auth.Password)
+	opts.BearerToken = os.ExpandEnv(c.Auth.BearerToken)
+	return opts, nil
+}
+
+func (c *Config) scheme() string {
+	if c.TLS.enabled() {
+		return "https"
+	}
+	return "http"
+}
+
+// profileNames son los nombres de los perfiles, en orden alfabético
+func (c *Config) profileNames() []string {
+	names := make([]string, 0, len(c.Profiles))
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// applyProfile aplica sobre la configuración las claves del perfil name.
+// Las listas del perfil reemplazan a las de la configuración y los mapas y
+// secciones se combinan con ellas.
+func (c *Config) applyProfile(name string) error {
+	if name == "" {
+		return nil
+	}
+	node, ok := c.Profiles[name]
+	if !ok {
+		if len(c.Profiles) == 0 {
+			return fmt.Errorf("perfil desconocido: %s (la configuración no tiene profiles)", name)
+		}
+		return fmt.Errorf("perfil desconocido: %s (disponibles: %s)", name, strings.Join(c.profileNames(), ", "))
+	}
+	profiles := c.Profiles
+	if err := node.Decode(c); err != nil {
+		return fmt.Errorf("profiles.%s: %w", name, err)
+	}
+	// Un perfil no puede definir otros perfiles
+	c.Profiles = profiles
+	return nil
+}
+
+// IntervalsConfig espacia las consultas más costosas, en segundos. Con 0
+// (por defecto) se consultan en cada ciclo junto con /stats, que usa
+// interval.
+type IntervalsConfig struct {
+	Inputs int `yaml:"inputs"`
+	// /state y /dataset (-state)
+	State int `yaml:"state"`
+}
+
+type SystemConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Rutas de los logs cuyos sistemas de archivos se muestran
+	Paths []string `yaml:"paths"`
+	// PID de Filebeat; por defecto se busca el proceso que escucha en port
+	PID int `yaml:"pid"`
+}
+
+// Rutas del panel Host si no se indica ninguna
+var defaultSystemPaths = []string{"/var/log"}
+
+func (c *SystemConfig) paths() []string {
+	if len(c.Paths) == 0 {
+		return defaultSystemPaths
+	}
+	return c.Paths
+}
+
+// ElasticsearchConfig está desactivado si URL está vacía. La contraseña y la
+// API key admiten variables de entorno (${ES_PASSWORD}) para no dejarlas
+// escritas en el archivo.
+type ElasticsearchConfig struct {
+	URL      string `yaml:"url"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+	APIKey   string `yaml:"api_key"`
+	// Índices o data streams donde escribe este Filebeat; admite patrones
+	Indices []string `yaml:"indices"`
+	// Intervalo de consulta en segundos; por defecto el global
+	Interval int    `yaml:"interval"`
+	CA       string `yaml:"ca"`
+	Insecure bool   `yaml:"insecure"`
+}
+
+func (c *ElasticsearchConfig) options(timeout time.Duration) elastic.Options {
+	return elastic.Options{
+		URL:      c.URL,
+		Username: c.Username,
+		Password: os.ExpandEnv(c.Password),
+		APIKey:   os.ExpandEnv(c.APIKey),
+		CAFile:   c.CA,
+		Insecure: c.Insecure,
+		Timeout:  timeout,
+	}
+}
+
+// KafkaConfig está desactivado si Brokers está vacío. Group es el grupo de
+// consumidores que lee el topic, para mostrar su lag. La contraseña admite
+// variables de entorno.
+type KafkaConfig struct {
+	Brokers []string `yaml:"brokers"`
+	Topic   string   `yaml:"topic"`
+	Group   string   `yaml:"group"`
+	// Intervalo de consulta en segundos; por defecto el global
+	Interval int `yaml:"interval"`
+	// Usuario y contraseña para SASL/PLAIN
+	Username string    `yaml:"username"`
+	Password string    `yaml:"password"`
+	TLS      TLSConfig `yaml:"tls"`
+}
+
+func (c *KafkaConfig) options(timeout time.Duration) (kafka.Options, error) {
+	tlsConfig, err := c.TLS.config()
+	if err != nil {
+		return kafka.Options{}, fmt.Errorf("tls: %w", err)
+	}
+	return kafka.Options{
+		Brokers:  c.Brokers,
+		TLS:      tlsConfig,
+		Username: c.Username,
+		Password: os.ExpandEnv(c.Password),
+		Timeout:  timeout,
+	}, nil
+}
+
+// kafkaTopic es el topic del panel Kafka, o "" si no se consulta Kafka
+func (c *Config) kafkaTopic() string {
+	if len(c.Kafka.Brokers) == 0 {
+		return ""
+	}
+	return c.Kafka.Topic
+}
+
+// RegistryConfig está desactivado si Path está vacío. Path es
+// data/registry de Filebeat (o data/registry/filebeat).
+type RegistryConfig struct {
+	Path string `yaml:"path"`
+	// Intervalo de lectura en segundos; por defecto el global
+	Interval int `yaml:"interval"`
+}
+
+// ProbeConfig está desactivado si Path está vacío. Path es un log de prueba
+// que Filebeat cosecha con un input propio, de id Input, para que el
+// contador de eventos del input solo cambie con las marcas; sin Input solo
+// se mide la llegada a Elasticsearch.
+type ProbeConfig struct {
+	Path  string `yaml:"path"`
+	Input string `yaml:"input"`
+	// Segundos entre marcas y espera máxima de cada una; por defecto
+	// defaultProbeInterval y defaultProbeTimeout
+	Interval int `yaml:"interval"`
+	Timeout  int `yaml:"timeout"`
+}
+
+const (
+	defaultProbeInterval = 30 * time.Second
+	defaultProbeTimeout  = 2 * time.Minute
+)
+
+func (c *ProbeConfig) interval() time.Duration {
+	if c.Interval > 0 {
+		return time.Duration(c.Interval) * time.Second
+	}
+	return defaultProbeInterval
+}
+
+func (c *ProbeConfig) timeout() time.Duration {
+	if c.Timeout > 0 {
+		return time.Duration(c.Timeout) * time.Second
+	}
+	return defaultProbeTimeout
+}
+
+// AnomaliesConfig está activa por defecto; los tiempos son en segundos y
+// los valores en 0 toman los de defaultAnomalies.
+type AnomaliesConfig struct {
+	Disabled bool `yaml:"disabled"`
+	// Ventana de la línea base de cada input
+	Window int `yaml:"window"`
+	// Veces por debajo o por encima de la línea base que se marcan
+	Factor float64 `yaml:"factor"`
+	// Eventos/s mínimos de la línea base para evaluar un input
+	MinRate float64 `yaml:"min_rate"`
+	// Tiempo sin eventos para dar un input por detenido
+	StoppedAfter int `yaml:"stopped_after"`
+}
+
+var defaultAnomalies = metrics.AnomalyOptions{
+	Window:       10 * time.Minute,
+	Factor:       3,
+	MinRate:      1,
+	StoppedAfter: 2 * time.Minute,
+}
+
+// rateSmoothing es la ventana de RateSmoothing; 0 para las instantáneas
+func (c *Config) rateSmoothing() time.Duration {
+	switch c.RateSmoothing {
+	case "1m":
+		return time.Minute
+	case "5m":
+		return 5 * time.Minute
+	}
+	return 0
+}
+
+// InputsConfig configura el panel Inputs. QuietAfter es en segundos; con 0
+// toma defaultQuietAfter.
+type InputsConfig struct {
+	// Muestra la columna Last Event con el último evento de cada input
+	LastEventColumn bool `yaml:"last_event_column"`
+	// Tiempo sin eventos a partir del cual se resalta un input que solía
+	// producirlos
+	QuietAfter int `yaml:"quiet_after"`
+	// Window es la ventana en segundos de avg_rate e increase() en las
+	// reglas de Highlight; con 0 toma defaultInputWindow
+	Window int `yaml:"window"`
+	// Highlight colorea las filas según la salud de cada input; gana la
+	// primera regla que se cumple. Sin reglas se usan defaultInputHighlight.
+	Highlight []InputHighlightConfig `yaml:"highlight"`
+}
+
+// InputHighlightConfig es una regla de color del panel Inputs; When se
+// evalúa con las variables de metrics.InputEnv
+type InputHighlightConfig struct {
+	Color string `yaml:"color"`
+	When  string `yaml:"when"`
+}
+
+const (
+	defaultQuietAfter  = 5 * time.Minute
+	defaultInputWindow = 5 * time.Minute
+)
+
+// defaultInputHighlight marca en rojo los inputs con descartes o errores
+// nuevos y en amarillo los que cayeron a menos de un tercio de su ritmo
+// habitual
+var defaultInputHighlight = []InputHighlightConfig{
+	{Color: "red", When: "increase(drops) > 0"},
+	{Color: "yellow", When: "avg_rate >= 1 && rate < avg_rate / 3"},
+}
+
+func (c *InputsConfig) quietAfter() time.Duration {
+	if c.QuietAfter > 0 {
+		return time.Duration(c.QuietAfter) * time.Second
+	}
+	return defaultQuietAfter
+}
+
+func (c *InputsConfig) window() time.Duration {
+	if c.Window > 0 {
+		return time.Duration(c.Window) * time.Second
+	}
+	return defaultInputWindow
+}
+
+// highlightRules compila las reglas ya validadas
+func (c *InputsConfig) highlightRules() []ui.InputRule {
+	highlight := c.Highlight
+	if len(highlight) == 0 {
+		highlight = defaultInputHighlight
+	}
+	rules := make([]ui.InputRule, len(highlight))
+	for i, rule := range highlight {
+		rules[i] = ui.InputRule{Color: rule.Color, When: mustCompile(rule.When, metrics.InputFunctions)}
+	}
+	return rules
+}
+
+func (c *InputsConfig) validate() error {
+	if c.QuietAfter < 0 {
+		return errors.New("quiet_after no puede ser negativo")
+	}
+	if c.Window < 0 {
+		return errors.New("window no puede ser negativo")
+	}
+	for i, rule := range c.Highlight {
+		switch rule.Color {
+		case "red", "orange", "yellow", "green", "blue", "gray":
+		default:
+			return fmt.Errorf("highlight[%d]: color debe ser red, orange, yellow, green, blue o gray", i)
+		}
+		e, err := expr.Compile(rule.When, metrics.InputFunctions)
+		if err != nil {
+			return fmt.Errorf("highlight[%d]: %w", i, err)
+		}
+		for _, name := range e.Metrics() {
+			if !metrics.IsInputVariable(name) {
+				return fmt.Errorf("highlight[%d]: variable desconocida: %s", i, name)
+			}
+		}
+	}
+	return nil
+}
+
+func (c *AnomaliesConfig) options() metrics.AnomalyOptions {
+	opts := defaultAnomalies
+	if c.Window > 0 {
+		opts.Window = time.Duration(c.Window) * time.Second
+	}
+	if c.Factor > 0 {
+		opts.Factor = c.Factor
+	}
+	if c.MinRate > 0 {
+		opts.MinRate = c.MinRate
+	}
+	if c.StoppedAfter > 0 {
+		opts.StoppedAfter = time.Duration(c.StoppedAfter) * time.Second
+	}
+	return opts
+}
+
+type EndpointConfig struct {
+	Name string `yaml:"name"`
+	URL  string `yaml:"url"`
+	// Intervalo de consulta en segundos; por defecto el global
+	Interval int           `yaml:"interval"`
+	Fields   []FieldConfig `yaml:"fields"`
+}
+
+// FieldConfig mapea un valor del documento (ruta estilo JSONPath) a una fila
+type FieldConfig struct {
+	Label string `yaml:"label"`
+	Path  string `yaml:"path"`
+}
+
+// ComputedConfig es un mapa nombre: expresión que conserva el orden, de modo
+// que una métrica calculada pueda usar las definidas antes.
+type ComputedConfig []NamedExpr
+
+type NamedExpr struct {
+	Name string
+	Expr string
+}
+
+func (c *ComputedConfig) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind != yaml.MappingNode {
+		return fmt.Errorf("línea %d: computed debe ser un mapa nombre: expresión", node.Line)
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		var name, src string
+		if err := node.Content[i].Decode(&name); err != nil {
+			return err
+		}
+		if err := node.Content[i+1].Decode(&src); err != nil {
+			return err
+		}
+		*c = append(*c, NamedExpr{Name: name, Expr: src})
+	}
+	return nil
+}
+
+// cliFlags son los flags que también se pueden definir en el archivo, con
+// los nombres de los que se indicaron de forma explícita.
+type cliFlags struct {
+	host     string
+	port     int
+	interval int
+	system   bool
+	pid      int
+	registry string
+	explicit map[string]bool
+}
+
+// applyFlags completa la configuración con los flags. Los indicados de
+// forma explícita tienen prioridad; los demás solo se usan si el archivo no
+// define el valor.
+func (c *Config) applyFlags(f cliFlags) {
+	if f.explicit["host"] || c.Host == "" {
+		c.Host = f.host
+	}
+	if f.explicit["port"] || c.Port == 0 {
+		c.Port = f.port
+	}
+	if f.explicit["interval"] || c.Interval == 0 {
+		c.Interval = f.interval
+	}
+	if f.explicit["system"] {
+		c.System.Enabled = f.system
+	}
+	if f.explicit["pid"] {
+		c.System.PID = f.pid
+	}
+	if f.explicit["registry"] {
+		c.Registry.Path = f.registry
+	}
+}
+
+// clientIntervals devuelve cada cuánto consultar /inputs/ y /state y /dataset
+func (c *Config) clientIntervals() (inputs, state time.Duration) {
+	return time.Duration(c.Intervals.Inputs) * time.Second, time.Duration(c.Intervals.State) * time.Second
+}
+
+func (c *Config) beatURL() string {
+	return c.hostURL(c.Host, c.Port)
+}
+
+// hostURL es la URL base de Filebeat en host y port; un socket o named
+// pipe (unix://, npipe://) no tiene puerto
+func (c *Config) hostURL(host string, port int) string {
+	if url, ok := client.SocketURL(host); ok {
+		return url
+	}
+	return fmt.Sprintf("%s://%s:%d", c.scheme(), host, port)
+}
+
+// beatTarget es un Filebeat a monitorear. group es el host al que
+// pertenece, para agruparlo con los demás beats de ese host.
+type beatTarget struct {
+	name  string
+	url   string
+	group string
+}
+
+// beatTargets devuelve los Filebeats de targets o, si no hay, el de host y
+// port
+func (c *Config) beatTargets() []beatTarget {
+	if len(c.Targets) == 0 {
+		name := fmt.Sprintf("%s:%d", c.Host, c.Port)
+		if client.IsSocket(c.Host) {
+			name = c.Host
+		}
+		return []beatTarget{{name: name, url: c.beatURL(), group: c.Host}}
+	}
+	var targets []beatTarget
+	for _, target := range c.Targets {
+		group := target.Name
+		if group == "" {
+			group = target.Host
+		}
+		if len(target.Ports) > 0 {
+			for _, port := range target.Ports {
+				name := fmt.Sprintf("%s:%d", group, port)
+				targets = append(targets, beatTarget{name: name, url: c.hostURL(target.Host, port), group: group})
+			}
+			continue
+		}
+		port := target.Port
+		if port == 0 {
+			port = defaultPort
+		}
+		name := target.Name
+		switch {
+		case name != "":
+		case client.IsSocket(target.Host):
+			name = target.Host
+		default:
+			name = fmt.Sprintf("%s:%d", target.Host, port)
+		}
+		targets = append(targets, beatTarget{name: name, url: c.hostURL(target.Host, port), group: group})
+	}
+	return targets
+}
+
+// endpointPanels describe los paneles de los endpoints para la interfaz
+func (c *Config) endpointPanels() []ui.EndpointPanel {
+	var panels []ui.EndpointPanel
+	for _, endpoint := range c.Endpoints {
+		panel := ui.EndpointPanel{Name: endpoint.Name}
+		for _, field := range endpoint.Fields {
+			label := field.Label
+			if label == "" {
+				label = field.Path
+			}
+			panel.Labels = append(panel.Labels, label)
+		}
+		panels = append(panels, panel)
+	}
+	return panels
+}
+
+func (c *Config) serverEndpoints() []server.Endpoint {
+	var endpoints []server.Endpoint
+	for i, panel := range c.endpointPanels() {
+		endpoints = append(endpoints, server.Endpoint{Name: panel.Name, URL: c.Endpoints[i].URL, Labels: panel.Labels})
+	}
+	return endpoints
+}
+
+// computedNames devuelve los nombres de las métricas calculadas, en orden
+func (c *Config) computedNames() []string {
+	var names []string
+	for _, named := range c.Computed {
+		names = append(names, named.Name)
+	}
+	return names
+}
+
+// computedMetrics compila las métricas calculadas ya validadas
+func (c *Config) computedMetrics() []metrics.Computed {
+	computed := make([]metrics.Computed, len(c.Computed))
+	for i, named := range c.Computed {
+		computed[i] = metrics.Computed{Name: named.Name, Expr: mustCompile(named.Expr, metrics.Functions)}
+	}
+	return computed
+}
+
+func (c *Config) alertRules() []alerts.Rule {
+	rules := make([]alerts.Rule, len(c.Alerts))
+	for i, alert := range c.Alerts {
+		rules[i] = alerts.Rule{
+			Name:      alert.Name,
+			Severity:  alert.Severity,
+			Condition: mustCompile(alert.Expr, metrics.Functions),
+		}
+		if rules[i].Severity == "" {
+			rules[i].Severity = "warning"
+		}
+		if alert.Value != "" {
+			rules[i].Value = mustCompile(alert.Value, metrics.Functions)
+		}
+	}
+	return rules
+}
+
+// alertPanels indica a la interfaz qué panel destacar con cada alerta
+func (c *Config) alertPanels() []ui.AlertRule {
+	rules := make([]ui.AlertRule, len(c.Alerts))
+	for i, alert := range c.Alerts {
+		rules[i] = ui.AlertRule{Name: alert.Name, Metrics: mustCompile(alert.Expr, metrics.Functions).Metrics(), Panel: alert.Panel}
+	}
+	return rules
+}
+
+// hasPanel indica si name es un panel de la página principal o el título
+// de un panel propio
+func (c *Config) hasPanel(name string) bool {
+	for _, panel := range ui.MainPanels {
+		if panel == name {
+			return true
+		}
+	}
+	for _, panel := range c.Panels {
+		if panel.Title == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Config) hasAlert(name string) bool {
+	for _, alert := range c.Alerts {
+		if alert.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// userPanels devuelve la descripción de los paneles para la interfaz y las
+// expresiones de cada uno de ellos.
+func (c *Config) userPanels() ([]ui.Panel, [][]metrics.Computed) {
+	panels := make([]ui.Panel, len(c.Panels))
+	exprs := make([][]metrics.Computed, len(c.Panels))
+	for i, panel := range c.Panels {
+		panels[i] = ui.Panel{
+			Title: panel.Title,
+			Type:  panel.Type,
+			Max:   panel.Max,
+			Right: panel.Position == "right",
+		}
+		if panels[i].Type == "" {
+			panels[i].Type = ui.PanelTable
+		}
+		for _, metric := range panel.Metrics {
+			label := metric.Label
+			if label == "" {
+				label = metric.Expr
+			}
+			panels[i].Labels = append(panels[i].Labels, label)
+			exprs[i] = append(exprs[i], metrics.Computed{Name: label, Expr: mustCompile(metric.Expr, metrics.Functions)})
+		}
+	}
+	return panels, exprs
+}
+
+func mustCompile(src string, functions expr.Functions) *expr.Expr {
+	e, err := expr.Compile(src, functions)
+	if err != nil {
+		panic(err)
+	}
+	return e
+}
+
+type AlertConfig struct {
+	Name string `yaml:"name"`
+	// Condición que dispara la alerta, p. ej. drop_ratio > 0.01
+	Expr string `yaml:"expr"`
+	// Valor mostrado con la alerta; por defecto la primera métrica de expr
+	Value    string `yaml:"value"`
+	Severity string `yaml:"severity"`
+	// Panel es el panel de la página principal que se destaca mientras está
+	// activa; por defecto el que muestra las métricas de expr
+	Panel string `yaml:"panel"`
+}
+
+// SilenceConfig silencia las alertas de target, rule o metric (todas si no
+// se indica ninguno) desde start hasta end o durante duration segundos. Sin
+// start empieza al cargar la configuración, también al recargarla.
+type SilenceConfig struct {
+	// Nombre del beat en targets; solo filtra con más de uno
+	Target   string    `yaml:"target"`
+	Rule     string    `yaml:"rule"`
+	Metric   string    `yaml:"metric"`
+	Start    time.Time `yaml:"start"`
+	End      time.Time `yaml:"end"`
+	Duration int       `yaml:"duration"`
+	Reason   string    `yaml:"reason"`
+}
+
+// silences son los silencios de la configuración cargada en now
+func (c *Config) silences(now time.Time) []alerts.Silence {
+	silences := make([]alerts.Silence, len(c.Silences))
+	for i, silence := range c.Silences {
+		start, end := silence.Start, silence.End
+		if start.IsZero() {
+			start = now
+		}
+		if end.IsZero() {
+			end = start.Add(time.Duration(silence.Duration) * time.Second)
+		}
+		silences[i] = alerts.Silence{
+			Target: silence.Target,
+			Rule:   silence.Rule,
+			Metric: silence.Metric,
+			Start:  start,
+			End:    end,
+			Reason: silence.Reason,
+		}
+	}
+	return silences
+}
+
+// AlertHistoryConfig configura el historial de la página Alerts y de
+// /api/alerts/history. Sus cambios se aplican al reiniciar filtop.
+type AlertHistoryConfig struct {
+	// Transiciones retenidas en memoria; por defecto defaultAlertHistory
+	Size int `yaml:"size"`
+	// Archivo JSON Lines donde se agregan para conservarlas entre
+	// ejecuciones; admite variables de entorno. Vacío no las guarda.
+	Path string `yaml:"path"`
+	// Segundos tras los que se descartan; 0 las conserva hasta completar
+	// size
+	Retention int `yaml:"retention"`
+}
+
+const defaultAlertHistory = 1000
+
+func (c *AlertHistoryConfig) size() int {
+	if c.Size > 0 {
+		return c.Size
+	}
+	return defaultAlertHistory
+}
+
+func (c *AlertHistoryConfig) maxAge() time.Duration {
+	return time.Duration(c.Retention) * time.Second
+}
+
+// HistoryConfig guarda en disco el historial de algunas métricas, para
+// /api/history. Sus cambios se aplican al reiniciar filtop.
+type HistoryConfig struct {
+	// Archivo JSON Lines; admite variables de entorno. Vacío no lo guarda.
+	Path string `yaml:"path"`
+	// Rutas de /stats que se guardan, además de las métricas calculadas;
+	// por defecto defaultHistoryMetrics
+	Metrics []string `yaml:"metrics"`
+	// Segundos durante los que se guardan todas las muestras; las más
+	// viejas se promedian en intervalos de rollup segundos y se descartan
+	// pasados retention segundos
+	Raw       int `yaml:"raw"`
+	Rollup    int `yaml:"rollup"`
+	Retention int `yaml:"retention"`
+}
+
+var defaultHistoryMetrics = []string{
+	"pipeline.events.total",
+	"pipeline.events.dropped",
+	"pipeline.events.failed",
+	"pipeline.queue.filled.events",
+	"output.events.acked",
+	"output.write.bytes",
+	"harvester.running",
+	"memstats.rss",
+	"cpu.total.time.ms",
+}
+
+const (
+	defaultHistoryRaw       = 2 * 60 * 60
+	defaultHistoryRollup    = 60
+	defaultHistoryRetention = 7 * 24 * 60 * 60
+)
+
+func (c *HistoryConfig) metrics() []string {
+	if len(c.Metrics) > 0 {
+		return c.Metrics
+	}
+	return defaultHistoryMetrics
+}
+
+func (c *HistoryConfig) options() archive.Options {
+	seconds := func(v, def int) time.Duration {
+		if v == 0 {
+			v = def
+		}
+		return time.Duration(v) * time.Second
+	}
+	return archive.Options{
+		Raw:       seconds(c.Raw, defaultHistoryRaw),
+		Rollup:    seconds(c.Rollup, defaultHistoryRollup),
+		Retention: seconds(c.Retention, defaultHistoryRetention),
+	}
+}
+
+// NotificationsConfig son los destinos de las alertas
+type NotificationsConfig struct {
+	Slack []SlackConfig `yaml:"slack"`
+	Email []EmailConfig `yaml:"email"`
+}
+
+// NotifyOptions son las opciones comunes a todos los destinos
+type NotifyOptions struct {
+	// Severidades que se envían; por defecto todas
+	Severities []string `yaml:"severities"`
+	// Segundos mínimos entre dos mensajes; las alertas que llegan antes se
+	// agrupan en el siguiente. Por defecto defaultRateLimit.
+	RateLimit int `yaml:"rate_limit"`
+	// Hora local (HH:MM) del resumen de las últimas 24 horas; vacío no lo
+	// envía
+	DailySummary string `yaml:"daily_summary"`
+}
+
+// SlackConfig envía las alertas a un incoming webhook. La URL admite
+// variables de entorno (${SLACK_WEBHOOK}).
+type SlackConfig struct {
+	WebhookURL string `yaml:"webhook_url"`
+	// Canal que reemplaza al del webhook
+	Channel string `yaml:"channel"`
+	// Se antepone a las alertas nuevas, p. ej. <!here> o <@U024BE7LH>
+	Mention       string `yaml:"mention"`
+	NotifyOptions `yaml:",inline"`
+}
+
+// EmailConfig envía las alertas por SMTP. La contraseña admite variables de
+// entorno.
+type EmailConfig struct {
+	Host string `yaml:"smtp_host"`
+	// Por defecto defaultSMTPPort
+	Port          int      `yaml:"smtp_port"`
+	Username      string   `yaml:"username"`
+	Password      string   `yaml:"password"`
+	From          string   `yaml:"from"`
+	To            []string `yaml:"to"`
+	NotifyOptions `yaml:",inline"`
+}
+
+const (
+	defaultRateLimit = time.Minute
+	defaultSMTPPort  = 587
+)
+
+func (o *NotifyOptions) channel(notifier notify.Notifier) notify.Channel {
+	channel := notify.Channel{Notifier: notifier, Severities: o.Severities, RateLimit: defaultRateLimit, SummaryAt: -1}
+	if o.RateLimit > 0 {
+		channel.RateLimit = time.Duration(o.RateLimit) * time.Second
+	}
+	if at, err := time.Parse("15:04", o.DailySummary); err == nil {
+		channel.SummaryAt = time.Duration(at.Hour())*time.Hour + time.Duration(at.Minute())*time.Minute
+	}
+	return channel
+}
+
+func (o *NotifyOptions) validate() error {
+	for _, severity := range o.Severities {
+		switch severity {
+		case "info", "warning", "critical":
+		default:
+			return fmt.Errorf("severidad desconocida: %s", severity)
+		}
+	}
+	if o.RateLimit < 0 {
+		return errors.New("rate_limit no puede ser negativo")
+	}
+	if o.DailySummary != "" {
+		if _, err := time.Parse("15:04", o.DailySummary); err != nil {
+			return fmt.Errorf("daily_summary debe ser HH:MM: %s", o.DailySummary)
+		}
+	}
+	return nil
+}
+
+// RemoteWriteConfig está desactivado si URL está vacía. La contraseña y el
+// token admiten variables de entorno.
+type RemoteWriteConfig struct {
+	URL         string `yaml:"url"`
+	Username    string `yaml:"username"`
+	Password    string `yaml:"password"`
+	BearerToken string `yaml:"bearer_token"`
+	// Encabezados de cada envío, p. ej. X-Scope-OrgID de Mimir
+	Headers map[string]string `yaml:"headers"`
+	// Etiquetas de todas las series; job por defecto es filtop e instance
+	// es el nombre del target
+	Labels map[string]string `yaml:"labels"`
+	// Segundos entre envíos; por defecto defaultRemoteWriteInterval
+	Interval int `yaml:"interval"`
+	// Muestras que se conservan mientras el receptor no responde; por
+	// defecto defaultRemoteWritePending
+	MaxPending int `yaml:"max_pending"`
+}
+
+const (
+	defaultRemoteWriteInterval = 30 * time.Second
+	defaultRemoteWritePending  = 100000
+)
+
+var labelName = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+func (c *RemoteWriteConfig) options(httpClient *http.Client) remotewrite.Options {
+	opts := remotewrite.Options{
+		URL:         c.URL,
+		Username:    c.Username,
+		Password:    os.ExpandEnv(c.Password),
+		BearerToken: os.ExpandEnv(c.BearerToken),
+		Headers:     c.Headers,
+		Labels:      c.Labels,
+		Interval:    defaultRemoteWriteInterval,
+		MaxPending:  defaultRemoteWritePending,
+		HTTP:        httpClient,
+	}
+	if c.Interval > 0 {
+		opts.Interval = time.Duration(c.Interval) * time.Second
+	}
+	if c.MaxPending > 0 {
+		opts.MaxPending = c.MaxPending
+	}
+	return opts
+}
+
+func (c *RemoteWriteConfig) validate() error {
+	if c.URL == "" {
+		return nil
+	}
+	if u, err := url.Parse(c.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("url inválida: %s", c.URL)
+	}
+	if c.Username != "" && c.BearerToken != "" {
+		return errors.New("username y bearer_token son excluyentes")
+	}
+	if c.Interval < 0 || c.MaxPending < 0 {
+		return errors.New("interval y max_pending no pueden ser negativos")
+	}
+	for name := range c.Labels {
+		if !labelName.MatchString(name) || strings.HasPrefix(name, "__") {
+			return fmt.Errorf("nombre de etiqueta inválido: %s", name)
+		}
+		if name == "instance" {
+			return errors.New("la etiqueta instance es el nombre del target")
+		}
+	}
+	return nil
+}
+
+// notifyChannels arma los destinos de las alertas de la configuración
+func (c *Config) notifyChannels(httpClient *http.Client) []notify.Channel {
+	var channels []notify.Channel
+	for _, slack := range c.Notifications.Slack {
+		channels = append(channels, slack.channel(&notify.Slack{
+			WebhookURL: os.ExpandEnv(slack.WebhookURL),
+			Channel:    slack.Channel,
+			Mention:    slack.Mention,
+			HTTP:       httpClient,
+		}))
+	}
+	for _, email := range c.Notifications.Email {
+		port := email.Port
+		if port == 0 {
+			port = defaultSMTPPort
+		}
+		channels = append(channels, email.channel(&notify.Email{
+			Host:     email.Host,
+			Port:     port,
+			Username: email.Username,
+			Password: os.ExpandEnv(email.Password),
+			From:     email.From,
+			To:       email.To,
+		}))
+	}
+	for _, notifier := range c.Plugins.Notifiers {
+		channels = append(channels, notifier.channel(&plugins.Notifier{Command: notifier.command()}))
+	}
+	return channels
+}
+
+// PluginsConfig son los plugins de sinks y notifiers. Los cambios de los
+// sinks se aplican al reiniciar filtop; los de los notifiers, al recargar.
+type PluginsConfig struct {
+	Sinks     []PluginConfig         `yaml:"sinks"`
+	Notifiers []NotifierPluginConfig `yaml:"notifiers"`
+}
+
+// PluginConfig es el programa de un plugin. Los valores de env admiten
+// variables de entorno.
+type PluginConfig struct {
+	Name    string            `yaml:"name"`
+	Command string            `yaml:"command"`
+	Args    []string          `yaml:"args"`
+	Env     map[string]string `yaml:"env"`
+}
+
+type NotifierPluginConfig struct {
+	PluginConfig  `yaml:",inline"`
+	NotifyOptions `yaml:",inline"`
+}
+
+func (c *PluginConfig) command() plugins.Command {
+	command := plugins.Command{Name: c.Name, Path: c.Command, Args: c.Args}
+	if len(c.Env) > 0 {
+		command.Env = make(map[string]string, len(c.Env))
+		for key, value := range c.Env {
+			command.Env[key] = os.ExpandEnv(value)
+		}
+	}
+	return command
+}
+
+func (c *PluginConfig) validate() error {
+	if c.Name == "" || c.Command == "" {
+		return errors.New("name y command son obligatorios")
+	}
+	return nil
+}
+
+func (c *PluginsConfig) validate() error {
+	names := make(map[string]bool)
+	for i, sink := range c.Sinks {
+		if err := sink.validate(); err != nil {
+			return fmt.Errorf("sinks[%d]: %w", i, err)
+		}
+		if names[sink.Name] {
+			return fmt.Errorf("sinks[%d]: %s está repetido", i, sink.Name)
+		}
+		names[sink.Name] = true
+	}
+	for i, notifier := range c.Notifiers {
+		if err := notifier.PluginConfig.validate(); err != nil {
+			return fmt.Errorf("notifiers[%d]: %w", i, err)
+		}
+		if names[notifier.Name] {
+			return fmt.Errorf("notifiers[%d]: %s está repetido", i, notifier.Name)
+		}
+		names[notifier.Name] = true
+		if err := notifier.NotifyOptions.validate(); err != nil {
+			return fmt.Errorf("notifiers[%d]: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// pluginSinks crea los sinks de los plugins
+func (c *Config) pluginSinks() []*plugins.Sink {
+	sinks := make([]*plugins.Sink, len(c.Plugins.Sinks))
+	for i, sink := range c.Plugins.Sinks {
+		sinks[i] = plugins.NewSink(sink.command())
+	}
+	return sinks
+}
+
+type PanelConfig struct {
+	Title string `yaml:"title"`
+	// table (por defecto), gauge o sparkline
+	Type string `yaml:"type"`
+	// left (por defecto) o right
+	Position string `yaml:"position"`
+	// Valor que corresponde al 100% de un gauge
+	Max     float64             `yaml:"max"`
+	Metrics []PanelMetricConfig `yaml:"metrics"`
+}
+
+// PanelMetricConfig es una fila del panel: una ruta de /stats, una métrica
+// calculada o cualquier expresión.
+type PanelMetricConfig struct {
+	Label string `yaml:"label"`
+	Expr  string `yaml:"expr"`
+}
+
+// defaultConfigPath es la configuración del usuario o, si no existe y sí
+// la del equipo (en Windows), la del equipo
+func defaultConfigPath() string {
+	var path string
+	if dir, err := os.UserConfigDir(); err == nil {
+		path = filepath.Join(dir, "filtop", "config.yaml")
+	}
+	system := systemConfigPath()
+	if system == "" {
+		return path
+	}
+	if _, err := os.Stat(path); path != "" && err == nil {
+		return path
+	}
+	if _, err := os.Stat(system); err == nil {
+		return system
+	}
+	return path
+}
+
+// defaultBaselinePath es donde se guarda la línea base del modo comparación
+func defaultBaselinePath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "filtop", "baseline.json")
+}
+
+// loadConfig lee el archivo de configuración y le aplica profile, si no
+// está vacío. Si no se indicó uno de forma explícita y el de por defecto no
+// existe, devuelve una configuración vacía.
+func loadConfig(path string, explicit bool, profile string) (*Config, error) {
+	cfg := &Config{}
+	if path == "" {
+		return cfg, cfg.applyProfile(profile)
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) && !explicit {
+		return cfg, cfg.applyProfile(profile)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := yaml.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if err := cfg.applyProfile(profile); err != nil {
+		return nil, err
+	}
+	return cfg, cfg.validate()
+}
+
+func (c *Config) validate() error {
+	if c.Intervals.Inputs < 0 || c.Intervals.State < 0 {
+		return errors.New("intervals: los intervalos no pueden ser negativos")
+	}
+	if a := c.Anomalies; a.Window < 0 || a.MinRate < 0 || a.StoppedAfter < 0 {
+		return errors.New("anomalies: los valores no pueden ser negativos")
+	}
+	if f := c.Anomalies.Factor; f != 0 && f <= 1 {
+		return errors.New("anomalies: factor debe ser mayor que 1")
+	}
+	if c.Registry.Interval < 0 {
+		return errors.New("registry: interval no puede ser negativo")
+	}
+	if p := c.Probe; p.Path != "" {
+		if p.Input == "" && c.Elasticsearch.URL == "" {
+			return errors.New("probe: hace falta input o elasticsearch para medir la latencia")
+		}
+		if p.Interval < 0 || p.Timeout < 0 {
+			return errors.New("probe: interval y timeout no pueden ser negativos")
+		}
+	}
+	if c.TLS.Cert != "" && c.TLS.Key == "" || c.TLS.Cert == "" && c.TLS.Key != "" {
+		return errors.New("tls: cert y key van juntos")
+	}
+	if c.Auth.Username != "" && c.Auth.BearerToken != "" {
+		return errors.New("auth: username y bearer_token son excluyentes")
+	}
+	for i, target := range c.Targets {
+		if target.Host == "" {
+			return fmt.Errorf("targets[%d]: host es obligatorio", i)
+		}
+		if target.Port < 0 {
+			return fmt.Errorf("targets[%d]: port no puede ser negativo", i)
+		}
+		if target.Port != 0 && len(target.Ports) > 0 {
+			return fmt.Errorf("targets[%d]: port y ports son excluyentes", i)
+		}
+		if client.IsSocket(target.Host) && len(target.Ports) > 0 {
+			return fmt.Errorf("targets[%d]: un socket o named pipe no lleva ports", i)
+		}
+		for _, port := range target.Ports {
+			if port <= 0 {
+				return fmt.Errorf("targets[%d]: puerto inválido en ports: %d", i, port)
+			}
+		}
+	}
+	targets := c.beatTargets()
+	if len(targets) > maxTargets {
+		return fmt.Errorf("targets: como mucho %d beats", maxTargets)
+	}
+	urls := make(map[string]bool)
+	for _, target := range targets {
+		if urls[target.url] {
+			return fmt.Errorf("targets: %s está repetido", target.url)
+		}
+		urls[target.url] = true
+	}
+	if c.AlertHistory.Size < 0 {
+		return errors.New("alert_history: size no puede ser negativo")
+	}
+	if c.AlertHistory.Retention < 0 {
+		return errors.New("alert_history: retention no puede ser negativo")
+	}
+	if c.History.Raw < 0 || c.History.Rollup < 0 || c.History.Retention < 0 {
+		return errors.New("history: raw, rollup y retention no pueden ser negativos")
+	}
+	if opts := c.History.options(); opts.Retention <= opts.Raw {
+		return errors.New("history: retention debe ser mayor que raw")
+	}
+	for i, slack := range c.Notifications.Slack {
+		if slack.WebhookURL == "" {
+			return fmt.Errorf("notifications.slack[%d]: webhook_url es obligatorio", i)
+		}
+		if err := slack.validate(); err != nil {
+			return fmt.Errorf("notifications.slack[%d]: %w", i, err)
+		}
+	}
+	for i, email := range c.Notifications.Email {
+		if email.Host == "" || email.From == "" || len(email.To) == 0 {
+			return fmt.Errorf("notifications.email[%d]: smtp_host, from y to son obligatorios", i)
+		}
+		if err := email.validate(); err != nil {
+			return fmt.Errorf("notifications.email[%d]: %w", i, err)
+		}
+	}
+	if err := c.RemoteWrite.validate(); err != nil {
+		return fmt.Errorf("remote_write: %w", err)
+	}
+	if err := c.Plugins.validate(); err != nil {
+		return fmt.Errorf("plugins: %w", err)
+	}
+	for i, path := range c.Watch {
+		if strings.TrimSpace(path) == "" {
+			return fmt.Errorf("watch[%d]: la ruta no puede estar vacía", i)
+		}
+		for _, other := range c.Watch[:i] {
+			if other == path {
+				return fmt.Errorf("watch[%d]: %s está repetida", i, path)
+			}
+		}
+	}
+	switch c.RateSmoothing {
+	case "", "instant", "1m", "5m":
+	default:
+		return errors.New("rate_smoothing: debe ser instant, 1m o 5m")
+	}
+	if err := c.Inputs.validate(); err != nil {
+		return fmt.Errorf("inputs: %w", err)
+	}
+	if es := c.Elasticsearch; es.URL != "" {
+		if len(es.Indices) == 0 {
+			return errors.New("elasticsearch: indices es obligatorio")
+		}
+		if es.Username != "" && es.APIKey != "" {
+			return errors.New("elasticsearch: username y api_key son excluyentes")
+		}
+		if es.Interval < 0 {
+			return errors.New("elasticsearch: interval no puede ser negativo")
+		}
+	}
+	if k := c.Kafka; len(k.Brokers) > 0 {
+		if k.Topic == "" {
+			return errors.New("kafka: topic es obligatorio")
+		}
+		if k.Interval < 0 {
+			return errors.New("kafka: interval no puede ser negativo")
+		}
+		if k.Password != "" && k.Username == "" {
+			return errors.New("kafka: password requiere username")
+		}
+	}
+	for i, ep := range c.Endpoints {
+		if ep.Name == "" || ep.URL == "" {
+			return fmt.Errorf("endpoints[%d]: name y url son obligatorios", i)
+		}
+		for j, field := range ep.Fields {
+			if field.Path == "" {
+				return fmt.Errorf("endpoints[%d].fields[%d]: path es obligatorio", i, j)
+			}
+		}
+	}
+	for _, named := range c.Computed {
+		if _, err := expr.Compile(named.Expr, metrics.Functions); err != nil {
+			return fmt.Errorf("computed.%s: %w", named.Name, err)
+		}
+	}
+	for i, alert := range c.Alerts {
+		if alert.Name == "" || alert.Expr == "" {
+			return fmt.Errorf("alerts[%d]: name y expr son obligatorios", i)
+		}
+		if _, err := expr.Compile(alert.Expr, metrics.Functions); err != nil {
+			return fmt.Errorf("alerts[%d]: %w", i, err)
+		}
+		if alert.Value != "" {
+			if _, err := expr.Compile(alert.Value, metrics.Functions); err != nil {
+				return fmt.Errorf("alerts[%d].value: %w", i, err)
+			}
+		}
+		switch alert.Severity {
+		case "", "info", "warning", "critical":
+		default:
+			return fmt.Errorf("alerts[%d]: severity debe ser info, warning o critical", i)
+		}
+		if alert.Panel != "" && !c.hasPanel(alert.Panel) {
+			return fmt.Errorf("alerts[%d]: panel debe ser %s o el título de un panel propio", i, strings.Join(ui.MainPanels, ", "))
+		}
+	}
+	for i, silence := range c.Silences {
+		if silence.End.IsZero() == (silence.Duration == 0) {
+			return fmt.Errorf("silences[%d]: hace falta end o duration, no los dos", i)
+		}
+		if silence.Duration < 0 {
+			return fmt.Errorf("silences[%d]: duration no puede ser negativa", i)
+		}
+		if !silence.End.IsZero() && !silence.Start.IsZero() && !silence.End.After(silence.Start) {
+			return fmt.Errorf("silences[%d]: end debe ser posterior a start", i)
+		}
+		if silence.Rule != "" && !c.hasAlert(silence.Rule) {
+			return fmt.Errorf("silences[%d]: no hay una alerta %s", i, silence.Rule)
+		}
+	}
+	for i, panel := range c.Panels {
+		if panel.Title == "" || len(panel.Metrics) == 0 {
+			return fmt.Errorf("panels[%d]: title y metrics son obligatorios", i)
+		}
+		switch panel.Type {
+		case "", ui.PanelTable, ui.PanelGauge, ui.PanelSparkline:
+		default:
+			return fmt.Errorf("panels[%d]: type debe ser table, gauge o sparkline", i)
+		}
+		switch panel.Position {
+		case "", "left", "right":
+		default:
+			return fmt.Errorf("panels[%d]: position debe ser left o right", i)
+		}
+		for j, metric := range panel.Metrics {
+			if _, err := expr.Compile(metric.Expr, metrics.Functions); err != nil {
+				return fmt.Errorf("panels[%d].metrics[%d]: %w", i, j, err)
+			}
+		}
+	}
+	return nil
+}
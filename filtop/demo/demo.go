@@ -0,0 +1,326 @@
+// Package demo simula la API HTTP de monitoreo de Filebeat con datos que
+// evolucionan (olas en la cola, picos de eventos descartados y reinicios),
+// para explorar filtop sin un Filebeat real (filtop -demo).
+package demo
+
+import (
+	"encoding/json"
+	"math"
+	"math/rand"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	version     = "8.12.0"
+	queueMax    = 3200
+	restartDown = 4 * time.Second
+)
+
+// DefaultSeed es la semilla por defecto: sin -demo-seed cada ejecución
+// muestra los mismos datos
+const DefaultSeed = 1
+
+// epoch es el comienzo del reloj simulado
+var epoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// Options configura la simulación
+type Options struct {
+	// Seed elige la secuencia de datos
+	Seed int64
+	// Step es cuánto avanza el reloj simulado con cada consulta de /stats;
+	// debería ser el intervalo de refresco de filtop. Por defecto 1s.
+	Step time.Duration
+}
+
+type input struct {
+	id     string
+	path   string
+	rate   float64 // eventos/s promedio
+	size   float64 // bytes promedio por evento
+	phase  float64
+	files  uint64
+	events float64
+	bytes  float64
+	opened uint64
+	closed uint64
+	errors uint64
+}
+
+type module struct {
+	name    string
+	enabled bool
+	errors  int
+}
+
+// Server es el Filebeat simulado. El estado avanza con un reloj simulado
+// que da un paso en cada consulta de /stats, así que con la misma semilla
+// la secuencia de respuestas se repite.
+type Server struct {
+	mu    sync.Mutex
+	rnd   *rand.Rand
+	step  time.Duration
+	clock time.Time
+
+	started     time.Time
+	last        time.Time
+	nextRestart time.Time
+	downUntil   time.Time
+	spikeUntil  time.Time
+
+	total, dropped, failed, filtered float64
+	cpuMS, outputBytes               float64
+	inputs                           []*input
+	modules                          []module
+}
+
+func New(opts Options) *Server {
+	if opts.Step <= 0 {
+		opts.Step = time.Second
+	}
+	s := &Server{rnd: rand.New(rand.NewSource(opts.Seed)), step: opts.Step, clock: epoch}
+	s.reset(epoch)
+	s.modules = []module{
+		{name: "nginx", enabled: true},
+		{name: "system", enabled: true},
+		{name: "apache", enabled: false},
+	}
+	return s
+}
+
+// Start sirve el Filebeat simulado en addr ("127.0.0.1:0" elige un puerto
+// libre) y devuelve su URL base.
+func Start(addr string, opts Options) (string, error) {
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		return "", err
+	}
+	go http.Serve(lis, New(opts))
+	return "http://" + lis.Addr().String(), nil
+}
+
+// reset simula un reinicio de Filebeat: los contadores vuelven a cero
+func (s *Server) reset(now time.Time) {
+	s.started = now
+	s.last = now
+	s.nextRestart = now.Add(time.Duration(5+s.rnd.Intn(4)) * time.Minute)
+	s.total, s.dropped, s.failed, s.filtered = 0, 0, 0, 0
+	s.cpuMS, s.outputBytes = 0, 0
+	s.inputs = []*input{
+		{id: "nginx-access", path: "/var/log/nginx/access.log", rate: 180, size: 310, files: 2},
+		{id: "nginx-error", path: "/var/log/nginx/error.log", rate: 4, size: 220, files: 1},
+		{id: "system-syslog", path: "/var/log/syslog", rate: 35, size: 160, files: 1},
+		{id: "filestream-app", path: "/var/log/app/*.json", rate: 90, size: 540, files: 4},
+	}
+	for _, in := range s.inputs {
+		in.phase = s.rnd.Float64() * 2 * math.Pi
+		in.opened = in.files
+	}
+}
+
+func (s *Server) advance(now time.Time) {
+	if now.After(s.nextRestart) {
+		s.downUntil = now.Add(restartDown)
+		s.reset(now.Add(restartDown))
+		return
+	}
+	dt := now.Sub(s.last).Seconds()
+	if dt <= 0 {
+		return
+	}
+	s.last = now
+	t := now.Sub(s.started).Seconds()
+
+	// Un pico de descartes cada dos minutos en promedio, de unos 10 segundos
+	if now.After(s.spikeUntil) && s.rnd.Float64() < dt/120 {
+		s.spikeUntil = now.Add(10 * time.Second)
+	}
+
+	var produced float64
+	for _, in := range s.inputs {
+		rate := in.rate * (1 + 0.4*math.Sin(t/30+in.phase) + 0.1*s.rnd.NormFloat64())
+		if rate < 0 {
+			rate = 0
+		}
+		n := rate * dt
+		in.events += n
+		in.bytes += n * in.size
+		produced += n
+
+		// Rotación de archivos ocasional
+		if s.rnd.Float64() < dt/90 {
+			in.opened++
+			in.closed++
+		}
+		if s.rnd.Float64() < dt/300 {
+			in.errors++
+		}
+	}
+
+	s.total += produced
+	s.filtered += produced * 0.02
+	if now.Before(s.spikeUntil) {
+		s.dropped += produced * 0.08
+		s.failed += produced * 0.01
+	}
+	s.outputBytes += produced * 280
+	s.cpuMS += dt * 1000 * (0.08 + 0.04*math.Sin(t/45))
+
+	if s.rnd.Float64() < dt/200 {
+		s.modules[0].errors++
+	}
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if r.URL.Path == "/stats" {
+		s.clock = s.clock.Add(s.step)
+	}
+	now := s.clock
+	if now.Before(s.downUntil) {
+		http.Error(w, "filebeat reiniciando", http.StatusServiceUnavailable)
+		return
+	}
+	s.advance(now)
+
+	var body interface{}
+	switch r.URL.Path {
+	case "/":
+		body = map[string]interface{}{
+			"beat":     "filebeat",
+			"hostname": "demo",
+			"name":     "demo",
+			"uuid":     "00000000-0000-4000-8000-000000000000",
+			"version":  version,
+		}
+	case "/stats":
+		body = s.stats(now)
+	case "/inputs/":
+		body = s.inputList()
+	case "/state":
+		body = s.state()
+	default:
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(body)
+}
+
+func (s *Server) stats(now time.Time) map[string]interface{} {
+	t := now.Sub(s.started).Seconds()
+	uptime := now.Sub(s.started).Milliseconds()
+
+	// La cola sube y baja en olas de 90 segundos
+	fill := 0.5 + 0.45*math.Sin(2*math.Pi*t/90) + 0.05*s.rnd.NormFloat64()
+	fill = math.Max(0, math.Min(1, fill))
+	filled := uint64(fill * queueMax)
+
+	var modules []map[string]interface{}
+	for _, m := range s.modules {
+		modules = append(modules, map[string]interface{}{"name": m.name, "enabled": m.enabled, "errors": m.errors})
+	}
+
+	return map[string]interface{}{
+		"beat": map[string]interface{}{
+			"cpu": map[string]interface{}{
+				"system": map[string]interface{}{"ticks": uint64(s.cpuMS / 30), "time": map[string]interface{}{"ms": uint64(s.cpuMS / 3)}},
+				"user":   map[string]interface{}{"ticks": uint64(s.cpuMS / 15), "time": map[string]interface{}{"ms": uint64(s.cpuMS * 2 / 3)}},
+				"total":  map[string]interface{}{"ticks": uint64(s.cpuMS / 10), "time": map[string]interface{}{"ms": uint64(s.cpuMS)}, "value": uint64(s.cpuMS)},
+			},
+			"memstats": map[string]interface{}{
+				"memory_alloc": 40<<20 + uint64(8<<20*(1+math.Sin(t/20))),
+				"rss":          120<<20 + uint64(16<<20*(1+math.Sin(t/60))),
+			},
+			"info": map[string]interface{}{
+				"uptime":  map[string]interface{}{"ms": uptime},
+				"version": version,
+			},
+		},
+		"libbeat": map[string]interface{}{
+			"pipeline": map[string]interface{}{
+				"queue": map[string]interface{}{
+					"filled":     map[string]interface{}{"events": filled},
+					"max_events": queueMax,
+				},
+				"events": map[string]interface{}{
+					"active":    filled,
+					"total":     uint64(s.total),
+					"dropped":   uint64(s.dropped),
+					"failed":    uint64(s.failed),
+					"filtered":  uint64(s.filtered),
+					"published": uint64(s.total - s.dropped - s.failed - s.filtered),
+				},
+			},
+			"output": map[string]interface{}{
+				"type": "elasticsearch",
+				"events": map[string]interface{}{
+					"acked":  uint64(s.total - s.dropped - s.failed - s.filtered),
+					"failed": uint64(s.failed),
+				},
+				"write": map[string]interface{}{"bytes": uint64(s.outputBytes)},
+			},
+		},
+		"filebeat": map[string]interface{}{
+			"harvester": map[string]interface{}{"running": 0, "open_files": 0},
+			"modules":   map[string]interface{}{"list": modules},
+		},
+		"system": map[string]interface{}{
+			"load": map[string]interface{}{
+				"norm": map[string]interface{}{
+					"1":  0.3 + 0.2*math.Sin(t/40),
+					"5":  0.3 + 0.1*math.Sin(t/200),
+					"15": 0.3,
+				},
+			},
+		},
+	}
+}
+
+func (s *Server) inputList() []map[string]interface{} {
+	var list []map[string]interface{}
+	for _, in := range s.inputs {
+		p50 := 200000 + 50000*s.rnd.Float64()
+		list = append(list, map[string]interface{}{
+			"id":                      in.id,
+			"input":                   "filestream",
+			"path":                    in.path,
+			"events_processed_total":  uint64(in.events),
+			"bytes_processed_total":   uint64(in.bytes),
+			"messages_read_total":     uint64(in.events),
+			"files_active":            in.files,
+			"files_opened_total":      in.opened,
+			"files_closed_total":      in.closed,
+			"processing_errors_total": in.errors,
+			"processing_time": map[string]interface{}{
+				"histogram": map[string]interface{}{"median": p50, "p95": p50 * 3, "p99": p50 * 6, "count": uint64(in.events)},
+			},
+			"arrival_period": map[string]interface{}{
+				"histogram": map[string]interface{}{"median": 1e9 / math.Max(in.rate, 1), "count": uint64(in.events)},
+			},
+		})
+	}
+	return list
+}
+
+func (s *Server) state() map[string]interface{} {
+	var inputs, modules []string
+	for _, in := range s.inputs {
+		inputs = append(inputs, in.id)
+	}
+	for _, m := range s.modules {
+		if m.enabled {
+			modules = append(modules, m.name)
+		}
+	}
+	return map[string]interface{}{
+		"input":  map[string]interface{}{"count": len(inputs), "names": inputs},
+		"module": map[string]interface{}{"count": len(modules), "names": modules},
+		"output": map[string]interface{}{"name": "elasticsearch"},
+		"queue":  map[string]interface{}{"name": "mem"},
+	}
+}
@@ -0,0 +1,45 @@
+package demo
+
+import (
+	"io"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// frames consulta /stats e /inputs/ n veces y devuelve las respuestas
+func frames(s *Server, n int) []string {
+	var out []string
+	for i := 0; i < n; i++ {
+		for _, path := range []string{"/stats", "/inputs/"} {
+			rec := httptest.NewRecorder()
+			s.ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+			body, _ := io.ReadAll(rec.Result().Body)
+			out = append(out, rec.Result().Status+" "+string(body))
+		}
+	}
+	return out
+}
+
+func TestRepeatable(t *testing.T) {
+	// 400 pasos de 2s cruzan al menos un reinicio
+	opts := Options{Seed: DefaultSeed, Step: 2 * time.Second}
+	a, b := frames(New(opts), 400), frames(New(opts), 400)
+	restarts := 0
+	for i := range a {
+		if a[i] != b[i] {
+			t.Fatalf("la respuesta %d difiere con la misma semilla:\n%s\n%s", i, a[i], b[i])
+		}
+		if a[i][:3] == "503" {
+			restarts++
+		}
+	}
+	if restarts == 0 {
+		t.Error("la simulación no pasó por ningún reinicio")
+	}
+
+	other := frames(New(Options{Seed: 2, Step: 2 * time.Second}), 1)
+	if other[0] == a[0] {
+		t.Error("otra semilla devuelve los mismos datos")
+	}
+}
@@ -0,0 +1,160 @@
+// Package elastic consulta el Elasticsearch de destino para contrastar lo
+// que Filebeat envía con lo que efectivamente se indexa.
+package elastic
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// Options describe la conexión con el clúster
+type Options struct {
+	URL string
+	// Usuario y contraseña, o una API key ("id:clave" en base64)
+	Username string
+	Password string
+	APIKey   string
+	// CAFile es el certificado de la CA del clúster, si no es de confianza
+	// para el sistema; Insecure desactiva la verificación
+	CAFile   string
+	Insecure bool
+	Timeout  time.Duration
+}
+
+type Client struct {
+	opts Options
+	http *http.Client
+}
+
+// New crea un cliente para el clúster de opts
+func New(opts Options) (*Client, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if opts.CAFile != "" || opts.Insecure {
+		tlsConfig := &tls.Config{InsecureSkipVerify: opts.Insecure}
+		if opts.CAFile != "" {
+			pem, err := os.ReadFile(opts.CAFile)
+			if err != nil {
+				return nil, err
+			}
+			tlsConfig.RootCAs = x509.NewCertPool()
+			if !tlsConfig.RootCAs.AppendCertsFromPEM(pem) {
+				return nil, fmt.Errorf("%s: no contiene certificados PEM", opts.CAFile)
+			}
+		}
+		transport.TLSClientConfig = tlsConfig
+	}
+	return &Client{
+		opts: opts,
+		http: &http.Client{Timeout: opts.Timeout, Transport: transport},
+	}, nil
+}
+
+// Error es una respuesta de error de Elasticsearch
+type Error struct {
+	Status int
+	Reason string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("elasticsearch: %s (%d)", e.Reason, e.Status)
+}
+
+// get consulta path y decodifica la respuesta JSON en v
+func (c *Client) get(ctx context.Context, path string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(c.opts.URL, "/")+path, nil)
+	if err != nil {
+		return err
+	}
+	switch {
+	case c.opts.APIKey != "":
+		req.Header.Set("Authorization", "ApiKey "+c.opts.APIKey)
+	case c.opts.Username != "":
+		req.SetBasicAuth(c.opts.Username, c.opts.Password)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return responseError(resp)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// responseError extrae el motivo de un error de Elasticsearch, que viene en
+// error.reason salvo en algunos errores de autenticación.
+func responseError(resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	var doc struct {
+		Error struct {
+			Reason string `json:"reason"`
+		} `json:"error"`
+	}
+	reason := resp.Status
+	if json.Unmarshal(body, &doc) == nil && doc.Error.Reason != "" {
+		reason = doc.Error.Reason
+	}
+	return &Error{Status: resp.StatusCode, Reason: reason}
+}
+
+// IndexTotal devuelve los documentos indexados en las réplicas primarias de
+// indices (índices, data streams o patrones). Al borrarse un índice viejo
+// por ILM el total puede bajar.
+func (c *Client) IndexTotal(ctx context.Context, indices []string) (uint64, error) {
+	var doc struct {
+		All struct {
+			Primaries struct {
+				Indexing struct {
+					IndexTotal uint64 `json:"index_total"`
+				} `json:"indexing"`
+			} `json:"primaries"`
+		} `json:"_all"`
+	}
+	path := "/" + indexList(indices) + "/_stats/indexing?filter_path=_all.primaries.indexing.index_total"
+	if err := c.get(ctx, path, &doc); err != nil {
+		return 0, err
+	}
+	return doc.All.Primaries.Indexing.IndexTotal, nil
+}
+
+// Count devuelve los documentos de indices cuyo campo message contiene la
+// frase phrase, p. ej. una marca de latencia
+func (c *Client) Count(ctx context.Context, indices []string, phrase string) (uint64, error) {
+	var doc struct {
+		Count uint64 `json:"count"`
+	}
+	query := url.QueryEscape(`message:"` + phrase + `"`)
+	if err := c.get(ctx, "/"+indexList(indices)+"/_count?q="+query, &doc); err != nil {
+		return 0, err
+	}
+	return doc.Count, nil
+}
+
+// indexList arma la lista de índices de una ruta de la API
+func indexList(indices []string) string {
+	escaped := make([]string, len(indices))
+	for i, index := range indices {
+		escaped[i] = url.PathEscape(index)
+	}
+	return strings.Join(escaped, ",")
+}
+
+// Ingestion contrasta los eventos por segundo que Filebeat confirmó (acked)
+// con los documentos indexados desde Since. Sent es nil si no hay muestras
+// de Filebeat que cubran el intervalo.
+type Ingestion struct {
+	Since   time.Time `json:"since"`
+	Indexed float64   `json:"indexed_per_sec"`
+	Sent    *float64  `json:"sent_per_sec,omitempty"`
+}
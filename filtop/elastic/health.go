@@ -0,0 +1,95 @@
+package elastic
+
+import (
+	"context"
+	"sort"
+	"strings"
+)
+
+// Health es el estado del clúster de destino. Cuando las métricas de salida
+// de Filebeat empeoran, la causa suele estar en el clúster: shards sin
+// asignar, bulk rechazados por una cola de escritura llena o índices que ILM
+// no pudo rotar.
+type Health struct {
+	// Status es green, yellow o red
+	Status           string `json:"status"`
+	Nodes            int    `json:"nodes"`
+	UnassignedShards int    `json:"unassigned_shards"`
+	// WriteQueue son las tareas de escritura encoladas en todos los nodos
+	WriteQueue int `json:"write_queue"`
+	// WriteRejected es el total de rechazos de escritura desde que arrancó
+	// cada nodo
+	WriteRejected uint64 `json:"write_rejected"`
+	// RejectedPerSec solo lo calcula Monitor
+	RejectedPerSec float64 `json:"write_rejected_per_sec"`
+	// ILMErrors son los índices de indices cuyo paso de ILM falló
+	ILMErrors []ILMError `json:"ilm_errors"`
+}
+
+// ILMError es un índice detenido en el paso ERROR de su política
+type ILMError struct {
+	Index string `json:"index"`
+	// FailedStep es el paso que falló, p. ej. check-rollover-ready
+	FailedStep string `json:"failed_step"`
+	Reason     string `json:"reason"`
+}
+
+// Health consulta el estado del clúster, la cola de escritura de los nodos
+// y los errores de ILM de indices. Requiere los privilegios monitor del
+// clúster y view_index_metadata sobre los índices.
+func (c *Client) Health(ctx context.Context, indices []string) (*Health, error) {
+	var cluster struct {
+		Status           string `json:"status"`
+		Nodes            int    `json:"number_of_nodes"`
+		UnassignedShards int    `json:"unassigned_shards"`
+	}
+	if err := c.get(ctx, "/_cluster/health?filter_path=status,number_of_nodes,unassigned_shards", &cluster); err != nil {
+		return nil, err
+	}
+	health := &Health{
+		Status:           cluster.Status,
+		Nodes:            cluster.Nodes,
+		UnassignedShards: cluster.UnassignedShards,
+		ILMErrors:        []ILMError{},
+	}
+
+	var nodes struct {
+		Nodes map[string]struct {
+			ThreadPool struct {
+				Write struct {
+					Queue    int    `json:"queue"`
+					Rejected uint64 `json:"rejected"`
+				} `json:"write"`
+			} `json:"thread_pool"`
+		} `json:"nodes"`
+	}
+	if err := c.get(ctx, "/_nodes/stats/thread_pool?filter_path=nodes.*.thread_pool.write.queue,nodes.*.thread_pool.write.rejected", &nodes); err != nil {
+		return nil, err
+	}
+	for _, node := range nodes.Nodes {
+		health.WriteQueue += node.ThreadPool.Write.Queue
+		health.WriteRejected += node.ThreadPool.Write.Rejected
+	}
+
+	var explain struct {
+		Indices map[string]struct {
+			FailedStep string `json:"failed_step"`
+			StepInfo   struct {
+				Reason string `json:"reason"`
+			} `json:"step_info"`
+		} `json:"indices"`
+	}
+	path := "/" + indexList(indices) + "/_ilm/explain?only_errors=true&filter_path=indices.*.failed_step,indices.*.step_info.reason"
+	if err := c.get(ctx, path, &explain); err != nil {
+		return nil, err
+	}
+	for index, info := range explain.Indices {
+		health.ILMErrors = append(health.ILMErrors, ILMError{
+			Index:      index,
+			FailedStep: info.FailedStep,
+			Reason:     strings.TrimSpace(info.StepInfo.Reason),
+		})
+	}
+	sort.Slice(health.ILMErrors, func(i, j int) bool { return health.ILMErrors[i].Index < health.ILMErrors[j].Index })
+	return health, nil
+}
@@ -0,0 +1,68 @@
+package elastic
+
+import (
+	"context"
+	"time"
+)
+
+// Stats es el resultado de una consulta de Monitor
+type Stats struct {
+	Time time.Time `json:"time"`
+	// Ingestion es nil en la primera consulta o si el total de documentos
+	// bajó (ILM borró un índice del patrón): ese intervalo se descarta en
+	// lugar de mostrar una pérdida que no existe
+	Ingestion *Ingestion `json:"ingestion,omitempty"`
+	// Health es nil si no se pudo consultar, p. ej. por falta de
+	// privilegios; HealthError indica el motivo
+	Health      *Health `json:"health,omitempty"`
+	HealthError string  `json:"health_error,omitempty"`
+}
+
+// Monitor consulta el clúster en cada ciclo y calcula las tasas respecto de
+// la consulta anterior. No es seguro usarlo desde varias goroutines.
+type Monitor struct {
+	client  *Client
+	indices []string
+
+	prevAt       time.Time
+	prevTotal    uint64
+	prevRejected *uint64
+}
+
+// NewMonitor crea un Monitor para los índices o data streams de indices
+func NewMonitor(client *Client, indices []string) *Monitor {
+	return &Monitor{client: client, indices: indices}
+}
+
+// Collect consulta el clúster. Solo devuelve error si no se pudo obtener el
+// total de documentos indexados.
+func (m *Monitor) Collect(ctx context.Context) (*Stats, error) {
+	total, err := m.client.IndexTotal(ctx, m.indices)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	stats := &Stats{Time: now}
+	if !m.prevAt.IsZero() && total >= m.prevTotal {
+		stats.Ingestion = &Ingestion{
+			Since:   m.prevAt,
+			Indexed: float64(total-m.prevTotal) / now.Sub(m.prevAt).Seconds(),
+		}
+	}
+
+	health, err := m.client.Health(ctx, m.indices)
+	if err != nil {
+		stats.HealthError = err.Error()
+		m.prevRejected = nil
+	} else {
+		// Los rechazos se cuentan por nodo: si uno se reinicia el total baja
+		if m.prevRejected != nil && health.WriteRejected >= *m.prevRejected {
+			health.RejectedPerSec = float64(health.WriteRejected-*m.prevRejected) / now.Sub(m.prevAt).Seconds()
+		}
+		rejected := health.WriteRejected
+		m.prevRejected = &rejected
+		stats.Health = health
+	}
+	m.prevAt, m.prevTotal = now, total
+	return stats, nil
+}
@@ -0,0 +1,226 @@
+// Package expr implementa el pequeño lenguaje de expresiones de filtop para
+// métricas calculadas y alertas, por ejemplo:
+//
+//	pipeline.events.dropped / pipeline.events.total
+//	rate(pipeline.events.total) > 100 && pipeline.events.dropped == 0
+//
+// Todos los valores son float64; las comparaciones y operadores lógicos
+// devuelven 1 (verdadero) o 0 (falso).
+package expr
+
+import (
+	"fmt"
+	"math"
+)
+
+// Env resuelve las métricas y funciones que aparecen en una expresión
+type Env interface {
+	Metric(path string) (float64, bool)
+	Call(name string, args []Node) (float64, error)
+}
+
+// Node es un nodo del árbol sintáctico
+type Node interface {
+	Eval(env Env) (float64, error)
+	String() string
+}
+
+// Expr es una expresión compilada
+type Expr struct {
+	src  string
+	root Node
+}
+
+// Functions son los nombres de las funciones que implementa un Env
+type Functions map[string]bool
+
+// Compile analiza src y devuelve la expresión lista para evaluar. Rechaza
+// las funciones que no están en functions, para que un nombre mal escrito o
+// que el entorno no implementa falle al validar la configuración y no
+// recién al evaluarla.
+func Compile(src string, functions Functions) (*Expr, error) {
+	p := &parser{lex: newLexer(src), functions: functions}
+	p.next()
+	root, err := p.parseOr()
+	if err == nil {
+		err = p.err
+	}
+	if err != nil {
+		return nil, err
+	}
+	if p.tok.kind != tokEOF {
+		return nil, p.errorf("token inesperado %q", p.tok.text)
+	}
+	return &Expr{src: src, root: root}, nil
+}
+
+// Eval evalúa la expresión. Una división por cero produce NaN.
+func (e *Expr) Eval(env Env) (float64, error) {
+	return e.root.Eval(env)
+}
+
+// Bool evalúa la expresión como condición
+func (e *Expr) Bool(env Env) (bool, error) {
+	v, err := e.Eval(env)
+	return truthy(v), err
+}
+
+func (e *Expr) String() string { return e.src }
+
+// Metrics devuelve las rutas de métricas referenciadas, incluidas las que
+// aparecen como argumentos de funciones.
+func (e *Expr) Metrics() []string {
+	var paths []string
+	var walk func(n Node)
+	walk = func(n Node) {
+		switch n := n.(type) {
+		case *MetricNode:
+			paths = append(paths, n.Path)
+		case *unaryNode:
+			walk(n.x)
+		case *binaryNode:
+			walk(n.x)
+			walk(n.y)
+		case *CallNode:
+			for _, arg := range n.Args {
+				walk(arg)
+			}
+		}
+	}
+	walk(e.root)
+	return paths
+}
+
+type numberNode struct{ value float64 }
+
+func (n *numberNode) Eval(Env) (float64, error) { return n.value, nil }
+func (n *numberNode) String() string            { return fmt.Sprint(n.value) }
+
+// MetricNode es una referencia a una métrica, como pipeline.events.total
+type MetricNode struct{ Path string }
+
+func (n *MetricNode) Eval(env Env) (float64, error) {
+	v, ok := env.Metric(n.Path)
+	if !ok {
+		return 0, fmt.Errorf("métrica desconocida: %s", n.Path)
+	}
+	return v, nil
+}
+
+func (n *MetricNode) String() string { return n.Path }
+
+// CallNode es una llamada a función; los argumentos se pasan sin evaluar
+// para que funciones como rate() puedan usar la ruta de la métrica.
+type CallNode struct {
+	Name string
+	Args []Node
+}
+
+func (n *CallNode) Eval(env Env) (float64, error) { return env.Call(n.Name, n.Args) }
+
+func (n *CallNode) String() string {
+	s := n.Name + "("
+	for i, arg := range n.Args {
+		if i > 0 {
+			s += ", "
+		}
+		s += arg.String()
+	}
+	return s + ")"
+}
+
+type unaryNode struct {
+	op string
+	x  Node
+}
+
+func (n *unaryNode) Eval(env Env) (float64, error) {
+	x, err := n.x.Eval(env)
+	if err != nil {
+		return 0, err
+	}
+	if n.op == "!" {
+		return boolValue(!truthy(x)), nil
+	}
+	return -x, nil
+}
+
+func (n *unaryNode) String() string { return n.op + n.x.String() }
+
+type binaryNode struct {
+	op   string
+	x, y Node
+}
+
+func (n *binaryNode) Eval(env Env) (float64, error) {
+	x, err := n.x.Eval(env)
+	if err != nil {
+		return 0, err
+	}
+	// Evaluación en cortocircuito
+	switch n.op {
+	case "&&":
+		if !truthy(x) {
+			return 0, nil
+		}
+	case "||":
+		if truthy(x) {
+			return 1, nil
+		}
+	}
+	y, err := n.y.Eval(env)
+	if err != nil {
+		return 0, err
+	}
+
+	switch n.op {
+	case "+":
+		return x + y, nil
+	case "-":
+		return x - y, nil
+	case "*":
+		return x * y, nil
+	case "/":
+		if y == 0 {
+			return math.NaN(), nil
+		}
+		return x / y, nil
+	case "%":
+		if y == 0 {
+			return math.NaN(), nil
+		}
+		return math.Mod(x, y), nil
+	case "<":
+		return boolValue(x < y), nil
+	case "<=":
+		return boolValue(x <= y), nil
+	case ">":
+		return boolValue(x > y), nil
+	case ">=":
+		return boolValue(x >= y), nil
+	case "==":
+		return boolValue(x == y), nil
+	case "!=":
+		return boolValue(x != y), nil
+	case "&&", "||":
+		return boolValue(truthy(y)), nil
+	}
+	return 0, fmt.Errorf("operador desconocido %q", n.op)
+}
+
+func (n *binaryNode) String() string {
+	return "(" + n.x.String() + " " + n.op + " " + n.y.String() + ")"
+}
+
+// truthy indica si v es verdadero como condición: NaN, como el de una
+// división por cero, es falso igual que 0
+func truthy(v float64) bool {
+	return v != 0 && !math.IsNaN(v)
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
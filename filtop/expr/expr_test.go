@@ -0,0 +1,249 @@
+package expr
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// testEnv resuelve métricas de un mapa y cuenta las llamadas a funciones,
+// para comprobar el cortocircuito
+type testEnv struct {
+	metrics map[string]float64
+	calls   int
+}
+
+func (e *testEnv) Metric(path string) (float64, bool) {
+	v, ok := e.metrics[path]
+	return v, ok
+}
+
+func (e *testEnv) Call(name string, args []Node) (float64, error) {
+	e.calls++
+	if name == "abs" && len(args) == 1 {
+		v, err := args[0].Eval(e)
+		return math.Abs(v), err
+	}
+	return 0, fmt.Errorf("%s() no disponible", name)
+}
+
+// functions son las que acepta Compile en las pruebas
+var functions = Functions{"rate": true, "delta": true, "increase": true, "abs": true, "min": true, "max": true}
+
+func newTestEnv() *testEnv {
+	return &testEnv{metrics: map[string]float64{
+		"pipeline.events.total":   100,
+		"pipeline.events.dropped": 5,
+		"inputs[0].events":        7,
+		"zero":                    0,
+	}}
+}
+
+func TestEval(t *testing.T) {
+	tests := []struct {
+		src  string
+		want float64
+	}{
+		// Precedencia y asociatividad
+		{"1 + 2 * 3", 7},
+		{"(1 + 2) * 3", 9},
+		{"2 - 3 - 4", -5},
+		{"24 / 4 / 2", 3},
+		{"10 % 4 * 2", 4},
+		{"2 * 7 % 4", 2},
+		{"-2 * 3", -6},
+		{"--2", 2},
+		{"!0 + 1", 2},
+		{"1 + 2 > 2", 1},
+		{"1 < 2 == 1", 1},
+		{"1 > 2 || 3 > 2 && 0", 0},
+		{"1 > 2 || 3 > 2 && 1", 1},
+		{"0 && 1 || 1", 1},
+		// Números
+		{"1e3", 1000},
+		{"1e-3", 0.001},
+		{".5 + 0.25", 0.75},
+		{"true + true", 2},
+		{"false", 0},
+		// Métricas y funciones
+		{"pipeline.events.dropped / pipeline.events.total * 100", 5},
+		{"inputs[0].events - 7", 0},
+		{"abs(zero - 3)", 3},
+		{"-pipeline.events.dropped", -5},
+		{"!pipeline.events.total", 0},
+		// NaN es falso en los operadores lógicos, como en Bool
+		{"0 / 0 || 0", 0},
+		{"0 || 0 / 0", 0},
+		{"0 / 0 && 1", 0},
+		{"1 && 1 / zero", 0},
+		{"!(0 / 0)", 1},
+		{"!!(0 / 0)", 0},
+		{"0 / 0 || 1", 1},
+	}
+	for _, tt := range tests {
+		e, err := Compile(tt.src, functions)
+		if err != nil {
+			t.Errorf("Compile(%q): %v", tt.src, err)
+			continue
+		}
+		got, err := e.Eval(newTestEnv())
+		if err != nil {
+			t.Errorf("Eval(%q): %v", tt.src, err)
+			continue
+		}
+		if math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("Eval(%q) = %v, se esperaba %v", tt.src, got, tt.want)
+		}
+	}
+}
+
+func TestShortCircuit(t *testing.T) {
+	tests := []struct {
+		src     string
+		want    float64
+		calls   int
+		wantErr bool
+	}{
+		// El lado derecho no se evalúa: ni la métrica desconocida ni la
+		// función
+		{src: "0 && unknown.metric", want: 0},
+		{src: "1 || unknown.metric", want: 1},
+		{src: "zero && abs(1)", want: 0},
+		{src: "pipeline.events.total || abs(1)", want: 1},
+		// El lado derecho sí se evalúa
+		{src: "1 && abs(-2)", want: 1, calls: 1},
+		{src: "0 || abs(0)", want: 0, calls: 1},
+		{src: "1 && unknown.metric", wantErr: true},
+		{src: "0 || unknown.metric", wantErr: true},
+		// && y || devuelven 0 o 1
+		{src: "5 && 7", want: 1},
+		{src: "0 || 7", want: 1},
+	}
+	for _, tt := range tests {
+		e, err := Compile(tt.src, functions)
+		if err != nil {
+			t.Errorf("Compile(%q): %v", tt.src, err)
+			continue
+		}
+		env := newTestEnv()
+		got, err := e.Eval(env)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("Eval(%q) = %v, se esperaba un error", tt.src, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Eval(%q): %v", tt.src, err)
+			continue
+		}
+		if got != tt.want || env.calls != tt.calls {
+			t.Errorf("Eval(%q) = %v con %d llamadas, se esperaba %v con %d", tt.src, got, env.calls, tt.want, tt.calls)
+		}
+	}
+}
+
+func TestDivisionByZero(t *testing.T) {
+	for _, src := range []string{"1 / 0", "0 / 0", "5 % 0", "pipeline.events.dropped / zero", "1 / zero + 1"} {
+		e, err := Compile(src, functions)
+		if err != nil {
+			t.Errorf("Compile(%q): %v", src, err)
+			continue
+		}
+		got, err := e.Eval(newTestEnv())
+		if err != nil || !math.IsNaN(got) {
+			t.Errorf("Eval(%q) = %v, %v; se esperaba NaN", src, got, err)
+		}
+		// NaN no es una condición verdadera
+		if ok, err := e.Bool(newTestEnv()); ok || err != nil {
+			t.Errorf("Bool(%q) = %v, %v; se esperaba false", src, ok, err)
+		}
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		// Errores del lexer, con la posición del carácter
+		{"1 # 2", "posición 2: carácter inesperado '#'"},
+		{"a & b", "posición 2: carácter inesperado '&'"},
+		{"a | b", "posición 2: carácter inesperado '|'"},
+		{"   @", "posición 3: carácter inesperado '@'"},
+		{"x == 'a'", "posición 5: carácter inesperado '\\''"},
+		// Errores del parser
+		{"1 +", "posición 3: fin inesperado de la expresión"},
+		{"", "posición 0: fin inesperado de la expresión"},
+		{"(1 + 2", "posición 6: se esperaba ')'"},
+		{"1 2", "posición 2: token inesperado \"2\""},
+		{"abs(1 2)", "posición 6: se esperaba ',' o ')'"},
+		{"* 2", "posición 0: token inesperado \"*\""},
+		{"1..2", "posición 0: número inválido \"1..2\""},
+		// Funciones desconocidas
+		{"foo(x)", "posición 0: función desconocida: foo()"},
+		{"1 + rates(a.b) > 0", "posición 4: función desconocida: rates()"},
+		{"abs(sqrt(2))", "posición 4: función desconocida: sqrt()"},
+	}
+	for _, tt := range tests {
+		_, err := Compile(tt.src, functions)
+		if err == nil {
+			t.Errorf("Compile(%q): se esperaba un error", tt.src)
+			continue
+		}
+		if err.Error() != tt.want {
+			t.Errorf("Compile(%q) = %q, se esperaba %q", tt.src, err, tt.want)
+		}
+	}
+}
+
+func TestKnownFunctions(t *testing.T) {
+	for _, src := range []string{"rate(a.b)", "delta(a.b)", "increase(drops)", "abs(-1)", "min(1, 2)", "max(1, 2, 3)"} {
+		if _, err := Compile(src, functions); err != nil {
+			t.Errorf("Compile(%q): %v", src, err)
+		}
+	}
+	// Solo las del entorno
+	if _, err := Compile("abs(increase(drops))", Functions{"abs": true}); err == nil || err.Error() != "posición 4: función desconocida: increase()" {
+		t.Errorf("increase() fuera del entorno: %v", err)
+	}
+}
+
+func TestUnknownIdentifiers(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{"unknown.metric > 0", "métrica desconocida: unknown.metric"},
+		{"abs(missing)", "métrica desconocida: missing"},
+		// Conocida por el lenguaje pero no por este entorno
+		{"rate(pipeline.events.total)", "rate() no disponible"},
+	}
+	for _, tt := range tests {
+		e, err := Compile(tt.src, functions)
+		if err != nil {
+			t.Errorf("Compile(%q): %v", tt.src, err)
+			continue
+		}
+		_, err = e.Eval(newTestEnv())
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Eval(%q) = %v, se esperaba %q", tt.src, err, tt.want)
+		}
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	e, err := Compile("rate(a.b) + c[0].d * max(e, 2) > a.b", functions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"a.b", "c[0].d", "e", "a.b"}
+	if got := e.Metrics(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Metrics() = %v, se esperaba %v", got, want)
+	}
+	if e.String() != "rate(a.b) + c[0].d * max(e, 2) > a.b" {
+		t.Errorf("String() = %q", e.String())
+	}
+}
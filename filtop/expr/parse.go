@@ -0,0 +1,227 @@
+package expr
+
+import (
+	"fmt"
+	"strconv"
+	"unicode"
+)
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokNumber
+	tokIdent
+	tokOp
+	tokLParen
+	tokRParen
+	tokComma
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+type lexer struct {
+	src []rune
+	pos int
+}
+
+func newLexer(src string) *lexer { return &lexer{src: []rune(src)} }
+
+// Operadores de dos caracteres, se prueban antes que los de uno
+var twoCharOps = []string{"<=", ">=", "==", "!=", "&&", "||"}
+
+func (l *lexer) next() (token, error) {
+	for l.pos < len(l.src) && unicode.IsSpace(l.src[l.pos]) {
+		l.pos++
+	}
+	if l.pos >= len(l.src) {
+		return token{kind: tokEOF, pos: l.pos}, nil
+	}
+
+	start := l.pos
+	c := l.src[l.pos]
+	switch {
+	case unicode.IsDigit(c) || (c == '.' && l.pos+1 < len(l.src) && unicode.IsDigit(l.src[l.pos+1])):
+		for l.pos < len(l.src) && (unicode.IsDigit(l.src[l.pos]) || l.src[l.pos] == '.' || l.src[l.pos] == 'e' ||
+			((l.src[l.pos] == '-' || l.src[l.pos] == '+') && (l.src[l.pos-1] == 'e'))) {
+			l.pos++
+		}
+		return token{kind: tokNumber, text: string(l.src[start:l.pos]), pos: start}, nil
+	case unicode.IsLetter(c) || c == '_' || c == '$':
+		// Las rutas de métricas admiten puntos e índices: a.b[0].c
+		for l.pos < len(l.src) && isIdentRune(l.src[l.pos]) {
+			l.pos++
+		}
+		return token{kind: tokIdent, text: string(l.src[start:l.pos]), pos: start}, nil
+	case c == '(':
+		l.pos++
+		return token{kind: tokLParen, text: "(", pos: start}, nil
+	case c == ')':
+		l.pos++
+		return token{kind: tokRParen, text: ")", pos: start}, nil
+	case c == ',':
+		l.pos++
+		return token{kind: tokComma, text: ",", pos: start}, nil
+	}
+
+	if l.pos+1 < len(l.src) {
+		pair := string(l.src[l.pos : l.pos+2])
+		for _, op := range twoCharOps {
+			if pair == op {
+				l.pos += 2
+				return token{kind: tokOp, text: op, pos: start}, nil
+			}
+		}
+	}
+	switch c {
+	case '+', '-', '*', '/', '%', '<', '>', '!':
+		l.pos++
+		return token{kind: tokOp, text: string(c), pos: start}, nil
+	}
+	return token{}, fmt.Errorf("posición %d: carácter inesperado %q", start, c)
+}
+
+func isIdentRune(c rune) bool {
+	return unicode.IsLetter(c) || unicode.IsDigit(c) || c == '_' || c == '.' || c == '[' || c == ']' || c == '$'
+}
+
+type parser struct {
+	lex       *lexer
+	functions Functions
+	tok       token
+	err       error
+}
+
+func (p *parser) next() {
+	if p.err != nil {
+		return
+	}
+	p.tok, p.err = p.lex.next()
+	if p.err != nil {
+		p.tok = token{kind: tokEOF}
+	}
+}
+
+func (p *parser) errorf(format string, args ...interface{}) error {
+	if p.err != nil {
+		return p.err
+	}
+	return fmt.Errorf("posición %d: %s", p.tok.pos, fmt.Sprintf(format, args...))
+}
+
+func (p *parser) isOp(ops ...string) bool {
+	if p.tok.kind != tokOp {
+		return false
+	}
+	for _, op := range ops {
+		if p.tok.text == op {
+			return true
+		}
+	}
+	return false
+}
+
+// binary analiza una cadena asociativa a izquierda de operadores del mismo nivel
+func (p *parser) binary(operand func() (Node, error), ops ...string) (Node, error) {
+	x, err := operand()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOp(ops...) {
+		op := p.tok.text
+		p.next()
+		y, err := operand()
+		if err != nil {
+			return nil, err
+		}
+		x = &binaryNode{op: op, x: x, y: y}
+	}
+	return x, nil
+}
+
+func (p *parser) parseOr() (Node, error)  { return p.binary(p.parseAnd, "||") }
+func (p *parser) parseAnd() (Node, error) { return p.binary(p.parseCmp, "&&") }
+func (p *parser) parseCmp() (Node, error) {
+	return p.binary(p.parseAdd, "<", "<=", ">", ">=", "==", "!=")
+}
+func (p *parser) parseAdd() (Node, error) { return p.binary(p.parseMul, "+", "-") }
+func (p *parser) parseMul() (Node, error) { return p.binary(p.parseUnary, "*", "/", "%") }
+
+func (p *parser) parseUnary() (Node, error) {
+	if p.isOp("-", "!") {
+		op := p.tok.text
+		p.next()
+		x, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &unaryNode{op: op, x: x}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *parser) parsePrimary() (Node, error) {
+	switch p.tok.kind {
+	case tokNumber:
+		v, err := strconv.ParseFloat(p.tok.text, 64)
+		if err != nil {
+			return nil, p.errorf("número inválido %q", p.tok.text)
+		}
+		p.next()
+		return &numberNode{value: v}, nil
+
+	case tokIdent:
+		name, pos := p.tok.text, p.tok.pos
+		p.next()
+		switch name {
+		case "true":
+			return &numberNode{value: 1}, nil
+		case "false":
+			return &numberNode{value: 0}, nil
+		}
+		if p.tok.kind != tokLParen {
+			return &MetricNode{Path: name}, nil
+		}
+
+		if !p.functions[name] {
+			return nil, fmt.Errorf("posición %d: función desconocida: %s()", pos, name)
+		}
+		p.next()
+		call := &CallNode{Name: name}
+		for p.tok.kind != tokRParen {
+			arg, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			call.Args = append(call.Args, arg)
+			if p.tok.kind == tokComma {
+				p.next()
+			} else if p.tok.kind != tokRParen {
+				return nil, p.errorf("se esperaba ',' o ')'")
+			}
+		}
+		p.next()
+		return call, nil
+
+	case tokLParen:
+		p.next()
+		x, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.tok.kind != tokRParen {
+			return nil, p.errorf("se esperaba ')'")
+		}
+		p.next()
+		return x, nil
+	}
+
+	if p.tok.kind == tokEOF {
+		return nil, p.errorf("fin inesperado de la expresión")
+	}
+	return nil, p.errorf("token inesperado %q", p.tok.text)
+}
@@ -1,19 +1,31 @@
 package main
 
 import (
-	"encoding/json"
-	"flag"
+	"context"
 	"fmt"
-	"log"
+	"io"
+	"log/slog"
+	"net"
 	"net/http"
+	"net/url"
 	"os"
-	"os/signal"
-	"strings"
-	"syscall"
+	"strconv"
 	"time"
 
-	"github.com/gdamore/tcell/v2"
-	"github.com/rivo/tview"
+	"github.com/iTiagoCO/filtop/filtop/alerts"
+	"github.com/iTiagoCO/filtop/filtop/client"
+	"github.com/iTiagoCO/filtop/filtop/elastic"
+	"github.com/iTiagoCO/filtop/filtop/kafka"
+	"github.com/iTiagoCO/filtop/filtop/metrics"
+	"github.com/iTiagoCO/filtop/filtop/mirror"
+	"github.com/iTiagoCO/filtop/filtop/notify"
+	"github.com/iTiagoCO/filtop/filtop/plugins"
+	"github.com/iTiagoCO/filtop/filtop/probe"
+	"github.com/iTiagoCO/filtop/filtop/registry"
+	"github.com/iTiagoCO/filtop/filtop/remotewrite"
+	"github.com/iTiagoCO/filtop/filtop/system"
+	"github.com/iTiagoCO/filtop/filtop/telemetry"
+	"github.com/iTiagoCO/filtop/filtop/ui"
 )
 
 const (
@@ -21,530 +33,781 @@ const (
 	defaultPort     = 5066
 	defaultInterval = 5
 	historySize     = 30
-)
 
-var (
-	app          *tview.Application
-	pages        *tview.Pages
-	pageMap      map[string]tview.Primitive
-	lastStats    *FilebeatStats
-	history      []*FilebeatStats
-	refresh      time.Duration
-	currentFocus int
+	defaultListen    = ":8066"
+	defaultRetention = time.Hour
+	shutdownTimeout  = 5 * time.Second
+
+	// Uso a partir del cual se avisa que un disco de logs está por llenarse
+	diskFullPercent = 90
+
+	// Eventos que Elasticsearch confirmó a Filebeat
+	ackedEventsPath = "output.events.acked"
 )
 
-// Estructuras de datos mejoradas para mapear correctamente la respuesta JSON
-type FilebeatStats struct {
-	Timestamp time.Time `json:"timestamp"`
-	Beat      struct {
-		CPU struct {
-			System struct {
-				Ticks uint64 `json:"ticks"`
-				Time  struct {
-					MS uint64 `json:"ms"`
-				} `json:"time"`
-			} `json:"system"`
-			Total struct {
-				Ticks uint64 `json:"ticks"`
-				Time  struct {
-					MS uint64 `json:"ms"`
-				} `json:"time"`
-				Value uint64 `json:"value"`
-			} `json:"total"`
-			User struct {
-				Ticks uint64 `json:"ticks"`
-				Time  struct {
-					MS uint64 `json:"ms"`
-				} `json:"time"`
-			} `json:"user"`
-		} `json:"cpu"`
-		Memstats struct {
-			MemoryAlloc uint64 `json:"memory_alloc"`
-			RSS         uint64 `json:"rss"`
-		} `json:"memstats"`
-		Info struct {
-			Uptime struct {
-				MS uint64 `json:"ms"`
-			} `json:"uptime"`
-		} `json:"info"`
-	} `json:"beat"`
-	Libbeat struct {
-		Pipeline struct {
-			Queue struct {
-				Filled struct {
-					Events uint64 `json:"events"`
-				} `json:"filled"`
-				MaxEvents uint64 `json:"max_events"`
-			} `json:"queue"`
-			Events struct {
-				Total    uint64 `json:"total"`
-				Dropped  uint64 `json:"dropped"`
-				Failed   uint64 `json:"failed"`
-				Filtered uint64 `json:"filtered"`
-			} `json:"events"`
-		} `json:"pipeline"`
-	} `json:"libbeat"`
-	Filebeat struct {
-		Harvester struct {
-			Running    uint64 `json:"running"`
-			Open       uint64 `json:"open_files"`
-			Closed     uint64 `json:"closed"`
-			Started    uint64 `json:"started"`
-			Terminated uint64 `json:"skipped"`
-		} `json:"harvester"`
-		Inputs  []Input `json:"inputs"`
-		Modules struct {
-			List []struct {
-				Name    string `json:"name"`
-				Enabled bool   `json:"enabled"`
-				Errors  int    `json:"errors"`
-			} `json:"list"`
-		} `json:"modules"`
-	} `json:"filebeat"`
-	System struct {
-		Load struct {
-			Norm struct {
-				Load1  float64 `json:"1"`
-				Load5  float64 `json:"5"`
-				Load15 float64 `json:"15"`
-			} `json:"norm"`
-		} `json:"load"`
-	} `json:"system"`
-}
+var refresh time.Duration
 
-type Input struct {
-	ID            string `json:"id"`
-	Type          string `json:"input"`
-	Device        string `json:"device"`
-	Packets       uint64 `json:"packets"`
-	Bytes         uint64 `json:"bytes"`
-	Events        uint64 `json:"events"`
-	Active        bool   `json:"active"`
-	ArrivalPeriod struct {
-		Histogram map[string]interface{} `json:"histogram"`
-	} `json:"arrival_period"`
-	ProcessingTime struct {
-		Histogram map[string]interface{} `json:"histogram"`
-	} `json:"processing_time"`
-	Throughput struct {
-		Bytes  float64 `json:"bytes"`
-		Events float64 `json:"events"`
-	} `json:"throughput"`
-	Files uint64 `json:"files"`
-}
+// selfMetrics son las métricas propias de filtop que se exponen en /metrics
+var selfMetrics = telemetry.New()
 
 func main() {
-	host := flag.String("host", defaultHost, "Host de Filebeat")
-	port := flag.Int("port", defaultPort, "Puerto de Filebeat")
-	interval := flag.Int("interval", defaultInterval, "Intervalo de refresco en segundos")
-	flag.Parse()
+	// Subcomandos: filtop serve [flags] ejecuta el colector sin interfaz y
+	// expone la API; filtop watch [flags] solo informa cuando Filebeat deja
+	// de funcionar o se recupera; filtop bench [flags] resume una prueba de
+	// carga; filtop assert [flags] comprueba una condición para un pipeline
+	// de CI; filtop attach [flags] host:puerto sigue la pantalla de otro
+	// filtop; filtop init [flags] prepara la configuración. Cada uno tiene
+	// sus propios flags; sin subcomando se abre la interfaz de terminal.
+	args := os.Args[1:]
+	command := ""
+	if len(args) > 0 {
+		command = args[0]
+	}
+	switch command {
+	case "serve":
+		runServe(args[1:])
+	case "watch":
+		runWatch(args[1:])
+	case "bench":
+		runBench(args[1:])
+	case "assert":
+		runAssert(args[1:])
+	case "attach":
+		runAttach(args[1:])
+	case "init":
+		runInit(args[1:])
+	default:
+		runTUI(args)
+	}
+}
 
-	refresh = time.Duration(*interval) * time.Second
+// runTUI abre la interfaz de terminal
+func runTUI(args []string) {
+	fs := newFlagSet("filtop", "filtop [flags]\n     filtop serve|watch|bench|assert|attach|init [flags] (con -h, los flags de cada uno)")
+	flags := &beatFlags{}
+	flags.register(fs, "")
+	filebeatLog := fs.String("filebeat-log", "", "Ruta del log de Filebeat para detallar errores de módulos")
+	filebeatConfig := fs.String("filebeat-config", "", "Ruta del filebeat.yml para la página filebeat.yml (tecla y)")
+	baselinePath := fs.String("baseline", defaultBaselinePath(), "Archivo de la línea base (se captura con la tecla b)")
+	compare := fs.Bool("compare", false, "Mostrar cada valor junto con su desviación respecto de la línea base")
+	share := fs.String("share", "", "Dirección donde se comparte la pantalla para filtop attach, p. ej. :7070 (desactivado si está vacío)")
+	shareToken := fs.String("share-token", "", "Token que exige -share y que envía filtop attach")
+	flags.parse(fs, args)
+
+	s := newSession("", flags)
+	if s.logFile != nil {
+		defer s.logFile.Close()
+	}
+	s.startOutputs()
 
-	app = tview.NewApplication()
-	pages = tview.NewPages()
-	pageMap = make(map[string]tview.Primitive)
+	var mirrorServer *mirror.Server
+	if *share != "" {
+		lis, err := net.Listen("tcp", *share)
+		if err != nil {
+			fatal("Error escuchando", "addr", *share, "err", err)
+		}
+		mirrorServer = mirror.NewServer(*shareToken)
+		mux := http.NewServeMux()
+		mux.Handle(mirror.Path, mirrorServer)
+		go http.Serve(lis, mux)
+		slog.Info("Pantalla compartida para filtop attach", "addr", *share)
+	}
 
-	initUI()
-	go dataWorker(*host, *port)
-	setupSignalHandler()
+	var (
+		reloadUI      func()
+		selectProfile func(string)
+		setInterval   func(int)
+	)
+	uiOptions := func() ui.Options {
+		cfg := s.cfg
+		panels, _ := cfg.userPanels()
+		var systemPaths []string
+		if cfg.System.Enabled {
+			systemPaths = cfg.System.paths()
+		}
+		tabs := make([]ui.Tab, len(s.beats))
+		for i, b := range s.beats {
+			tabs[i] = ui.Tab{Name: b.name, Group: b.group, Store: b.store, PprofURL: b.pprofURL}
+		}
+		return ui.Options{
+			Tabs:               tabs,
+			FilebeatLogPath:    *filebeatLog,
+			FilebeatConfigPath: *filebeatConfig,
+			RegistryPath:       cfg.Registry.Path,
+			Endpoints:          cfg.endpointPanels(),
+			Computed:           cfg.computedNames(),
+			Panels:             panels,
+			Watch:              cfg.Watch,
+			SystemPaths:        systemPaths,
+			Elasticsearch:      cfg.Elasticsearch.URL != "",
+			KafkaTopic:         cfg.kafkaTopic(),
+			Probe:              cfg.Probe.Path != "",
+			ProbeInput:         cfg.Probe.Input,
+			AlertLog:           s.alertLog,
+			AlertRules:         cfg.alertPanels(),
+			Silences:           s.silences,
+			RateSmoothing:      cfg.rateSmoothing(),
+			LastEventColumn:    cfg.Inputs.LastEventColumn,
+			QuietAfter:         cfg.Inputs.quietAfter(),
+			InputRules:         cfg.Inputs.highlightRules(),
+			InputWindow:        cfg.Inputs.window(),
+			LogPath:            flags.logPath,
+			Reload:             reloadUI,
+			Profiles:           cfg.profileNames(),
+			Profile:            s.profile,
+			SelectProfile:      selectProfile,
+			SetInterval:        setInterval,
+			Transport:          s.beatHTTP.Transport,
+			Mirror:             mirrorServer,
+			BaselinePath:       *baselinePath,
+			Compare:            *compare,
+		}
+	}
+	reloadUI = func() {
+		s.reload(tuiSink{}, func() { ui.Reconfigure(uiOptions()) }, ui.ConfigError)
+	}
+	// selectProfile recarga la configuración con otro perfil; si falla se
+	// sigue con el anterior
+	selectProfile = func(name string) {
+		s.reloadMu.Lock()
+		previous := s.profile
+		s.profile = name
+		s.reloadMu.Unlock()
+		if !s.reload(tuiSink{}, func() { ui.Reconfigure(uiOptions()) }, ui.ConfigError) {
+			s.reloadMu.Lock()
+			s.profile = previous
+			s.reloadMu.Unlock()
+		}
+	}
+	// setInterval recarga la configuración con otro intervalo, como si se
+	// hubiera indicado con -interval; si falla se sigue con el anterior
+	setInterval = func(seconds int) {
+		explicit := s.overrides.explicit
+		s.reloadMu.Lock()
+		previous, wasExplicit := s.overrides.interval, explicit["interval"]
+		s.overrides.interval, explicit["interval"] = seconds, true
+		s.reloadMu.Unlock()
+		if !s.reload(tuiSink{}, func() { ui.Reconfigure(uiOptions()) }, ui.ConfigError) {
+			s.reloadMu.Lock()
+			s.overrides.interval, explicit["interval"] = previous, wasExplicit
+			s.reloadMu.Unlock()
+		}
+	}
+	ui.Init(uiOptions())
+	setLogOutput(io.MultiWriter(s.logFile, ui.LogWriter()))
+	s.startWorkers(tuiSink{})
+	setupReloadHandler(reloadUI)
+	go func() {
+		<-s.ctx.Done()
+		ui.Stop()
+	}()
 
-	if err := app.Run(); err != nil {
-		log.Fatalf("Error ejecutando la aplicación: %v", err)
+	// La interfaz también se puede cerrar desde el teclado
+	err := ui.Run()
+	setLogOutput(io.MultiWriter(os.Stderr, s.logFile))
+	s.cancel()
+	s.shutdown()
+	tuiSink{}.Close()
+	s.finish()
+	if err != nil {
+		fatal("Error ejecutando la aplicación", "err", err)
 	}
 }
 
-func setupSignalHandler() {
-	c := make(chan os.Signal, 1)
-	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
-	go func() {
-		<-c
-		log.Println("Apagando la aplicación...")
-		app.Stop()
-		os.Exit(0)
-	}()
+// runAttach sigue la pantalla que comparte otro filtop con -share
+func runAttach(args []string) {
+	fs := newFlagSet("attach", "filtop attach [flags] host:puerto")
+	shareToken := fs.String("share-token", "", "Token que exige el -share del otro filtop")
+	level := fs.String("log-level", "info", "Nivel de log: debug, info, warn o error")
+	fs.Parse(args)
+	setLogLevel(*level)
+	if fs.NArg() != 1 {
+		fatal("Uso: filtop attach [-share-token token] host:puerto")
+	}
+	if err := mirror.Attach(context.Background(), fs.Arg(0), *shareToken); err != nil {
+		fatal("Error en filtop attach", "err", err)
+	}
 }
 
-func initUI() {
-	mainFlex := tview.NewFlex().SetDirection(tview.FlexRow)
-
-	header := tview.NewTextView().
-		SetDynamicColors(true).
-		SetTextAlign(tview.AlignCenter).
-		SetText("[::b]FILTOP[::-] v2.0")
-
-	body := tview.NewFlex()
-	leftPanel := tview.NewFlex().SetDirection(tview.FlexRow)
-	rightPanel := tview.NewFlex().SetDirection(tview.FlexRow)
-
-	leftPanel.AddItem(createSystemPanel(), 8, 1, false)
-	leftPanel.AddItem(createQueuePanel(), 6, 1, false)
-	leftPanel.AddItem(createHarvesterChart(), 8, 1, false)
-
-	rightPanel.AddItem(createInputsTable(), 0, 2, false)
-	rightPanel.AddItem(createModulesWidget(), 0, 1, false)
-
-	body.AddItem(leftPanel, 0, 1, false)
-	body.AddItem(rightPanel, 0, 2, false)
-
-	mainFlex.AddItem(header, 1, 1, false)
-	mainFlex.AddItem(body, 0, 1, false)
-
-	pages.AddPage("main", mainFlex, true, true)
-	pageMap["main"] = mainFlex
-	app.SetRoot(pages, true)
-
-	app.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
-		switch event.Key() {
-		case tcell.KeyEsc:
-			pages.SwitchToPage("main")
-		case tcell.KeyTab:
-			currentFocus = (currentFocus + 1) % 2
-			app.SetFocus(getFocusableComponent(currentFocus))
-		case tcell.KeyBacktab:
-			currentFocus = (currentFocus - 1 + 2) % 2
-			app.SetFocus(getFocusableComponent(currentFocus))
-		case tcell.KeyEnter:
-			if currentFocus == 1 {
-				showInputDetails()
-			}
-		}
-		return event
-	})
+// beat es un Filebeat monitoreado, con su propio colector, historial y
+// métricas derivadas. index es su posición en targets y su pestaña.
+type beat struct {
+	index int
+	name  string
+	url   string
+	// group es el host del beat, para agrupar la vista de flota
+	group   string
+	source  *client.Client
+	history *metrics.History
+	store   *metrics.Store
+	derived *derivedMetrics
+	// label identifica al beat en el log; vacío si es el único
+	label               string
+	pprofURL, expvarURL string
 }
 
-func getFocusableComponent(index int) tview.Primitive {
-	if mainPage := getPrimitiveFromPage("main"); mainPage != nil {
-		if flex, ok := mainPage.(*tview.Flex); ok {
-			body := flex.GetItem(1).(*tview.Flex)
-			switch index {
-			case 0:
-				return body.GetItem(0).(*tview.Flex).GetItem(0)
-			case 1:
-				return body.GetItem(1).(*tview.Flex).GetItem(0)
-			}
+// targetsChanged indica si targets ya no son los Filebeats monitoreados
+func targetsChanged(beats []*beat, targets []beatTarget) bool {
+	if len(beats) != len(targets) {
+		return true
+	}
+	for i, b := range beats {
+		if b.name != targets[i].name || b.url != targets[i].url || b.group != targets[i].group {
+			return true
 		}
 	}
-	return nil
+	return false
 }
 
-func showInputDetails() {
-	if lastStats == nil || len(lastStats.Filebeat.Inputs) == 0 {
-		return
-	}
-
-	list := tview.NewList().ShowSecondaryText(false)
-	list.SetTitle(" Detalles de Inputs ").SetBorder(true)
+// derivedMetrics agrupa las métricas calculadas y las alertas de la
+// configuración, que se evalúan después de cada muestra.
+type derivedMetrics struct {
+	env      *metrics.Env
+	computed []metrics.Computed
+	alerts   *alerts.Engine
+	panels   [][]metrics.Computed
+	// Alertas resueltas al recargar, que se publican con la próxima muestra
+	pending []alerts.Event
+	// anomalies es nil si la detección está desactivada
+	anomalies *metrics.AnomalyDetector
+	history   *metrics.History
+	// label es el del beat, para el log y el historial de alertas
+	label    string
+	alertLog *alerts.Log
+	notifier *notify.Dispatcher
+}
 
-	for _, input := range lastStats.Filebeat.Inputs {
-		list.AddItem(fmt.Sprintf("%s (%s)", input.Type, input.Device), "", 0, func() {
-			showInputMetrics(input)
-		})
+func newDerivedMetrics(cfg *Config, history *metrics.History, label string, alertLog *alerts.Log, notifier *notify.Dispatcher, silences *alerts.Silences) *derivedMetrics {
+	_, panels := cfg.userPanels()
+	d := &derivedMetrics{
+		env:      metrics.NewEnv(history),
+		computed: cfg.computedMetrics(),
+		alerts:   alerts.NewEngine(cfg.alertRules()),
+		panels:   panels,
+		history:  history,
+		label:    label,
+		alertLog: alertLog,
+		notifier: notifier,
 	}
+	d.alerts.SilenceWith(silences, label)
+	if !cfg.Anomalies.Disabled {
+		d.anomalies = metrics.NewAnomalyDetector(cfg.Anomalies.options())
+	}
+	return d
+}
 
-	list.AddItem("Regresar", "", 'b', func() {
-		pages.SwitchToPage("main")
-	})
-
-	pages.AddPage("input_details", list, true, true)
-	pages.SwitchToPage("input_details")
+// reconfigure reemplaza las expresiones por las de cfg, conservando las
+// alertas activas de las reglas que siguen definidas. Solo se llama con el
+// colector detenido.
+func (d *derivedMetrics) reconfigure(cfg *Config, now time.Time) {
+	_, d.panels = cfg.userPanels()
+	d.computed = cfg.computedMetrics()
+	for _, event := range d.alerts.SetRules(cfg.alertRules(), now) {
+		targetLogger(d.label).Info("Alerta resuelta", "rule", event.Alert.Rule)
+		d.pending = append(d.pending, event)
+	}
+	switch {
+	case cfg.Anomalies.Disabled:
+		d.anomalies = nil
+	case d.anomalies == nil:
+		d.anomalies = metrics.NewAnomalyDetector(cfg.Anomalies.options())
+	default:
+		d.anomalies.SetOptions(cfg.Anomalies.options())
+	}
 }
 
-func showInputMetrics(input Input) {
-	textView := tview.NewTextView().SetDynamicColors(true)
-	textView.SetBorder(true).SetTitle(fmt.Sprintf(" Métricas: %s ", input.ID))
-
-	var builder strings.Builder
-	fmt.Fprintf(&builder, "[yellow]Tipo:[-] %s\n", input.Type)
-	fmt.Fprintf(&builder, "[yellow]Dispositivo:[-] %s\n", input.Device)
-	fmt.Fprintf(&builder, "[yellow]Paquetes:[-] %d\n", input.Packets)
-	fmt.Fprintf(&builder, "[yellow]Bytes:[-] %s\n", formatBytes(input.Bytes))
-	fmt.Fprintf(&builder, "[yellow]Eventos:[-] %d\n", input.Events)
-	fmt.Fprintf(&builder, "[yellow]Activo:[-] %t\n", input.Active)
-	fmt.Fprintf(&builder, "\n[yellow]Histogramas:[-]\n")
-	fmt.Fprintf(&builder, "Arrival Period:\n%s", formatHistogram(input.ArrivalPeriod.Histogram))
-	fmt.Fprintf(&builder, "\nProcessing Time:\n%s", formatHistogram(input.ProcessingTime.Histogram))
-
-	textView.SetText(builder.String())
-
-	modal := tview.NewModal().
-		SetText(textView.GetText(true)).
-		AddButtons([]string{"Regresar"}).
-		SetDoneFunc(func(_ int, _ string) {
-			pages.SwitchToPage("input_details")
-		})
+// reset olvida las líneas base de los inputs al cambiar de Filebeat
+func (d *derivedMetrics) reset() {
+	if d.anomalies != nil {
+		d.anomalies.Reset()
+	}
+}
 
-	pages.AddPage("input_metrics", modal, true, true)
-	pages.SwitchToPage("input_metrics")
+// derivedValues es el resultado de evaluar las métricas derivadas
+type derivedValues struct {
+	computed []metrics.ComputedValue
+	alerts   []alerts.Alert
+	events   []alerts.Event
+	panels   [][]metrics.ComputedValue
+	// Inputs cuyo ritmo de eventos se apartó de su línea base
+	anomalies []metrics.Anomaly
 }
 
-func formatHistogram(histo map[string]interface{}) string {
-	var builder strings.Builder
-	for k, v := range histo {
-		if val, ok := v.(float64); ok {
-			fmt.Fprintf(&builder, "  %-15s: %.2f\n", k, val)
+func (d *derivedMetrics) update(stats *client.FilebeatStats) derivedValues {
+	now := stats.Timestamp
+	log := targetLogger(d.label)
+	values := metrics.EvaluateComputed(d.env, d.computed)
+	events, errs := d.alerts.Evaluate(d.env, now)
+	for _, err := range errs {
+		log.Warn("Error evaluando una expresión", "err", err)
+	}
+	for _, event := range events {
+		if event.Raised {
+			log.Warn("Alerta activada", "rule", event.Alert.Rule, "severity", event.Alert.Severity, "value", event.Alert.Value, "silenced", event.Alert.Silenced)
+		} else {
+			log.Info("Alerta resuelta", "rule", event.Alert.Rule)
 		}
 	}
-	return builder.String()
-}
 
-func formatBytes(bytes uint64) string {
-	const unit = 1024
-	if bytes < unit {
-		return fmt.Sprintf("%d B", bytes)
+	if len(d.pending) > 0 {
+		events = append(d.pending, events...)
+		d.pending = nil
 	}
-	div, exp := int64(unit), 0
-	for n := bytes / unit; n >= unit; n /= unit {
-		div *= unit
-		exp++
+	if err := d.alertLog.Add(d.label, events); err != nil {
+		log.Warn("Error guardando el historial de alertas", "err", err)
 	}
-	return fmt.Sprintf("%.1f %ciB", float64(bytes)/float64(div), "KMGTPE"[exp])
+	d.notifier.Notify(d.label, events)
+	result := derivedValues{computed: values, alerts: d.alerts.Active(), events: events}
+	for _, panel := range d.panels {
+		result.panels = append(result.panels, metrics.Evaluate(d.env, panel))
+	}
+	if d.anomalies != nil {
+		d.updateAnomalies(stats)
+		result.anomalies = d.anomalies.Active()
+	}
+	return result
 }
 
-func createSystemPanel() *tview.Table {
-	table := tview.NewTable().SetBorders(false)
-	table.SetTitle(" Sistema ").SetBorder(true)
-	addMetricRow(table, 0, "CPU Total:", "0.0%", tcell.ColorOrange)
-	addMetricRow(table, 1, "Memoria RSS:", "0.0 MB", tcell.ColorGreen)
-	addMetricRow(table, 2, "Uptime:", "0h 0m", tcell.ColorBlue)
-	addMetricRow(table, 3, "Load Avg:", "0.00 0.00 0.00", tcell.ColorYellow)
-	return table
+// updateAnomalies evalúa el ritmo de cada input, solo cuando la muestra
+// trae inputs nuevos
+func (d *derivedMetrics) updateAnomalies(stats *client.FilebeatStats) {
+	if stats.InputsAt.Before(stats.Timestamp) {
+		return
+	}
+	rates := make(map[string]float64)
+	for _, input := range stats.Filebeat.Inputs {
+		if rate, ok := d.history.InputRate(input.ID); ok {
+			rates[input.ID] = rate
+		}
+	}
+	started, ended := d.anomalies.Update(rates, stats.Timestamp)
+	log := targetLogger(d.label)
+	changed := make(map[string]bool)
+	for _, anomaly := range started {
+		log.Warn("Anomalía en un input", "input", anomaly.Input, "kind", anomaly.Kind, "rate", anomaly.Rate, "baseline", anomaly.Baseline)
+		changed[anomaly.Input] = true
+	}
+	// Una caída que pasa a input detenido no se informa como resuelta
+	for _, anomaly := range ended {
+		if !changed[anomaly.Input] {
+			log.Info("Anomalía resuelta", "input", anomaly.Input, "kind", anomaly.Kind)
+		}
+	}
 }
 
-func dataWorker(host string, port int) {
-	statsURL := fmt.Sprintf("http://%s:%d/stats", host, port)
-	inputsURL := fmt.Sprintf("http://%s:%d/inputs", host, port)
+// registerSelfMetrics agrega a /metrics los totales de remote_write y de
+// los plugins
+func registerSelfMetrics(publisher *remotewrite.Publisher, sinks []*plugins.Sink) {
+	stat := func(field func(remotewrite.Stats) int) func() float64 {
+		return func() float64 { return float64(field(publisher.Stats())) }
+	}
+	selfMetrics.CounterFunc("filtop_remote_write_samples_sent_total", "Muestras enviadas por remote_write", stat(func(s remotewrite.Stats) int { return s.Sent }))
+	selfMetrics.CounterFunc("filtop_remote_write_samples_dropped_total", "Muestras de remote_write descartadas por superar max_pending o por un error permanente", stat(func(s remotewrite.Stats) int { return s.Dropped }))
+	selfMetrics.CounterFunc("filtop_remote_write_failures_total", "Envíos de remote_write fallidos", stat(func(s remotewrite.Stats) int { return s.Failures }))
+	selfMetrics.GaugeFunc("filtop_remote_write_samples_pending", "Muestras de remote_write que esperan el próximo envío", stat(func(s remotewrite.Stats) int { return s.Pending }))
+	if len(sinks) > 0 {
+		selfMetrics.CounterFunc("filtop_plugin_samples_dropped_total", "Muestras que los plugins no leyeron a tiempo", func() float64 {
+			var dropped uint64
+			for _, sink := range sinks {
+				dropped += sink.Dropped()
+			}
+			return float64(dropped)
+		})
+	}
+}
 
-	client := &http.Client{Timeout: 10 * time.Second}
+// dataWorker toma una muestra del beat al arrancar y luego una por cada
+// tick hasta que se cancela ctx.
+func dataWorker(ctx context.Context, b *beat, out sink, expvarURL string) {
+	ticker := time.NewTicker(refresh)
+	defer ticker.Stop()
 
 	for {
-		stats, err := fetchStats(client, statsURL)
-		if err != nil {
-			log.Printf("Error obteniendo estadísticas: %v", err)
-			time.Sleep(refresh)
-			continue
+		collect(ctx, b, out, expvarURL)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
 		}
+	}
+}
 
-		inputs, err := fetchInputs(client, inputsURL)
-		if err != nil {
-			log.Printf("Error obteniendo inputs: %v", err)
+func collect(ctx context.Context, b *beat, out sink, expvarURL string) {
+	source, log := b.source, targetLogger(b.label)
+	if source.Info() == nil {
+		if err := source.DetectVersion(ctx); err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			log.Error("Error detectando la versión de Filebeat", "err", err)
 		} else {
-			stats.Filebeat.Inputs = inputs
-		}
-
-		history = append(history, stats)
-		if len(history) > historySize {
-			history = history[1:]
+			source.Probe(ctx)
+			if ok, reason := source.InputsStatus(); !ok {
+				log.Warn("Inputs no disponibles", "reason", reason)
+			}
 		}
-
-		lastStats = stats
-		app.QueueUpdateDraw(updateUI)
-		time.Sleep(refresh)
 	}
 
-}
-
-func fetchStats(client *http.Client, url string) (*FilebeatStats, error) {
-	resp, err := client.Get(url)
+	start := time.Now()
+	stats, inputsErr, err := source.Fetch(ctx)
+	if ctx.Err() != nil {
+		// Apagando: la consulta se canceló y la muestra no es válida
+		return
+	}
+	selfMetrics.ObserveScrape(b.name, time.Since(start), err)
 	if err != nil {
-		return nil, err
+		log.Error("Error obteniendo estadísticas", "err", err)
+		b.store.Failed(time.Now())
+		out.StatsError(b.index, err)
+		// Filebeat pudo reiniciarse con otra versión: se vuelve a detectar
+		source.Reset()
+		if doc, err := client.FetchExpvar(ctx, source.HTTP, expvarURL); err != nil {
+			if ctx.Err() == nil {
+				log.Warn("Error obteniendo expvar", "err", err)
+			}
+		} else {
+			out.Expvar(b.index, doc)
+		}
+		return
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("error: código de estado %d", resp.StatusCode)
+	if inputsErr != nil {
+		log.Warn("Error obteniendo inputs", "err", inputsErr)
 	}
+	if err := source.AttachState(ctx, stats); err != nil && ctx.Err() == nil {
+		log.Warn("Error obteniendo estado de inputs", "err", err)
+	}
+	source.Normalize(stats)
 
-	var stats FilebeatStats
-	if err := json.NewDecoder(resp.Body).Decode(&stats); err != nil {
-		return nil, err
+	sample := metrics.Sample{
+		Stats:  stats,
+		Info:   source.Info(),
+		Schema: source.Schema().Name(),
+		State:  source.State(),
 	}
-	stats.Timestamp = time.Now()
-	return &stats, nil
+	b.store.Add(sample)
+	log.Debug("Muestra obtenida", "fetch", stats.FetchDuration, "inputs", len(stats.Filebeat.Inputs))
+	out.Sample(b.index, sample, b.derived.update(stats))
 }
 
-func fetchInputs(client *http.Client, url string) ([]Input, error) {
-	resp, err := client.Get(url)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
+// systemWorker toma una muestra del host en cada ciclo y avisa en el log
+// cuando un sistema de archivos de los logs está por llenarse.
+func systemWorker(ctx context.Context, collector *system.Collector, out sink) {
+	ticker := time.NewTicker(refresh)
+	defer ticker.Stop()
+	nearFull := make(map[string]bool)
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("error: código de estado %d", resp.StatusCode)
-	}
+	for {
+		stats, err := collector.Collect(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			slog.Warn("Error obteniendo métricas del host", "err", err)
+		} else {
+			for _, fs := range stats.Filesystems {
+				full := fs.UsedPercent >= diskFullPercent
+				if full && !nearFull[fs.Mountpoint] {
+					slog.Warn("Sistema de archivos de logs casi lleno", "mountpoint", fs.Mountpoint, "used", fmt.Sprintf("%.0f%%", fs.UsedPercent))
+				}
+				nearFull[fs.Mountpoint] = full
+			}
+		}
+		out.System(stats, err)
 
-	var inputs []Input // Ahora decodifica directamente a []Input
-	if err := json.NewDecoder(resp.Body).Decode(&inputs); err != nil {
-		return nil, err
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
 	}
-
-	return inputs, nil
 }
 
-func updateUI() {
-	if lastStats == nil {
-		return
-	}
-	updateSystemMetrics()
-	updateQueue()
-	updateHarvesters()
-	updateInputs()
-	updateModules()
-}
+// registryWorker lee el registry de Filebeat en cada ciclo y avisa en el
+// log de cada rotación o truncado de un archivo cosechado, y de cada
+// relectura con los eventos que probablemente se dupliquen según el tamaño
+// promedio de los eventos del beat.
+func registryWorker(ctx context.Context, path string, tracker *registry.Tracker, interval time.Duration, store *metrics.Store, out sink) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
 
-func addMetricRow(table *tview.Table, row int, label, value string, color tcell.Color) {
-	table.SetCell(row, 0, tview.NewTableCell(label).SetTextColor(tcell.ColorWhite))
-	table.SetCell(row, 1, tview.NewTableCell(value).SetTextColor(color))
-}
+	for {
+		entries, err := registry.Read(path)
+		if err != nil {
+			slog.Warn("Error leyendo el registry de Filebeat", "path", path, "err", err)
+		} else {
+			for _, change := range tracker.Update(entries, time.Now()) {
+				switch change.Kind {
+				case registry.Rotated:
+					slog.Info("Archivo rotado", "path", change.Path, "from", change.From, "to", change.To)
+				case registry.Truncated:
+					slog.Warn("Archivo truncado", "path", change.Path, "offset_before", change.OffsetBefore, "offset_after", change.OffsetAfter)
+				case registry.Reread:
+					args := []interface{}{"path", change.Path, "offset_before", change.OffsetBefore, "offset_after", change.OffsetAfter, "size", change.Size, "bytes", change.Duplicated()}
+					if change.From != "" {
+						args = append(args, "from", change.From, "to", change.To)
+					}
+					if size, ok := store.EventSize(); ok {
+						args = append(args, "events", int64(float64(change.Duplicated())/size))
+					}
+					slog.Warn("Posible ingesta duplicada: Filebeat vuelve a leer el archivo", args...)
+				}
+			}
+		}
+		out.Registry(tracker.Files(), err)
 
-func createQueuePanel() *tview.TextView {
-	view := tview.NewTextView().SetDynamicColors(true)
-	view.SetTitle(" Pipeline Queue ").SetBorder(true)
-	view.SetText("[green]0/0 [white]| [gray]....................")
-	return view
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
 }
 
-func createHarvesterChart() *tview.TextView {
-	view := tview.NewTextView().SetDynamicColors(true)
-	view.SetTitle(" Harvesters ").SetBorder(true)
-	view.SetText("Active: 0 | Open Files: 0")
-	return view
-}
-func createInputsTable() *tview.Table {
-	table := tview.NewTable().SetBorders(true)
-	table.SetTitle(" Inputs ").SetBorder(true)
-	headers := []string{"Type", "Active", "Events", "Throughput", "Files"}
-	for col, h := range headers {
-		table.SetCell(0, col, tview.NewTableCell(h).SetTextColor(tcell.ColorYellow).SetAlign(tview.AlignCenter))
-	}
-	return table
-}
+// elasticWorker consulta el clúster de destino en cada ciclo: compara los
+// documentos indexados desde la consulta anterior con los eventos que
+// Filebeat confirmó en ese mismo intervalo y avisa en el log cuando el
+// clúster deja de estar en verde o un índice queda con errores de ILM.
+func elasticWorker(ctx context.Context, monitor *elastic.Monitor, interval time.Duration, history *metrics.History, out sink) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	status := "green"
+	ilmErrors := make(map[string]bool)
 
-func createModulesWidget() *tview.List {
-	list := tview.NewList().ShowSecondaryText(false)
-	list.SetTitle(" Modules ").SetBorder(true)
-	list.AddItem("Loading...", "", 0, nil)
-	return list
-}
+	for {
+		stats, err := monitor.Collect(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			slog.Warn("Error consultando Elasticsearch", "err", err)
+		} else {
+			if stats.Ingestion != nil {
+				if sent, ok := history.RateSince(ackedEventsPath, stats.Ingestion.Since); ok {
+					stats.Ingestion.Sent = &sent
+				}
+			}
+			if health := stats.Health; health != nil {
+				if health.Status != status && health.Status != "green" {
+					slog.Warn("Clúster de Elasticsearch degradado", "status", health.Status, "unassigned_shards", health.UnassignedShards)
+				}
+				status = health.Status
+				current := make(map[string]bool)
+				for _, ilm := range health.ILMErrors {
+					if !ilmErrors[ilm.Index] {
+						slog.Warn("Error de ILM", "index", ilm.Index, "step", ilm.FailedStep, "reason", ilm.Reason)
+					}
+					current[ilm.Index] = true
+				}
+				ilmErrors = current
+			} else {
+				slog.Debug("Estado del clúster no disponible", "err", stats.HealthError)
+			}
+		}
+		out.Elastic(stats, err)
 
-func getPrimitiveFromPage(pageName string) tview.Primitive {
-	if primitive, exists := pageMap[pageName]; exists {
-		return primitive
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
 	}
-	return nil
 }
 
-func updateSystemMetrics() {
-	if mainPage := getPrimitiveFromPage("main"); mainPage != nil {
-		if flex, ok := mainPage.(*tview.Flex); ok {
-			panel := flex.GetItem(1).(*tview.Flex).GetItem(0).(*tview.Flex).GetItem(0).(*tview.Table)
-			if lastStats != nil {
-				// CPU
-				totalMs := lastStats.Beat.CPU.Total.Time.MS
-				cpuPercent := float64(totalMs) / float64(lastStats.Beat.Info.Uptime.MS) * 100
-
-				// Memoria
-				rssMB := float64(lastStats.Beat.Memstats.RSS) / 1024 / 1024
-
-				// Uptime
-				uptime := time.Duration(lastStats.Beat.Info.Uptime.MS) * time.Millisecond
-
-				// Load Average
-				load1 := lastStats.System.Load.Norm.Load1
-				load5 := lastStats.System.Load.Norm.Load5
-				load15 := lastStats.System.Load.Norm.Load15
-
-				panel.GetCell(0, 1).SetText(fmt.Sprintf("%.1f%%", cpuPercent))
-				panel.GetCell(1, 1).SetText(fmt.Sprintf("%.1f MB", rssMB))
-				panel.GetCell(2, 1).SetText(fmt.Sprintf("%v", uptime.Truncate(time.Minute)))
-				panel.GetCell(3, 1).SetText(fmt.Sprintf("%.2f %.2f %.2f", load1, load5, load15))
+// kafkaWorker consulta el topic de destino en cada ciclo: compara los
+// mensajes escritos desde la consulta anterior con los eventos que Filebeat
+// confirmó en ese mismo intervalo y avisa en el log cuando el grupo de
+// consumidores empieza a atrasarse o alguna partición se queda sin líder.
+func kafkaWorker(ctx context.Context, monitor *kafka.Monitor, interval time.Duration, history *metrics.History, out sink) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	defer monitor.Close()
+	leaderless := 0
+	var lag int64
+
+	for {
+		stats, err := monitor.Collect(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			slog.Warn("Error consultando Kafka", "err", err)
+		} else {
+			if stats.Production != nil {
+				if sent, ok := history.RateSince(ackedEventsPath, stats.Production.Since); ok {
+					stats.Production.Sent = &sent
+				}
+			}
+			if stats.Leaderless > leaderless {
+				slog.Warn("Particiones de Kafka sin líder", "topic", stats.Topic, "partitions", stats.Leaderless)
 			}
+			leaderless = stats.Leaderless
+			if consumer := stats.Consumer; consumer != nil {
+				// Se avisa una vez cada vez que el lag se multiplica por diez
+				if consumer.Lag >= kafkaLagWarn && consumer.Lag >= 10*lag {
+					slog.Warn("El grupo de consumidores de Kafka se atrasa", "group", consumer.Group, "lag", consumer.Lag)
+					lag = consumer.Lag
+				} else if consumer.Lag < kafkaLagWarn {
+					lag = 0
+				}
+			} else if stats.ConsumerError != "" {
+				slog.Debug("Grupo de consumidores no disponible", "err", stats.ConsumerError)
+			}
+		}
+		out.Kafka(stats, err)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
 		}
 	}
 }
 
-func updateHarvesters() {
-	if mainPage := getPrimitiveFromPage("main"); mainPage != nil {
-		if flex, ok := mainPage.(*tview.Flex); ok {
-			view := flex.GetItem(1).(*tview.Flex).GetItem(0).(*tview.Flex).GetItem(2).(*tview.TextView)
+// Lag del grupo de consumidores a partir del cual se avisa en el log
+const kafkaLagWarn = 10000
 
-			if lastStats != nil {
-				harvester := lastStats.Filebeat.Harvester // Correcto: Harvester (singular)
-				view.SetText(fmt.Sprintf("Active: %d | Open Files: %d", harvester.Running, harvester.Open))
-			} else {
-				view.SetText("Active: 0 | Open Files: 0")
+// Cada cuánto la sonda de latencia busca la marca en curso en /inputs/ y
+// en Elasticsearch
+const (
+	probePoll   = 250 * time.Millisecond
+	probeESPoll = time.Second
+)
+
+// probeWorker escribe una marca en el log de prueba cada probe.interval y
+// la busca hasta que llega al contador de eventos del input y a
+// Elasticsearch, o vence; cada marca terminada se registra en el log.
+func probeWorker(ctx context.Context, prober *probe.Prober, settings ProbeConfig, source *client.Client, es *elastic.Client, indices []string, out sink) {
+	ticker := time.NewTicker(probePoll)
+	defer ticker.Stop()
+	var next, searched time.Time
+
+	for {
+		now := time.Now()
+		pending, ok := prober.Pending()
+		switch {
+		case !ok && !now.Before(next):
+			next = now.Add(settings.interval())
+			events, err := probeInputEvents(ctx, source, settings.Input)
+			if err == nil {
+				_, err = prober.Send(time.Now(), events)
+			}
+			if ctx.Err() != nil {
+				return
+			}
+			if err != nil {
+				slog.Warn("Error enviando la marca de latencia", "path", settings.Path, "err", err)
+			}
+			out.Probe(prober.Results(), err)
+		case ok:
+			if settings.Input != "" && pending.Filebeat == 0 {
+				if events, err := probeInputEvents(ctx, source, settings.Input); err == nil {
+					prober.ObserveInput(events, time.Now())
+				}
+			}
+			if es != nil && pending.Elasticsearch == 0 && now.Sub(searched) >= probeESPoll {
+				searched = now
+				count, err := es.Count(ctx, indices, pending.Marker())
+				if err != nil && ctx.Err() == nil {
+					slog.Debug("Error buscando la marca de latencia en Elasticsearch", "err", err)
+				}
+				if count > 0 {
+					prober.ObserveIndexed(time.Now())
+				}
+			}
+			if result, done := prober.Finish(time.Now()); done {
+				args := []interface{}{"marker", result.Marker()}
+				if result.Filebeat > 0 {
+					args = append(args, "filebeat", result.Filebeat.Round(time.Millisecond))
+				}
+				if result.Elasticsearch > 0 {
+					args = append(args, "elasticsearch", result.Elasticsearch.Round(time.Millisecond))
+				}
+				if result.TimedOut {
+					slog.Warn("La marca de latencia no llegó a tiempo", append(args, "timeout", settings.timeout())...)
+				} else {
+					slog.Info("Latencia de ingesta", args...)
+				}
+				out.Probe(prober.Results(), nil)
 			}
 		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
 	}
 }
-func updateQueue() {
-	if mainPage := getPrimitiveFromPage("main"); mainPage != nil {
-		if flex, ok := mainPage.(*tview.Flex); ok {
-			view := flex.GetItem(1).(*tview.Flex).GetItem(0).(*tview.Flex).GetItem(1).(*tview.TextView)
-
-			if lastStats != nil {
-				queue := lastStats.Libbeat.Pipeline
-				percent := 0.0
-				if queue.Queue.MaxEvents > 0 { // Correcto: MaxEvents
-					percent = float64(queue.Queue.Filled.Events) / float64(queue.Queue.MaxEvents) * 100 // Correcto: Filled.Events
-				}
 
-				bars := int(percent / 5)
-				if bars < 0 {
-					bars = 0
-				}
+// probeInputEvents es el contador de eventos del input id; 0 si no se mide
+func probeInputEvents(ctx context.Context, source *client.Client, id string) (uint64, error) {
+	if id == "" {
+		return 0, nil
+	}
+	if source.Info() == nil {
+		if err := source.DetectVersion(ctx); err != nil {
+			return 0, err
+		}
+	}
+	inputs, err := source.Inputs(ctx)
+	if err != nil {
+		source.Reset()
+		return 0, err
+	}
+	for _, input := range inputs {
+		if input.ID == id {
+			return input.Events, nil
+		}
+	}
+	return 0, fmt.Errorf("el input %q no aparece en /inputs/", id)
+}
 
-				view.Clear()
-				fmt.Fprintf(view, "[green]%d/%d [white]| %s", queue.Queue.Filled.Events, queue.Queue.MaxEvents, strings.Repeat("█", bars)) // Correcto
-			} else {
-				view.SetText("[green]0/0 [white]| [gray]....................")
-			}
+// localBeatPort devuelve el puerto de beatURL si Filebeat corre en esta
+// máquina; solo entonces tiene sentido buscar su proceso.
+func localBeatPort(beatURL string) (int, bool) {
+	u, err := url.Parse(beatURL)
+	if err != nil {
+		return 0, false
+	}
+	if host := u.Hostname(); host != "localhost" {
+		if ip := net.ParseIP(host); ip == nil || !ip.IsLoopback() {
+			return 0, false
 		}
 	}
+	port, err := strconv.Atoi(u.Port())
+	return port, err == nil
 }
 
-func updateInputs() {
-	if mainPage := getPrimitiveFromPage("main"); mainPage != nil {
-		if flex, ok := mainPage.(*tview.Flex); ok {
-			// Accede a la tabla a través de la jerarquía conocida
-			if table, ok := flex.GetItem(1).(*tview.Flex).GetItem(1).(*tview.Flex).GetItem(0).(*tview.Table); ok {
+// endpointWorker consulta un endpoint JSON declarado en la configuración y
+// publica los campos mapeados en su panel.
+func endpointWorker(ctx context.Context, index int, endpoint EndpointConfig, httpClient *http.Client, out sink) {
+	interval := refresh
+	if endpoint.Interval > 0 {
+		interval = time.Duration(endpoint.Interval) * time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
 
-				// Limpia las filas previas
-				for row := 1; row < table.GetRowCount(); row++ {
-					table.RemoveRow(row)
-				}
+	for {
+		doc, err := client.FetchJSON(ctx, httpClient, endpoint.URL)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			slog.Warn("Error consultando el endpoint", "endpoint", endpoint.Name, "err", err)
+		}
 
-				// Actualiza los inputs
-				if lastStats != nil {
-					for i, input := range lastStats.Filebeat.Inputs {
-						table.SetCell(i+1, 0, tview.NewTableCell(input.Type).SetTextColor(tcell.ColorWhite))
-						table.SetCell(i+1, 1, tview.NewTableCell(fmt.Sprintf("%t", input.Active)).SetTextColor(tcell.ColorWhite))
-						table.SetCell(i+1, 2, tview.NewTableCell(fmt.Sprintf("%d", input.Events)).SetTextColor(tcell.ColorWhite))
-						table.SetCell(i+1, 3, tview.NewTableCell(fmt.Sprintf("%.2f", input.Throughput.Bytes)).SetTextColor(tcell.ColorWhite))
-						table.SetCell(i+1, 4, tview.NewTableCell(fmt.Sprintf("%d", input.Files)).SetTextColor(tcell.ColorWhite))
-					}
-				}
-			}
+		values := make([]interface{}, len(endpoint.Fields))
+		for i, field := range endpoint.Fields {
+			values[i], _ = client.Lookup(doc, field.Path)
 		}
-	}
-}
+		out.Endpoint(index, values, err)
 
-func updateModules() {
-	if mainPage := getPrimitiveFromPage("main"); mainPage != nil {
-		if flex, ok := mainPage.(*tview.Flex); ok {
-			list := flex.GetItem(1).(*tview.Flex).GetItem(1).(*tview.Flex).GetItem(1).(*tview.List)
-
-			list.Clear()
-			if lastStats != nil {
-				for _, module := range lastStats.Filebeat.Modules.List {
-					status := "[red]✗"
-					if module.Enabled {
-						status = "[green]✓"
-					}
-					list.AddItem(fmt.Sprintf("%s %s (%d errors)", status, module.Name, module.Errors), "", 0, nil)
-				}
-			}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
 		}
 	}
 }
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/iTiagoCO/filtop/filtop/client"
+)
+
+// Puertos que prueba filtop init si no se indicó -port: el de Filebeat por
+// defecto y los siguientes, que suelen tomar otros beats del mismo host.
+var probePorts = []int{defaultPort, defaultPort + 1, defaultPort + 2}
+
+const probeTimeout = 3 * time.Second
+
+// httpSnippet es lo que hay que agregar a filebeat.yml para exponer la API
+const httpSnippet = `http.enabled: true
+http.host: localhost
+http.port: %d
+`
+
+const configTemplate = `# Generado por filtop init
+host: %s
+port: %d
+interval: %d
+
+# Métricas calculadas y alertas (ver el README)
+# computed:
+#   drop_ratio: pipeline.events.dropped / pipeline.events.total
+# alerts:
+#   - name: drops
+#     expr: drop_ratio > 0.01
+#     severity: critical
+`
+
+// runInit ejecuta filtop init
+func runInit(args []string) {
+	fs := newFlagSet("init", "filtop init [flags]")
+	configPath := fs.String("config", defaultConfigPath(), "Archivo de configuración YAML a escribir")
+	host := fs.String("host", defaultHost, "Host de Filebeat")
+	port := fs.Int("port", defaultPort, "Puerto de Filebeat (por defecto se prueban el de Filebeat y los dos siguientes)")
+	interval := fs.Int("interval", defaultInterval, "Intervalo de refresco en segundos propuesto")
+	level := fs.String("log-level", "info", "Nivel de log: debug, info, warn o error")
+	fs.Parse(args)
+	setLogLevel(*level)
+
+	ports := probePorts
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == "port" {
+			ports = []int{*port}
+		}
+	})
+	if err := initConfig(*configPath, *host, ports, *interval); err != nil {
+		fatal("Error en filtop init", "err", err)
+	}
+}
+
+// initConfig busca un Filebeat con la API HTTP habilitada, explica cómo
+// habilitarla si no la encuentra y escribe la configuración inicial.
+func initConfig(configPath, host string, ports []int, interval int) error {
+	p := prompter{in: bufio.NewReader(os.Stdin), out: os.Stdout}
+
+	port, info := probeFilebeat(host, ports)
+	if info != nil {
+		fmt.Printf("Filebeat %s encontrado en %s:%d\n", info.Version, host, port)
+	} else {
+		port = ports[0]
+		switch running, known := filebeatRunning(); {
+		case running:
+			fmt.Println("Filebeat está en ejecución pero su API HTTP no responde.")
+		case known:
+			fmt.Println("No se encontró Filebeat en ejecución.")
+		default:
+			fmt.Println("No se encontró la API HTTP de Filebeat.")
+		}
+		fmt.Printf("Para habilitar la API HTTP agrega a filebeat.yml y reinicia Filebeat:\n\n")
+		fmt.Printf(httpSnippet+"\n", port)
+		if !p.confirm(fmt.Sprintf("¿Escribir igualmente la configuración para %s:%d?", host, port), false) {
+			return nil
+		}
+	}
+
+	if configPath == "" {
+		return errors.New("no se pudo determinar la ruta de la configuración; usa -config")
+	}
+	if _, err := os.Stat(configPath); err == nil {
+		if !p.confirm(fmt.Sprintf("%s ya existe. ¿Sobrescribirlo?", configPath), false) {
+			return nil
+		}
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	answer := p.ask("Intervalo de refresco en segundos", strconv.Itoa(interval))
+	if n, err := strconv.Atoi(answer); err == nil && n > 0 {
+		interval = n
+	}
+
+	if err := os.MkdirAll(filepath.Dir(configPath), 0o755); err != nil {
+		return err
+	}
+	content := fmt.Sprintf(configTemplate, host, port, interval)
+	if err := os.WriteFile(configPath, []byte(content), 0o644); err != nil {
+		return err
+	}
+	fmt.Printf("Configuración escrita en %s\n", configPath)
+	return nil
+}
+
+// probeFilebeat devuelve el primer puerto donde responde Filebeat. Los
+// otros beats se informan y se descartan.
+func probeFilebeat(host string, ports []int) (int, *client.BeatInfo) {
+	for _, port := range ports {
+		url := fmt.Sprintf("http://%s:%d", host, port)
+		fmt.Printf("Probando %s... ", url)
+
+		c := client.New(url)
+		c.Retries = 0
+		ctx, cancel := context.WithTimeout(context.Background(), probeTimeout)
+		err := c.DetectVersion(ctx)
+		if err == nil {
+			c.Probe(ctx)
+		}
+		cancel()
+
+		switch info := c.Info(); {
+		case err != nil:
+			fmt.Println("sin respuesta")
+		case info.Beat != "" && info.Beat != "filebeat":
+			fmt.Printf("responde %s, no Filebeat\n", info.Beat)
+		default:
+			fmt.Println("ok")
+			if ok, reason := c.InputsStatus(); !ok {
+				fmt.Printf("Aviso: /inputs/ no está disponible (%s); el panel Inputs quedará vacío\n", reason)
+			}
+			return port, info
+		}
+	}
+	return 0, nil
+}
+
+// filebeatRunning busca un proceso filebeat en /proc. known es false si no
+// se pudo determinar (por ejemplo, fuera de Linux).
+func filebeatRunning() (running, known bool) {
+	entries, err := os.ReadDir("/proc")
+	if err != nil {
+		return false, false
+	}
+	for _, entry := range entries {
+		if _, err := strconv.Atoi(entry.Name()); err != nil {
+			continue
+		}
+		comm, err := os.ReadFile(filepath.Join("/proc", entry.Name(), "comm"))
+		if err == nil && strings.TrimSpace(string(comm)) == "filebeat" {
+			return true, true
+		}
+	}
+	return false, true
+}
+
+// prompter hace preguntas por la terminal; sin entrada (EOF) se usa la
+// respuesta por defecto.
+type prompter struct {
+	in  *bufio.Reader
+	out io.Writer
+}
+
+func (p prompter) ask(question, def string) string {
+	fmt.Fprintf(p.out, "%s [%s]: ", question, def)
+	line, err := p.in.ReadString('\n')
+	line = strings.TrimSpace(line)
+	if err != nil && line == "" {
+		fmt.Fprintln(p.out)
+		return def
+	}
+	if line == "" {
+		return def
+	}
+	return line
+}
+
+func (p prompter) confirm(question string, def bool) bool {
+	hint := "s/N"
+	if def {
+		hint = "S/n"
+	}
+	switch strings.ToLower(p.ask(question, hint)) {
+	case "s", "si", "sí", "y", "yes":
+		return true
+	case "n", "no":
+		return false
+	}
+	return def
+}
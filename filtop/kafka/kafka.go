@@ -0,0 +1,415 @@
+// Package kafka consulta los brokers de Kafka a los que escribe Filebeat
+// para contrastar lo que envía con lo que llega al topic y con lo que
+// consume el grupo de consumidores que lo lee.
+package kafka
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Options describe la conexión con el clúster
+type Options struct {
+	// Brokers son algunos brokers del clúster (host:puerto); el resto se
+	// conoce por los metadatos
+	Brokers []string
+	// TLS, si no es nil, cifra las conexiones
+	TLS *tls.Config
+	// Usuario y contraseña para SASL/PLAIN, si el clúster lo pide
+	Username string
+	Password string
+	Timeout  time.Duration
+}
+
+// Client mantiene una conexión por broker. No es seguro usarlo desde varias
+// goroutines.
+type Client struct {
+	opts  Options
+	conns map[string]*conn
+}
+
+// New crea un cliente para el clúster de opts; las conexiones se abren con
+// la primera consulta
+func New(opts Options) *Client {
+	return &Client{opts: opts, conns: make(map[string]*conn)}
+}
+
+// Close cierra las conexiones abiertas
+func (c *Client) Close() {
+	for addr, conn := range c.conns {
+		conn.Close()
+		delete(c.conns, addr)
+	}
+}
+
+type conn struct {
+	net.Conn
+	correlation int32
+}
+
+// broker devuelve la conexión con addr, abriéndola y autenticándose si
+// hace falta
+func (c *Client) broker(ctx context.Context, addr string) (*conn, error) {
+	if existing := c.conns[addr]; existing != nil {
+		return existing, nil
+	}
+	dialer := &net.Dialer{Timeout: c.opts.Timeout}
+	var raw net.Conn
+	var err error
+	if c.opts.TLS != nil {
+		tlsDialer := &tls.Dialer{NetDialer: dialer, Config: c.opts.TLS}
+		raw, err = tlsDialer.DialContext(ctx, "tcp", addr)
+	} else {
+		raw, err = dialer.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+	broker := &conn{Conn: raw}
+	if c.opts.Username != "" {
+		if err := c.authenticate(ctx, broker); err != nil {
+			broker.Close()
+			return nil, fmt.Errorf("%s: %w", addr, err)
+		}
+	}
+	c.conns[addr] = broker
+	return broker, nil
+}
+
+// request envía una petición a addr y devuelve el cuerpo de la respuesta.
+// Si falla, la conexión se cierra y se vuelve a abrir en la próxima.
+func (c *Client) request(ctx context.Context, addr string, api, version int16, body []byte) (*decoder, error) {
+	broker, err := c.broker(ctx, addr)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.roundTrip(ctx, broker, api, version, body)
+	if err != nil {
+		broker.Close()
+		delete(c.conns, addr)
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (c *Client) roundTrip(ctx context.Context, broker *conn, api, version int16, body []byte) (*decoder, error) {
+	deadline := time.Now().Add(c.opts.Timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	broker.SetDeadline(deadline)
+	stop := context.AfterFunc(ctx, func() { broker.SetDeadline(time.Now()) })
+	defer stop()
+
+	broker.correlation++
+	header := encoder{buf: make([]byte, 4, 4+14+len(clientID)+len(body))}
+	header.int16(api)
+	header.int16(version)
+	header.int32(broker.correlation)
+	header.string(clientID)
+	msg := append(header.buf, body...)
+	binary.BigEndian.PutUint32(msg, uint32(len(msg)-4))
+	if _, err := broker.Write(msg); err != nil {
+		return nil, err
+	}
+
+	var size [4]byte
+	if _, err := io.ReadFull(broker, size[:]); err != nil {
+		return nil, err
+	}
+	n := binary.BigEndian.Uint32(size[:])
+	if n < 4 || n > maxResponse {
+		return nil, fmt.Errorf("kafka: respuesta de %d bytes", n)
+	}
+	buf := make([]byte, n)
+	if _, err := io.ReadFull(broker, buf); err != nil {
+		return nil, err
+	}
+	resp := &decoder{buf: buf}
+	if correlation := resp.int32(); correlation != broker.correlation {
+		return nil, errors.New("kafka: respuesta a otra petición")
+	}
+	return resp, nil
+}
+
+// authenticate se autentica con SASL/PLAIN
+func (c *Client) authenticate(ctx context.Context, broker *conn) error {
+	var req encoder
+	req.string("PLAIN")
+	resp, err := c.roundTrip(ctx, broker, apiSaslHandshake, versionSaslHandshake, req.buf)
+	if err != nil {
+		return err
+	}
+	if err := decodeSaslHandshake(resp); err != nil {
+		return err
+	}
+
+	req = encoder{}
+	req.bytes([]byte("\x00" + c.opts.Username + "\x00" + c.opts.Password))
+	resp, err = c.roundTrip(ctx, broker, apiSaslAuthenticate, versionSaslAuthenticate, req.buf)
+	if err != nil {
+		return err
+	}
+	return decodeSaslAuthenticate(resp)
+}
+
+// decodeSaslHandshake comprueba que el broker acepte PLAIN; si no, el error
+// indica los mecanismos que acepta
+func decodeSaslHandshake(resp *decoder) error {
+	code := resp.int16()
+	var mechanisms []string
+	for n := resp.array(); n > 0; n-- {
+		mechanisms = append(mechanisms, resp.string())
+	}
+	switch {
+	case resp.err != nil:
+		return resp.err
+	case code != 0 && len(mechanisms) > 0:
+		return fmt.Errorf("%w (el broker acepta %s)", codeError(code), strings.Join(mechanisms, ", "))
+	}
+	return codeError(code)
+}
+
+func decodeSaslAuthenticate(resp *decoder) error {
+	code := resp.int16()
+	message := resp.string()
+	resp.bytes() // auth_bytes
+	switch {
+	case resp.err != nil:
+		return resp.err
+	case code != 0 && message != "":
+		return fmt.Errorf("%w: %s", codeError(code), message)
+	}
+	return codeError(code)
+}
+
+// Partition es una partición del topic y su líder, -1 si no tiene
+type Partition struct {
+	ID     int32
+	Leader int32
+}
+
+// Metadata son los brokers del clúster, por id, y las particiones del topic
+type Metadata struct {
+	Brokers    map[int32]string
+	Partitions []Partition
+}
+
+// Metadata consulta los brokers y las particiones de topic al primer broker
+// de Options que responda
+func (c *Client) Metadata(ctx context.Context, topic string) (*Metadata, error) {
+	var req encoder
+	req.array(1)
+	req.string(topic)
+	// Consultar no debe crear el topic
+	req.bool(false)
+
+	var firstErr error
+	for _, addr := range c.opts.Brokers {
+		resp, err := c.request(ctx, addr, apiMetadata, versionMetadata, req.buf)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			if ctx.Err() != nil {
+				break
+			}
+			continue
+		}
+		return decodeMetadata(resp, topic)
+	}
+	return nil, firstErr
+}
+
+func decodeMetadata(resp *decoder, topic string) (*Metadata, error) {
+	meta := &Metadata{Brokers: make(map[int32]string)}
+	resp.int32() // throttle_time_ms
+	for n := resp.array(); n > 0; n-- {
+		id := resp.int32()
+		host := resp.string()
+		port := resp.int32()
+		resp.string() // rack
+		meta.Brokers[id] = net.JoinHostPort(host, strconv.Itoa(int(port)))
+	}
+	resp.string() // cluster_id
+	resp.int32()  // controller_id
+	var topicErr error
+	found := false
+	for n := resp.array(); n > 0; n-- {
+		code := resp.int16()
+		name := resp.string()
+		resp.bool() // is_internal
+		for p := resp.array(); p > 0; p-- {
+			resp.int16() // error_code
+			partition := Partition{ID: resp.int32(), Leader: resp.int32()}
+			resp.int32s() // replica_nodes
+			resp.int32s() // isr_nodes
+			resp.int32s() // offline_replicas
+			if name == topic {
+				meta.Partitions = append(meta.Partitions, partition)
+			}
+		}
+		if name == topic {
+			found, topicErr = true, codeError(code)
+		}
+	}
+	switch {
+	case resp.err != nil:
+		return nil, resp.err
+	case topicErr != nil:
+		return nil, fmt.Errorf("topic %s: %w", topic, topicErr)
+	case !found:
+		return nil, fmt.Errorf("topic %s: %w", topic, codeError(3))
+	}
+	return meta, nil
+}
+
+// EndOffsets consulta a cada líder el offset final (el siguiente a escribir)
+// de sus particiones. Las particiones sin líder no se incluyen.
+func (c *Client) EndOffsets(ctx context.Context, topic string, meta *Metadata) (map[int32]int64, error) {
+	byLeader := make(map[int32][]int32)
+	for _, partition := range meta.Partitions {
+		if _, ok := meta.Brokers[partition.Leader]; ok {
+			byLeader[partition.Leader] = append(byLeader[partition.Leader], partition.ID)
+		}
+	}
+
+	offsets := make(map[int32]int64, len(meta.Partitions))
+	for leader, partitions := range byLeader {
+		var req encoder
+		req.int32(-1) // replica_id: un cliente
+		req.int8(0)   // isolation_level: read_uncommitted
+		req.array(1)
+		req.string(topic)
+		req.array(len(partitions))
+		for _, id := range partitions {
+			req.int32(id)
+			req.int64(-1) // timestamp: el último offset
+		}
+		resp, err := c.request(ctx, meta.Brokers[leader], apiListOffsets, versionListOffsets, req.buf)
+		if err != nil {
+			return nil, err
+		}
+		if err := decodeListOffsets(resp, offsets); err != nil {
+			return nil, err
+		}
+	}
+	return offsets, nil
+}
+
+// decodeListOffsets agrega a offsets el offset final de cada partición de
+// la respuesta
+func decodeListOffsets(resp *decoder, offsets map[int32]int64) error {
+	resp.int32() // throttle_time_ms
+	for n := resp.array(); n > 0; n-- {
+		resp.string() // name
+		for p := resp.array(); p > 0; p-- {
+			id := resp.int32()
+			code := resp.int16()
+			resp.int64() // timestamp
+			offset := resp.int64()
+			if err := codeError(code); err != nil && resp.err == nil {
+				return fmt.Errorf("partición %d: %w", id, err)
+			}
+			offsets[id] = offset
+		}
+	}
+	return resp.err
+}
+
+// Committed consulta al coordinador de group los offsets que confirmó en
+// las particiones de topic; las particiones sin offset confirmado tienen -1
+func (c *Client) Committed(ctx context.Context, group, topic string, meta *Metadata) (map[int32]int64, error) {
+	coordinator, err := c.coordinator(ctx, group)
+	if err != nil {
+		return nil, err
+	}
+	var req encoder
+	req.string(group)
+	req.array(1)
+	req.string(topic)
+	req.array(len(meta.Partitions))
+	for _, partition := range meta.Partitions {
+		req.int32(partition.ID)
+	}
+	resp, err := c.request(ctx, coordinator, apiOffsetFetch, versionOffsetFetch, req.buf)
+	if err != nil {
+		return nil, err
+	}
+	return decodeOffsetFetch(resp)
+}
+
+func decodeOffsetFetch(resp *decoder) (map[int32]int64, error) {
+	offsets := make(map[int32]int64)
+	var partitionErr error
+	resp.int32() // throttle_time_ms
+	for n := resp.array(); n > 0; n-- {
+		resp.string() // name
+		for p := resp.array(); p > 0; p-- {
+			id := resp.int32()
+			offset := resp.int64()
+			resp.string() // metadata
+			if err := codeError(resp.int16()); err != nil && partitionErr == nil {
+				partitionErr = fmt.Errorf("partición %d: %w", id, err)
+			}
+			offsets[id] = offset
+		}
+	}
+	code := resp.int16()
+	switch {
+	case resp.err != nil:
+		return nil, resp.err
+	case code != 0:
+		return nil, codeError(code)
+	case partitionErr != nil:
+		return nil, partitionErr
+	}
+	return offsets, nil
+}
+
+// coordinator busca el broker que coordina group
+func (c *Client) coordinator(ctx context.Context, group string) (string, error) {
+	var req encoder
+	req.string(group)
+	req.int8(0) // key_type: grupo
+
+	var firstErr error
+	for _, addr := range c.opts.Brokers {
+		resp, err := c.request(ctx, addr, apiFindCoordinator, versionFindCoordinator, req.buf)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			if ctx.Err() != nil {
+				break
+			}
+			continue
+		}
+		return decodeFindCoordinator(resp)
+	}
+	return "", firstErr
+}
+
+func decodeFindCoordinator(resp *decoder) (string, error) {
+	resp.int32() // throttle_time_ms
+	code := resp.int16()
+	resp.string() // error_message
+	resp.int32()  // node_id
+	host := resp.string()
+	port := resp.int32()
+	if resp.err != nil {
+		return "", resp.err
+	}
+	if err := codeError(code); err != nil {
+		return "", err
+	}
+	return net.JoinHostPort(host, strconv.Itoa(int(port))), nil
+}
@@ -0,0 +1,137 @@
+package kafka
+
+import (
+	"context"
+	"time"
+)
+
+// Stats es el resultado de una consulta de Monitor
+type Stats struct {
+	Time       time.Time `json:"time"`
+	Topic      string    `json:"topic"`
+	Brokers    int       `json:"brokers"`
+	Partitions int       `json:"partitions"`
+	// Leaderless son las particiones sin líder, en las que no se puede
+	// escribir
+	Leaderless int `json:"leaderless"`
+	// Production es nil en la primera consulta
+	Production *Production `json:"production,omitempty"`
+	// Consumer es nil si no se configuró un grupo o no se pudo consultar;
+	// ConsumerError indica el motivo
+	Consumer      *Consumer `json:"consumer,omitempty"`
+	ConsumerError string    `json:"consumer_error,omitempty"`
+}
+
+// Production compara los mensajes que llegaron al topic con los eventos que
+// Filebeat envió en el mismo intervalo
+type Production struct {
+	Since time.Time `json:"since"`
+	// Written son los mensajes/s escritos en el topic por todos sus
+	// productores
+	Written float64 `json:"written_per_sec"`
+	// Sent son los eventos/s que Kafka confirmó a Filebeat; los completa
+	// quien llama a Collect
+	Sent *float64 `json:"sent_per_sec,omitempty"`
+}
+
+// Consumer es el progreso del grupo de consumidores que lee el topic
+type Consumer struct {
+	Group string `json:"group"`
+	// Lag son los mensajes del topic que el grupo todavía no confirmó
+	Lag int64 `json:"lag"`
+	// Uncommitted son las particiones en las que el grupo no confirmó
+	// ningún offset; no cuentan en Lag
+	Uncommitted int `json:"uncommitted"`
+	// Consumed son los mensajes/s que el grupo confirmó desde la consulta
+	// anterior; nil en la primera
+	Consumed *float64 `json:"consumed_per_sec,omitempty"`
+}
+
+// Monitor consulta el topic en cada ciclo y calcula las tasas respecto de
+// la consulta anterior. Las tasas se calculan por partición, así que una
+// partición que no respondió o cuyo offset bajó (el topic se recreó) no
+// cuenta en ese intervalo. No es seguro usarlo desde varias goroutines.
+type Monitor struct {
+	client *Client
+	topic  string
+	group  string
+
+	prevAt        time.Time
+	prevEnd       map[int32]int64
+	prevCommitted map[int32]int64
+}
+
+// NewMonitor crea un Monitor para topic y, si no está vacío, el grupo de
+// consumidores group
+func NewMonitor(client *Client, topic, group string) *Monitor {
+	return &Monitor{client: client, topic: topic, group: group}
+}
+
+// Close cierra las conexiones con los brokers
+func (m *Monitor) Close() { m.client.Close() }
+
+// Collect consulta el clúster. Solo devuelve error si no se pudieron
+// obtener los offsets finales del topic.
+func (m *Monitor) Collect(ctx context.Context) (*Stats, error) {
+	meta, err := m.client.Metadata(ctx, m.topic)
+	if err != nil {
+		return nil, err
+	}
+	end, err := m.client.EndOffsets(ctx, m.topic, meta)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	stats := &Stats{
+		Time:       now,
+		Topic:      m.topic,
+		Brokers:    len(meta.Brokers),
+		Partitions: len(meta.Partitions),
+		Leaderless: len(meta.Partitions) - len(end),
+	}
+	if m.prevEnd != nil {
+		stats.Production = &Production{
+			Since:   m.prevAt,
+			Written: advance(m.prevEnd, end) / now.Sub(m.prevAt).Seconds(),
+		}
+	}
+
+	if m.group != "" {
+		committed, err := m.client.Committed(ctx, m.group, m.topic, meta)
+		if err != nil {
+			stats.ConsumerError = err.Error()
+			m.prevCommitted = nil
+		} else {
+			consumer := &Consumer{Group: m.group}
+			for id, offset := range committed {
+				latest, ok := end[id]
+				switch {
+				case offset < 0:
+					consumer.Uncommitted++
+				case ok && latest > offset:
+					consumer.Lag += latest - offset
+				}
+			}
+			if m.prevCommitted != nil {
+				consumed := advance(m.prevCommitted, committed) / now.Sub(m.prevAt).Seconds()
+				consumer.Consumed = &consumed
+			}
+			m.prevCommitted = committed
+			stats.Consumer = consumer
+		}
+	}
+	m.prevAt, m.prevEnd = now, end
+	return stats, nil
+}
+
+// advance es cuánto avanzaron en total los offsets de las particiones que
+// están en las dos consultas
+func advance(before, after map[int32]int64) float64 {
+	var total int64
+	for id, offset := range after {
+		if previous, ok := before[id]; ok && previous >= 0 && offset > previous {
+			total += offset - previous
+		}
+	}
+	return float64(total)
+}
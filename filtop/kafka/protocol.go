@@ -0,0 +1,198 @@
+package kafka
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// Codificación del protocolo de Kafka a mano, para no depender de un
+// cliente completo: solo las pocas peticiones que usa filtop, en versiones
+// sin campos etiquetados (anteriores a las "flexibles") que los brokers
+// aceptan desde Kafka 1.0 hasta 4.x.
+
+// Claves y versiones de las peticiones
+const (
+	apiListOffsets      int16 = 2
+	apiMetadata         int16 = 3
+	apiOffsetFetch      int16 = 9
+	apiFindCoordinator  int16 = 10
+	apiSaslHandshake    int16 = 17
+	apiSaslAuthenticate int16 = 36
+
+	versionListOffsets      int16 = 2
+	versionMetadata         int16 = 5
+	versionOffsetFetch      int16 = 3
+	versionFindCoordinator  int16 = 1
+	versionSaslHandshake    int16 = 1
+	versionSaslAuthenticate int16 = 0
+)
+
+const clientID = "filtop"
+
+// Tamaño máximo de una respuesta; las de filtop son de pocos KiB
+const maxResponse = 16 << 20
+
+var errShortResponse = errors.New("kafka: respuesta incompleta")
+
+// Error es un código de error de Kafka
+type Error struct {
+	Code int16
+}
+
+// Nombres de los errores más probables al consultar offsets
+var errorNames = map[int16]string{
+	-1: "UNKNOWN_SERVER_ERROR",
+	3:  "UNKNOWN_TOPIC_OR_PARTITION",
+	5:  "LEADER_NOT_AVAILABLE",
+	6:  "NOT_LEADER_OR_FOLLOWER",
+	7:  "REQUEST_TIMED_OUT",
+	14: "COORDINATOR_LOAD_IN_PROGRESS",
+	15: "COORDINATOR_NOT_AVAILABLE",
+	16: "NOT_COORDINATOR",
+	29: "TOPIC_AUTHORIZATION_FAILED",
+	30: "GROUP_AUTHORIZATION_FAILED",
+	31: "CLUSTER_AUTHORIZATION_FAILED",
+	33: "UNSUPPORTED_SASL_MECHANISM",
+	34: "ILLEGAL_SASL_STATE",
+	35: "UNSUPPORTED_VERSION",
+	58: "SASL_AUTHENTICATION_FAILED",
+	69: "GROUP_ID_NOT_FOUND",
+}
+
+func (e *Error) Error() string {
+	if name, ok := errorNames[e.Code]; ok {
+		return "kafka: " + name
+	}
+	return fmt.Sprintf("kafka: error %d", e.Code)
+}
+
+func codeError(code int16) error {
+	if code == 0 {
+		return nil
+	}
+	return &Error{Code: code}
+}
+
+// encoder arma el cuerpo de una petición
+type encoder struct {
+	buf []byte
+}
+
+func (e *encoder) int8(v int8) { e.buf = append(e.buf, byte(v)) }
+
+func (e *encoder) int16(v int16) { e.buf = binary.BigEndian.AppendUint16(e.buf, uint16(v)) }
+
+func (e *encoder) int32(v int32) { e.buf = binary.BigEndian.AppendUint32(e.buf, uint32(v)) }
+
+func (e *encoder) int64(v int64) { e.buf = binary.BigEndian.AppendUint64(e.buf, uint64(v)) }
+
+func (e *encoder) bool(v bool) {
+	if v {
+		e.int8(1)
+	} else {
+		e.int8(0)
+	}
+}
+
+func (e *encoder) string(s string) {
+	e.int16(int16(len(s)))
+	e.buf = append(e.buf, s...)
+}
+
+func (e *encoder) bytes(b []byte) {
+	e.int32(int32(len(b)))
+	e.buf = append(e.buf, b...)
+}
+
+// array escribe la cantidad de elementos de un arreglo, que van después
+func (e *encoder) array(n int) { e.int32(int32(n)) }
+
+// decoder lee una respuesta; el primer error se conserva y los valores
+// siguientes son cero
+type decoder struct {
+	buf []byte
+	err error
+}
+
+func (d *decoder) take(n int) []byte {
+	if d.err != nil {
+		return nil
+	}
+	if n < 0 || len(d.buf) < n {
+		d.err = errShortResponse
+		return nil
+	}
+	b := d.buf[:n]
+	d.buf = d.buf[n:]
+	return b
+}
+
+func (d *decoder) int8() int8 {
+	if b := d.take(1); b != nil {
+		return int8(b[0])
+	}
+	return 0
+}
+
+func (d *decoder) int16() int16 {
+	if b := d.take(2); b != nil {
+		return int16(binary.BigEndian.Uint16(b))
+	}
+	return 0
+}
+
+func (d *decoder) int32() int32 {
+	if b := d.take(4); b != nil {
+		return int32(binary.BigEndian.Uint32(b))
+	}
+	return 0
+}
+
+func (d *decoder) int64() int64 {
+	if b := d.take(8); b != nil {
+		return int64(binary.BigEndian.Uint64(b))
+	}
+	return 0
+}
+
+func (d *decoder) bool() bool { return d.int8() != 0 }
+
+// string lee un string que puede ser nulo (longitud -1)
+func (d *decoder) string() string {
+	n := d.int16()
+	if n < 0 {
+		return ""
+	}
+	return string(d.take(int(n)))
+}
+
+func (d *decoder) bytes() []byte {
+	n := d.int32()
+	if n < 0 {
+		return nil
+	}
+	return d.take(int(n))
+}
+
+// array lee la cantidad de elementos de un arreglo; un arreglo nulo tiene 0
+func (d *decoder) array() int {
+	n := d.int32()
+	if d.err != nil || n < 0 {
+		return 0
+	}
+	// Cada elemento ocupa al menos un byte
+	if int(n) > len(d.buf) {
+		d.err = errShortResponse
+		return 0
+	}
+	return int(n)
+}
+
+func (d *decoder) int32s() []int32 {
+	values := make([]int32, d.array())
+	for i := range values {
+		values[i] = d.int32()
+	}
+	return values
+}
@@ -0,0 +1,195 @@
+package kafka
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// readFixture lee una respuesta de testdata: el cuerpo en hexadecimal, sin
+// el tamaño ni el correlation id, con un comentario por campo
+func readFixture(t *testing.T, name string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var text strings.Builder
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		text.WriteString(strings.TrimSpace(line))
+	}
+	buf, err := hex.DecodeString(text.String())
+	if err != nil {
+		t.Fatalf("%s: %v", name, err)
+	}
+	return buf
+}
+
+func fixture(t *testing.T, name string) *decoder {
+	return &decoder{buf: readFixture(t, name)}
+}
+
+func TestDecodeMetadata(t *testing.T) {
+	meta, err := decodeMetadata(fixture(t, "metadata_v5.hex"), "logs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &Metadata{
+		Brokers:    map[int32]string{1: "kafka-1:9092", 2: "kafka-2:9093"},
+		Partitions: []Partition{{ID: 0, Leader: 1}, {ID: 1, Leader: 2}, {ID: 2, Leader: -1}},
+	}
+	if !reflect.DeepEqual(meta, want) {
+		t.Errorf("%+v, se esperaba %+v", meta, want)
+	}
+
+	// Los topics que no se pidieron se ignoran
+	meta, err = decodeMetadata(fixture(t, "metadata_v5.hex"), "__consumer_offsets")
+	if err != nil || !reflect.DeepEqual(meta.Partitions, []Partition{{ID: 0, Leader: 2}}) {
+		t.Errorf("__consumer_offsets: %+v, %v", meta, err)
+	}
+	_, err = decodeMetadata(fixture(t, "metadata_v5.hex"), "otro")
+	checkError(t, "topic ausente", err, 3, "topic otro: kafka: UNKNOWN_TOPIC_OR_PARTITION")
+	_, err = decodeMetadata(fixture(t, "metadata_v5_unknown_topic.hex"), "logz")
+	checkError(t, "topic desconocido", err, 3, "topic logz: kafka: UNKNOWN_TOPIC_OR_PARTITION")
+}
+
+func TestDecodeListOffsets(t *testing.T) {
+	offsets := map[int32]int64{5: 10}
+	if err := decodeListOffsets(fixture(t, "list_offsets_v2.hex"), offsets); err != nil {
+		t.Fatal(err)
+	}
+	// Las respuestas de cada líder se suman en el mismo mapa
+	want := map[int32]int64{0: 1843002, 1: 1790455, 5: 10}
+	if !reflect.DeepEqual(offsets, want) {
+		t.Errorf("%v, se esperaba %v", offsets, want)
+	}
+
+	err := decodeListOffsets(fixture(t, "list_offsets_v2_not_leader.hex"), make(map[int32]int64))
+	checkError(t, "sin líder", err, 6, "partición 1: kafka: NOT_LEADER_OR_FOLLOWER")
+}
+
+func TestDecodeOffsetFetch(t *testing.T) {
+	offsets, err := decodeOffsetFetch(fixture(t, "offset_fetch_v3.hex"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[int32]int64{0: 1842870, 1: 1790001, 2: -1}
+	if !reflect.DeepEqual(offsets, want) {
+		t.Errorf("%v, se esperaba %v", offsets, want)
+	}
+
+	_, err = decodeOffsetFetch(fixture(t, "offset_fetch_v3_loading.hex"))
+	checkError(t, "coordinador cargando", err, 14, "kafka: COORDINATOR_LOAD_IN_PROGRESS")
+}
+
+func TestDecodeFindCoordinator(t *testing.T) {
+	addr, err := decodeFindCoordinator(fixture(t, "find_coordinator_v1.hex"))
+	if err != nil || addr != "kafka-2:9093" {
+		t.Errorf("%q, %v; se esperaba kafka-2:9093", addr, err)
+	}
+
+	_, err = decodeFindCoordinator(fixture(t, "find_coordinator_v1_unavailable.hex"))
+	checkError(t, "sin coordinador", err, 15, "kafka: COORDINATOR_NOT_AVAILABLE")
+}
+
+func TestDecodeSasl(t *testing.T) {
+	if err := decodeSaslHandshake(fixture(t, "sasl_handshake_v1.hex")); err != nil {
+		t.Errorf("handshake: %v", err)
+	}
+	err := decodeSaslHandshake(fixture(t, "sasl_handshake_v1_unsupported.hex"))
+	checkError(t, "mecanismo no soportado", err, 33, "kafka: UNSUPPORTED_SASL_MECHANISM (el broker acepta SCRAM-SHA-256, SCRAM-SHA-512)")
+
+	if err := decodeSaslAuthenticate(fixture(t, "sasl_authenticate_v0.hex")); err != nil {
+		t.Errorf("authenticate: %v", err)
+	}
+	err = decodeSaslAuthenticate(fixture(t, "sasl_authenticate_v0_failed.hex"))
+	checkError(t, "credenciales inválidas", err, 58, "kafka: SASL_AUTHENTICATION_FAILED: Authentication failed: Invalid username or password")
+}
+
+func checkError(t *testing.T, name string, err error, code int16, text string) {
+	t.Helper()
+	var kafkaErr *Error
+	if !errors.As(err, &kafkaErr) || kafkaErr.Code != code {
+		t.Errorf("%s: %v, se esperaba el código %d", name, err, code)
+		return
+	}
+	if err.Error() != text {
+		t.Errorf("%s: %q, se esperaba %q", name, err, text)
+	}
+}
+
+// decoders decodifica cada respuesta de testdata con la función que le
+// corresponde
+var decoders = map[string]func(*decoder) error{
+	"metadata_v5.hex": func(d *decoder) error {
+		_, err := decodeMetadata(d, "logs")
+		return err
+	},
+	"metadata_v5_unknown_topic.hex": func(d *decoder) error {
+		_, err := decodeMetadata(d, "logz")
+		return err
+	},
+	"list_offsets_v2.hex": func(d *decoder) error {
+		return decodeListOffsets(d, make(map[int32]int64))
+	},
+	"list_offsets_v2_not_leader.hex": func(d *decoder) error {
+		return decodeListOffsets(d, make(map[int32]int64))
+	},
+	"offset_fetch_v3.hex": func(d *decoder) error {
+		_, err := decodeOffsetFetch(d)
+		return err
+	},
+	"offset_fetch_v3_loading.hex": func(d *decoder) error {
+		_, err := decodeOffsetFetch(d)
+		return err
+	},
+	"find_coordinator_v1.hex": func(d *decoder) error {
+		_, err := decodeFindCoordinator(d)
+		return err
+	},
+	"find_coordinator_v1_unavailable.hex": func(d *decoder) error {
+		_, err := decodeFindCoordinator(d)
+		return err
+	},
+	"sasl_handshake_v1.hex":             decodeSaslHandshake,
+	"sasl_handshake_v1_unsupported.hex": decodeSaslHandshake,
+	"sasl_authenticate_v0.hex":          decodeSaslAuthenticate,
+	"sasl_authenticate_v0_failed.hex":   decodeSaslAuthenticate,
+}
+
+func TestDecodeTruncated(t *testing.T) {
+	names, err := filepath.Glob(filepath.Join("testdata", "*.hex"))
+	if err != nil || len(names) != len(decoders) {
+		t.Fatalf("%d respuestas en testdata, se esperaban %d (%v)", len(names), len(decoders), err)
+	}
+	for name, decode := range decoders {
+		full := readFixture(t, name)
+		// Cortada en cualquier byte, la respuesta da un error y no un pánico
+		for n := 0; n < len(full); n++ {
+			err := decode(&decoder{buf: full[:n:n]})
+			if !errors.Is(err, errShortResponse) {
+				t.Errorf("%s cortada en %d de %d bytes: %v", name, n, len(full), err)
+			}
+		}
+	}
+}
+
+func TestDecodeHugeArray(t *testing.T) {
+	// Un largo de arreglo absurdo no reserva memoria según lo que dice
+	buf := readFixture(t, "offset_fetch_v3.hex")
+	copy(buf[4:], []byte{0x7f, 0xff, 0xff, 0xff})
+	if _, err := decodeOffsetFetch(&decoder{buf: buf}); !errors.Is(err, errShortResponse) {
+		t.Errorf("%v, se esperaba %v", err, errShortResponse)
+	}
+	if values := (&decoder{buf: []byte{0x7f, 0xff, 0xff, 0xff}}).int32s(); len(values) != 0 {
+		t.Errorf("int32s() = %d valores", len(values))
+	}
+}
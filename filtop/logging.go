@@ -0,0 +1,58 @@
+package main
+
+import (
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+)
+
+// logLevel es el nivel elegido con -log-level; es compartido por todos los
+// destinos del log.
+var logLevel = new(slog.LevelVar)
+
+// setLogLevel aplica -log-level y dirige el log a stderr
+func setLogLevel(level string) {
+	if err := logLevel.UnmarshalText([]byte(level)); err != nil {
+		fatal("Nivel de log inválido", "level", level)
+	}
+	setLogOutput(os.Stderr)
+}
+
+// setLogOutput dirige el log (slog y el paquete log) a w
+func setLogOutput(w io.Writer) {
+	slog.SetDefault(slog.New(slog.NewTextHandler(w, &slog.HandlerOptions{Level: logLevel})))
+}
+
+// targetLogger agrega el Filebeat a los mensajes del log cuando se
+// monitorea más de uno; con label vacío es el logger por defecto
+func targetLogger(label string) *slog.Logger {
+	if label == "" {
+		return slog.Default()
+	}
+	return slog.With("target", label)
+}
+
+// fatal registra un error y termina el programa
+func fatal(msg string, args ...interface{}) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
+
+// defaultLogPath es donde se escribe el log en modo terminal, para que no
+// se mezcle con la pantalla de tview.
+func defaultLogPath() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return filepath.Join(os.TempDir(), "filtop.log")
+	}
+	return filepath.Join(dir, "filtop", "filtop.log")
+}
+
+// openLog abre el archivo de log en modo append, creando su directorio
+func openLog(path string) (*os.File, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	return os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+}
@@ -0,0 +1,191 @@
+package metrics
+
+import (
+	"sort"
+	"time"
+)
+
+// Tipos de anomalía de un input
+const (
+	// AnomalyStopped: el input dejó de producir eventos
+	AnomalyStopped = "stopped"
+	// AnomalyDrop y AnomalySpike: el ritmo cayó o subió más de Factor
+	// veces respecto de la línea base
+	AnomalyDrop  = "drop"
+	AnomalySpike = "spike"
+)
+
+// Cantidad de evaluaciones seguidas fuera de rango antes de marcar una caída
+// o un pico, para no reaccionar a una sola muestra irregular
+const anomalyConfirmations = 2
+
+// AnomalyOptions configura el detector de anomalías
+type AnomalyOptions struct {
+	// Window es el período de la media móvil exponencial (EWMA) que sirve
+	// de línea base; también es lo que se espera antes de evaluar un input
+	Window time.Duration
+	// Factor es cuántas veces por debajo o por encima de la línea base
+	// tiene que estar el ritmo para considerarlo una anomalía
+	Factor float64
+	// MinRate es la línea base mínima (eventos/s) para evaluar un input:
+	// los de poco volumen son demasiado irregulares
+	MinRate float64
+	// StoppedAfter es el tiempo sin eventos para dar un input por detenido
+	StoppedAfter time.Duration
+}
+
+// Anomaly es un input cuyo ritmo de eventos se apartó de su línea base.
+// Since es desde cuándo: para un input detenido, su último evento.
+type Anomaly struct {
+	Input    string    `json:"input"`
+	Kind     string    `json:"kind"`
+	Rate     float64   `json:"events_per_sec"`
+	Baseline float64   `json:"baseline_per_sec"`
+	Since    time.Time `json:"since"`
+}
+
+// inputBaseline es la línea base de un input y su anomalía actual
+type inputBaseline struct {
+	ewma       float64
+	first      time.Time
+	last       time.Time
+	lastEvents time.Time
+	// Evaluaciones seguidas fuera de rango y desde cuándo
+	outside      int
+	outsideSince time.Time
+	anomaly      *Anomaly
+}
+
+// AnomalyDetector lleva una línea base del ritmo de eventos de cada input.
+// No es seguro usarlo desde varias goroutines.
+type AnomalyDetector struct {
+	opts   AnomalyOptions
+	inputs map[string]*inputBaseline
+}
+
+func NewAnomalyDetector(opts AnomalyOptions) *AnomalyDetector {
+	return &AnomalyDetector{opts: opts, inputs: make(map[string]*inputBaseline)}
+}
+
+// SetOptions cambia la configuración al recargarla, conservando las líneas
+// base ya calculadas
+func (d *AnomalyDetector) SetOptions(opts AnomalyOptions) {
+	d.opts = opts
+}
+
+// Update incorpora el ritmo actual de cada input (eventos/s) y devuelve las
+// anomalías que empezaron y las que terminaron. Los inputs que desaparecen
+// se olvidan sin más.
+func (d *AnomalyDetector) Update(rates map[string]float64, now time.Time) (started, ended []Anomaly) {
+	for id := range d.inputs {
+		if _, ok := rates[id]; !ok {
+			delete(d.inputs, id)
+		}
+	}
+
+	for id, rate := range rates {
+		b, ok := d.inputs[id]
+		if !ok {
+			d.inputs[id] = &inputBaseline{ewma: rate, first: now, last: now, lastEvents: now}
+			continue
+		}
+		if rate > 0 {
+			b.lastEvents = now
+		}
+
+		kind := d.classify(b, rate, now)
+		switch {
+		case kind == "":
+			b.outside = 0
+		case b.outside == 0:
+			b.outside, b.outsideSince = 1, now
+		default:
+			b.outside++
+		}
+
+		previous := b.anomaly
+		switch {
+		case kind == AnomalyStopped:
+			b.anomaly = &Anomaly{Input: id, Kind: kind, Since: b.lastEvents}
+		case kind != "" && b.outside >= anomalyConfirmations:
+			since := b.outsideSince
+			if previous != nil && previous.Kind == kind {
+				since = previous.Since
+			}
+			b.anomaly = &Anomaly{Input: id, Kind: kind, Since: since}
+		case kind == "":
+			b.anomaly = nil
+		}
+		if b.anomaly != nil {
+			b.anomaly.Rate, b.anomaly.Baseline = rate, b.ewma
+		}
+		switch {
+		case previous != nil && (b.anomaly == nil || b.anomaly.Kind != previous.Kind):
+			ended = append(ended, *previous)
+			if b.anomaly != nil {
+				started = append(started, *b.anomaly)
+			}
+		case previous == nil && b.anomaly != nil:
+			started = append(started, *b.anomaly)
+		}
+
+		// Un input detenido no arrastra la línea base hacia cero: así sigue
+		// marcado hasta que vuelva a producir eventos. Una caída o un pico
+		// sí la mueven, y si el nuevo ritmo se mantiene deja de ser anomalía.
+		if kind != AnomalyStopped {
+			b.ewma += d.alpha(now.Sub(b.last)) * (rate - b.ewma)
+		}
+		b.last = now
+	}
+	return started, ended
+}
+
+// classify compara rate con la línea base. Devuelve "" si está dentro de lo
+// normal o si el input todavía no tiene una línea base confiable.
+func (d *AnomalyDetector) classify(b *inputBaseline, rate float64, now time.Time) string {
+	if b.anomaly != nil && b.anomaly.Kind == AnomalyStopped && rate == 0 {
+		return AnomalyStopped
+	}
+	if now.Sub(b.first) < d.opts.Window || b.ewma < d.opts.MinRate {
+		return ""
+	}
+	switch {
+	case rate == 0 && now.Sub(b.lastEvents) >= d.opts.StoppedAfter:
+		return AnomalyStopped
+	case rate < b.ewma/d.opts.Factor:
+		return AnomalyDrop
+	case rate > b.ewma*d.opts.Factor:
+		return AnomalySpike
+	}
+	return ""
+}
+
+// alpha es el peso de una muestra tomada elapsed después de la anterior, de
+// modo que la línea base no dependa del intervalo de refresco
+func (d *AnomalyDetector) alpha(elapsed time.Duration) float64 {
+	if d.opts.Window <= 0 {
+		return 1
+	}
+	a := elapsed.Seconds() / d.opts.Window.Seconds()
+	if a > 1 {
+		return 1
+	}
+	return a
+}
+
+// Active devuelve las anomalías en curso ordenadas por input
+func (d *AnomalyDetector) Active() []Anomaly {
+	var active []Anomaly
+	for _, b := range d.inputs {
+		if b.anomaly != nil {
+			active = append(active, *b.anomaly)
+		}
+	}
+	sort.Slice(active, func(i, j int) bool { return active[i].Input < active[j].Input })
+	return active
+}
+
+// Reset olvida las líneas base, p. ej. al cambiar de Filebeat
+func (d *AnomalyDetector) Reset() {
+	d.inputs = make(map[string]*inputBaseline)
+}
@@ -0,0 +1,158 @@
+package metrics
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/iTiagoCO/filtop/filtop/client"
+)
+
+// QueueFullPercent es el llenado a partir del cual la cola cuenta como llena
+// en una prueba de carga
+const QueueFullPercent = 95
+
+// Bench registra una prueba de carga: a diferencia de Session guarda cada
+// intervalo entre muestras, para resumir el caudal y la cola con
+// percentiles y no solo con el promedio y el pico. No es seguro usarlo
+// desde varias goroutines.
+type Bench struct {
+	intervals []BenchInterval
+	summary   BenchSummary
+	prev      sessionCounters
+	sampled   bool
+	// batches y batched son los lotes de la salida y sus eventos en la
+	// muestra anterior; batchCount y batchEvents lo que sumaron en la prueba
+	batches, batched        uint64
+	batchCount, batchEvents uint64
+}
+
+// BenchInterval es lo ocurrido entre dos muestras
+type BenchInterval struct {
+	At time.Time
+	// Events son los eventos/s que confirmó la salida y Bytes los bytes/s
+	// que escribió
+	Events, Bytes float64
+	// Queue es el llenado de la cola al final del intervalo, de QueueMax
+	Queue, QueueMax uint64
+	Dropped, Failed uint64
+}
+
+// BenchSummary resume una prueba de carga
+type BenchSummary struct {
+	First, Last time.Time
+	Samples     int
+	// Errors son las consultas que fallaron
+	Errors int
+	// Totales de la prueba, sumados entre muestras como en SessionSummary
+	Acked, Failed, Dropped, Bytes uint64
+	// Events, ByteRate y Queue son los percentiles de los eventos/s, los
+	// bytes/s y el llenado de la cola de los intervalos
+	Events, ByteRate, Queue Percentiles
+	QueueMax                uint64
+	Intervals               int
+	// QueueFull son los intervalos que terminaron con la cola llena según
+	// QueueFullPercent y DropIntervals los que descartaron eventos
+	QueueFull, DropIntervals int
+	// Batch son los eventos promedio por lote de la salida; 0 si no lo
+	// informa
+	Batch    float64
+	Restarts int
+}
+
+// Percentiles de una serie de valores, por el método del rango más cercano
+type Percentiles struct {
+	P50, P90, P95, P99, Max, Mean float64
+}
+
+// NewPercentiles calcula los percentiles de values; ceros si está vacía
+func NewPercentiles(values []float64) Percentiles {
+	if len(values) == 0 {
+		return Percentiles{}
+	}
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	rank := func(p float64) float64 {
+		i := int(math.Ceil(p/100*float64(len(sorted)))) - 1
+		return sorted[max(i, 0)]
+	}
+	sum := 0.0
+	for _, v := range sorted {
+		sum += v
+	}
+	return Percentiles{
+		P50:  rank(50),
+		P90:  rank(90),
+		P95:  rank(95),
+		P99:  rank(99),
+		Max:  sorted[len(sorted)-1],
+		Mean: sum / float64(len(sorted)),
+	}
+}
+
+// Add registra una muestra
+func (b *Bench) Add(stats *client.FilebeatStats) {
+	sum := &b.summary
+	curr := countersOf(stats)
+	batches := rawCounter(stats, "libbeat.output.events.batches")
+	batched := rawCounter(stats, "libbeat.output.events.total")
+	if sum.Samples == 0 {
+		sum.First = stats.Timestamp
+	}
+	sum.Last = stats.Timestamp
+	sum.Samples++
+
+	queue := stats.Libbeat.Pipeline.Queue
+	sum.QueueMax = max(sum.QueueMax, queue.MaxEvents)
+	if elapsed := curr.at.Sub(b.prev.at).Seconds(); b.sampled && elapsed > 0 {
+		prev := b.prev
+		if curr.uptime < prev.uptime {
+			sum.Restarts++
+		}
+		interval := BenchInterval{
+			At:       stats.Timestamp,
+			Events:   float64(increase(prev.acked, curr.acked)) / elapsed,
+			Bytes:    float64(increase(prev.bytes, curr.bytes)) / elapsed,
+			Queue:    queue.Filled.Events,
+			QueueMax: queue.MaxEvents,
+			Dropped:  increase(prev.dropped, curr.dropped),
+			Failed:   increase(prev.failed, curr.failed),
+		}
+		b.intervals = append(b.intervals, interval)
+		sum.Acked += increase(prev.acked, curr.acked)
+		sum.Bytes += increase(prev.bytes, curr.bytes)
+		sum.Failed += interval.Failed
+		sum.Dropped += interval.Dropped
+		b.batchCount += increase(b.batches, batches)
+		b.batchEvents += increase(b.batched, batched)
+	}
+	b.prev, b.batches, b.batched, b.sampled = curr, batches, batched, true
+}
+
+// Failed registra una consulta fallida
+func (b *Bench) Failed() {
+	b.summary.Errors++
+}
+
+// Summary resume lo registrado hasta ahora
+func (b *Bench) Summary() BenchSummary {
+	sum := b.summary
+	sum.Intervals = len(b.intervals)
+	events := make([]float64, len(b.intervals))
+	bytes := make([]float64, len(b.intervals))
+	queue := make([]float64, len(b.intervals))
+	for i, interval := range b.intervals {
+		events[i], bytes[i], queue[i] = interval.Events, interval.Bytes, float64(interval.Queue)
+		if interval.QueueMax > 0 && float64(interval.Queue) >= float64(interval.QueueMax)*QueueFullPercent/100 {
+			sum.QueueFull++
+		}
+		if interval.Dropped > 0 {
+			sum.DropIntervals++
+		}
+	}
+	sum.Events, sum.ByteRate, sum.Queue = NewPercentiles(events), NewPercentiles(bytes), NewPercentiles(queue)
+	if b.batchCount > 0 {
+		sum.Batch = float64(b.batchEvents) / float64(b.batchCount)
+	}
+	return sum
+}
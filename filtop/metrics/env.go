@@ -0,0 +1,160 @@
+package metrics
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/iTiagoCO/filtop/filtop/expr"
+)
+
+// Env resuelve las métricas de una expresión contra la muestra más reciente
+// del historial y las métricas calculadas ya evaluadas.
+type Env struct {
+	history *History
+	values  map[string]float64
+}
+
+func NewEnv(h *History) *Env {
+	return &Env{history: h, values: make(map[string]float64)}
+}
+
+// Set define una métrica calculada para que otras expresiones la usen
+func (e *Env) Set(name string, value float64) {
+	e.values[name] = value
+}
+
+func (e *Env) Metric(path string) (float64, bool) {
+	if v, ok := e.values[path]; ok {
+		return v, true
+	}
+	if path == QueueFullIn {
+		return e.queueFullIn()
+	}
+	v, _, ok := e.history.Value(0, path)
+	return v, ok
+}
+
+func (e *Env) queueFullIn() (float64, bool) {
+	capacity, _, ok := e.history.Value(0, "pipeline.queue.max_events")
+	if !ok {
+		return 0, false
+	}
+	forecast, ok := e.history.ForecastQueue(uint64(capacity), ForecastWindow)
+	if !ok {
+		return math.Inf(1), true
+	}
+	return forecast.FullIn.Seconds(), true
+}
+
+// Functions son las funciones que implementa Env
+var Functions = expr.Functions{"rate": true, "delta": true, "abs": true, "min": true, "max": true}
+
+// Call implementa las funciones del lenguaje: rate() y delta() comparan las
+// dos últimas muestras; abs(), min() y max() operan sobre valores.
+func (e *Env) Call(name string, args []expr.Node) (float64, error) {
+	switch name {
+	case "rate", "delta":
+		if len(args) != 1 {
+			return 0, fmt.Errorf("%s() espera un argumento", name)
+		}
+		metric, ok := args[0].(*expr.MetricNode)
+		if !ok {
+			return 0, fmt.Errorf("%s() espera una métrica", name)
+		}
+		return e.counterChange(name, metric.Path)
+
+	}
+	return callMath(e, name, args)
+}
+
+// callMath implementa abs(), min() y max(), comunes a todos los entornos
+func callMath(env expr.Env, name string, args []expr.Node) (float64, error) {
+	switch name {
+	case "abs", "min", "max":
+		if len(args) == 0 || (name == "abs" && len(args) != 1) {
+			return 0, fmt.Errorf("número de argumentos inválido para %s()", name)
+		}
+		values := make([]float64, len(args))
+		for i, arg := range args {
+			v, err := arg.Eval(env)
+			if err != nil {
+				return 0, err
+			}
+			values[i] = v
+		}
+		result := values[0]
+		for _, v := range values[1:] {
+			if name == "min" {
+				result = math.Min(result, v)
+			} else {
+				result = math.Max(result, v)
+			}
+		}
+		if name == "abs" {
+			result = math.Abs(result)
+		}
+		return result, nil
+	}
+	return 0, fmt.Errorf("función desconocida: %s()", name)
+}
+
+// counterChange devuelve NaN mientras no haya dos muestras que comparar
+func (e *Env) counterChange(fn, path string) (float64, error) {
+	if e.history.Len() < 2 {
+		return math.NaN(), nil
+	}
+
+	before, prevTime, ok := e.history.Value(1, path)
+	if !ok {
+		return 0, fmt.Errorf("métrica desconocida: %s", path)
+	}
+	after, currTime, ok := e.history.Value(0, path)
+	if !ok {
+		return 0, fmt.Errorf("métrica desconocida: %s", path)
+	}
+
+	if fn == "delta" {
+		return after - before, nil
+	}
+	elapsed := currTime.Sub(prevTime).Seconds()
+	if elapsed <= 0 || after < before {
+		return 0, nil
+	}
+	return (after - before) / elapsed, nil
+}
+
+// Computed es una métrica derivada definida por el usuario
+type Computed struct {
+	Name string
+	Expr *expr.Expr
+}
+
+type ComputedValue struct {
+	Name  string
+	Value float64
+	Err   error
+}
+
+// Evaluate evalúa expresiones sin registrarlas como métricas calculadas
+func Evaluate(env expr.Env, list []Computed) []ComputedValue {
+	values := make([]ComputedValue, len(list))
+	for i, c := range list {
+		v, err := c.Expr.Eval(env)
+		values[i] = ComputedValue{Name: c.Name, Value: v, Err: err}
+	}
+	return values
+}
+
+// EvaluateComputed evalúa las métricas calculadas en orden; cada una puede
+// usar las anteriores por su nombre.
+func EvaluateComputed(env *Env, computed []Computed) []ComputedValue {
+	values := make([]ComputedValue, len(computed))
+	for i, c := range computed {
+		v, err := c.Expr.Eval(env)
+		values[i] = ComputedValue{Name: c.Name, Value: v, Err: err}
+		if err == nil {
+			env.Set(c.Name, v)
+		}
+	}
+	return values
+}
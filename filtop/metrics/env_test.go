@@ -0,0 +1,35 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/iTiagoCO/filtop/filtop/expr"
+)
+
+func TestFunctions(t *testing.T) {
+	history := NewHistory(10)
+	envs := []struct {
+		name      string
+		env       expr.Env
+		functions expr.Functions
+		rejected  string
+	}{
+		{"beat", NewEnv(history), Functions, "increase(pipeline.events.total) > 0"},
+		{"input", history.InputEnv("nginx", time.Minute), InputFunctions, "rate(events) > 0"},
+	}
+	for _, tt := range envs {
+		// Lo que Compile acepta el entorno lo implementa
+		for name := range tt.functions {
+			_, err := tt.env.Call(name, []expr.Node{&expr.MetricNode{Path: "events"}})
+			if err != nil && strings.Contains(err.Error(), "función desconocida") {
+				t.Errorf("%s: %s() se acepta al compilar pero no se implementa", tt.name, name)
+			}
+		}
+		// Y lo que no implementa se rechaza al compilar
+		if _, err := expr.Compile(tt.rejected, tt.functions); err == nil || !strings.Contains(err.Error(), "función desconocida") {
+			t.Errorf("%s: Compile(%q) = %v, se esperaba función desconocida", tt.name, tt.rejected, err)
+		}
+	}
+}
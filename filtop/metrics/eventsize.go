@@ -0,0 +1,92 @@
+package metrics
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// Tamaño de los eventos: Filebeat no lo informa, pero se estima dividiendo
+// cuántos bytes leyó cada input por cuántos eventos produjo. Sirve para
+// planificar la capacidad y para notar un input que de pronto envía eventos
+// enormes, p. ej. un multiline mal configurado.
+
+// EventSizeBounds son los límites de los rangos de tamaño de
+// EventSizeSeries, en bytes
+var EventSizeBounds = []float64{256, 1024, 4096, 16384}
+
+// InputEventSize es el tamaño promedio de los eventos que el input leyó en
+// los últimos window (con window 0, desde la muestra anterior). ok es false
+// si no leyó eventos en ese tiempo.
+func (h *History) InputEventSize(id string, window time.Duration) (size float64, ok bool) {
+	events, _, ok := h.inputChange(id, inputCounters["events"], window)
+	if !ok || events == 0 {
+		return 0, false
+	}
+	bytes, _, _ := h.inputChange(id, inputCounters["bytes"], window)
+	return bytes / events, true
+}
+
+// EventSizeSeries reparte los eventos/s de cada intervalo entre muestras
+// con inputs según el tamaño promedio de los eventos de cada input en el
+// intervalo: rates[b] son los de los inputs cuyos eventos miden menos que
+// bounds[b] y el último, los de los demás. sizes es el tamaño promedio de
+// todos los eventos del intervalo, cero si no hubo eventos.
+func (h *History) EventSizeSeries(bounds []float64) (rates [][]Point, sizes []Point) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	rates = make([][]Point, len(bounds)+1)
+	var prev *record
+	for back := h.n - 1; back >= 0; back-- {
+		rec := h.at(back)
+		if len(rec.inputs) == 0 {
+			continue
+		}
+		if prev == nil {
+			prev = rec
+			continue
+		}
+		elapsed := rec.time.Sub(prev.time).Seconds()
+		if elapsed <= 0 {
+			prev = rec
+			continue
+		}
+		buckets := make([]float64, len(rates))
+		var events, bytes float64
+		for i := range rec.inputs {
+			e, ok := counterChange(prev.inputs, rec.inputs, i)
+			if !ok || e == 0 {
+				continue
+			}
+			b, _ := counterChange(prev.inputBytes, rec.inputBytes, i)
+			size := b / e
+			bucket := sort.Search(len(bounds), func(k int) bool { return size < bounds[k] })
+			buckets[bucket] += e
+			events += e
+			bytes += b
+		}
+		for b := range rates {
+			rates[b] = append(rates[b], Point{Time: rec.time, Value: buckets[b] / elapsed})
+		}
+		size := 0.0
+		if events > 0 {
+			size = bytes / events
+		}
+		sizes = append(sizes, Point{Time: rec.time, Value: size})
+		prev = rec
+	}
+	return rates, sizes
+}
+
+// counterChange es cuánto aumentó el contador i de un input entre dos
+// muestras; si bajó, Filebeat se reinició y el aumento es el valor actual.
+// ok es false si alguna de las muestras no tiene el input.
+func counterChange(before, after []float64, i int) (change float64, ok bool) {
+	if i >= len(before) || i >= len(after) || math.IsNaN(before[i]) || math.IsNaN(after[i]) {
+		return 0, false
+	}
+	if after[i] < before[i] {
+		return after[i], true
+	}
+	return after[i] - before[i], true
+}
@@ -0,0 +1,93 @@
+package metrics
+
+import "time"
+
+// Rutas del llenado de la cola; las versiones sin métricas de la cola
+// informan los eventos en curso del pipeline
+var queueFilledPaths = []string{"pipeline.queue.filled.events", "pipeline.events.active"}
+
+const ackedPath = "output.events.acked"
+
+// ForecastWindow son las muestras recientes con que se proyecta la cola:
+// lo bastante para no reaccionar a un solo ciclo y lo bastante corto para
+// seguir un cambio de ritmo
+const ForecastWindow = 30 * time.Second
+
+// QueueFullIn es la variable de las expresiones con los segundos que faltan
+// para que se llene la cola; +Inf si no está creciendo
+const QueueFullIn = "queue_full_in"
+
+// QueueForecast estima cuánto falta para que se llene la cola si sigue
+// creciendo como en la ventana analizada.
+type QueueForecast struct {
+	// Growth son los eventos/s en que crece la cola, la pendiente de su
+	// llenado
+	Growth float64
+	// Acked son los eventos/s que confirmó la salida en la ventana; AckedOK
+	// es false si la salida no lo informa
+	Acked   float64
+	AckedOK bool
+	FullIn  time.Duration
+}
+
+// ForecastQueue proyecta el llenado de la cola de las muestras de los
+// últimos window sobre su capacidad. ok es false si la cola no crece,
+// ya está llena o no hay suficientes muestras.
+func (h *History) ForecastQueue(capacity uint64, window time.Duration) (forecast QueueForecast, ok bool) {
+	if capacity == 0 {
+		return forecast, false
+	}
+	var filled float64
+	for _, path := range queueFilledPaths {
+		if forecast.Growth, filled, ok = h.trend(path, window); ok {
+			break
+		}
+	}
+	if !ok || forecast.Growth <= 0 || filled >= float64(capacity) {
+		return forecast, false
+	}
+	forecast.FullIn = time.Duration((float64(capacity) - filled) / forecast.Growth * float64(time.Second))
+	if _, at, found := h.Value(0, ackedPath); found {
+		forecast.Acked, forecast.AckedOK = h.RateSince(ackedPath, at.Add(-window))
+	}
+	return forecast, true
+}
+
+// trend es la pendiente por segundo de una ruta en las muestras de los
+// últimos window, por mínimos cuadrados, y su valor más reciente. Hacen
+// falta al menos tres muestras.
+func (h *History) trend(path string, window time.Duration) (slope, latest float64, ok bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	i, found := h.resolve(path)
+	if !found || h.n == 0 {
+		return 0, 0, false
+	}
+	last := h.at(0)
+	latest, ok = last.value(i)
+	if !ok {
+		return 0, 0, false
+	}
+	var n, sumX, sumY, sumXY, sumXX float64
+	for back := 0; back < h.n; back++ {
+		rec := h.at(back)
+		if last.time.Sub(rec.time) > window {
+			break
+		}
+		v, valid := rec.value(i)
+		if !valid {
+			continue
+		}
+		x := rec.time.Sub(last.time).Seconds()
+		n++
+		sumX += x
+		sumY += v
+		sumXY += x * v
+		sumXX += x * x
+	}
+	denominator := n*sumXX - sumX*sumX
+	if n < 3 || denominator == 0 {
+		return 0, 0, false
+	}
+	return (n*sumXY - sumX*sumY) / denominator, latest, true
+}
@@ -0,0 +1,124 @@
+package metrics
+
+import (
+	"math"
+	"time"
+
+	"github.com/iTiagoCO/filtop/filtop/client"
+)
+
+// HealthWindow son las muestras recientes con que se miden los descartes
+// del puntaje de salud
+const HealthWindow = time.Minute
+
+// Peso máximo de cada componente del puntaje, que suman 100
+const (
+	healthDropsWeight       = 35
+	healthQueueWeight       = 25
+	healthDisconnectsWeight = 25
+	healthLagWeight         = 15
+)
+
+// Umbrales de cada componente: a partir de ellos resta su peso completo
+const (
+	// Proporción de eventos descartados
+	healthDropsFull = 0.01
+	// Llenado de la cola desde el que empieza a restar y con el que resta
+	// todo
+	healthQueueFrom = 0.5
+	healthQueueFull = 0.95
+	// Desconexiones de la sesión
+	healthDisconnectsFull = 5
+	// Segundos que tardaría la salida en vaciar la cola
+	healthLagFull = 60
+)
+
+// Health es el puntaje de salud de un beat: 100 menos lo que resta cada
+// componente, para ordenar una flota y encontrar la peor instancia. Cada
+// componente es lo que resta, de 0 a su peso.
+type Health struct {
+	Score float64
+	// Drops son los eventos descartados en HealthWindow, Queue el llenado
+	// de la cola, Disconnects los cortes de conexión de la sesión (los 100
+	// puntos si está caído) y Lag lo que tardaría la salida en vaciar la cola
+	Drops, Queue, Disconnects, Lag float64
+	// DropRatio, QueueFill y LagSeconds son los valores medidos
+	DropRatio  float64
+	QueueFill  float64
+	LagSeconds float64
+}
+
+// Worst es el componente que más resta; vacío si ninguno resta
+func (h Health) Worst() string {
+	worst, penalty := "", 0.0
+	for _, c := range []struct {
+		name    string
+		penalty float64
+	}{{"descartes", h.Drops}, {"cola", h.Queue}, {"conexión", h.Disconnects}, {"retraso", h.Lag}} {
+		if c.penalty > penalty {
+			worst, penalty = c.name, c.penalty
+		}
+	}
+	return worst
+}
+
+// ScoreHealth calcula el puntaje de la última muestra (nil si no hay) con
+// el historial y la sesión del beat
+func ScoreHealth(stats *client.FilebeatStats, history *History, session SessionSummary) Health {
+	var h Health
+	switch {
+	case session.Down:
+		// Sin conexión no hay nada más que medir: es el peor puntaje
+		h.Disconnects = 100
+		return h
+	case session.Disconnects > 0:
+		h.Disconnects = healthDisconnectsWeight * math.Min(1, float64(session.Disconnects)/healthDisconnectsFull)
+	}
+	if stats != nil {
+		dropped, okDropped := recentRate(history, droppedPath, HealthWindow)
+		total, okTotal := recentRate(history, totalPath, HealthWindow)
+		if okDropped && okTotal && dropped > 0 {
+			h.DropRatio = dropped / math.Max(total, dropped)
+			h.Drops = healthDropsWeight * math.Min(1, h.DropRatio/healthDropsFull)
+		}
+		queue := stats.Libbeat.Pipeline.Queue
+		if queue.MaxEvents > 0 {
+			h.QueueFill = float64(queue.Filled.Events) / float64(queue.MaxEvents)
+			h.Queue = healthQueueWeight * clamp01((h.QueueFill-healthQueueFrom)/(healthQueueFull-healthQueueFrom))
+		}
+		if filled := queue.Filled.Events; filled > 0 {
+			if acked, ok := recentRate(history, ackedPath, HealthWindow); ok {
+				h.LagSeconds = math.Inf(1)
+				if acked > 0 {
+					h.LagSeconds = float64(filled) / acked
+				}
+				h.Lag = healthLagWeight * math.Min(1, h.LagSeconds/healthLagFull)
+			}
+		}
+	}
+	h.Score = 100 - h.Drops - h.Queue - h.Disconnects - h.Lag
+	return h
+}
+
+const (
+	droppedPath = "pipeline.events.dropped"
+	totalPath   = "pipeline.events.total"
+)
+
+// recentRate es el incremento por segundo de un contador en los últimos
+// window, o desde la muestra más antigua si el historial es más corto
+func recentRate(history *History, path string, window time.Duration) (float64, bool) {
+	_, at, ok := history.Value(0, path)
+	if !ok {
+		return 0, false
+	}
+	since := at.Add(-window)
+	if _, oldest, ok := history.Value(history.Len()-1, path); ok && oldest.After(since) {
+		since = oldest
+	}
+	return history.RateSince(path, since)
+}
+
+func clamp01(v float64) float64 {
+	return math.Max(0, math.Min(1, v))
+}
@@ -0,0 +1,577 @@
+// Package metrics conserva el historial de muestras de Filebeat y calcula
+// tasas a partir de los contadores acumulados.
+package metrics
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/iTiagoCO/filtop/filtop/client"
+)
+
+// Prefijos que se prueban cuando una ruta no existe tal cual, para poder
+// escribir pipeline.events.total en lugar de libbeat.pipeline.events.total.
+var pathPrefixes = []string{"", "libbeat.", "filebeat.", "beat.", "system."}
+
+// Point es el valor de una serie en una muestra
+type Point struct {
+	Time  time.Time
+	Value float64
+}
+
+// record es una muestra compacta: solo los valores numéricos de /stats y
+// los contadores de cada input. values[i] corresponde a History.paths[i] y
+// vale NaN si la ruta no estaba en la muestra; lo mismo inputs,
+// inputBytes, inputErrors e inputDropped con History.inputIDs.
+type record struct {
+	time         time.Time
+	values       []float64
+	inputs       []float64
+	inputBytes   []float64
+	inputErrors  []float64
+	inputDropped []float64
+}
+
+// History conserva las últimas muestras en orden cronológico, en un buffer
+// circular reservado de antemano. Es seguro usarlo desde varias goroutines
+// (colector, interfaz y API).
+type History struct {
+	mu      sync.RWMutex
+	records []record
+	// start es la muestra más antigua y n cuántas hay
+	start, n int
+
+	// Rutas e ids de input vistos, compartidos por todas las muestras
+	paths     []string
+	pathIndex map[string]int
+	inputIDs  []string
+	inputIdx  map[string]int
+	// activity[i] corresponde a inputIDs[i]; a diferencia de las muestras,
+	// abarca todo lo observado desde que el input apareció
+	activity []InputActivity
+	// seen[i] es la última muestra, contando desde added, que incluyó a
+	// inputIDs[i]. Los inputs que no están en ninguna muestra retenida se
+	// descartan con pruneInputs, para que los ids que cambian (p. ej. los
+	// de autodiscover) no se acumulen.
+	seen  []int
+	added int
+}
+
+// InputActivity resume cuándo un input produjo eventos mientras se lo
+// observó. LastEvent es la última muestra en la que su contador aumentó;
+// vale cero si no aumentó desde FirstSeen. LongestGap es la mayor pausa
+// entre dos aumentos.
+type InputActivity struct {
+	FirstSeen  time.Time
+	LastEvent  time.Time
+	LongestGap time.Duration
+	events     uint64
+}
+
+// NewHistory crea un historial que retiene como máximo size muestras
+func NewHistory(size int) *History {
+	return &History{
+		records:   make([]record, size),
+		pathIndex: make(map[string]int),
+		inputIdx:  make(map[string]int),
+	}
+}
+
+// Add agrega una muestra y descarta la más antigua si se supera el tamaño
+func (h *History) Add(stats *client.FilebeatStats) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(h.records) == 0 {
+		return
+	}
+
+	var rec *record
+	if h.n < len(h.records) {
+		rec = &h.records[(h.start+h.n)%len(h.records)]
+		h.n++
+	} else {
+		// Se reutiliza la memoria de la muestra descartada
+		rec = &h.records[h.start]
+		h.start = (h.start + 1) % len(h.records)
+		defer h.pruneInputs()
+	}
+	h.added++
+
+	rec.time = stats.Timestamp
+	rec.values = rec.values[:0]
+	h.flatten("", stats.Raw, rec)
+	rec.inputs = rec.inputs[:0]
+	rec.inputBytes = rec.inputBytes[:0]
+	rec.inputErrors = rec.inputErrors[:0]
+	rec.inputDropped = rec.inputDropped[:0]
+	if stats.InputsAt.Before(stats.Timestamp) {
+		// Inputs repetidos de una consulta anterior: no aportan a las tasas
+		return
+	}
+	for _, input := range stats.Filebeat.Inputs {
+		i, ok := h.inputIdx[input.ID]
+		if !ok {
+			i = len(h.inputIDs)
+			h.inputIDs = append(h.inputIDs, input.ID)
+			h.inputIdx[input.ID] = i
+			h.seen = append(h.seen, 0)
+		}
+		h.seen[i] = h.added
+		rec.inputs = setAt(rec.inputs, i, float64(input.Events))
+		rec.inputBytes = setAt(rec.inputBytes, i, float64(input.Bytes))
+		rec.inputErrors = setAt(rec.inputErrors, i, float64(input.Errors))
+		rec.inputDropped = setAt(rec.inputDropped, i, float64(input.Dropped))
+		h.trackActivity(i, input.Events, stats.Timestamp)
+	}
+}
+
+// pruneInputs descarta los inputs que no están en ninguna muestra retenida
+// y renumera los demás en las muestras. Para no hacerlo en cada muestra
+// espera a que los descartados sean la mitad.
+func (h *History) pruneInputs() {
+	stale := 0
+	for _, seen := range h.seen {
+		if seen <= h.added-h.n {
+			stale++
+		}
+	}
+	if stale == 0 || stale*2 < len(h.inputIDs) {
+		return
+	}
+
+	// keep[i] es el nuevo índice del input i, o -1 si se descarta
+	keep := make([]int, len(h.inputIDs))
+	ids := h.inputIDs[:0]
+	for i, id := range h.inputIDs {
+		if h.seen[i] <= h.added-h.n {
+			keep[i] = -1
+			delete(h.inputIdx, id)
+			continue
+		}
+		keep[i] = len(ids)
+		h.inputIdx[id] = len(ids)
+		h.seen[len(ids)] = h.seen[i]
+		ids = append(ids, id)
+	}
+	h.inputIDs = ids
+	h.seen = h.seen[:len(ids)]
+	activity := h.activity[:0]
+	for i, a := range h.activity {
+		if keep[i] >= 0 {
+			activity = append(activity, a)
+		}
+	}
+	h.activity = activity
+	for i := range h.records {
+		rec := &h.records[i]
+		rec.inputs = remapInputs(rec.inputs, keep)
+		rec.inputBytes = remapInputs(rec.inputBytes, keep)
+		rec.inputErrors = remapInputs(rec.inputErrors, keep)
+		rec.inputDropped = remapInputs(rec.inputDropped, keep)
+	}
+}
+
+// remapInputs mueve cada valor de values a su nuevo índice en keep. Como
+// los índices que quedan conservan su orden, se hace sobre el mismo slice.
+func remapInputs(values []float64, keep []int) []float64 {
+	n := 0
+	for i, v := range values {
+		if keep[i] >= 0 {
+			values[keep[i]] = v
+			n = keep[i] + 1
+		}
+	}
+	return values[:n]
+}
+
+// trackActivity registra si el contador de eventos del input i aumentó
+func (h *History) trackActivity(i int, events uint64, now time.Time) {
+	for len(h.activity) <= i {
+		h.activity = append(h.activity, InputActivity{})
+	}
+	a := &h.activity[i]
+	switch {
+	case a.FirstSeen.IsZero():
+		a.FirstSeen = now
+	case events > a.events:
+		if !a.LastEvent.IsZero() {
+			a.LongestGap = max(a.LongestGap, now.Sub(a.LastEvent))
+		}
+		a.LastEvent = now
+	}
+	// Si el contador bajó, Filebeat se reinició: se sigue desde el nuevo valor
+	a.events = events
+}
+
+// flatten guarda en rec cada valor numérico o booleano de doc con su ruta
+// en la sintaxis de client.Lookup ("a.b[0].c").
+func (h *History) flatten(path string, doc interface{}, rec *record) {
+	switch v := doc.(type) {
+	case map[string]interface{}:
+		for key, child := range v {
+			if path != "" {
+				key = path + "." + key
+			}
+			h.flatten(key, child, rec)
+		}
+	case []interface{}:
+		for i, child := range v {
+			h.flatten(path+"["+strconv.Itoa(i)+"]", child, rec)
+		}
+	case float64:
+		h.setValue(path, v, rec)
+	case bool:
+		if v {
+			h.setValue(path, 1, rec)
+		} else {
+			h.setValue(path, 0, rec)
+		}
+	}
+}
+
+func (h *History) setValue(path string, v float64, rec *record) {
+	i, ok := h.pathIndex[path]
+	if !ok {
+		i = len(h.paths)
+		h.paths = append(h.paths, path)
+		h.pathIndex[path] = i
+	}
+	rec.values = setAt(rec.values, i, v)
+}
+
+// setAt asigna values[i], completando con NaN las posiciones intermedias
+func setAt(values []float64, i int, v float64) []float64 {
+	for len(values) <= i {
+		values = append(values, math.NaN())
+	}
+	values[i] = v
+	return values
+}
+
+// at devuelve la muestra back posiciones antes de la más reciente
+func (h *History) at(back int) *record {
+	return &h.records[(h.start+h.n-1-back)%len(h.records)]
+}
+
+// resolve busca el índice de una ruta, probando los prefijos habituales
+func (h *History) resolve(path string) (int, bool) {
+	path = strings.TrimPrefix(strings.TrimPrefix(path, "$"), ".")
+	for _, prefix := range pathPrefixes {
+		if i, ok := h.pathIndex[prefix+path]; ok {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+func (r *record) value(i int) (float64, bool) {
+	if i >= len(r.values) || math.IsNaN(r.values[i]) {
+		return 0, false
+	}
+	return r.values[i], true
+}
+
+// Value devuelve el valor de una ruta de /stats back muestras antes de la
+// más reciente (0 es la última), junto con el instante de esa muestra.
+func (h *History) Value(back int, path string) (float64, time.Time, bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	if back < 0 || back >= h.n {
+		return 0, time.Time{}, false
+	}
+	i, ok := h.resolve(path)
+	if !ok {
+		return 0, time.Time{}, false
+	}
+	rec := h.at(back)
+	v, ok := rec.value(i)
+	return v, rec.time, ok
+}
+
+// Series devuelve los valores retenidos de una ruta de /stats, del más
+// antiguo al más reciente. found es false si la ruta nunca apareció.
+func (h *History) Series(path string) (points []Point, found bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	i, ok := h.resolve(path)
+	if !ok {
+		return nil, false
+	}
+	for back := h.n - 1; back >= 0; back-- {
+		rec := h.at(back)
+		if v, ok := rec.value(i); ok {
+			points = append(points, Point{Time: rec.time, Value: v})
+		}
+	}
+	return points, true
+}
+
+// RateSeries es como Series, pero para un contador: cada punto es el
+// incremento por segundo desde la muestra anterior.
+func (h *History) RateSeries(path string) (points []Point, found bool) {
+	return h.RateSeriesOver(path, 0)
+}
+
+// RateSeriesOver es como RateSeries, pero cada punto promedia las tasas de
+// los últimos window ponderadas por el tiempo que abarca cada una, para
+// suavizar los inputs con ráfagas; con window 0 es RateSeries
+func (h *History) RateSeriesOver(path string, window time.Duration) (points []Point, found bool) {
+	series, found := h.Series(path)
+	var sum, span float64
+	start := 1
+	for i := 1; i < len(series); i++ {
+		prev, curr := series[i-1], series[i]
+		rate := Rate(uint64(prev.Value), uint64(curr.Value), curr.Time.Sub(prev.Time))
+		if window == 0 {
+			points = append(points, Point{Time: curr.Time, Value: rate})
+			continue
+		}
+		seconds := curr.Time.Sub(prev.Time).Seconds()
+		sum, span = sum+rate*seconds, span+seconds
+		// Las tasas que empiezan antes de la ventana salen del promedio
+		for ; start < i && curr.Time.Sub(series[start-1].Time) > window; start++ {
+			first, second := series[start-1], series[start]
+			seconds := second.Time.Sub(first.Time).Seconds()
+			sum -= Rate(uint64(first.Value), uint64(second.Value), second.Time.Sub(first.Time)) * seconds
+			span -= seconds
+		}
+		value := 0.0
+		if span > 0 {
+			value = sum / span
+		}
+		points = append(points, Point{Time: curr.Time, Value: value})
+	}
+	return points, found
+}
+
+// RateOver es la tasa de un contador promediada en los últimos window, como
+// el último punto de RateSeriesOver; con window 0, entre las dos últimas
+// muestras. ok es false si no hay dos muestras con la ruta.
+func (h *History) RateOver(path string, window time.Duration) (rate float64, ok bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	i, found := h.resolve(path)
+	if !found {
+		return 0, false
+	}
+	var curr, oldest *record
+	var newer, increase float64
+	for back := 0; back < h.n; back++ {
+		rec := h.at(back)
+		v, valid := rec.value(i)
+		if !valid {
+			continue
+		}
+		if curr == nil {
+			curr, newer = rec, v
+			continue
+		}
+		if oldest != nil && curr.time.Sub(rec.time) > window {
+			break
+		}
+		// Si el contador retrocede, Filebeat se reinició: ese tramo no suma
+		if newer >= v {
+			increase += newer - v
+		}
+		oldest, newer = rec, v
+		if window == 0 {
+			break
+		}
+	}
+	if oldest == nil {
+		return 0, false
+	}
+	seconds := curr.time.Sub(oldest.time).Seconds()
+	if seconds <= 0 {
+		return 0, false
+	}
+	return increase / seconds, true
+}
+
+// RateSince calcula el incremento por segundo de un contador entre la
+// última muestra tomada hasta since y la más reciente, para comparar con
+// una tasa medida por otra fuente en ese mismo intervalo. ok es false si
+// no hay muestras que cubran el intervalo.
+func (h *History) RateSince(path string, since time.Time) (rate float64, ok bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	i, found := h.resolve(path)
+	if !found || h.n < 2 {
+		return 0, false
+	}
+	curr := h.at(0)
+	after, valid := curr.value(i)
+	if !valid {
+		return 0, false
+	}
+	for back := 1; back < h.n; back++ {
+		rec := h.at(back)
+		if rec.time.After(since) {
+			continue
+		}
+		before, valid := rec.value(i)
+		if !valid {
+			return 0, false
+		}
+		return Rate(uint64(before), uint64(after), curr.time.Sub(rec.time)), true
+	}
+	return 0, false
+}
+
+// Reset descarta las muestras retenidas, p. ej. al cambiar de Filebeat
+func (h *History) Reset() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.start, h.n = 0, 0
+	h.activity = nil
+	h.inputIDs, h.seen = nil, nil
+	h.inputIdx = make(map[string]int)
+}
+
+func (h *History) Len() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.n
+}
+
+// Size devuelve la cantidad máxima de muestras retenidas
+func (h *History) Size() int { return len(h.records) }
+
+// InputEventRate calcula eventos/s de un input entre las dos últimas
+// muestras que lo incluyen. Si /inputs/ se consulta con menos frecuencia
+// que /stats, no todas las muestras tienen inputs.
+func (h *History) InputEventRate(id string) float64 {
+	rate, _ := h.InputRate(id)
+	return rate
+}
+
+// InputRate es como InputEventRate, pero ok es false si todavía no hay dos
+// muestras con el input.
+func (h *History) InputRate(id string) (rate float64, ok bool) {
+	return h.inputRate(id, func(rec *record) []float64 { return rec.inputs })
+}
+
+// InputByteRate calcula bytes/s de un input como InputRate
+func (h *History) InputByteRate(id string) (rate float64, ok bool) {
+	return h.inputRate(id, func(rec *record) []float64 { return rec.inputBytes })
+}
+
+// InputRateOver es como InputRate, pero promedia los últimos window; con
+// window 0 es InputRate
+func (h *History) InputRateOver(id string, window time.Duration) (rate float64, ok bool) {
+	if window == 0 {
+		return h.InputRate(id)
+	}
+	return h.inputRateOver(id, func(rec *record) []float64 { return rec.inputs }, window)
+}
+
+// InputByteRateOver es como InputByteRate, pero promedia los últimos
+// window; con window 0 es InputByteRate
+func (h *History) InputByteRateOver(id string, window time.Duration) (rate float64, ok bool) {
+	if window == 0 {
+		return h.InputByteRate(id)
+	}
+	return h.inputRateOver(id, func(rec *record) []float64 { return rec.inputBytes }, window)
+}
+
+func (h *History) inputRateOver(id string, counters func(*record) []float64, window time.Duration) (rate float64, ok bool) {
+	change, elapsed, ok := h.inputChange(id, counters, window)
+	if !ok || elapsed <= 0 {
+		return 0, false
+	}
+	return change / elapsed.Seconds(), true
+}
+
+// inputRate calcula la tasa de un contador del input entre las dos últimas
+// muestras que lo incluyen; counters elige el contador de cada muestra.
+func (h *History) inputRate(id string, counters func(*record) []float64) (rate float64, ok bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	i, found := h.inputIdx[id]
+	if !found {
+		return 0, false
+	}
+	var records []*record
+	for back := 0; back < h.n && len(records) < 2; back++ {
+		rec := h.at(back)
+		if values := counters(rec); i < len(values) && !math.IsNaN(values[i]) {
+			records = append(records, rec)
+		}
+	}
+	if len(records) < 2 {
+		return 0, false
+	}
+	curr, prev := records[0], records[1]
+	return Rate(uint64(counters(prev)[i]), uint64(counters(curr)[i]), curr.time.Sub(prev.time)), true
+}
+
+// inputChange devuelve cuánto aumentó un contador del input entre la
+// última muestra que lo incluye y la más antigua que lo incluye dentro de
+// window antes que ella; con window 0, la anterior. ok es false si no hay
+// dos muestras que comparar. Si el contador bajó, Filebeat se reinició y
+// se toma el valor actual como aumento.
+func (h *History) inputChange(id string, counters func(*record) []float64, window time.Duration) (change float64, elapsed time.Duration, ok bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	i, found := h.inputIdx[id]
+	if !found {
+		return 0, 0, false
+	}
+	var curr, prev *record
+	for back := 0; back < h.n; back++ {
+		rec := h.at(back)
+		if values := counters(rec); i >= len(values) || math.IsNaN(values[i]) {
+			continue
+		}
+		if curr == nil {
+			curr = rec
+			continue
+		}
+		if prev != nil && curr.time.Sub(rec.time) > window {
+			break
+		}
+		prev = rec
+		if window == 0 {
+			break
+		}
+	}
+	if prev == nil {
+		return 0, 0, false
+	}
+	before, after := counters(prev)[i], counters(curr)[i]
+	if after < before {
+		return after, curr.time.Sub(prev.time), true
+	}
+	return after - before, curr.time.Sub(prev.time), true
+}
+
+// inputLatest devuelve el último valor de un contador del input
+func (h *History) inputLatest(id string, counters func(*record) []float64) (float64, bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	i, found := h.inputIdx[id]
+	if !found {
+		return 0, false
+	}
+	for back := 0; back < h.n; back++ {
+		if values := counters(h.at(back)); i < len(values) && !math.IsNaN(values[i]) {
+			return values[i], true
+		}
+	}
+	return 0, false
+}
+
+// InputActivity devuelve cuándo el input produjo eventos por última vez;
+// ok es false si nunca se lo vio
+func (h *History) InputActivity(id string) (activity InputActivity, ok bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	i, found := h.inputIdx[id]
+	if !found || i >= len(h.activity) {
+		return InputActivity{}, false
+	}
+	return h.activity[i], true
+}
@@ -0,0 +1,39 @@
+package metrics
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/iTiagoCO/filtop/filtop/client"
+)
+
+func TestInputIDsPruned(t *testing.T) {
+	const size = 10
+	h := NewHistory(size)
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	// Cada muestra tiene un input nuevo y el de la anterior, como los ids
+	// de autodiscover que cambian con cada contenedor
+	for k := 1; k <= 1000; k++ {
+		stats := &client.FilebeatStats{Timestamp: start.Add(time.Duration(k) * time.Second)}
+		stats.InputsAt = stats.Timestamp
+		for _, id := range []int{k - 1, k} {
+			stats.Filebeat.Inputs = append(stats.Filebeat.Inputs, client.Input{ID: fmt.Sprintf("in-%d", id), Events: uint64(k * 10)})
+		}
+		h.Add(stats)
+		if len(h.inputIDs) > 2*(size+1) || len(h.activity) != len(h.inputIDs) {
+			t.Fatalf("muestra %d: %d ids y %d actividades retenidos", k, len(h.inputIDs), len(h.activity))
+		}
+	}
+
+	if _, ok := h.InputActivity("in-0"); ok {
+		t.Error("in-0 sigue retenido")
+	}
+	// Los que quedan conservan sus contadores tras renumerarlos
+	if rate, ok := h.InputRate("in-999"); !ok || rate != 10 {
+		t.Errorf("InputRate(in-999) = %v, %v", rate, ok)
+	}
+	if rate, ok := h.InputRate("in-995"); !ok || rate != 10 {
+		t.Errorf("InputRate(in-995) = %v, %v", rate, ok)
+	}
+}
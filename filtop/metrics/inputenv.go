@@ -0,0 +1,120 @@
+package metrics
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/iTiagoCO/filtop/filtop/expr"
+)
+
+// Contadores de un input que pueden aparecer en sus expresiones
+var inputCounters = map[string]func(*record) []float64{
+	"events":  func(r *record) []float64 { return r.inputs },
+	"bytes":   func(r *record) []float64 { return r.inputBytes },
+	"errors":  func(r *record) []float64 { return r.inputErrors },
+	"dropped": func(r *record) []float64 { return r.inputDropped },
+}
+
+// IsInputVariable indica si name es una variable de InputEnv
+func IsInputVariable(name string) bool {
+	switch name {
+	case "rate", "byte_rate", "avg_rate", "drops", "event_size", "avg_event_size":
+		return true
+	}
+	_, ok := inputCounters[name]
+	return ok
+}
+
+// InputEnv resuelve las expresiones que se evalúan por input, p. ej. las
+// reglas de resaltado del panel Inputs:
+//
+//	events, bytes, errors, dropped  contadores del input
+//	drops                           errors + dropped
+//	rate, byte_rate                 eventos/s y bytes/s actuales
+//	avg_rate                        eventos/s promedio en la ventana
+//	event_size                      bytes por evento desde la muestra anterior
+//	avg_event_size                  bytes por evento en la ventana
+//	delta(x)                        aumento de un contador desde la muestra anterior
+//	increase(x)                     aumento de un contador en la ventana
+type InputEnv struct {
+	history *History
+	id      string
+	window  time.Duration
+}
+
+// InputEnv crea el entorno del input id; window es la ventana de avg_rate e
+// increase()
+func (h *History) InputEnv(id string, window time.Duration) *InputEnv {
+	return &InputEnv{history: h, id: id, window: window}
+}
+
+func (e *InputEnv) Metric(name string) (float64, bool) {
+	switch name {
+	case "rate":
+		return e.history.InputRate(e.id)
+	case "byte_rate":
+		return e.history.InputByteRate(e.id)
+	case "avg_rate":
+		change, elapsed, ok := e.history.inputChange(e.id, inputCounters["events"], e.window)
+		if !ok || elapsed <= 0 {
+			return 0, false
+		}
+		return change / elapsed.Seconds(), true
+	case "event_size":
+		return e.history.InputEventSize(e.id, 0)
+	case "avg_event_size":
+		return e.history.InputEventSize(e.id, e.window)
+	case "drops":
+		errors, ok := e.history.inputLatest(e.id, inputCounters["errors"])
+		dropped, _ := e.history.inputLatest(e.id, inputCounters["dropped"])
+		return errors + dropped, ok
+	}
+	if counters, ok := inputCounters[name]; ok {
+		return e.history.inputLatest(e.id, counters)
+	}
+	return 0, false
+}
+
+// InputFunctions son las funciones que implementa InputEnv
+var InputFunctions = expr.Functions{"delta": true, "increase": true, "abs": true, "min": true, "max": true}
+
+func (e *InputEnv) Call(name string, args []expr.Node) (float64, error) {
+	switch name {
+	case "delta", "increase":
+		if len(args) != 1 {
+			return 0, fmt.Errorf("%s() espera un argumento", name)
+		}
+		metric, ok := args[0].(*expr.MetricNode)
+		if !ok {
+			return 0, fmt.Errorf("%s() espera un contador del input", name)
+		}
+		window := time.Duration(0)
+		if name == "increase" {
+			window = e.window
+		}
+		return e.change(metric.Path, window)
+	}
+	return callMath(e, name, args)
+}
+
+// change devuelve NaN mientras no haya dos muestras que comparar
+func (e *InputEnv) change(counter string, window time.Duration) (float64, error) {
+	names := []string{counter}
+	if counter == "drops" {
+		names = []string{"errors", "dropped"}
+	}
+	total := 0.0
+	for _, name := range names {
+		counters, ok := inputCounters[name]
+		if !ok {
+			return 0, fmt.Errorf("contador desconocido: %s", counter)
+		}
+		change, _, ok := e.history.inputChange(e.id, counters, window)
+		if !ok {
+			return math.NaN(), nil
+		}
+		total += change
+	}
+	return total, nil
+}
@@ -0,0 +1,110 @@
+package metrics
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/iTiagoCO/filtop/filtop/client"
+)
+
+// Cambios de los inputs que se retienen; con autodiscover o recargas de la
+// configuración frecuentes los más viejos se descartan
+const lifecycleSize = 1000
+
+// InputChangeKind es el tipo de un InputChange
+type InputChangeKind string
+
+const (
+	// InputPresent es un input que ya estaba en la primera muestra
+	InputPresent InputChangeKind = "present"
+	InputStarted InputChangeKind = "started"
+	InputStopped InputChangeKind = "stopped"
+	// InputRestarted es un input que se volvió a crear con el mismo id: sus
+	// contadores volvieron a empezar
+	InputRestarted InputChangeKind = "restarted"
+	InputActivated InputChangeKind = "activated"
+	// InputDeactivated es un input que dejó de estar activo sin desaparecer
+	InputDeactivated InputChangeKind = "deactivated"
+)
+
+// InputChange es un cambio en los inputs de un beat entre dos muestras
+type InputChange struct {
+	At   time.Time
+	ID   string
+	Type string
+	Kind InputChangeKind
+}
+
+// Lifecycle registra cuándo los inputs aparecen, desaparecen o cambian de
+// estado entre muestras, lo que con autodiscover o recargas de la
+// configuración ocurre a menudo. Como Session, abarca todo lo observado y no
+// solo lo retenido en el historial.
+type Lifecycle struct {
+	mu      sync.Mutex
+	changes []InputChange
+	// known son los inputs de la última muestra con inputs, por id
+	known   map[string]client.Input
+	sampled bool
+}
+
+// add compara los inputs de stats con los de la muestra anterior. Las
+// muestras que repiten inputs de una consulta anterior o en las que
+// /inputs/ no respondió no cambian nada.
+func (l *Lifecycle) add(stats *client.FilebeatStats) {
+	if stats.InputsAt.Before(stats.Timestamp) || stats.InputsError != "" {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	at := stats.Timestamp
+	current := make(map[string]client.Input, len(stats.Filebeat.Inputs))
+	for _, input := range stats.Filebeat.Inputs {
+		current[input.ID] = input
+		previous, ok := l.known[input.ID]
+		switch {
+		case !l.sampled:
+			l.record(at, input, InputPresent)
+		case !ok:
+			l.record(at, input, InputStarted)
+		case input.Events < previous.Events || input.Bytes < previous.Bytes:
+			l.record(at, input, InputRestarted)
+		case input.Active && !previous.Active:
+			l.record(at, input, InputActivated)
+		case !input.Active && previous.Active:
+			l.record(at, input, InputDeactivated)
+		}
+	}
+	var stopped []string
+	for id := range l.known {
+		if _, ok := current[id]; !ok {
+			stopped = append(stopped, id)
+		}
+	}
+	sort.Strings(stopped)
+	for _, id := range stopped {
+		l.record(at, l.known[id], InputStopped)
+	}
+	l.known = current
+	l.sampled = true
+}
+
+func (l *Lifecycle) record(at time.Time, input client.Input, kind InputChangeKind) {
+	if len(l.changes) >= lifecycleSize {
+		l.changes = append(l.changes[:0], l.changes[1:]...)
+	}
+	l.changes = append(l.changes, InputChange{At: at, ID: input.ID, Type: input.Type, Kind: kind})
+}
+
+// Changes devuelve los cambios retenidos, del más antiguo al más reciente
+func (l *Lifecycle) Changes() []InputChange {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]InputChange(nil), l.changes...)
+}
+
+func (l *Lifecycle) reset() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.changes, l.known, l.sampled = nil, nil, false
+}
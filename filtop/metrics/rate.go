@@ -0,0 +1,13 @@
+package metrics
+
+import "time"
+
+// Rate devuelve el incremento por segundo de un contador entre dos lecturas.
+// Si el contador retrocede (Filebeat se reinició) la tasa es cero.
+func Rate(before, after uint64, elapsed time.Duration) float64 {
+	seconds := elapsed.Seconds()
+	if seconds <= 0 || after < before {
+		return 0
+	}
+	return float64(after-before) / seconds
+}
@@ -0,0 +1,163 @@
+package metrics
+
+import (
+	"sync"
+	"time"
+
+	"github.com/iTiagoCO/filtop/filtop/client"
+)
+
+// Session acumula lo ocurrido desde que filtop empezó a monitorear un beat,
+// p. ej. para el resumen de un cambio de turno. A diferencia de History no
+// descarta muestras: solo guarda totales y picos.
+type Session struct {
+	mu      sync.Mutex
+	summary SessionSummary
+	// prev son los contadores de la muestra anterior
+	prev     sessionCounters
+	sampled  bool
+	failedAt time.Time
+}
+
+// SessionSummary son los totales de una Session. Los contadores suman lo
+// que aumentaron entre muestras, así los reinicios de Filebeat no los
+// vuelven a cero.
+type SessionSummary struct {
+	// First y Last son la primera y la última muestra
+	First, Last time.Time
+	Samples     int
+	// Acked son los eventos que confirmó la salida, Failed los que fallaron
+	// y Dropped los que descartó el pipeline
+	Acked   uint64
+	Failed  uint64
+	Dropped uint64
+	// Bytes son los que escribió la salida
+	Bytes uint64
+	// PeakRate son los eventos/s más altos del pipeline entre dos muestras
+	PeakRate   float64
+	PeakRateAt time.Time
+	// PeakQueue es el mayor llenado de la cola, de QueueMax eventos
+	PeakQueue   uint64
+	QueueMax    uint64
+	PeakQueueAt time.Time
+	// Disconnects son las veces que se perdió la conexión; Downtime el
+	// tiempo sin ella, incluido el corte en curso si Down
+	Disconnects int
+	Downtime    time.Duration
+	Down        bool
+	// Restarts son los reinicios de Filebeat, cuando su uptime bajó
+	Restarts int
+}
+
+// Rates son los eventos/s y bytes/s promedio de la sesión
+func (s SessionSummary) Rates() (events, bytes float64, ok bool) {
+	elapsed := s.Last.Sub(s.First).Seconds()
+	if elapsed <= 0 {
+		return 0, 0, false
+	}
+	return float64(s.Acked) / elapsed, float64(s.Bytes) / elapsed, true
+}
+
+type sessionCounters struct {
+	acked, failed, dropped, bytes, total, uptime uint64
+	at                                           time.Time
+}
+
+func countersOf(stats *client.FilebeatStats) sessionCounters {
+	pipeline := stats.Libbeat.Pipeline.Events
+	return sessionCounters{
+		acked:   rawCounter(stats, "libbeat.output.events.acked"),
+		failed:  rawCounter(stats, "libbeat.output.events.failed"),
+		bytes:   rawCounter(stats, "libbeat.output.write.bytes"),
+		dropped: pipeline.Dropped,
+		total:   pipeline.Total,
+		uptime:  stats.Beat.Info.Uptime.MS,
+		at:      stats.Timestamp,
+	}
+}
+
+func rawCounter(stats *client.FilebeatStats, path string) uint64 {
+	if v, ok := client.Lookup(stats.Raw, path); ok {
+		if f, ok := v.(float64); ok && f > 0 {
+			return uint64(f)
+		}
+	}
+	return 0
+}
+
+// increase es cuánto aumentó un contador; si bajó, Filebeat se reinició y
+// cuenta desde cero
+func increase(prev, curr uint64) uint64 {
+	if curr < prev {
+		return curr
+	}
+	return curr - prev
+}
+
+// add registra una muestra
+func (s *Session) add(stats *client.FilebeatStats) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sum := &s.summary
+	curr := countersOf(stats)
+	if sum.Samples == 0 {
+		sum.First = stats.Timestamp
+	}
+	sum.Last = stats.Timestamp
+	sum.Samples++
+	if sum.Down {
+		sum.Downtime += stats.Timestamp.Sub(s.failedAt)
+		sum.Down = false
+	}
+
+	if s.sampled {
+		prev := s.prev
+		if curr.uptime < prev.uptime {
+			sum.Restarts++
+		}
+		sum.Acked += increase(prev.acked, curr.acked)
+		sum.Failed += increase(prev.failed, curr.failed)
+		sum.Dropped += increase(prev.dropped, curr.dropped)
+		sum.Bytes += increase(prev.bytes, curr.bytes)
+		if rate := Rate(prev.total, curr.total, curr.at.Sub(prev.at)); rate > sum.PeakRate {
+			sum.PeakRate, sum.PeakRateAt = rate, stats.Timestamp
+		}
+	}
+	s.prev, s.sampled = curr, true
+
+	queue := stats.Libbeat.Pipeline.Queue
+	if queue.Filled.Events > sum.PeakQueue || sum.PeakQueueAt.IsZero() {
+		sum.PeakQueue, sum.PeakQueueAt = queue.Filled.Events, stats.Timestamp
+	}
+	sum.QueueMax = max(sum.QueueMax, queue.MaxEvents)
+}
+
+// fail registra una consulta fallida en at. Solo cuenta como desconexión
+// si la anterior había funcionado.
+func (s *Session) fail(at time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.sampled || s.summary.Down {
+		return
+	}
+	s.summary.Disconnects++
+	s.summary.Down = true
+	s.failedAt = at
+}
+
+func (s *Session) reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.summary, s.prev, s.sampled, s.failedAt = SessionSummary{}, sessionCounters{}, false, time.Time{}
+}
+
+// Summary devuelve los totales hasta now
+func (s *Session) Summary(now time.Time) SessionSummary {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sum := s.summary
+	if sum.Down {
+		sum.Downtime += now.Sub(s.failedAt)
+	}
+	return sum
+}
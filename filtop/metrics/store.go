@@ -0,0 +1,96 @@
+package metrics
+
+import (
+	"sync"
+	"time"
+
+	"github.com/iTiagoCO/filtop/filtop/client"
+)
+
+// Sample es una muestra de /stats junto con lo que se sabía del beat al
+// obtenerla.
+type Sample struct {
+	Stats  *client.FilebeatStats
+	Info   *client.BeatInfo
+	Schema string
+	State  *client.BeatState
+}
+
+// Store es el estado que comparten los colectores y quienes muestran los
+// datos (interfaz y API): el historial, la última muestra y los totales de
+// la sesión. Es seguro
+// usarlo desde varias goroutines; las muestras no se modifican después de
+// agregarlas.
+type Store struct {
+	mu        sync.RWMutex
+	history   *History
+	latest    Sample
+	session   Session
+	lifecycle Lifecycle
+}
+
+// NewStore crea un Store que guarda las muestras en history
+func NewStore(history *History) *Store {
+	return &Store{history: history}
+}
+
+// Add registra una muestra como la más reciente y la agrega al historial
+func (s *Store) Add(sample Sample) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.history.Add(sample.Stats)
+	s.session.add(sample.Stats)
+	s.lifecycle.add(sample.Stats)
+	s.latest = sample
+}
+
+// Failed registra que la consulta al beat falló en at
+func (s *Store) Failed(at time.Time) {
+	s.session.fail(at)
+}
+
+// Reset descarta la última muestra, el historial y la sesión
+func (s *Store) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.history.Reset()
+	s.session.reset()
+	s.lifecycle.reset()
+	s.latest = Sample{}
+}
+
+// Latest devuelve la última muestra; Stats es nil si todavía no hay ninguna
+func (s *Store) Latest() Sample {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.latest
+}
+
+// History devuelve el historial de muestras
+func (s *Store) History() *History { return s.history }
+
+// Session devuelve los totales desde la primera muestra hasta now
+func (s *Store) Session(now time.Time) SessionSummary { return s.session.Summary(now) }
+
+// InputChanges devuelve cuándo aparecieron, desaparecieron o cambiaron de
+// estado los inputs, del cambio más antiguo al más reciente
+func (s *Store) InputChanges() []InputChange { return s.lifecycle.Changes() }
+
+// EventSize es el tamaño promedio de un evento leído por los inputs en la
+// última muestra, para estimar cuántos eventos son unos bytes de un
+// archivo. ok es false si los inputs todavía no leyeron nada.
+func (s *Store) EventSize() (size float64, ok bool) {
+	latest := s.Latest()
+	if latest.Stats == nil {
+		return 0, false
+	}
+	var bytes, events uint64
+	for _, input := range latest.Stats.Filebeat.Inputs {
+		bytes += input.Bytes
+		events += input.Events
+	}
+	if events == 0 || bytes == 0 {
+		return 0, false
+	}
+	return float64(bytes) / float64(events), true
+}
@@ -0,0 +1,157 @@
+package mirror
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/gorilla/websocket"
+)
+
+// Espera antes de volver a conectarse si se corta la conexión
+const retryDelay = 2 * time.Second
+
+// ErrUnauthorized indica que el servidor rechazó el token
+var ErrUnauthorized = errors.New("el servidor rechazó el token (-share-token)")
+
+// Attach muestra la pantalla compartida en addr (host:puerto) hasta que se
+// sale con q, Esc o Ctrl-C o se cancela ctx. Si la conexión se corta se
+// vuelve a conectar; el teclado no llega al filtop que comparte.
+func Attach(ctx context.Context, addr, token string) error {
+	screen, err := tcell.NewScreen()
+	if err != nil {
+		return err
+	}
+	if err := screen.Init(); err != nil {
+		return err
+	}
+	defer screen.Fini()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	resized := make(chan struct{}, 1)
+	go func() {
+		for {
+			switch event := screen.PollEvent().(type) {
+			case nil:
+				return
+			case *tcell.EventKey:
+				if event.Key() == tcell.KeyEscape || event.Key() == tcell.KeyCtrlC || event.Rune() == 'q' {
+					cancel()
+				}
+			case *tcell.EventResize:
+				select {
+				case resized <- struct{}{}:
+				default:
+				}
+			}
+		}
+	}()
+
+	frames := make(chan Frame)
+	statuses := make(chan string)
+	failed := make(chan error, 1)
+	go follow(ctx, addr, token, frames, statuses, failed)
+
+	var last Frame
+	status := "conectando a " + addr + "..."
+	for {
+		screen.Clear()
+		last.Draw(screen)
+		drawStatus(screen, last, addr, status)
+		screen.Show()
+		select {
+		case <-ctx.Done():
+			return nil
+		case err := <-failed:
+			return err
+		case last = <-frames:
+			status = ""
+		case status = <-statuses:
+		case <-resized:
+			screen.Sync()
+		}
+	}
+}
+
+// follow recibe las pantallas de addr, reconectándose hasta que se cancela
+// ctx; los errores que no se resuelven reintentando van a failed
+func follow(ctx context.Context, addr, token string, frames chan<- Frame, statuses chan<- string, failed chan<- error) {
+	target := url.URL{Scheme: "ws", Host: addr, Path: Path}
+	header := http.Header{}
+	if token != "" {
+		header.Set("Authorization", "Bearer "+token)
+	}
+	dialer := websocket.Dialer{HandshakeTimeout: 10 * time.Second, EnableCompression: true}
+	for {
+		conn, resp, err := dialer.DialContext(ctx, target.String(), header)
+		switch {
+		case ctx.Err() != nil:
+			return
+		case resp != nil && resp.StatusCode == http.StatusUnauthorized:
+			failed <- ErrUnauthorized
+			return
+		case err == nil:
+			err = receive(ctx, conn, frames)
+			if ctx.Err() != nil {
+				return
+			}
+		}
+		select {
+		case statuses <- fmt.Sprintf("desconectado: %s; reintentando...", err):
+		case <-ctx.Done():
+			return
+		}
+		timer := time.NewTimer(retryDelay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+// receive lee pantallas de conn hasta que se corta o se cancela ctx
+func receive(ctx context.Context, conn *websocket.Conn, frames chan<- Frame) error {
+	defer conn.Close()
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+	for {
+		var frame Frame
+		if err := conn.ReadJSON(&frame); err != nil {
+			return err
+		}
+		select {
+		case frames <- frame:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// drawStatus muestra debajo de la pantalla compartida, si hay lugar, que
+// es de solo lectura; los errores de conexión se muestran siempre
+func drawStatus(screen tcell.Screen, frame Frame, addr, status string) {
+	width, height := screen.Size()
+	style := tcell.StyleDefault.Foreground(tcell.ColorGray)
+	text := "filtop attach · " + addr + " · solo lectura · q: salir"
+	switch {
+	case status != "":
+		style = tcell.StyleDefault.Foreground(tcell.ColorYellow).Reverse(true)
+		text = status
+	case frame.Height >= height:
+		return
+	}
+	y := min(frame.Height, height-1)
+	for x, r := range []rune(text) {
+		if x >= width {
+			break
+		}
+		screen.SetContent(x, y, r, nil, style)
+	}
+}
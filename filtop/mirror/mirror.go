@@ -0,0 +1,84 @@
+// Package mirror comparte la pantalla de filtop para que otro filtop la siga
+// en modo solo lectura con filtop attach, p. ej. para acompañar una
+// investigación sin acceso por SSH a la máquina monitoreada. El servidor
+// envía por WebSocket, como JSON, cada pantalla dibujada que cambió.
+package mirror
+
+import (
+	"github.com/gdamore/tcell/v2"
+	"github.com/mattn/go-runewidth"
+)
+
+// Path es la ruta del WebSocket
+const Path = "/mirror"
+
+// Frame es una pantalla dibujada, línea por línea
+type Frame struct {
+	Width  int     `json:"width"`
+	Height int     `json:"height"`
+	Lines  [][]Run `json:"lines"`
+}
+
+// Run es un tramo de una línea con el mismo estilo; los colores y los
+// atributos son los valores de tcell
+type Run struct {
+	Text  string `json:"t"`
+	Fg    uint64 `json:"f,omitempty"`
+	Bg    uint64 `json:"b,omitempty"`
+	Attrs int    `json:"a,omitempty"`
+}
+
+func (r Run) style() tcell.Style {
+	return tcell.StyleDefault.
+		Foreground(tcell.Color(r.Fg)).
+		Background(tcell.Color(r.Bg)).
+		Attributes(tcell.AttrMask(r.Attrs))
+}
+
+// Capture copia lo que muestra screen
+func Capture(screen tcell.Screen) Frame {
+	width, height := screen.Size()
+	frame := Frame{Width: width, Height: height, Lines: make([][]Run, height)}
+	for y := 0; y < height; y++ {
+		var line []Run
+		for x := 0; x < width; {
+			mainc, _, style, cells := screen.GetContent(x, y)
+			if mainc == 0 {
+				mainc = ' '
+			}
+			fg, bg, attrs := style.Decompose()
+			run := Run{Text: string(mainc), Fg: uint64(fg), Bg: uint64(bg), Attrs: int(attrs)}
+			if n := len(line); n > 0 && line[n-1].Fg == run.Fg && line[n-1].Bg == run.Bg && line[n-1].Attrs == run.Attrs {
+				line[n-1].Text += run.Text
+			} else {
+				line = append(line, run)
+			}
+			// Un carácter ancho ocupa también la celda siguiente
+			x += max(cells, 1)
+		}
+		frame.Lines[y] = line
+	}
+	return frame
+}
+
+// Draw dibuja la pantalla desde la esquina superior izquierda de screen; lo
+// que no entra se recorta
+func (f Frame) Draw(screen tcell.Screen) {
+	width, height := screen.Size()
+	for y, line := range f.Lines {
+		if y >= height {
+			return
+		}
+		x := 0
+		for _, run := range line {
+			style := run.style()
+			for _, r := range run.Text {
+				if x >= width {
+					break
+				}
+				screen.SetContent(x, y, r, nil, style)
+				x += max(runewidth.RuneWidth(r), 1)
+			}
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package mirror
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Server envía la pantalla a quienes la siguen con filtop attach. Publish
+// se llama desde la goroutine que dibuja y no se bloquea: un cliente lento
+// recibe solo la pantalla más reciente.
+type Server struct {
+	token string
+
+	mu sync.Mutex
+	// last es la última pantalla, para quien se conecta; sent es la última
+	// enviada, para no repetirla
+	last    Frame
+	sent    []byte
+	clients map[chan []byte]struct{}
+}
+
+// NewServer crea un Server; con token, los clientes deben enviarlo como
+// Authorization: Bearer
+func NewServer(token string) *Server {
+	return &Server{token: token, clients: make(map[chan []byte]struct{})}
+}
+
+// Publish envía frame a los clientes si cambió
+func (s *Server) Publish(frame Frame) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.last = frame
+	if len(s.clients) == 0 {
+		s.sent = nil
+		return
+	}
+	msg, err := json.Marshal(frame)
+	if err != nil {
+		slog.Error("Error codificando la pantalla", "err", err)
+		return
+	}
+	if string(msg) == string(s.sent) {
+		return
+	}
+	s.sent = msg
+	for ch := range s.clients {
+		// Se reemplaza la pantalla que el cliente todavía no leyó
+		select {
+		case <-ch:
+		default:
+		}
+		ch <- msg
+	}
+}
+
+// Clients es la cantidad de clientes conectados
+func (s *Server) Clients() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.clients)
+}
+
+func (s *Server) subscribe() (chan []byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ch := make(chan []byte, 1)
+	if s.last.Lines != nil {
+		msg, err := json.Marshal(s.last)
+		if err != nil {
+			return nil, err
+		}
+		ch <- msg
+		s.sent = msg
+	}
+	s.clients[ch] = struct{}{}
+	return ch, nil
+}
+
+func (s *Server) unsubscribe(ch chan []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.clients, ch)
+}
+
+var upgrader = websocket.Upgrader{EnableCompression: true}
+
+// ServeHTTP envía la pantalla actual y después cada cambio. Solo se leen
+// los mensajes de control para detectar el cierre de la conexión.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if s.token != "" && subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), []byte("Bearer "+s.token)) != 1 {
+		http.Error(w, "token inválido", http.StatusUnauthorized)
+		return
+	}
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	ch, err := s.subscribe()
+	if err != nil {
+		slog.Error("Error codificando la pantalla", "err", err)
+		return
+	}
+	defer s.unsubscribe(ch)
+	slog.Info("Pantalla compartida con un cliente", "remote", r.RemoteAddr)
+	defer slog.Info("Cliente de la pantalla compartida desconectado", "remote", r.RemoteAddr)
+
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.NextReader(); err != nil {
+				return
+			}
+		}
+	}()
+
+	for {
+		select {
+		case msg := <-ch:
+			conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+			if err := conn.WriteMessage(websocket.TextMessage, msg); err != nil {
+				return
+			}
+		case <-closed:
+			return
+		}
+	}
+}
@@ -0,0 +1,298 @@
+// Package notify envía las alertas a Slack o por correo, filtradas por
+// severidad, agrupando las que llegan demasiado seguidas y, si se pide, con
+// un resumen diario del historial.
+package notify
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/iTiagoCO/filtop/filtop/alerts"
+)
+
+// Tiempo máximo de cada envío
+const sendTimeout = 30 * time.Second
+
+// Cada cuánto se revisan los mensajes demorados y los resúmenes pendientes
+const checkInterval = 10 * time.Second
+
+// Message es lo que se envía. Mention es verdadero si anuncia alguna alerta
+// nueva, para que Slack avise a quien corresponda.
+type Message struct {
+	Subject string
+	Text    string
+	Mention bool
+	// Events son las transiciones que anuncia; vacío en el resumen diario
+	Events []TargetEvent
+}
+
+// TargetEvent es una transición de las alertas de Target, vacío si se
+// monitorea un solo beat
+type TargetEvent struct {
+	Target string
+	alerts.Event
+}
+
+// Notifier es un destino de los mensajes
+type Notifier interface {
+	Name() string
+	Send(ctx context.Context, msg Message) error
+}
+
+// Channel es un destino con sus opciones
+type Channel struct {
+	Notifier Notifier
+	// Severities son las que se envían; vacío envía todas
+	Severities []string
+	// RateLimit es el tiempo mínimo entre dos mensajes; las alertas que
+	// llegan antes se agrupan en el siguiente
+	RateLimit time.Duration
+	// SummaryAt es la hora del día (desde las 0) del resumen de las últimas
+	// 24 horas; negativa si no se envía
+	SummaryAt time.Duration
+}
+
+func (c *Channel) accepts(severity string) bool {
+	if len(c.Severities) == 0 {
+		return true
+	}
+	for _, s := range c.Severities {
+		if s == severity {
+			return true
+		}
+	}
+	return false
+}
+
+// entry es una transición que espera ser enviada
+type entry struct {
+	target string
+	event  alerts.Event
+}
+
+type channelState struct {
+	Channel
+	pending     []entry
+	lastSent    time.Time
+	lastSummary time.Time
+}
+
+// Dispatcher reparte las transiciones de las alertas entre los canales. Los
+// envíos se hacen desde Run, así Notify no demora al colector.
+type Dispatcher struct {
+	mu       sync.Mutex
+	channels []*channelState
+	history  *alerts.Log
+	wake     chan struct{}
+}
+
+// NewDispatcher crea un Dispatcher sin canales. history alimenta los
+// resúmenes diarios.
+func NewDispatcher(history *alerts.Log) *Dispatcher {
+	return &Dispatcher{history: history, wake: make(chan struct{}, 1)}
+}
+
+// SetChannels reemplaza los canales, p. ej. al recargar la configuración.
+// Las alertas que esperaban en los anteriores se descartan.
+func (d *Dispatcher) SetChannels(channels []Channel, now time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.channels = make([]*channelState, len(channels))
+	for i, channel := range channels {
+		// El primer resumen es el de la próxima hora indicada
+		d.channels[i] = &channelState{Channel: channel, lastSummary: now}
+	}
+}
+
+// Notify encola las transiciones de las alertas de target
+func (d *Dispatcher) Notify(target string, events []alerts.Event) {
+	if len(events) == 0 {
+		return
+	}
+	d.mu.Lock()
+	for _, channel := range d.channels {
+		for _, event := range events {
+			// Las silenciadas quedan solo en el historial
+			if !event.Alert.Silenced && channel.accepts(event.Alert.Severity) {
+				channel.pending = append(channel.pending, entry{target: target, event: event})
+			}
+		}
+	}
+	d.mu.Unlock()
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run envía los mensajes hasta que se cancela ctx; entonces intenta enviar
+// los que quedaron demorados.
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(checkInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			flushCtx, cancel := context.WithTimeout(context.Background(), sendTimeout)
+			d.send(flushCtx, d.due(time.Now(), true))
+			cancel()
+			return
+		case <-ticker.C:
+		case <-d.wake:
+		}
+		d.send(ctx, d.due(time.Now(), false))
+	}
+}
+
+type delivery struct {
+	notifier Notifier
+	msg      Message
+}
+
+// due arma los mensajes que corresponde enviar en now. Con flush se ignora
+// el límite de frecuencia.
+func (d *Dispatcher) due(now time.Time, flush bool) []delivery {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	var deliveries []delivery
+	for _, channel := range d.channels {
+		if len(channel.pending) > 0 && (flush || now.Sub(channel.lastSent) >= channel.RateLimit) {
+			deliveries = append(deliveries, delivery{channel.Notifier, alertsMessage(channel.pending)})
+			channel.pending = nil
+			channel.lastSent = now
+		}
+		if channel.SummaryAt >= 0 && d.history != nil {
+			if at := summaryTime(now, channel.SummaryAt); channel.lastSummary.Before(at) && !now.Before(at) {
+				deliveries = append(deliveries, delivery{channel.Notifier, summaryMessage(d.history.Records(), channel, now)})
+				channel.lastSummary = now
+			}
+		}
+	}
+	return deliveries
+}
+
+func (d *Dispatcher) send(ctx context.Context, deliveries []delivery) {
+	for _, delivery := range deliveries {
+		sendCtx, cancel := context.WithTimeout(ctx, sendTimeout)
+		err := delivery.notifier.Send(sendCtx, delivery.msg)
+		cancel()
+		if err != nil {
+			slog.Warn("Error enviando una notificación", "notifier", delivery.notifier.Name(), "err", err)
+		}
+	}
+}
+
+// summaryTime es la hora at del día de now
+func summaryTime(now time.Time, at time.Duration) time.Time {
+	year, month, day := now.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, now.Location()).Add(at)
+}
+
+// alertsMessage describe una o varias transiciones
+func alertsMessage(entries []entry) Message {
+	var msg Message
+	lines := make([]string, len(entries))
+	for i, e := range entries {
+		lines[i] = eventLine(e)
+		msg.Events = append(msg.Events, TargetEvent{Target: e.target, Event: e.event})
+		if e.event.Raised {
+			msg.Mention = true
+		}
+	}
+	if len(entries) == 1 {
+		msg.Subject = "filtop: " + lines[0]
+	} else {
+		msg.Subject = fmt.Sprintf("filtop: %d cambios en las alertas", len(entries))
+	}
+	msg.Text = strings.Join(lines, "\n")
+	return msg
+}
+
+func eventLine(e entry) string {
+	alert := e.event.Alert
+	var line string
+	if e.event.Raised {
+		line = fmt.Sprintf("[%s] %s activada: %s", alert.Severity, alert.Rule, formatValue(alert.Value))
+	} else {
+		line = fmt.Sprintf("[%s] %s resuelta tras %s", alert.Severity, alert.Rule, e.event.At.Sub(alert.Since).Round(time.Second))
+	}
+	if e.target != "" {
+		line += " (" + e.target + ")"
+	}
+	return line
+}
+
+func formatValue(v float64) string {
+	if math.IsNaN(v) {
+		return "sin valor"
+	}
+	return strconv.FormatFloat(v, 'g', 4, 64)
+}
+
+// ruleSummary resume las veces que se activó una regla en el día
+type ruleSummary struct {
+	name     string
+	severity string
+	raised   int
+	max      float64
+}
+
+// summaryMessage resume las alertas del canal activadas en las 24 horas
+// anteriores a now
+func summaryMessage(records []alerts.Record, channel *channelState, now time.Time) Message {
+	rules := make(map[string]*ruleSummary)
+	for _, record := range records {
+		if !record.Raised || record.Silenced || now.Sub(record.At) > 24*time.Hour || !channel.accepts(record.Severity) {
+			continue
+		}
+		name := record.Rule
+		if record.Target != "" {
+			name += " (" + record.Target + ")"
+		}
+		summary, ok := rules[name]
+		if !ok {
+			summary = &ruleSummary{name: name, severity: record.Severity, max: math.NaN()}
+			rules[name] = summary
+		}
+		summary.raised++
+		if record.Value != nil && (math.IsNaN(summary.max) || *record.Value > summary.max) {
+			summary.max = *record.Value
+		}
+	}
+
+	msg := Message{Subject: "filtop: resumen diario de alertas"}
+	if len(rules) == 0 {
+		msg.Text = "Sin alertas en las últimas 24 horas."
+		return msg
+	}
+	sorted := make([]*ruleSummary, 0, len(rules))
+	total := 0
+	for _, summary := range rules {
+		sorted = append(sorted, summary)
+		total += summary.raised
+	}
+	// Las que más se activaron primero
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].raised != sorted[j].raised {
+			return sorted[i].raised > sorted[j].raised
+		}
+		return sorted[i].name < sorted[j].name
+	})
+	lines := []string{fmt.Sprintf("%d alertas activadas en las últimas 24 horas:", total)}
+	for _, summary := range sorted {
+		times := "veces"
+		if summary.raised == 1 {
+			times = "vez"
+		}
+		lines = append(lines, fmt.Sprintf("[%s] %s: %d %s, máximo %s", summary.severity, summary.name, summary.raised, times, formatValue(summary.max)))
+	}
+	msg.Text = strings.Join(lines, "\n")
+	return msg
+}
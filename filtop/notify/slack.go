@@ -0,0 +1,53 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// Slack envía los mensajes a un incoming webhook
+type Slack struct {
+	WebhookURL string
+	// Channel reemplaza el canal del webhook, p. ej. #ops
+	Channel string
+	// Mention se antepone a los mensajes que anuncian alertas nuevas, p. ej.
+	// <!here> o <@U024BE7LH>
+	Mention string
+	HTTP    *http.Client
+}
+
+func (s *Slack) Name() string { return "slack" }
+
+func (s *Slack) Send(ctx context.Context, msg Message) error {
+	text := msg.Text
+	if msg.Mention && s.Mention != "" {
+		text = s.Mention + " " + text
+	}
+	payload := map[string]string{"text": text}
+	if s.Channel != "" {
+		payload["channel"] = s.Channel
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.WebhookURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := s.HTTP.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 256))
+		return fmt.Errorf("slack respondió %s: %s", resp.Status, bytes.TrimSpace(detail))
+	}
+	return nil
+}
@@ -0,0 +1,165 @@
+// Package offline reproduce respuestas de /stats capturadas (un archivo, un
+// directorio o la entrada estándar) como si vinieran de un Filebeat real.
+package offline
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Transport responde las consultas del cliente con los documentos
+// capturados. Cada consulta a /stats avanza al siguiente; al llegar al
+// último se queda en él.
+type Transport struct {
+	mu     sync.Mutex
+	stats  []json.RawMessage
+	inputs []json.RawMessage
+	next   int
+}
+
+// Read lee uno o varios documentos JSON seguidos (por ejemplo, varias
+// capturas de /stats concatenadas)
+func Read(r io.Reader) (*Transport, error) {
+	docs, err := decodeAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(docs) == 0 {
+		return nil, errors.New("no se encontró ningún documento JSON")
+	}
+	return &Transport{stats: docs}, nil
+}
+
+// Load lee un archivo o todos los *.json de un directorio en orden
+// alfabético. Los archivos cuyo nombre contiene "inputs" se toman como
+// respuestas de /inputs/ y se emparejan en orden con las de /stats.
+func Load(path string) (*Transport, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		return Read(f)
+	}
+
+	files, err := filepath.Glob(filepath.Join(path, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+
+	t := &Transport{}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		docs, err := decodeAll(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+		if strings.Contains(filepath.Base(file), "inputs") {
+			t.inputs = append(t.inputs, docs...)
+		} else {
+			t.stats = append(t.stats, docs...)
+		}
+	}
+	if len(t.stats) == 0 {
+		return nil, fmt.Errorf("%s: no hay capturas de /stats", path)
+	}
+	return t, nil
+}
+
+func decodeAll(r io.Reader) ([]json.RawMessage, error) {
+	var docs []json.RawMessage
+	dec := json.NewDecoder(r)
+	for {
+		var doc json.RawMessage
+		err := dec.Decode(&doc)
+		if err == io.EOF {
+			return docs, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		docs = append(docs, doc)
+	}
+}
+
+// Len devuelve la cantidad de capturas de /stats
+func (t *Transport) Len() int { return len(t.stats) }
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	// La consulta a /stats avanza; /inputs/ usa la misma posición
+	current := t.next - 1
+	if current < 0 {
+		current = 0
+	}
+
+	var body []byte
+	switch req.URL.Path {
+	case "/":
+		body = t.info(t.stats[current])
+	case "/stats":
+		current = t.next
+		if t.next < len(t.stats)-1 {
+			t.next++
+		}
+		body = t.stats[current]
+	case "/inputs", "/inputs/":
+		if current < len(t.inputs) {
+			body = t.inputs[current]
+		} else {
+			// Sin capturas de /inputs/ el panel queda vacío
+			body = []byte("[]")
+		}
+	}
+
+	status := http.StatusOK
+	if body == nil {
+		status = http.StatusNotFound
+		body = []byte("404 page not found")
+	}
+	return &http.Response{
+		StatusCode: status,
+		Status:     http.StatusText(status),
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(bytes.NewReader(body)),
+		Request:    req,
+	}, nil
+}
+
+// info arma la respuesta del endpoint raíz con la versión de la captura
+func (t *Transport) info(stats json.RawMessage) []byte {
+	var doc struct {
+		Beat struct {
+			Info struct {
+				Version string `json:"version"`
+			} `json:"info"`
+		} `json:"beat"`
+	}
+	json.Unmarshal(stats, &doc)
+	body, _ := json.Marshal(map[string]string{
+		"beat":    "filebeat",
+		"name":    "offline",
+		"version": doc.Beat.Info.Version,
+	})
+	return body
+}
@@ -0,0 +1,7 @@
+//go:build !windows
+
+package main
+
+// systemConfigPath es la configuración compartida del equipo; fuera de
+// Windows solo se usa la del usuario
+func systemConfigPath() string { return "" }
@@ -0,0 +1,19 @@
+//go:build windows
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// systemConfigPath es la configuración compartida del equipo, en
+// %ProgramData%\filtop. Un servicio corre con la cuenta del sistema y no ve
+// el %AppData% de quien lo instaló.
+func systemConfigPath() string {
+	dir := os.Getenv("ProgramData")
+	if dir == "" {
+		dir = `C:\ProgramData`
+	}
+	return filepath.Join(dir, "filtop", "config.yaml")
+}
@@ -0,0 +1,86 @@
+package plugins
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/iTiagoCO/filtop/filtop/notify"
+)
+
+// Largo máximo de stderr que se usa como error de un envío
+const maxErrorOutput = 512
+
+// Notification es la línea que recibe un notifier
+type Notification struct {
+	Subject string `json:"subject"`
+	Text    string `json:"text"`
+	// Mention es verdadero si anuncia alguna alerta nueva
+	Mention bool `json:"mention"`
+	// Events son las transiciones que anuncia; vacío en el resumen diario
+	Events []Event `json:"events"`
+}
+
+// Event es una transición de una alerta de Target, vacío si se monitorea
+// un solo beat
+type Event struct {
+	Alert
+	Target string    `json:"target,omitempty"`
+	Raised bool      `json:"raised"`
+	At     time.Time `json:"at"`
+}
+
+// Notifier envía los mensajes de las alertas a un plugin
+type Notifier struct {
+	Command Command
+}
+
+func (n *Notifier) Name() string { return "plugin " + n.Command.Name }
+
+func (n *Notifier) Send(ctx context.Context, msg notify.Message) error {
+	notification := Notification{Subject: msg.Subject, Text: msg.Text, Mention: msg.Mention, Events: []Event{}}
+	for _, event := range msg.Events {
+		notification.Events = append(notification.Events, Event{
+			Alert:  newAlert(event.Alert),
+			Target: event.Target,
+			Raised: event.Raised,
+			At:     event.At,
+		})
+	}
+	line, err := json.Marshal(notification)
+	if err != nil {
+		return err
+	}
+
+	cmd := n.Command.command()
+	cmd.Stdin = bytes.NewReader(append(line, '\n'))
+	cmd.Stdout = &logWriter{log: n.Command.logger(), stream: "stdout"}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	cmd.WaitDelay = stopGrace
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	exited := make(chan error, 1)
+	go func() { exited <- cmd.Wait() }()
+	select {
+	case err = <-exited:
+	case <-ctx.Done():
+		cmd.Process.Kill()
+		<-exited
+		return ctx.Err()
+	}
+	if err == nil {
+		return nil
+	}
+	detail := bytes.TrimSpace(stderr.Bytes())
+	if len(detail) > maxErrorOutput {
+		detail = detail[:maxErrorOutput]
+	}
+	if len(detail) == 0 {
+		return err
+	}
+	return fmt.Errorf("%w: %s", err, detail)
+}
@@ -0,0 +1,90 @@
+// Package plugins ejecuta programas externos que agregan destinos para las
+// muestras (sinks) y para las alertas (notifiers) sin modificar filtop.
+// Se comunican por la entrada estándar con JSON, un objeto por línea:
+//
+//   - Un sink es un proceso que corre mientras filtop y recibe una línea
+//     por cada muestra de cada beat ({"type":"sample",...}, ver Sample). Si
+//     termina se vuelve a lanzar; al apagar filtop se le escriben las
+//     muestras pendientes, se cierra su entrada y se le da un momento para
+//     terminar.
+//   - Un notifier se lanza por cada mensaje, recibe una sola línea
+//     (Notification) y debe terminar con código 0; si no, lo que escribió
+//     en stderr es el error del envío.
+//
+// Lo que los plugins escriben en stdout y stderr va al log de filtop.
+package plugins
+
+import (
+	"bytes"
+	"log/slog"
+	"os"
+	"os/exec"
+	"sort"
+)
+
+// Command es el programa de un plugin
+type Command struct {
+	// Name identifica al plugin en el log
+	Name string
+	Path string
+	Args []string
+	// Env se agrega al entorno de filtop
+	Env map[string]string
+}
+
+func (c Command) command() *exec.Cmd {
+	cmd := exec.Command(c.Path, c.Args...)
+	if len(c.Env) > 0 {
+		cmd.Env = os.Environ()
+		keys := make([]string, 0, len(c.Env))
+		for key := range c.Env {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			cmd.Env = append(cmd.Env, key+"="+c.Env[key])
+		}
+	}
+	return cmd
+}
+
+func (c Command) logger() *slog.Logger {
+	return slog.With("plugin", c.Name)
+}
+
+// Largo máximo de una línea del plugin en el log
+const maxLogLine = 4096
+
+// logWriter pasa al log cada línea que escribe el plugin
+type logWriter struct {
+	log    *slog.Logger
+	stream string
+	buf    []byte
+}
+
+func (w *logWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		w.line(w.buf[:i])
+		w.buf = w.buf[i+1:]
+	}
+	if len(w.buf) > maxLogLine {
+		w.line(w.buf)
+		w.buf = nil
+	}
+	return len(p), nil
+}
+
+func (w *logWriter) line(line []byte) {
+	line = bytes.TrimRight(line, "\r")
+	if len(line) > maxLogLine {
+		line = line[:maxLogLine]
+	}
+	if len(line) > 0 {
+		w.log.Info("Salida del plugin", "stream", w.stream, "line", string(line))
+	}
+}
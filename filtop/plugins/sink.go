@@ -0,0 +1,218 @@
+package plugins
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sync/atomic"
+	"time"
+
+	"github.com/iTiagoCO/filtop/filtop/alerts"
+	"github.com/iTiagoCO/filtop/filtop/client"
+	"github.com/iTiagoCO/filtop/filtop/metrics"
+)
+
+// Muestras que esperan ser escritas; si el plugin no las lee a tiempo se
+// descartan las nuevas
+const sinkQueue = 256
+
+// Espera antes de volver a lanzar un sink que terminó
+const restartDelay = 5 * time.Second
+
+// Tiempo que se espera a que un sink termine tras cerrar su entrada, y
+// antes a que reciba las muestras que quedaron en la cola
+const stopGrace = 5 * time.Second
+
+// Sample es la línea que recibe un sink por cada muestra
+type Sample struct {
+	Type   string    `json:"type"`
+	Target string    `json:"target"`
+	At     time.Time `json:"at"`
+	// Stats es el documento /stats completo
+	Stats map[string]interface{} `json:"stats"`
+	// Inputs solo está si la muestra trae inputs nuevos
+	Inputs   []client.Input     `json:"inputs,omitempty"`
+	Computed map[string]float64 `json:"computed,omitempty"`
+	Alerts   []Alert            `json:"alerts"`
+}
+
+// Alert es una alerta activa o una transición; Value es nil si no se pudo
+// calcular
+type Alert struct {
+	Rule     string    `json:"rule"`
+	Severity string    `json:"severity"`
+	Value    *float64  `json:"value"`
+	Since    time.Time `json:"since"`
+	Silenced bool      `json:"silenced,omitempty"`
+}
+
+func newAlert(alert alerts.Alert) Alert {
+	a := Alert{Rule: alert.Rule, Severity: alert.Severity, Since: alert.Since, Silenced: alert.Silenced}
+	if v := alert.Value; !math.IsNaN(v) && !math.IsInf(v, 0) {
+		a.Value = &v
+	}
+	return a
+}
+
+// Sink envía las muestras a un plugin. Add no bloquea al colector: Run las
+// escribe desde otra goroutine.
+type Sink struct {
+	command Command
+	queue   chan []byte
+	dropped atomic.Uint64
+	// dropping evita repetir el aviso mientras el plugin siga saturado
+	dropping atomic.Bool
+}
+
+func NewSink(command Command) *Sink {
+	return &Sink{command: command, queue: make(chan []byte, sinkQueue)}
+}
+
+// Add encola una muestra de target
+func (s *Sink) Add(target string, stats *client.FilebeatStats, computed []metrics.ComputedValue, active []alerts.Alert) {
+	sample := Sample{Type: "sample", Target: target, At: stats.Timestamp, Stats: stats.Raw, Alerts: []Alert{}}
+	// Inputs repetidos de una consulta anterior ya se enviaron
+	if !stats.InputsAt.Before(stats.Timestamp) {
+		sample.Inputs = stats.Filebeat.Inputs
+	}
+	for _, value := range computed {
+		if value.Err == nil && !math.IsNaN(value.Value) && !math.IsInf(value.Value, 0) {
+			if sample.Computed == nil {
+				sample.Computed = make(map[string]float64)
+			}
+			sample.Computed[value.Name] = value.Value
+		}
+	}
+	for _, alert := range active {
+		sample.Alerts = append(sample.Alerts, newAlert(alert))
+	}
+	line, err := json.Marshal(sample)
+	if err != nil {
+		s.command.logger().Warn("Error codificando la muestra para el plugin", "err", err)
+		return
+	}
+	select {
+	case s.queue <- append(line, '\n'):
+		s.dropping.Store(false)
+	default:
+		s.dropped.Add(1)
+		if !s.dropping.Swap(true) {
+			s.command.logger().Warn("El plugin no lee las muestras a tiempo: se descartan")
+		}
+	}
+}
+
+// Dropped es la cantidad de muestras descartadas
+func (s *Sink) Dropped() uint64 {
+	return s.dropped.Load()
+}
+
+// Run lanza el plugin y le escribe las muestras hasta que se cancela ctx,
+// volviéndolo a lanzar cada vez que termina
+func (s *Sink) Run(ctx context.Context) {
+	log := s.command.logger()
+	for {
+		err := s.run(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		log.Warn("El plugin terminó: se vuelve a lanzar", "err", err, "in", restartDelay)
+		timer := time.NewTimer(restartDelay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+func (s *Sink) run(ctx context.Context) error {
+	log := s.command.logger()
+	cmd := s.command.command()
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	cmd.Stdout = &logWriter{log: log, stream: "stdout"}
+	cmd.Stderr = &logWriter{log: log, stream: "stderr"}
+	// Si el plugin deja procesos hijos con su salida abierta, Wait no
+	// espera por ellos más que esto
+	cmd.WaitDelay = stopGrace
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	log.Info("Plugin lanzado", "pid", cmd.Process.Pid)
+	exited := make(chan error, 1)
+	go func() { exited <- cmd.Wait() }()
+
+	// Las escrituras pueden bloquearse si el plugin no lee: van en otra
+	// goroutine para no demorar el apagado
+	done := make(chan struct{})
+	defer close(done)
+	// Al apagar, drain pide escribir lo que quedó en la cola y drained
+	// avisa que terminó
+	drain := make(chan struct{})
+	drained := make(chan struct{})
+	writeErr := make(chan error, 1)
+	go func() {
+		defer close(drained)
+		for {
+			select {
+			case <-done:
+				return
+			case <-drain:
+				for {
+					select {
+					case line := <-s.queue:
+						if _, err := stdin.Write(line); err != nil {
+							return
+						}
+					default:
+						return
+					}
+				}
+			case line := <-s.queue:
+				if _, err := stdin.Write(line); err != nil {
+					writeErr <- err
+					return
+				}
+			}
+		}
+	}()
+
+	select {
+	case <-ctx.Done():
+		close(drain)
+		drainTimer := time.NewTimer(stopGrace)
+		select {
+		case <-drained:
+			drainTimer.Stop()
+		case <-drainTimer.C:
+			log.Warn("El plugin no recibió a tiempo las muestras pendientes: se descartan")
+		}
+		// Sin entrada el plugin debería terminar solo
+		stdin.Close()
+		timer := time.NewTimer(stopGrace)
+		defer timer.Stop()
+		select {
+		case <-exited:
+		case <-timer.C:
+			log.Warn("El plugin no terminó a tiempo: se mata")
+			cmd.Process.Kill()
+			<-exited
+		}
+		return nil
+	case err := <-exited:
+		if err == nil {
+			err = errors.New("salió con código 0")
+		}
+		return err
+	case err := <-writeErr:
+		cmd.Process.Kill()
+		<-exited
+		return fmt.Errorf("escribiendo: %w", err)
+	}
+}
@@ -0,0 +1,53 @@
+package plugins
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/iTiagoCO/filtop/filtop/client"
+)
+
+func TestSinkDrainsOnShutdown(t *testing.T) {
+	sh, err := exec.LookPath("sh")
+	if err != nil {
+		t.Skip("sin sh")
+	}
+	out := filepath.Join(t.TempDir(), "samples.jsonl")
+	sink := NewSink(Command{Name: "test", Path: sh, Args: []string{"-c", `cat > "$OUT"`}, Env: map[string]string{"OUT": out}})
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		sink.Run(ctx)
+		close(stopped)
+	}()
+	// El plugin ya corre cuando crea el archivo
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(10 * time.Millisecond) {
+		if _, err := os.Stat(out); err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("el plugin no arrancó")
+		}
+	}
+
+	// Las muestras encoladas justo antes de apagar llegan igual
+	const n = 100
+	for i := 0; i < n; i++ {
+		sink.Add("demo", &client.FilebeatStats{Timestamp: time.Now()}, nil, nil)
+	}
+	cancel()
+	<-stopped
+
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lines := bytes.Count(data, []byte("\n")); lines != n || sink.Dropped() != 0 {
+		t.Errorf("el plugin recibió %d de %d muestras (%d descartadas)", lines, n, sink.Dropped())
+	}
+}
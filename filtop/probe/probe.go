@@ -0,0 +1,139 @@
+// Package probe mide la latencia de ingesta de punta a punta: escribe una
+// línea de marca en un log de prueba que Filebeat cosecha y mide cuánto
+// tarda en reflejarse en el contador de eventos del input que lo lee y en
+// poder buscarse en Elasticsearch.
+package probe
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"time"
+)
+
+// Prefix es el comienzo de la marca de cada línea, p. ej.
+// filtop-probe-3f9a0c1d2e4b5a67
+const Prefix = "filtop-probe"
+
+// Marcas que se conservan
+const maxResults = 20
+
+// Result es una marca y lo que tardó en cada etapa
+type Result struct {
+	ID   string    `json:"id"`
+	Sent time.Time `json:"sent"`
+	// Filebeat es lo que tardó el contador de eventos del input en reflejar
+	// la marca y Elasticsearch lo que tardó en poder buscarse; 0 si no
+	// llegó o no se mide
+	Filebeat      time.Duration `json:"filebeat,omitempty"`
+	Elasticsearch time.Duration `json:"elasticsearch,omitempty"`
+	// Done indica que la marca terminó: llegó a todas las etapas o venció
+	// el timeout (TimedOut)
+	Done     bool `json:"done"`
+	TimedOut bool `json:"timed_out,omitempty"`
+}
+
+// Marker es el texto que identifica la línea de la marca
+func (r Result) Marker() string {
+	return Prefix + "-" + r.ID
+}
+
+// Prober escribe una marca a la vez y registra lo que tarda. No es seguro
+// usarlo desde varias goroutines.
+type Prober struct {
+	path string
+	// Etapas que se miden
+	filebeat, elasticsearch bool
+	timeout                 time.Duration
+	// baseline son los eventos del input antes de escribir la marca en curso
+	baseline uint64
+	results  []Result
+}
+
+// New crea un Prober que escribe en path. filebeat y elasticsearch son las
+// etapas que se miden; una marca que no llega a ellas en timeout vence.
+func New(path string, filebeat, elasticsearch bool, timeout time.Duration) *Prober {
+	return &Prober{path: path, filebeat: filebeat, elasticsearch: elasticsearch, timeout: timeout}
+}
+
+// Pending devuelve la marca en curso; ok es false si no hay ninguna
+func (p *Prober) Pending() (result Result, ok bool) {
+	if current := p.pending(); current != nil {
+		return *current, true
+	}
+	return Result{}, false
+}
+
+// Send agrega una marca al log de prueba. events es el contador de eventos
+// del input antes de escribirla.
+func (p *Prober) Send(now time.Time, events uint64) (Result, error) {
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return Result{}, err
+	}
+	result := Result{ID: hex.EncodeToString(id), Sent: now}
+	file, err := os.OpenFile(p.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return Result{}, err
+	}
+	_, err = fmt.Fprintf(file, "%s %s marca de latencia de filtop\n", now.UTC().Format(time.RFC3339Nano), result.Marker())
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return Result{}, err
+	}
+	p.baseline = events
+	p.results = append(p.results, result)
+	if len(p.results) > maxResults {
+		p.results = p.results[len(p.results)-maxResults:]
+	}
+	return result, nil
+}
+
+// ObserveInput registra el contador de eventos del input leído en at: si
+// aumentó desde que se escribió la marca, Filebeat ya la leyó
+func (p *Prober) ObserveInput(events uint64, at time.Time) {
+	current := p.pending()
+	// Si el contador bajó, Filebeat se reinició y también leyó la marca
+	if current == nil || current.Filebeat > 0 || events == p.baseline {
+		return
+	}
+	current.Filebeat = max(at.Sub(current.Sent), time.Nanosecond)
+}
+
+// ObserveIndexed registra que la marca se encontró en Elasticsearch en at
+func (p *Prober) ObserveIndexed(at time.Time) {
+	if current := p.pending(); current != nil && current.Elasticsearch == 0 {
+		current.Elasticsearch = max(at.Sub(current.Sent), time.Nanosecond)
+	}
+}
+
+// Finish termina la marca en curso si llegó a todas las etapas o venció, y
+// la devuelve
+func (p *Prober) Finish(now time.Time) (Result, bool) {
+	current := p.pending()
+	if current == nil {
+		return Result{}, false
+	}
+	arrived := (!p.filebeat || current.Filebeat > 0) && (!p.elasticsearch || current.Elasticsearch > 0)
+	if !arrived && now.Sub(current.Sent) < p.timeout {
+		return Result{}, false
+	}
+	current.Done, current.TimedOut = true, !arrived
+	return *current, true
+}
+
+// Results devuelve una copia de las últimas marcas, de la más antigua a la
+// más reciente
+func (p *Prober) Results() []Result {
+	return append([]Result(nil), p.results...)
+}
+
+func (p *Prober) pending() *Result {
+	if len(p.results) == 0 || p.results[len(p.results)-1].Done {
+		return nil
+	}
+	return &p.results[len(p.results)-1]
+}
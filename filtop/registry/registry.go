@@ -0,0 +1,205 @@
+// Package registry lee el registry de Filebeat (data/registry/filebeat),
+// donde guarda hasta qué offset leyó cada archivo, para seguir las
+// rotaciones y truncados de los archivos cosechados. Filebeat escribe
+// periódicamente un checkpoint (el JSON que indica active.dat) y entre uno y
+// otro agrega cada cambio a log.json.
+package registry
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Entry es el estado de un archivo en el registry
+type Entry struct {
+	// Key es la clave del registry, p. ej.
+	// filestream::mi-input::native::1234-64768
+	Key    string
+	Source string
+	// Identity identifica al archivo para Filebeat: inode-device con el
+	// identificador native, o el fingerprint o la ruta con los demás
+	Identity string
+	Offset   int64
+}
+
+// Dir devuelve el directorio con log.json: dir puede ser data/registry o
+// data/registry/filebeat
+func Dir(dir string) string {
+	for _, name := range []string{"log.json", "meta.json"} {
+		if exists(filepath.Join(dir, "filebeat", name)) {
+			return filepath.Join(dir, "filebeat")
+		}
+	}
+	return dir
+}
+
+// Read lee el checkpoint y le aplica las operaciones de log.json. Las
+// entradas que no son de archivos (sin source) se omiten. Un registry recién
+// creado, con meta.json pero todavía sin log.json, está vacío.
+func Read(dir string) (map[string]Entry, error) {
+	dir = Dir(dir)
+	values := make(map[string]json.RawMessage)
+	checkpoint, err := checkpointPath(dir)
+	if err != nil {
+		return nil, err
+	}
+	if checkpoint != "" {
+		if err := readCheckpoint(checkpoint, values); err != nil {
+			return nil, err
+		}
+	}
+	if err := readLog(filepath.Join(dir, "log.json"), values); err != nil {
+		if !errors.Is(err, fs.ErrNotExist) || checkpoint == "" && !exists(filepath.Join(dir, "meta.json")) {
+			return nil, err
+		}
+	}
+
+	entries := make(map[string]Entry, len(values))
+	for key, raw := range values {
+		entry, ok := parseEntry(key, raw)
+		if ok {
+			entries[key] = entry
+		}
+	}
+	return entries, nil
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// checkpointPath es el checkpoint que indica active.dat, o "" si todavía
+// no hay ninguno. active.dat tiene la ruta absoluta vista por Filebeat: si
+// no existe (p. ej. el registry montado en otra ruta) se busca en dir.
+func checkpointPath(dir string) (string, error) {
+	data, err := os.ReadFile(filepath.Join(dir, "active.dat"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	path := strings.TrimSpace(string(data))
+	if path == "" {
+		return "", nil
+	}
+	if _, err := os.Stat(path); err != nil {
+		path = filepath.Join(dir, filepath.Base(path))
+	}
+	return path, nil
+}
+
+func readCheckpoint(path string, values map[string]json.RawMessage) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var docs []map[string]json.RawMessage
+	if err := json.Unmarshal(data, &docs); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	for _, doc := range docs {
+		var key string
+		if err := json.Unmarshal(doc["_key"], &key); err != nil || key == "" {
+			continue
+		}
+		delete(doc, "_key")
+		raw, err := json.Marshal(doc)
+		if err != nil {
+			return err
+		}
+		values[key] = raw
+	}
+	return nil
+}
+
+// readLog aplica las operaciones de log.json: cada una es una línea
+// {"op":"set","id":N} seguida de otra con la clave y el valor. Una última
+// línea a medio escribir se ignora.
+func readLog(path string, values map[string]json.RawMessage) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	var op string
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		if op == "" {
+			var action struct {
+				Op string `json:"op"`
+			}
+			if json.Unmarshal(line, &action) != nil {
+				continue
+			}
+			op = action.Op
+			continue
+		}
+		var change struct {
+			K string          `json:"k"`
+			V json.RawMessage `json:"v"`
+		}
+		if json.Unmarshal(line, &change) == nil && change.K != "" {
+			switch op {
+			case "set":
+				values[change.K] = change.V
+			case "remove":
+				delete(values, change.K)
+			}
+		}
+		op = ""
+	}
+	return scanner.Err()
+}
+
+// parseEntry interpreta el valor de una clave del input log (source y
+// offset en la raíz) o de filestream (cursor.offset y meta.source)
+func parseEntry(key string, raw json.RawMessage) (Entry, bool) {
+	var value struct {
+		Source string `json:"source"`
+		Offset int64  `json:"offset"`
+		Cursor struct {
+			Offset int64 `json:"offset"`
+		} `json:"cursor"`
+		Meta struct {
+			Source string `json:"source"`
+		} `json:"meta"`
+		FileStateOS struct {
+			Inode  uint64 `json:"inode"`
+			Device uint64 `json:"device"`
+		} `json:"FileStateOS"`
+	}
+	if json.Unmarshal(raw, &value) != nil {
+		return Entry{}, false
+	}
+	entry := Entry{Key: key, Source: value.Source, Offset: value.Offset}
+	if strings.HasPrefix(key, "filestream::") {
+		entry.Source, entry.Offset = value.Meta.Source, value.Cursor.Offset
+	}
+	if entry.Source == "" {
+		return Entry{}, false
+	}
+	// La clave termina en <identificador>::<valor>, p. ej. native::1234-64768
+	if i := strings.LastIndex(key, "::"); i >= 0 {
+		entry.Identity = key[i+2:]
+	}
+	if value.FileStateOS.Inode != 0 {
+		entry.Identity = strconv.FormatUint(value.FileStateOS.Inode, 10) + "-" + strconv.FormatUint(value.FileStateOS.Device, 10)
+	}
+	return entry, true
+}
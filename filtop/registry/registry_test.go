@@ -0,0 +1,92 @@
+package registry
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestRead(t *testing.T) {
+	tests := []struct {
+		dir  string
+		want map[string]Entry
+	}{
+		// Checkpoint de un active.dat con la ruta vista por Filebeat, y un
+		// log.json que termina en una operación sin su valor
+		{"partial", map[string]Entry{
+			"filestream::nginx::native::1234-64768": {
+				Key: "filestream::nginx::native::1234-64768", Source: "/var/log/nginx/access.log",
+				Identity: "1234-64768", Offset: 2048,
+			},
+			"filestream::nginx::native::4321-64768": {
+				Key: "filestream::nginx::native::4321-64768", Source: "/var/log/nginx/error.log",
+				Identity: "4321-64768", Offset: 10,
+			},
+			"filebeat::logs::native::5678-64768": {
+				Key: "filebeat::logs::native::5678-64768", Source: "/var/log/syslog",
+				Identity: "5678-64768", Offset: 300,
+			},
+		}},
+		// La última línea quedó a medio escribir
+		{"truncated", map[string]Entry{
+			"filestream::app::native::99-1": {
+				Key: "filestream::app::native::99-1", Source: "/var/log/app.log",
+				Identity: "99-1", Offset: 20,
+			},
+		}},
+		// Recién creado: solo meta.json
+		{"empty", map[string]Entry{}},
+		// data/registry con un log.json vacío en filebeat/
+		{"nested", map[string]Entry{}},
+	}
+	for _, tt := range tests {
+		got, err := Read(filepath.Join("testdata", tt.dir))
+		if err != nil {
+			t.Errorf("%s: %v", tt.dir, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: %v\nse esperaba %v", tt.dir, got, tt.want)
+		}
+	}
+}
+
+func TestReadErrors(t *testing.T) {
+	// Un directorio que no es un registry
+	if _, err := Read(t.TempDir()); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("directorio vacío: %v, se esperaba fs.ErrNotExist", err)
+	}
+
+	// active.dat apunta a un checkpoint inválido
+	dir := t.TempDir()
+	write(t, dir, "active.dat", filepath.Join(dir, "7.json"))
+	write(t, dir, "7.json", `[{"_key":"a"`)
+	if _, err := Read(dir); err == nil {
+		t.Error("checkpoint truncado: se esperaba un error")
+	}
+}
+
+func TestReadCheckpointOnly(t *testing.T) {
+	// Justo después de un checkpoint Filebeat borra log.json
+	dir := t.TempDir()
+	write(t, dir, "active.dat", "/otra/ruta/3.json\n")
+	write(t, dir, "3.json", `[{"_key":"filestream::a::path::/var/log/a.log","cursor":{"offset":7},"meta":{"source":"/var/log/a.log"}}]`)
+	got, err := Read(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Entry{Key: "filestream::a::path::/var/log/a.log", Source: "/var/log/a.log", Identity: "/var/log/a.log", Offset: 7}
+	if len(got) != 1 || got[want.Key] != want {
+		t.Errorf("%v, se esperaba %v", got, want)
+	}
+}
+
+func write(t *testing.T, dir, name, data string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
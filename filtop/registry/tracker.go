@@ -0,0 +1,211 @@
+package registry
+
+import (
+	"os"
+	"sort"
+	"time"
+)
+
+// Tipos de cambio que detecta el Tracker
+const (
+	// Rotated: la ruta pasó a ser otro archivo (otro inode), como al rotar
+	// renombrando el archivo y creando uno nuevo
+	Rotated = "rotated"
+	// Truncated: el offset de un mismo archivo volvió atrás, como con
+	// copytruncate
+	Truncated = "truncated"
+	// Reread: el offset volvió atrás sin que el archivo se truncara, o el
+	// mismo archivo apareció con otra clave (p. ej. al cambiar el id de un
+	// input filestream): Filebeat lo vuelve a leer y duplica los eventos
+	Reread = "reread"
+)
+
+// Change es una rotación, un truncado o una relectura detectada entre dos
+// lecturas
+type Change struct {
+	Kind string
+	Path string
+	// From y To son la identidad anterior y la nueva en una rotación, o
+	// las claves en una relectura con otra clave
+	From, To string
+	// Offsets antes y después de un truncado o una relectura
+	OffsetBefore, OffsetAfter int64
+	// Size es el tamaño del archivo en una relectura, si se pudo leer
+	Size int64
+}
+
+// Duplicated son los bytes que se vuelven a leer en una relectura
+func (c Change) Duplicated() int64 {
+	return c.OffsetBefore - c.OffsetAfter
+}
+
+// File es el historial de una ruta cosechada
+type File struct {
+	Path string `json:"path"`
+	// Identity es la del archivo actual, p. ej. inode-device
+	Identity    string `json:"identity"`
+	Offset      int64  `json:"offset"`
+	Rotations   int    `json:"rotations"`
+	Truncations int    `json:"truncations"`
+	// LastRotation es la última rotación o el último truncado
+	LastRotation time.Time `json:"last_rotation,omitempty"`
+	// Rereads son las relecturas, DuplicatedBytes lo que se volvió a leer
+	// en todas y LastReread la última
+	Rereads         int       `json:"rereads"`
+	DuplicatedBytes int64     `json:"duplicated_bytes"`
+	LastReread      time.Time `json:"last_reread,omitempty"`
+	// Present es false si la ruta ya no está en el registry, p. ej. por
+	// clean_removed
+	Present bool `json:"present"`
+}
+
+// Tracker compara lecturas sucesivas del registry. La primera solo
+// registra el estado: los cambios se cuentan desde que filtop empezó. No es
+// seguro usarlo desde varias goroutines.
+type Tracker struct {
+	entries map[string]Entry
+	files   map[string]*File
+	// size es el tamaño actual de un archivo, para distinguir un truncado
+	// de una relectura
+	size func(path string) (int64, error)
+}
+
+func NewTracker() *Tracker {
+	return &Tracker{files: make(map[string]*File), size: fileSize}
+}
+
+func fileSize(path string) (int64, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0, err
+	}
+	return info.Size(), nil
+}
+
+// Update incorpora una lectura y devuelve las rotaciones, los truncados y
+// las relecturas desde la anterior
+func (t *Tracker) Update(entries map[string]Entry, now time.Time) []Change {
+	byPath := make(map[string][]Entry)
+	for _, entry := range entries {
+		byPath[entry.Source] = append(byPath[entry.Source], entry)
+	}
+	// Identidades de cada ruta en la lectura anterior
+	previous := make(map[string]map[string]bool)
+	for _, entry := range t.entries {
+		if previous[entry.Source] == nil {
+			previous[entry.Source] = make(map[string]bool)
+		}
+		previous[entry.Source][entry.Identity] = true
+	}
+
+	var changes []Change
+	for _, file := range t.files {
+		file.Present = false
+	}
+	for path, candidates := range byPath {
+		// El más avanzado primero, para elegir el actual si hay varios
+		sort.Slice(candidates, func(i, j int) bool { return candidates[i].Offset > candidates[j].Offset })
+		file := t.files[path]
+		if file == nil {
+			// Una ruta nueva no es una rotación
+			file = &File{Path: path, Identity: candidates[0].Identity}
+			t.files[path] = file
+		} else if t.entries != nil {
+			for _, entry := range candidates {
+				if previous[path][entry.Identity] || entry.Identity == file.Identity {
+					continue
+				}
+				changes = append(changes, Change{Kind: Rotated, Path: path, From: file.Identity, To: entry.Identity})
+				file.Rotations++
+				file.LastRotation = now
+				file.Identity = entry.Identity
+			}
+		}
+		current := candidates[0]
+		for _, entry := range candidates {
+			if entry.Identity == file.Identity {
+				current = entry
+				break
+			}
+		}
+		file.Identity, file.Offset, file.Present = current.Identity, current.Offset, true
+	}
+
+	for key, entry := range entries {
+		if t.entries == nil {
+			break
+		}
+		before, ok := t.entries[key]
+		if !ok {
+			// El mismo archivo con otra clave y menos offset se vuelve a leer
+			if previous, found := t.sameFile(entry); found && entry.Offset < previous.Offset {
+				change := Change{Kind: Reread, Path: entry.Source, From: previous.Key, To: key, OffsetBefore: previous.Offset, OffsetAfter: entry.Offset}
+				change.Size, _ = t.size(entry.Source)
+				changes = append(changes, t.reread(change, now))
+			}
+			continue
+		}
+		if entry.Offset >= before.Offset || entry.Source != before.Source {
+			continue
+		}
+		// La misma clave, el mismo archivo, con menos offset: si el archivo
+		// sigue teniendo lo que ya se había leído no se truncó
+		change := Change{Kind: Truncated, Path: entry.Source, OffsetBefore: before.Offset, OffsetAfter: entry.Offset}
+		if size, err := t.size(entry.Source); err == nil && size >= before.Offset {
+			change.Kind, change.Size = Reread, size
+			changes = append(changes, t.reread(change, now))
+			continue
+		}
+		changes = append(changes, change)
+		t.files[entry.Source].Truncations++
+		t.files[entry.Source].LastRotation = now
+	}
+	t.entries = entries
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+	return changes
+}
+
+// sameFile busca en la lectura anterior otra clave del mismo archivo en la
+// misma ruta
+func (t *Tracker) sameFile(entry Entry) (Entry, bool) {
+	for key, previous := range t.entries {
+		if key != entry.Key && previous.Source == entry.Source && previous.Identity == entry.Identity {
+			return previous, true
+		}
+	}
+	return Entry{}, false
+}
+
+// reread cuenta una relectura en el historial de su ruta
+func (t *Tracker) reread(change Change, now time.Time) Change {
+	file := t.files[change.Path]
+	file.Rereads++
+	file.DuplicatedBytes += change.Duplicated()
+	file.LastReread = now
+	return change
+}
+
+// Files devuelve una copia del historial de cada ruta: primero las que
+// cambiaron más recientemente y después por ruta
+func (t *Tracker) Files() []File {
+	files := make([]File, 0, len(t.files))
+	for _, file := range t.files {
+		files = append(files, *file)
+	}
+	sort.Slice(files, func(i, j int) bool {
+		a, b := files[i].LastChange(), files[j].LastChange()
+		if !a.Equal(b) {
+			return a.After(b)
+		}
+		return files[i].Path < files[j].Path
+	})
+	return files
+}
+
+// LastChange es la última rotación, truncado o relectura
+func (f File) LastChange() time.Time {
+	if f.LastReread.After(f.LastRotation) {
+		return f.LastReread
+	}
+	return f.LastRotation
+}
@@ -0,0 +1,80 @@
+package remotewrite
+
+import (
+	"encoding/binary"
+	"math"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// Codificación del WriteRequest de prompb a mano, para no depender de los
+// tipos generados de Prometheus:
+//
+//	WriteRequest { repeated TimeSeries timeseries = 1; }
+//	TimeSeries   { repeated Label labels = 1; repeated Sample samples = 2; }
+//	Label        { string name = 1; string value = 2; }
+//	Sample       { double value = 1; int64 timestamp = 2; }
+
+// encodeRequest arma el WriteRequest con las series en orden
+func encodeRequest(series []*timeSeries) []byte {
+	var buf, ts []byte
+	for _, s := range series {
+		ts = ts[:0]
+		for _, l := range s.labels {
+			ts = protowire.AppendTag(ts, 1, protowire.BytesType)
+			ts = protowire.AppendVarint(ts, uint64(labelSize(l)))
+			ts = protowire.AppendTag(ts, 1, protowire.BytesType)
+			ts = protowire.AppendString(ts, l.name)
+			ts = protowire.AppendTag(ts, 2, protowire.BytesType)
+			ts = protowire.AppendString(ts, l.value)
+		}
+		for _, sample := range s.samples {
+			ts = protowire.AppendTag(ts, 2, protowire.BytesType)
+			ts = protowire.AppendVarint(ts, uint64(sampleSize(sample)))
+			ts = protowire.AppendTag(ts, 1, protowire.Fixed64Type)
+			ts = protowire.AppendFixed64(ts, math.Float64bits(sample.value))
+			ts = protowire.AppendTag(ts, 2, protowire.VarintType)
+			ts = protowire.AppendVarint(ts, uint64(sample.at))
+		}
+		buf = protowire.AppendTag(buf, 1, protowire.BytesType)
+		buf = protowire.AppendBytes(buf, ts)
+	}
+	return buf
+}
+
+func labelSize(l label) int {
+	return protowire.SizeTag(1) + protowire.SizeBytes(len(l.name)) +
+		protowire.SizeTag(2) + protowire.SizeBytes(len(l.value))
+}
+
+func sampleSize(s sample) int {
+	return protowire.SizeTag(1) + protowire.SizeFixed64() +
+		protowire.SizeTag(2) + protowire.SizeVarint(uint64(s.at))
+}
+
+// Largo máximo de cada literal de snappyEncode
+const maxLiteral = 1 << 16
+
+// snappyEncode comprime src en el formato de bloque de snappy que exige
+// remote_write. Solo usa literales, sin buscar repeticiones: el resultado es
+// apenas más grande que src pero cualquier decodificador lo acepta, y evita
+// una dependencia por unos pocos KB por envío.
+func snappyEncode(src []byte) []byte {
+	dst := binary.AppendUvarint(make([]byte, 0, len(src)+len(src)/maxLiteral*3+16), uint64(len(src)))
+	for len(src) > 0 {
+		n := min(len(src), maxLiteral)
+		// El tag de un literal guarda n-1: en el propio byte hasta 60 y
+		// si no en 1 o 2 bytes más (tags 60 y 61)
+		switch {
+		case n <= 60:
+			dst = append(dst, byte(n-1)<<2)
+		case n <= 1<<8:
+			dst = append(dst, 60<<2, byte(n-1))
+		default:
+			dst = append(dst, 61<<2, byte(n-1), byte((n-1)>>8))
+		}
+		dst = append(dst, src[:n]...)
+		src = src[n:]
+	}
+	return dst
+}
@@ -0,0 +1,287 @@
+package remotewrite
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// snappyDecode decodifica un bloque de snappy con literales y copias, para
+// no depender de la librería de snappy en las pruebas
+func snappyDecode(src []byte) ([]byte, error) {
+	size, n := binary.Uvarint(src)
+	if n <= 0 {
+		return nil, errors.New("largo inválido")
+	}
+	src = src[n:]
+	var dst []byte
+	for len(src) > 0 {
+		tag := src[0]
+		switch tag & 3 {
+		case 0:
+			length := int(tag >> 2)
+			src = src[1:]
+			if extra := length - 59; extra > 0 {
+				if extra > 4 || len(src) < extra {
+					return nil, errors.New("tag de literal inválido")
+				}
+				length = 0
+				for i := extra - 1; i >= 0; i-- {
+					length = length<<8 | int(src[i])
+				}
+				src = src[extra:]
+			}
+			length++
+			if len(src) < length {
+				return nil, errors.New("literal incompleto")
+			}
+			dst = append(dst, src[:length]...)
+			src = src[length:]
+		default:
+			var length, offset int
+			switch tag & 3 {
+			case 1:
+				length, offset = 4+int(tag>>2)&7, int(tag&0xe0)<<3|int(src[1])
+				src = src[2:]
+			case 2:
+				length, offset = 1+int(tag>>2), int(binary.LittleEndian.Uint16(src[1:]))
+				src = src[3:]
+			case 3:
+				length, offset = 1+int(tag>>2), int(binary.LittleEndian.Uint32(src[1:]))
+				src = src[5:]
+			}
+			if offset <= 0 || offset > len(dst) {
+				return nil, errors.New("copia inválida")
+			}
+			for i := 0; i < length; i++ {
+				dst = append(dst, dst[len(dst)-offset])
+			}
+		}
+	}
+	if uint64(len(dst)) != size {
+		return nil, errors.New("el largo no coincide")
+	}
+	return dst, nil
+}
+
+func TestSnappyLiteralBoundaries(t *testing.T) {
+	tests := []struct {
+		size int
+		// tag es el comienzo del primer literal después del largo
+		tag []byte
+	}{
+		{0, nil},
+		{1, []byte{0 << 2}},
+		{59, []byte{58 << 2}},
+		{60, []byte{59 << 2}},
+		{61, []byte{60 << 2, 60}},
+		{255, []byte{60 << 2, 254}},
+		{256, []byte{60 << 2, 255}},
+		{257, []byte{61 << 2, 0, 1}},
+		{65535, []byte{61 << 2, 0xfe, 0xff}},
+		{65536, []byte{61 << 2, 0xff, 0xff}},
+		{65537, []byte{61 << 2, 0xff, 0xff}},
+		{3*65536 + 10, []byte{61 << 2, 0xff, 0xff}},
+	}
+	for _, tt := range tests {
+		src := make([]byte, tt.size)
+		for i := range src {
+			src[i] = byte(i * 7)
+		}
+		encoded := snappyEncode(src)
+
+		_, n := binary.Uvarint(encoded)
+		if got := encoded[n:min(len(encoded), n+len(tt.tag))]; !bytes.Equal(got, tt.tag) {
+			t.Errorf("%d bytes: tag % x, se esperaba % x", tt.size, got, tt.tag)
+		}
+		decoded, err := snappyDecode(encoded)
+		if err != nil {
+			t.Errorf("%d bytes: %v", tt.size, err)
+			continue
+		}
+		if !bytes.Equal(decoded, src) {
+			t.Errorf("%d bytes: el resultado decodificado no coincide", tt.size)
+		}
+	}
+}
+
+func TestSnappyLiteralSplit(t *testing.T) {
+	// 65537 bytes son un literal de 65536 y otro de 1
+	encoded := snappyEncode(make([]byte, 65537))
+	_, n := binary.Uvarint(encoded)
+	second := encoded[n+3+65536:]
+	if !bytes.Equal(second, []byte{0 << 2, 0}) {
+		t.Errorf("segundo literal % x, se esperaba 00 00", second)
+	}
+}
+
+type decodedSeries struct {
+	labels  []label
+	samples []sample
+}
+
+// decodeRequest decodifica el WriteRequest con protowire
+func decodeRequest(t *testing.T, buf []byte) []decodedSeries {
+	t.Helper()
+	var series []decodedSeries
+	for len(buf) > 0 {
+		num, typ, n := protowire.ConsumeTag(buf)
+		if n < 0 || num != 1 || typ != protowire.BytesType {
+			t.Fatalf("WriteRequest: campo %d tipo %d inesperado", num, typ)
+		}
+		buf = buf[n:]
+		ts, n := protowire.ConsumeBytes(buf)
+		if n < 0 {
+			t.Fatal("TimeSeries incompleta")
+		}
+		buf = buf[n:]
+
+		var s decodedSeries
+		for len(ts) > 0 {
+			num, typ, n := protowire.ConsumeTag(ts)
+			if n < 0 || typ != protowire.BytesType {
+				t.Fatalf("TimeSeries: campo %d tipo %d inesperado", num, typ)
+			}
+			ts = ts[n:]
+			msg, n := protowire.ConsumeBytes(ts)
+			if n < 0 {
+				t.Fatal("mensaje incompleto")
+			}
+			ts = ts[n:]
+			switch num {
+			case 1:
+				s.labels = append(s.labels, decodeLabel(t, msg))
+			case 2:
+				s.samples = append(s.samples, decodeSample(t, msg))
+			default:
+				t.Fatalf("TimeSeries: campo %d inesperado", num)
+			}
+		}
+		series = append(series, s)
+	}
+	return series
+}
+
+func decodeLabel(t *testing.T, msg []byte) label {
+	t.Helper()
+	var l label
+	for len(msg) > 0 {
+		num, _, n := protowire.ConsumeTag(msg)
+		msg = msg[n:]
+		value, n := protowire.ConsumeString(msg)
+		if n < 0 {
+			t.Fatal("Label incompleta")
+		}
+		msg = msg[n:]
+		switch num {
+		case 1:
+			l.name = value
+		case 2:
+			l.value = value
+		}
+	}
+	return l
+}
+
+func decodeSample(t *testing.T, msg []byte) sample {
+	t.Helper()
+	var s sample
+	for len(msg) > 0 {
+		num, typ, n := protowire.ConsumeTag(msg)
+		msg = msg[n:]
+		switch {
+		case num == 1 && typ == protowire.Fixed64Type:
+			v, n := protowire.ConsumeFixed64(msg)
+			if n < 0 {
+				t.Fatal("Sample incompleta")
+			}
+			s.value = math.Float64frombits(v)
+			msg = msg[n:]
+		case num == 2 && typ == protowire.VarintType:
+			v, n := protowire.ConsumeVarint(msg)
+			if n < 0 {
+				t.Fatal("Sample incompleta")
+			}
+			s.at = int64(v)
+			msg = msg[n:]
+		default:
+			t.Fatalf("Sample: campo %d tipo %d inesperado", num, typ)
+		}
+	}
+	return s
+}
+
+func TestEncodeRequest(t *testing.T) {
+	at := time.UnixMilli(1700000000123)
+	b := newBuilder(map[string]string{"env": "prod", "job": "logs", "az": "b"}, "web-1", at)
+	b.add("filebeat_events_total", 1500)
+	b.add("filebeat_input_events_total", -2.5, label{"input", "nginx"}, label{"type", "filestream"})
+
+	got := decodeRequest(t, encodeRequest(b.series))
+	want := []decodedSeries{
+		{
+			labels: []label{
+				{"__name__", "filebeat_events_total"}, {"az", "b"}, {"env", "prod"},
+				{"instance", "web-1"}, {"job", "logs"},
+			},
+			samples: []sample{{1500, at.UnixMilli()}},
+		},
+		{
+			labels: []label{
+				{"__name__", "filebeat_input_events_total"}, {"az", "b"}, {"env", "prod"},
+				{"input", "nginx"}, {"instance", "web-1"}, {"job", "logs"}, {"type", "filestream"},
+			},
+			samples: []sample{{-2.5, at.UnixMilli()}},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("series decodificadas:\n%v\nse esperaba:\n%v", got, want)
+	}
+}
+
+func TestLabelOrdering(t *testing.T) {
+	// Las etiquetas comunes salen de un mapa, en cualquier orden
+	labels := map[string]string{"zone": "1", "Zeta": "2", "_private": "3", "alpha": "4", "instance_group": "5"}
+	for i := 0; i < 20; i++ {
+		b := newBuilder(labels, "t", time.Unix(0, 0))
+		b.add("m", 1, label{"input", "x"})
+		series := decodeRequest(t, encodeRequest(b.series))
+		names := make([]string, len(series[0].labels))
+		for j, l := range series[0].labels {
+			names[j] = l.name
+		}
+		if !sort.StringsAreSorted(names) {
+			t.Fatalf("etiquetas desordenadas: %v", names)
+		}
+		if names[0] != "Zeta" || names[1] != "__name__" {
+			t.Fatalf("se ordena por bytes, como exige el protocolo: %v", names)
+		}
+	}
+}
+
+func TestEncodeRequestCompressed(t *testing.T) {
+	// Un envío grande ocupa varios literales de snappy
+	b := newBuilder(nil, "web-1", time.UnixMilli(1))
+	for i := 0; i < 5000; i++ {
+		b.add("filebeat_metric_with_a_long_name_total", float64(i))
+	}
+	raw := encodeRequest(b.series)
+	if len(raw) <= maxLiteral {
+		t.Fatalf("el envío de prueba debería superar %d bytes, tiene %d", maxLiteral, len(raw))
+	}
+	decoded, err := snappyDecode(snappyEncode(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	series := decodeRequest(t, decoded)
+	if len(series) != 5000 || series[4999].samples[0].value != 4999 {
+		t.Errorf("se decodificaron %d series", len(series))
+	}
+}
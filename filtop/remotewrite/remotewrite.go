@@ -0,0 +1,390 @@
+// Package remotewrite publica las muestras de Filebeat con el protocolo
+// remote_write de Prometheus (Mimir, Thanos Receive, Cortex,
+// VictoriaMetrics...), para cuando filtop corre en un host al que no se
+// puede hacer scrape. Las muestras se acumulan y se envían cada Interval;
+// si el receptor no responde se conservan hasta MaxPending y se reintentan
+// en el próximo envío.
+package remotewrite
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/iTiagoCO/filtop/filtop/alerts"
+	"github.com/iTiagoCO/filtop/filtop/client"
+	"github.com/iTiagoCO/filtop/filtop/metrics"
+)
+
+const (
+	// Tiempo máximo del envío final al apagar
+	flushTimeout = 10 * time.Second
+	// Muestras por petición, como max_samples_per_send de Prometheus
+	maxSamplesPerSend = 2000
+)
+
+// Options configura el envío; está desactivado si URL está vacía
+type Options struct {
+	URL      string
+	Username string
+	Password string
+	// BearerToken reemplaza a Username y Password
+	BearerToken string
+	// Headers se agregan a cada petición, p. ej. X-Scope-OrgID
+	Headers map[string]string
+	// Labels se agregan a todas las series; job por defecto es filtop
+	Labels   map[string]string
+	Interval time.Duration
+	// MaxPending son las muestras que se conservan mientras el receptor no
+	// responde; después se descartan las más antiguas
+	MaxPending int
+	HTTP       *http.Client
+}
+
+type label struct {
+	name, value string
+}
+
+type sample struct {
+	value float64
+	// at en milisegundos desde epoch
+	at int64
+}
+
+type timeSeries struct {
+	labels  []label
+	samples []sample
+}
+
+// batch son las series de una muestra de un Filebeat
+type batch struct {
+	series []*timeSeries
+}
+
+func (b batch) samples() int { return len(b.series) }
+
+// Publisher acumula las muestras y las envía desde Run, así Add no demora
+// al colector
+type Publisher struct {
+	mu      sync.Mutex
+	opts    Options
+	pending []batch
+	// queued son las muestras de pending
+	queued  int
+	dropped int
+	// stats son los totales desde que se creó
+	stats Stats
+	wake  chan struct{}
+}
+
+// Stats son los totales de un Publisher desde que se creó
+type Stats struct {
+	// Sent son las muestras enviadas y Dropped las descartadas por superar
+	// MaxPending
+	Sent, Dropped int
+	// Failures son los envíos que fallaron, se reintenten o no
+	Failures int
+	// Pending son las muestras que esperan el próximo envío
+	Pending int
+}
+
+// New crea un Publisher con opts
+func New(opts Options) *Publisher {
+	return &Publisher{opts: opts, wake: make(chan struct{}, 1)}
+}
+
+// SetOptions reemplaza las opciones, p. ej. al recargar la configuración.
+// Si se desactiva se descartan las muestras pendientes.
+func (p *Publisher) SetOptions(opts Options) {
+	p.mu.Lock()
+	p.opts = opts
+	if opts.URL == "" {
+		p.pending, p.queued = nil, 0
+	}
+	p.trim()
+	p.mu.Unlock()
+	select {
+	case p.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Add encola una muestra de target: sus métricas de /stats, las de cada
+// input, las calculadas y las alertas activas
+func (p *Publisher) Add(target string, stats *client.FilebeatStats, computed []metrics.ComputedValue, active []alerts.Alert) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.opts.URL == "" {
+		return
+	}
+	b := newBuilder(p.opts.Labels, target, stats.Timestamp)
+	b.flatten("filebeat", stats.Raw)
+	// Inputs repetidos de una consulta anterior ya se enviaron
+	if !stats.InputsAt.Before(stats.Timestamp) {
+		for _, input := range stats.Filebeat.Inputs {
+			id, kind := label{"input", input.ID}, label{"type", input.Type}
+			b.add("filebeat_input_events_total", float64(input.Events), id, kind)
+			b.add("filebeat_input_bytes_total", float64(input.Bytes), id, kind)
+			b.add("filebeat_input_files", float64(input.Files), id, kind)
+			b.add("filebeat_input_errors_total", float64(input.Errors), id, kind)
+		}
+	}
+	for _, value := range computed {
+		// Una tasa sin dos muestras todavía no tiene valor
+		if value.Err == nil && !math.IsNaN(value.Value) {
+			b.add("filtop_computed", value.Value, label{"name", value.Name})
+		}
+	}
+	for _, alert := range active {
+		b.add("filtop_alert_active", 1, label{"rule", alert.Rule}, label{"severity", alert.Severity})
+	}
+	p.pending = append(p.pending, b.batch)
+	p.queued += b.batch.samples()
+	p.trim()
+}
+
+// trim descarta las muestras más antiguas que superan MaxPending
+func (p *Publisher) trim() {
+	for p.opts.MaxPending > 0 && p.queued > p.opts.MaxPending && len(p.pending) > 0 {
+		p.queued -= p.pending[0].samples()
+		p.dropped += p.pending[0].samples()
+		p.stats.Dropped += p.pending[0].samples()
+		p.pending = p.pending[1:]
+	}
+}
+
+// Stats devuelve los totales del Publisher
+func (p *Publisher) Stats() Stats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	stats := p.stats
+	stats.Pending = p.queued
+	return stats
+}
+
+// Run envía las muestras cada Interval hasta que se cancela ctx; entonces
+// intenta enviar las que quedaron.
+func (p *Publisher) Run(ctx context.Context) {
+	for {
+		p.mu.Lock()
+		interval := p.opts.Interval
+		p.mu.Unlock()
+		timer := time.NewTimer(interval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			flushCtx, cancel := context.WithTimeout(context.Background(), flushTimeout)
+			p.flush(flushCtx)
+			cancel()
+			return
+		case <-p.wake:
+			// Cambiaron las opciones: se vuelve a tomar el intervalo
+			timer.Stop()
+			continue
+		case <-timer.C:
+		}
+		p.flush(ctx)
+	}
+}
+
+// flush envía lo pendiente de a maxSamplesPerSend muestras. Si un envío
+// falla por un error transitorio, vuelve a pending para el próximo.
+func (p *Publisher) flush(ctx context.Context) {
+	for {
+		p.mu.Lock()
+		opts := p.opts
+		if dropped := p.dropped; dropped > 0 {
+			p.dropped = 0
+			slog.Warn("remote_write: se descartaron muestras que no se pudieron enviar", "samples", dropped)
+		}
+		n, samples := 0, 0
+		for n < len(p.pending) && (n == 0 || samples+p.pending[n].samples() <= maxSamplesPerSend) {
+			samples += p.pending[n].samples()
+			n++
+		}
+		// Se sacan de pending mientras se envían, así trim no las cuenta
+		batches := p.pending[:n:n]
+		p.pending = p.pending[n:]
+		p.queued -= samples
+		p.mu.Unlock()
+		if len(batches) == 0 || opts.URL == "" {
+			return
+		}
+
+		err := send(ctx, opts, encodeRequest(merge(batches)))
+		if err != nil {
+			retry := retryable(err)
+			p.mu.Lock()
+			p.stats.Failures++
+			switch {
+			case !retry:
+				p.stats.Dropped += samples
+			case p.opts.URL != "":
+				p.pending = append(batches, p.pending...)
+				p.queued += samples
+				p.trim()
+			}
+			p.mu.Unlock()
+			if ctx.Err() == nil {
+				slog.Warn("Error enviando por remote_write", "url", opts.URL, "samples", samples, "retry", retry, "err", err)
+			}
+			return
+		}
+		p.mu.Lock()
+		p.stats.Sent += samples
+		p.mu.Unlock()
+		slog.Debug("Muestras enviadas por remote_write", "samples", samples)
+	}
+}
+
+// merge junta las muestras de cada serie; remote_write espera cada serie
+// una sola vez por petición, con sus muestras en orden
+func merge(batches []batch) []*timeSeries {
+	index := make(map[string]*timeSeries)
+	var merged []*timeSeries
+	var key strings.Builder
+	for _, b := range batches {
+		for _, s := range b.series {
+			key.Reset()
+			for _, l := range s.labels {
+				key.WriteString(l.name)
+				key.WriteByte(0)
+				key.WriteString(l.value)
+				key.WriteByte(0)
+			}
+			if existing, ok := index[key.String()]; ok {
+				existing.samples = append(existing.samples, s.samples...)
+				continue
+			}
+			copied := &timeSeries{labels: s.labels, samples: append([]sample(nil), s.samples...)}
+			index[key.String()] = copied
+			merged = append(merged, copied)
+		}
+	}
+	return merged
+}
+
+// statusError es una respuesta de error del receptor
+type statusError struct {
+	status int
+	body   string
+}
+
+func (e *statusError) Error() string {
+	if e.body == "" {
+		return fmt.Sprintf("el receptor respondió %d", e.status)
+	}
+	return fmt.Sprintf("el receptor respondió %d: %s", e.status, e.body)
+}
+
+// retryable indica si vale la pena reenviar: los errores de red, los 5xx y
+// 429. El resto (p. ej. muestras fuera de orden) se repetiría igual.
+func retryable(err error) bool {
+	if status, ok := err.(*statusError); ok {
+		return status.status >= 500 || status.status == http.StatusTooManyRequests
+	}
+	return true
+}
+
+func send(ctx context.Context, opts Options, request []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, opts.URL, bytes.NewReader(snappyEncode(request)))
+	if err != nil {
+		return err
+	}
+	for name, value := range opts.Headers {
+		req.Header.Set(name, value)
+	}
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	req.Header.Set("Content-Encoding", "snappy")
+	req.Header.Set("User-Agent", "filtop")
+	req.Header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+	switch {
+	case opts.BearerToken != "":
+		req.Header.Set("Authorization", "Bearer "+opts.BearerToken)
+	case opts.Username != "":
+		req.SetBasicAuth(opts.Username, opts.Password)
+	}
+	resp, err := opts.HTTP.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return &statusError{status: resp.StatusCode, body: strings.TrimSpace(string(body))}
+	}
+	io.Copy(io.Discard, resp.Body)
+	return nil
+}
+
+// builder arma las series de una muestra
+type builder struct {
+	batch
+	// common son las etiquetas de todas las series, sin __name__
+	common []label
+	at     int64
+}
+
+func newBuilder(labels map[string]string, target string, at time.Time) *builder {
+	common := []label{{"job", "filtop"}, {"instance", target}}
+	for name, value := range labels {
+		if name == "job" {
+			common[0].value = value
+			continue
+		}
+		common = append(common, label{name, value})
+	}
+	return &builder{common: common, at: at.UnixMilli()}
+}
+
+func (b *builder) add(name string, value float64, extra ...label) {
+	labels := make([]label, 0, len(b.common)+len(extra)+1)
+	labels = append(labels, label{"__name__", name})
+	labels = append(labels, b.common...)
+	labels = append(labels, extra...)
+	// El protocolo exige las etiquetas ordenadas por nombre
+	sort.Slice(labels, func(i, j int) bool { return labels[i].name < labels[j].name })
+	b.series = append(b.series, &timeSeries{labels: labels, samples: []sample{{value, b.at}}})
+}
+
+// flatten agrega cada valor numérico o booleano de doc, con la ruta como
+// nombre de la métrica: beat.memstats.rss es filebeat_beat_memstats_rss
+func (b *builder) flatten(name string, doc interface{}) {
+	switch v := doc.(type) {
+	case map[string]interface{}:
+		for key, child := range v {
+			b.flatten(name+"_"+metricName(key), child)
+		}
+	case []interface{}:
+		for i, child := range v {
+			b.flatten(name+"_"+strconv.Itoa(i), child)
+		}
+	case float64:
+		b.add(name, v)
+	case bool:
+		if v {
+			b.add(name, 1)
+		} else {
+			b.add(name, 0)
+		}
+	}
+}
+
+// metricName reemplaza lo que no admite un nombre de métrica por _
+func metricName(key string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
+			return r
+		}
+		return '_'
+	}, key)
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"net"
+	"net/http"
+
+	"github.com/iTiagoCO/filtop/filtop/server"
+
+	"google.golang.org/grpc"
+)
+
+// serverBeats son los beats de la sesión para el servidor
+func (s *session) serverBeats() []server.Beat {
+	beats := make([]server.Beat, len(s.beats))
+	for i, b := range s.beats {
+		beats[i] = server.Beat{Name: b.name, URL: b.url, History: b.history}
+	}
+	return beats
+}
+
+// runServe ejecuta el colector sin interfaz y expone lo que reúne en la API
+// HTTP y, si se pide, por streaming gRPC
+func runServe(args []string) {
+	fs := newFlagSet("serve", "filtop serve [flags]")
+	flags := &beatFlags{}
+	flags.register(fs, "serve")
+	listen := fs.String("listen", defaultListen, "Dirección de la API HTTP, con /metrics")
+	grpcListen := fs.String("grpc-listen", "", "Dirección del streaming gRPC (desactivado si está vacío)")
+	flags.parse(fs, args)
+
+	s := newSession("serve", flags)
+	if s.logFile != nil {
+		defer s.logFile.Close()
+	}
+	s.startOutputs()
+
+	srv := server.New(s.serverBeats(), s.cfg.serverEndpoints(), s.alertLog, s.archive)
+	heartbeat := newHeartbeat()
+	out := heartbeatSink{sink: serverSink{srv: srv, session: s}, heartbeat: heartbeat}
+	s.startWorkers(out)
+	setupReloadHandler(func() {
+		sdNotify("RELOADING=1")
+		s.reload(out, func() { srv.SetTargets(s.serverBeats(), s.cfg.serverEndpoints()) }, func(error) {})
+		sdNotify("READY=1")
+	})
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", selfMetrics.Handler())
+	mux.Handle("/", srv.Handler())
+	httpServer := &http.Server{Addr: *listen, Handler: mux}
+	grpcServer := grpc.NewServer()
+	srv.RegisterGRPC(grpcServer)
+	if *grpcListen != "" {
+		lis, err := net.Listen("tcp", *grpcListen)
+		if err != nil {
+			fatal("Error escuchando", "addr", *grpcListen, "err", err)
+		}
+		slog.Info("Streaming gRPC escuchando", "addr", *grpcListen)
+		go grpcServer.Serve(lis)
+	}
+
+	lis, err := net.Listen("tcp", *listen)
+	if err != nil {
+		fatal("Error escuchando", "addr", *listen, "err", err)
+	}
+	slog.Info("API de filtop escuchando", "addr", *listen)
+	serveErr := make(chan error, 1)
+	go func() { serveErr <- httpServer.Serve(lis) }()
+	// Listo recién cuando la API acepta conexiones
+	sdNotify("READY=1\nSTATUS=API escuchando en " + *listen)
+	go runWatchdog(s.ctx, heartbeat, watchdogStall(flags.timeout, flags.retries, 1))
+	select {
+	case err := <-serveErr:
+		fatal("Error ejecutando la API", "err", err)
+	case <-s.ctx.Done():
+	}
+
+	sdNotify("STOPPING=1")
+	s.shutdown()
+	out.Close()
+	s.finish()
+	grpcServer.GracefulStop()
+	shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancelShutdown()
+	if err := httpServer.Shutdown(shutdownCtx); err != nil {
+		slog.Warn("Error cerrando la API", "err", err)
+	}
+}
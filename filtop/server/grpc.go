@@ -0,0 +1,84 @@
+package server
+
+import (
+	"encoding/json"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// El servicio se describe en filtop.proto. Los mensajes son
+// google.protobuf.Struct con los mismos campos que la API JSON, así los
+// clientes de cualquier lenguaje no necesitan generar código propio.
+var samplesServiceDesc = grpc.ServiceDesc{
+	ServiceName: "filtop.v1.Samples",
+	HandlerType: (*interface{})(nil),
+	Streams: []grpc.StreamDesc{
+		{StreamName: "StreamSamples", Handler: streamSamples, ServerStreams: true},
+		{StreamName: "StreamAlerts", Handler: streamAlerts, ServerStreams: true},
+	},
+	Metadata: "filtop.proto",
+}
+
+// AlertEvent es una alerta que se activó o se resolvió
+type AlertEvent struct {
+	// Target es el nombre del beat
+	Target   string    `json:"target"`
+	Rule     string    `json:"rule"`
+	Severity string    `json:"severity"`
+	Value    *float64  `json:"value"`
+	Since    time.Time `json:"since"`
+	Raised   bool      `json:"raised"`
+	At       time.Time `json:"at"`
+	Silenced bool      `json:"silenced,omitempty"`
+}
+
+func streamSamples(srv interface{}, stream grpc.ServerStream) error {
+	s := srv.(*Server)
+	return s.stream(s.hub, stream)
+}
+
+func streamAlerts(srv interface{}, stream grpc.ServerStream) error {
+	s := srv.(*Server)
+	return s.stream(s.alertsHub, stream)
+}
+
+// RegisterGRPC registra el servicio de streaming en g
+func (s *Server) RegisterGRPC(g *grpc.Server) {
+	g.RegisterService(&samplesServiceDesc, s)
+}
+
+// stream envía cada mensaje del hub, de todos los beats, hasta que el
+// cliente cancela
+func (s *Server) stream(h *hub, stream grpc.ServerStream) error {
+	if err := stream.RecvMsg(new(emptypb.Empty)); err != nil {
+		return err
+	}
+
+	ch := h.subscribe("")
+	defer h.unsubscribe(ch)
+
+	for {
+		select {
+		case msg, ok := <-ch:
+			if !ok {
+				return nil
+			}
+			var fields map[string]interface{}
+			if err := json.Unmarshal(msg, &fields); err != nil {
+				return err
+			}
+			st, err := structpb.NewStruct(fields)
+			if err != nil {
+				return err
+			}
+			if err := stream.SendMsg(st); err != nil {
+				return err
+			}
+		case <-stream.Context().Done():
+			return stream.Context().Err()
+		}
+	}
+}
@@ -0,0 +1,481 @@
+// Package server expone por HTTP lo que filtop recolecta cuando se ejecuta
+// sin interfaz (filtop serve), junto con un tablero web que recibe cada
+// muestra por WebSocket.
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/iTiagoCO/filtop/filtop/alerts"
+	"github.com/iTiagoCO/filtop/filtop/archive"
+	"github.com/iTiagoCO/filtop/filtop/client"
+	"github.com/iTiagoCO/filtop/filtop/elastic"
+	"github.com/iTiagoCO/filtop/filtop/kafka"
+	"github.com/iTiagoCO/filtop/filtop/metrics"
+	"github.com/iTiagoCO/filtop/filtop/probe"
+	"github.com/iTiagoCO/filtop/filtop/registry"
+	"github.com/iTiagoCO/filtop/filtop/system"
+)
+
+// Target es un origen consultado por filtop: el beat o un endpoint JSON de
+// la configuración.
+type Target struct {
+	Name      string    `json:"name"`
+	Kind      string    `json:"kind"`
+	URL       string    `json:"url"`
+	Up        bool      `json:"up"`
+	Version   string    `json:"version,omitempty"`
+	Schema    string    `json:"schema,omitempty"`
+	LastFetch time.Time `json:"last_fetch,omitempty"`
+	LastError string    `json:"last_error,omitempty"`
+	// Valores de los campos de un endpoint, por etiqueta
+	Values map[string]interface{} `json:"values,omitempty"`
+}
+
+// Beat es un Filebeat monitoreado y su historial
+type Beat struct {
+	Name    string
+	URL     string
+	History *metrics.History
+}
+
+// Endpoint describe un endpoint JSON de la configuración
+type Endpoint struct {
+	Name   string
+	URL    string
+	Labels []string
+}
+
+type Point struct {
+	Time  time.Time `json:"t"`
+	Value float64   `json:"v"`
+}
+
+// beatState es lo que se sabe de un beat
+type beatState struct {
+	target  Target
+	history *metrics.History
+	// Series de las métricas calculadas, con la misma retención que history
+	computed map[string][]Point
+}
+
+type Server struct {
+	alertLog *alerts.Log
+	// archive es el historial guardado en disco; nil si no se configuró
+	archive *archive.Archive
+
+	mu sync.RWMutex
+	// Los beats por nombre; names los ordena como en la configuración y
+	// el primero es el que se publica si no se pide otro
+	beats     map[string]*beatState
+	names     []string
+	endpoints []Endpoint
+	// Estado de los endpoints, en el orden de endpoints
+	endpointTargets []Target
+	// Última muestra del host, que se publica con la del primer beat
+	host *system.Stats
+	// Última consulta a Elasticsearch o su error
+	elastic    *elastic.Stats
+	elasticErr string
+	// Última consulta a Kafka o su error
+	kafka    *kafka.Stats
+	kafkaErr string
+	// Rotaciones de los archivos del registry y el error de su última lectura
+	registry    []registry.File
+	registryErr string
+	// Últimas marcas de la sonda de latencia y el error de la última
+	probe    []probe.Result
+	probeErr string
+
+	hub       *hub
+	alertsHub *hub
+	// WebSocket abiertos, que Close espera a que se despidan
+	sockets sync.WaitGroup
+}
+
+func New(beats []Beat, endpoints []Endpoint, alertLog *alerts.Log, archive *archive.Archive) *Server {
+	s := &Server{
+		alertLog:  alertLog,
+		archive:   archive,
+		beats:     make(map[string]*beatState),
+		hub:       newHub(true),
+		alertsHub: newHub(false),
+	}
+	s.SetTargets(beats, endpoints)
+	return s
+}
+
+// SetTargets reemplaza los targets al recargar la configuración. Un beat
+// conserva su estado mientras mantenga el nombre, la URL y el historial;
+// si no, se descartan también las series de sus métricas calculadas.
+func (s *Server) SetTargets(beats []Beat, endpoints []Endpoint) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	states := make(map[string]*beatState, len(beats))
+	s.names = s.names[:0]
+	for _, b := range beats {
+		state := s.beats[b.Name]
+		if state == nil || state.target.URL != b.URL || state.history != b.History {
+			s.hub.forget(b.Name)
+			state = &beatState{
+				target:   Target{Name: b.Name, Kind: "beat", URL: b.URL},
+				history:  b.History,
+				computed: make(map[string][]Point),
+			}
+		}
+		states[b.Name] = state
+		s.names = append(s.names, b.Name)
+	}
+	for name := range s.beats {
+		if states[name] == nil {
+			s.hub.forget(name)
+		}
+	}
+	s.beats = states
+
+	s.endpoints = endpoints
+	s.endpointTargets = make([]Target, len(endpoints))
+	for i, endpoint := range endpoints {
+		s.endpointTargets[i] = Target{Name: endpoint.Name, Kind: "endpoint", URL: endpoint.URL}
+	}
+}
+
+// beat devuelve el estado del beat name, o el del primero si name está
+// vacío; nil si no existe
+func (s *Server) beat(name string) *beatState {
+	if name == "" && len(s.names) > 0 {
+		name = s.names[0]
+	}
+	return s.beats[name]
+}
+
+// RecordSample registra una muestra correcta del beat target. La muestra
+// en sí ya está en el historial; aquí se guarda el estado del target y los
+// valores calculados, y se envía la muestra al tablero web.
+func (s *Server) RecordSample(target string, stats *client.FilebeatStats, info *client.BeatInfo, schema string, computed []metrics.ComputedValue, active []alerts.Alert, anomalies []metrics.Anomaly) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state := s.beats[target]
+	if state == nil {
+		return
+	}
+	beat := &state.target
+	beat.Up = true
+	beat.LastFetch = stats.Timestamp
+	beat.LastError = ""
+	beat.Schema = schema
+	beat.Version = stats.Beat.Info.Version
+	if info != nil && info.Version != "" {
+		beat.Version = info.Version
+	}
+
+	for _, value := range computed {
+		if value.Err != nil || finite(value.Value) == nil {
+			continue
+		}
+		series := append(state.computed[value.Name], Point{Time: stats.Timestamp, Value: value.Value})
+		if len(series) > state.history.Size() {
+			series = series[1:]
+		}
+		state.computed[value.Name] = series
+	}
+
+	snap := newSnapshot(stats, beat.Version, schema, state.history, computed, active)
+	snap.Target = target
+	if anomalies != nil {
+		snap.Anomalies = anomalies
+	}
+	// El host, Elasticsearch, Kafka, el registry y la sonda siguen al
+	// primer beat
+	if target == s.names[0] {
+		snap.Host = s.host
+		snap.Elasticsearch, snap.ElasticsearchErr = s.elastic, s.elasticErr
+		snap.Kafka, snap.KafkaErr = s.kafka, s.kafkaErr
+		snap.Registry, snap.RegistryErr = s.registry, s.registryErr
+		snap.Probe, snap.ProbeErr = s.probe, s.probeErr
+	}
+	s.publish(snap)
+}
+
+// RecordAlerts publica las alertas del beat target que se activaron o
+// resolvieron
+func (s *Server) RecordAlerts(target string, events []alerts.Event) {
+	for _, event := range events {
+		msg, err := json.Marshal(AlertEvent{
+			Target:   target,
+			Rule:     event.Alert.Rule,
+			Severity: event.Alert.Severity,
+			Value:    finite(event.Alert.Value),
+			Since:    event.Alert.Since,
+			Raised:   event.Raised,
+			At:       event.At,
+			Silenced: event.Alert.Silenced,
+		})
+		if err != nil {
+			continue
+		}
+		s.alertsHub.broadcast(target, msg)
+	}
+}
+
+// RecordError registra un fallo al consultar el beat target
+func (s *Server) RecordError(target string, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if state := s.beats[target]; state != nil {
+		state.target.Up = false
+		state.target.LastError = err.Error()
+	}
+}
+
+// RecordHost registra las métricas del host. Un error solo se registra en
+// el log; la última muestra correcta se sigue publicando.
+func (s *Server) RecordHost(stats *system.Stats, err error) {
+	if err != nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.host = stats
+}
+
+// RecordElastic registra la última consulta al clúster de Elasticsearch.
+// Ante un error se deja de publicar la anterior.
+func (s *Server) RecordElastic(stats *elastic.Stats, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.elastic, s.elasticErr = stats, ""
+	if err != nil {
+		s.elasticErr = err.Error()
+	}
+}
+
+// RecordKafka registra la última consulta al topic de Kafka. Ante un error
+// se deja de publicar la anterior.
+func (s *Server) RecordKafka(stats *kafka.Stats, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.kafka, s.kafkaErr = stats, ""
+	if err != nil {
+		s.kafkaErr = err.Error()
+	}
+}
+
+// RecordRegistry registra el historial de rotaciones del registry de
+// Filebeat. Ante un error se sigue publicando el historial.
+func (s *Server) RecordRegistry(files []registry.File, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.registry, s.registryErr = files, ""
+	if err != nil {
+		s.registryErr = err.Error()
+	}
+}
+
+// RecordProbe registra las últimas marcas de la sonda de latencia. Ante un
+// error se siguen publicando las anteriores.
+func (s *Server) RecordProbe(results []probe.Result, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.probe, s.probeErr = results, ""
+	if err != nil {
+		s.probeErr = err.Error()
+	}
+}
+
+// RecordEndpoint registra la última consulta del endpoint index
+func (s *Server) RecordEndpoint(index int, values []interface{}, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	target := &s.endpointTargets[index]
+	target.LastFetch = time.Now()
+	if err != nil {
+		target.Up = false
+		target.LastError = err.Error()
+		return
+	}
+	target.Up = true
+	target.LastError = ""
+	target.Values = make(map[string]interface{}, len(values))
+	for i, label := range s.endpoints[index].Labels {
+		if i < len(values) {
+			target.Values[label] = values[i]
+		}
+	}
+}
+
+// Close termina los WebSocket y streams gRPC abiertos; las muestras
+// posteriores ya no se publican.
+func (s *Server) Close() {
+	s.hub.close()
+	s.alertsHub.close()
+	s.sockets.Wait()
+}
+
+// Handler devuelve las rutas de la API y el tablero web
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/targets", s.handleTargets)
+	mux.HandleFunc("/api/history", s.handleHistory)
+	mux.HandleFunc("/api/snapshot", s.handleSnapshot)
+	mux.HandleFunc("/api/alerts/history", s.handleAlertHistory)
+	mux.HandleFunc("/ws", s.handleWebSocket)
+	mux.Handle("/", webHandler())
+	return mux
+}
+
+func (s *Server) handleTargets(w http.ResponseWriter, r *http.Request) {
+	s.mu.RLock()
+	targets := make([]Target, 0, len(s.names)+len(s.endpointTargets))
+	for _, name := range s.names {
+		targets = append(targets, s.beats[name].target)
+	}
+	targets = append(targets, s.endpointTargets...)
+	s.mu.RUnlock()
+	writeJSON(w, http.StatusOK, targets)
+}
+
+// requestedBeat devuelve el nombre del beat del parámetro target, o el del
+// primero si no se indica. Si no existe responde con 404.
+func (s *Server) requestedBeat(w http.ResponseWriter, r *http.Request) (string, bool) {
+	s.mu.RLock()
+	state := s.beat(r.URL.Query().Get("target"))
+	s.mu.RUnlock()
+	if state == nil {
+		writeError(w, http.StatusNotFound, "target desconocido: "+r.URL.Query().Get("target"))
+		return "", false
+	}
+	return state.target.Name, true
+}
+
+// handleHistory devuelve la serie de una métrica: una métrica calculada por
+// su nombre o una ruta de /stats. since (duración, p. ej. 10m) limita la
+// ventana y target elige el beat. Las métricas que se guardan en disco se
+// leen de ahí, que llega más atrás que la memoria.
+func (s *Server) handleHistory(w http.ResponseWriter, r *http.Request) {
+	metric := r.URL.Query().Get("metric")
+	if metric == "" {
+		writeError(w, http.StatusBadRequest, "falta el parámetro metric")
+		return
+	}
+	var from time.Time
+	if since := r.URL.Query().Get("since"); since != "" {
+		d, err := time.ParseDuration(since)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "since inválido: "+err.Error())
+			return
+		}
+		from = time.Now().Add(-d)
+	}
+
+	target, ok := s.requestedBeat(w, r)
+	if !ok {
+		return
+	}
+	filtered := []Point{}
+	if archived, found := s.archived(target, metric, from); found {
+		filtered = append(filtered, archived...)
+	} else {
+		points, found := s.series(target, metric)
+		if !found {
+			writeError(w, http.StatusNotFound, "métrica desconocida: "+metric)
+			return
+		}
+		for _, p := range points {
+			if !p.Time.Before(from) {
+				filtered = append(filtered, p)
+			}
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"metric": metric,
+		"points": filtered,
+	})
+}
+
+// handleAlertHistory devuelve las alertas activadas y resueltas, de la más
+// antigua a la más reciente. since limita la ventana como en /api/history y
+// format=csv las devuelve como CSV.
+func (s *Server) handleAlertHistory(w http.ResponseWriter, r *http.Request) {
+	var from time.Time
+	if since := r.URL.Query().Get("since"); since != "" {
+		d, err := time.ParseDuration(since)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "since inválido: "+err.Error())
+			return
+		}
+		from = time.Now().Add(-d)
+	}
+	records := []alerts.Record{}
+	for _, record := range s.alertLog.Records() {
+		if !record.At.Before(from) {
+			records = append(records, record)
+		}
+	}
+
+	switch format := r.URL.Query().Get("format"); format {
+	case "", "json":
+		writeJSON(w, http.StatusOK, records)
+	case "csv":
+		w.Header().Set("Content-Type", "text/csv")
+		w.Header().Set("Content-Disposition", `attachment; filename="filtop-alerts.csv"`)
+		alerts.WriteCSV(w, records)
+	default:
+		writeError(w, http.StatusBadRequest, "format inválido: "+format)
+	}
+}
+
+// archived devuelve la serie guardada en disco desde from
+func (s *Server) archived(target, metric string, from time.Time) ([]Point, bool) {
+	if s.archive == nil {
+		return nil, false
+	}
+	saved, found := s.archive.Series(target, metric, from)
+	points := make([]Point, len(saved))
+	for i, p := range saved {
+		points[i] = Point{Time: p.Time, Value: p.Value}
+	}
+	return points, found
+}
+
+func (s *Server) series(target, metric string) ([]Point, bool) {
+	s.mu.RLock()
+	state := s.beats[target]
+	if state == nil {
+		s.mu.RUnlock()
+		return nil, false
+	}
+	computed, ok := state.computed[metric]
+	history := state.history
+	s.mu.RUnlock()
+	if ok {
+		return append([]Point(nil), computed...), true
+	}
+
+	if history.Len() == 0 {
+		// Aún no hay muestras con las que saber si la ruta existe
+		return nil, true
+	}
+	samples, found := history.Series(metric)
+	points := make([]Point, len(samples))
+	for i, sample := range samples {
+		points[i] = Point{Time: sample.Time, Value: sample.Value}
+	}
+	return points, found
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, map[string]string{"error": msg})
+}
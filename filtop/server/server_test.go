@@ -0,0 +1,80 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/iTiagoCO/filtop/filtop/client"
+	"github.com/iTiagoCO/filtop/filtop/metrics"
+)
+
+func get(t *testing.T, s *Server, path string, v interface{}) int {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	s.Handler().ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+	if v != nil && rec.Code == 200 {
+		if err := json.Unmarshal(rec.Body.Bytes(), v); err != nil {
+			t.Fatalf("%s: %v", path, err)
+		}
+	}
+	return rec.Code
+}
+
+func TestTargets(t *testing.T) {
+	alfa := Beat{Name: "alfa", URL: "http://alfa:5066", History: metrics.NewHistory(10)}
+	beta := Beat{Name: "beta", URL: "http://beta:5066", History: metrics.NewHistory(10)}
+	s := New([]Beat{alfa, beta}, []Endpoint{{Name: "app", URL: "http://app/health"}}, nil, nil)
+
+	stats := &client.FilebeatStats{Timestamp: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	beta.History.Add(stats)
+	s.RecordSample("beta", stats, nil, "8.x", []metrics.ComputedValue{{Name: "ratio", Value: 0.5}}, nil, nil)
+
+	var targets []Target
+	get(t, s, "/api/targets", &targets)
+	if len(targets) != 3 || targets[0].Name != "alfa" || targets[0].Up || targets[1].Name != "beta" || !targets[1].Up || targets[2].Kind != "endpoint" {
+		t.Fatalf("targets: %+v", targets)
+	}
+
+	// Sin target se publica el primero, que todavía no respondió
+	if code := get(t, s, "/api/snapshot", nil); code != 503 {
+		t.Errorf("snapshot del primero: %d", code)
+	}
+	var snap Snapshot
+	if code := get(t, s, "/api/snapshot?target=beta", &snap); code != 200 || snap.Target != "beta" {
+		t.Errorf("snapshot de beta: %d %+v", code, snap)
+	}
+	var history struct{ Points []Point }
+	if get(t, s, "/api/history?metric=ratio&target=beta", &history); len(history.Points) != 1 {
+		t.Errorf("serie de beta: %+v", history)
+	}
+	if code := get(t, s, "/api/history?metric=ratio", nil); code != 200 {
+		t.Errorf("serie del primero: %d", code)
+	}
+	if code := get(t, s, "/api/snapshot?target=gamma", nil); code != 404 {
+		t.Errorf("target desconocido: %d", code)
+	}
+
+	// Al recargar sin beta deja de existir; alfa conserva su estado
+	s.RecordError("alfa", errors.New("sin conexión"))
+	s.SetTargets([]Beat{alfa}, nil)
+	targets = nil
+	get(t, s, "/api/targets", &targets)
+	if len(targets) != 1 || targets[0].LastError != "sin conexión" {
+		t.Errorf("targets tras recargar: %+v", targets)
+	}
+	if code := get(t, s, "/api/snapshot?target=beta", nil); code != 404 {
+		t.Errorf("beta tras recargar: %d", code)
+	}
+}
+
+func TestClosedWebSocket(t *testing.T) {
+	s := New([]Beat{{Name: "alfa", History: metrics.NewHistory(10)}}, nil, nil, nil)
+	s.Close()
+	// Cerrado, un WebSocket nuevo se rechaza antes de aceptarlo
+	if code := get(t, s, "/ws", nil); code != 503 {
+		t.Errorf("WebSocket tras cerrar: %d", code)
+	}
+}
@@ -0,0 +1,371 @@
+package server
+
+import (
+	"embed"
+	"encoding/json"
+	"io/fs"
+	"log/slog"
+	"math"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/iTiagoCO/filtop/filtop/alerts"
+	"github.com/iTiagoCO/filtop/filtop/client"
+	"github.com/iTiagoCO/filtop/filtop/elastic"
+	"github.com/iTiagoCO/filtop/filtop/kafka"
+	"github.com/iTiagoCO/filtop/filtop/metrics"
+	"github.com/iTiagoCO/filtop/filtop/probe"
+	"github.com/iTiagoCO/filtop/filtop/registry"
+	"github.com/iTiagoCO/filtop/filtop/system"
+
+	"github.com/gorilla/websocket"
+)
+
+//go:embed web
+var webFiles embed.FS
+
+// Mensajes pendientes por cliente; si un navegador no los consume se le
+// desconecta en lugar de frenar al colector.
+const clientBuffer = 8
+
+// Snapshot es la vista de una muestra que se envía al tablero web. Refleja
+// los paneles de la interfaz de terminal.
+type Snapshot struct {
+	// Target es el nombre del beat
+	Target     string          `json:"target"`
+	Time       time.Time       `json:"time"`
+	Version    string          `json:"version"`
+	Schema     string          `json:"schema"`
+	CPUPercent float64         `json:"cpu_percent"`
+	RSSBytes   uint64          `json:"rss_bytes"`
+	UptimeMS   uint64          `json:"uptime_ms"`
+	FetchMS    float64         `json:"fetch_ms"`
+	Load       [3]float64      `json:"load"`
+	Queue      SnapshotQueue   `json:"queue"`
+	Harvester  SnapshotHarvest `json:"harvester"`
+	Inputs     []SnapshotInput `json:"inputs"`
+	InputsErr  string          `json:"inputs_error,omitempty"`
+	Modules    []client.Module `json:"modules"`
+	Computed   []SnapshotValue `json:"computed"`
+	Alerts     []SnapshotAlert `json:"alerts"`
+	// Anomalies son los inputs cuyo ritmo se apartó de su línea base
+	Anomalies []metrics.Anomaly `json:"anomalies"`
+	// Host son las métricas de la máquina (-system); nil si están desactivadas
+	Host *system.Stats `json:"host,omitempty"`
+	// Elasticsearch es el estado del clúster de destino y la comparación
+	// entre lo enviado y lo indexado; nil si no está configurado
+	Elasticsearch    *elastic.Stats `json:"elasticsearch,omitempty"`
+	ElasticsearchErr string         `json:"elasticsearch_error,omitempty"`
+	// Kafka es el topic de destino, la comparación entre lo enviado y lo
+	// escrito y el lag del grupo que lo consume; nil si no está configurado
+	Kafka    *kafka.Stats `json:"kafka,omitempty"`
+	KafkaErr string       `json:"kafka_error,omitempty"`
+	// Registry son las rotaciones y truncados de cada archivo cosechado;
+	// nil si no se configuró el registry
+	Registry    []registry.File `json:"registry,omitempty"`
+	RegistryErr string          `json:"registry_error,omitempty"`
+	// Probe son las últimas marcas de la sonda de latencia; nil si no se
+	// configuró
+	Probe    []probe.Result `json:"probe,omitempty"`
+	ProbeErr string         `json:"probe_error,omitempty"`
+}
+
+type SnapshotQueue struct {
+	Filled uint64 `json:"filled"`
+	Max    uint64 `json:"max"`
+}
+
+type SnapshotHarvest struct {
+	Running uint64 `json:"running"`
+	Open    uint64 `json:"open_files"`
+}
+
+type SnapshotInput struct {
+	ID           string  `json:"id"`
+	Type         string  `json:"type"`
+	Active       bool    `json:"active"`
+	Events       uint64  `json:"events"`
+	Bytes        uint64  `json:"bytes"`
+	EventsPerSec float64 `json:"events_per_sec"`
+	BytesPerSec  float64 `json:"bytes_per_sec"`
+	Files        uint64  `json:"files"`
+}
+
+// SnapshotValue es una métrica calculada; Value es nil si no hay valor
+type SnapshotValue struct {
+	Name  string   `json:"name"`
+	Value *float64 `json:"value"`
+	Error string   `json:"error,omitempty"`
+}
+
+type SnapshotAlert struct {
+	Rule     string    `json:"rule"`
+	Severity string    `json:"severity"`
+	Value    *float64  `json:"value"`
+	Since    time.Time `json:"since"`
+	Silenced bool      `json:"silenced,omitempty"`
+}
+
+func newSnapshot(stats *client.FilebeatStats, version, schema string, history *metrics.History, computed []metrics.ComputedValue, active []alerts.Alert) *Snapshot {
+	snap := &Snapshot{
+		Time:     stats.Timestamp,
+		Version:  version,
+		Schema:   schema,
+		RSSBytes: stats.Beat.Memstats.RSS,
+		UptimeMS: stats.Beat.Info.Uptime.MS,
+		FetchMS:  float64(stats.FetchDuration) / float64(time.Millisecond),
+		Load: [3]float64{
+			stats.System.Load.Norm.Load1,
+			stats.System.Load.Norm.Load5,
+			stats.System.Load.Norm.Load15,
+		},
+		Queue: SnapshotQueue{
+			Filled: stats.Libbeat.Pipeline.Queue.Filled.Events,
+			Max:    stats.Libbeat.Pipeline.Queue.MaxEvents,
+		},
+		Harvester: SnapshotHarvest{
+			Running: stats.Filebeat.Harvester.Running,
+			Open:    stats.Filebeat.Harvester.Open,
+		},
+		Modules:   stats.Filebeat.Modules.List,
+		InputsErr: stats.InputsError,
+		Inputs:    []SnapshotInput{},
+		Computed:  []SnapshotValue{},
+		Alerts:    []SnapshotAlert{},
+		Anomalies: []metrics.Anomaly{},
+	}
+	if snap.Modules == nil {
+		snap.Modules = []client.Module{}
+	}
+	if stats.Beat.Info.Uptime.MS > 0 {
+		snap.CPUPercent = float64(stats.Beat.CPU.Total.Time.MS) / float64(stats.Beat.Info.Uptime.MS) * 100
+	}
+	for _, input := range stats.Filebeat.Inputs {
+		byteRate, _ := history.InputByteRate(input.ID)
+		snap.Inputs = append(snap.Inputs, SnapshotInput{
+			ID:           input.ID,
+			Type:         input.Type,
+			Active:       input.Active,
+			Events:       input.Events,
+			Bytes:        input.Bytes,
+			EventsPerSec: history.InputEventRate(input.ID),
+			BytesPerSec:  byteRate,
+			Files:        input.Files,
+		})
+	}
+	for _, value := range computed {
+		v := SnapshotValue{Name: value.Name}
+		if value.Err != nil {
+			v.Error = value.Err.Error()
+		} else {
+			v.Value = finite(value.Value)
+		}
+		snap.Computed = append(snap.Computed, v)
+	}
+	for _, alert := range active {
+		snap.Alerts = append(snap.Alerts, SnapshotAlert{
+			Rule:     alert.Rule,
+			Severity: alert.Severity,
+			Value:    finite(alert.Value),
+			Since:    alert.Since,
+			Silenced: alert.Silenced,
+		})
+	}
+	return snap
+}
+
+// finite evita los NaN e Inf, que JSON no admite
+func finite(v float64) *float64 {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return nil
+	}
+	return &v
+}
+
+// hub reparte cada mensaje a los suscriptores conectados (navegadores o
+// streams gRPC). Cada mensaje es de un beat; un suscriptor recibe los de
+// uno o, con "", los de todos. Con replay, un suscriptor nuevo recibe el
+// último mensaje de cada beat que sigue.
+type hub struct {
+	mu      sync.Mutex
+	clients map[chan []byte]string
+	last    map[string][]byte
+	replay  bool
+	closed  bool
+}
+
+func newHub(replay bool) *hub {
+	return &hub{clients: make(map[chan []byte]string), last: make(map[string][]byte), replay: replay}
+}
+
+func (h *hub) broadcast(target string, msg []byte) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		return
+	}
+	if h.replay {
+		h.last[target] = msg
+	}
+	for ch, want := range h.clients {
+		if want != "" && want != target {
+			continue
+		}
+		select {
+		case ch <- msg:
+		default:
+			delete(h.clients, ch)
+			close(ch)
+		}
+	}
+}
+
+func (h *hub) subscribe(target string) chan []byte {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	// Lugar para lo que se repite además del margen de siempre
+	ch := make(chan []byte, clientBuffer+len(h.last))
+	if h.closed {
+		close(ch)
+		return ch
+	}
+	for name, msg := range h.last {
+		if target == "" || target == name {
+			ch <- msg
+		}
+	}
+	h.clients[ch] = target
+	return ch
+}
+
+func (h *hub) unsubscribe(ch chan []byte) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.clients[ch]; ok {
+		delete(h.clients, ch)
+		close(ch)
+	}
+}
+
+// forget descarta el último mensaje de un beat que ya no se monitorea
+func (h *hub) forget(target string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.last, target)
+}
+
+// close desconecta a todos los suscriptores y rechaza los nuevos
+func (h *hub) close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.closed = true
+	for ch := range h.clients {
+		delete(h.clients, ch)
+		close(ch)
+	}
+}
+
+// join cuenta una conexión más en sockets, salvo que el hub ya esté cerrado.
+// Como close toma el mismo lock, una conexión que se une antes de cerrar
+// queda contada cuando Close la espera.
+func (h *hub) join(sockets *sync.WaitGroup) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		return false
+	}
+	sockets.Add(1)
+	return true
+}
+
+func (h *hub) latest(target string) []byte {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.last[target]
+}
+
+func (s *Server) publish(snap *Snapshot) {
+	msg, err := json.Marshal(snap)
+	if err != nil {
+		slog.Error("Error codificando la muestra", "err", err)
+		return
+	}
+	s.hub.broadcast(snap.Target, msg)
+}
+
+// handleSnapshot devuelve la última muestra del beat target, por defecto
+// la del primero
+func (s *Server) handleSnapshot(w http.ResponseWriter, r *http.Request) {
+	target, ok := s.requestedBeat(w, r)
+	if !ok {
+		return
+	}
+	msg := s.hub.latest(target)
+	if msg == nil {
+		writeError(w, http.StatusServiceUnavailable, "todavía no hay muestras")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(msg)
+}
+
+var upgrader = websocket.Upgrader{}
+
+// handleWebSocket envía cada nueva muestra del beat target (por defecto el
+// primero) al navegador. Solo se leen los mensajes de control para
+// detectar el cierre de la conexión.
+func (s *Server) handleWebSocket(w http.ResponseWriter, r *http.Request) {
+	target, ok := s.requestedBeat(w, r)
+	if !ok {
+		return
+	}
+	if !s.hub.join(&s.sockets) {
+		writeError(w, http.StatusServiceUnavailable, "filtop se está cerrando")
+		return
+	}
+	defer s.sockets.Done()
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	ch := s.hub.subscribe(target)
+	defer s.hub.unsubscribe(ch)
+
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.NextReader(); err != nil {
+				return
+			}
+		}
+	}()
+
+	for {
+		select {
+		case msg, ok := <-ch:
+			if !ok {
+				conn.WriteControl(websocket.CloseMessage,
+					websocket.FormatCloseMessage(websocket.CloseGoingAway, ""), time.Now().Add(time.Second))
+				return
+			}
+			conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+			if err := conn.WriteMessage(websocket.TextMessage, msg); err != nil {
+				return
+			}
+		case <-closed:
+			return
+		}
+	}
+}
+
+func webHandler() http.Handler {
+	files, err := fs.Sub(webFiles, "web")
+	if err != nil {
+		panic(err)
+	}
+	return http.FileServer(http.FS(files))
+}
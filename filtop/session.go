@@ -0,0 +1,520 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/iTiagoCO/filtop/filtop/alerts"
+	"github.com/iTiagoCO/filtop/filtop/archive"
+	"github.com/iTiagoCO/filtop/filtop/client"
+	"github.com/iTiagoCO/filtop/filtop/demo"
+	"github.com/iTiagoCO/filtop/filtop/elastic"
+	"github.com/iTiagoCO/filtop/filtop/kafka"
+	"github.com/iTiagoCO/filtop/filtop/metrics"
+	"github.com/iTiagoCO/filtop/filtop/notify"
+	"github.com/iTiagoCO/filtop/filtop/offline"
+	"github.com/iTiagoCO/filtop/filtop/plugins"
+	"github.com/iTiagoCO/filtop/filtop/probe"
+	"github.com/iTiagoCO/filtop/filtop/registry"
+	"github.com/iTiagoCO/filtop/filtop/remotewrite"
+	"github.com/iTiagoCO/filtop/filtop/system"
+)
+
+// newFlagSet crea los flags de un subcomando; usage es la línea de uso que
+// encabeza -h
+func newFlagSet(name, usage string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Uso: %s\n\nFlags:\n", usage)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// beatFlags son los flags de los modos que consultan Filebeat: la interfaz
+// de terminal, serve, watch, bench y assert
+type beatFlags struct {
+	cliFlags
+	state         bool
+	expvarURL     string
+	pprofURL      string
+	configPath    string
+	profile       string
+	retention     time.Duration
+	timeout       time.Duration
+	retries       int
+	keepAlive     bool
+	idleTimeout   time.Duration
+	fromStdin     bool
+	fromFile      string
+	demo          bool
+	demoSeed      int64
+	metricsListen string
+	logPath       string
+	level         string
+}
+
+func (f *beatFlags) register(fs *flag.FlagSet, command string) {
+	fs.StringVar(&f.host, "host", defaultHost, "Host de Filebeat, o su socket (unix:///ruta.sock) o named pipe (npipe:///nombre)")
+	fs.IntVar(&f.port, "port", defaultPort, "Puerto de Filebeat")
+	fs.IntVar(&f.interval, "interval", defaultInterval, "Intervalo de refresco en segundos")
+	fs.BoolVar(&f.state, "state", false, "Consultar los endpoints opcionales /state y /dataset")
+	fs.StringVar(&f.expvarURL, "expvar-url", "", "URL de expvar usada como respaldo si /stats falla (por defecto <host>/debug/vars)")
+	fs.StringVar(&f.pprofURL, "pprof-url", "", "URL donde Filebeat expone /debug/pprof (por defecto <host>:<port>)")
+	fs.StringVar(&f.configPath, "config", defaultConfigPath(), "Archivo de configuración YAML")
+	fs.StringVar(&f.profile, "profile", "", "Perfil de conexión de la configuración (profiles)")
+	fs.DurationVar(&f.retention, "retention", defaultRetention, "Historial retenido (gráficos, API y métricas calculadas)")
+	fs.DurationVar(&f.timeout, "timeout", client.DefaultHTTPOptions.Timeout, "Timeout de cada consulta HTTP")
+	fs.IntVar(&f.retries, "retries", 1, "Reintentos por ciclo ante fallos transitorios (red, timeouts, 5xx)")
+	fs.BoolVar(&f.keepAlive, "keepalive", true, "Reusar conexiones HTTP entre ciclos")
+	fs.DurationVar(&f.idleTimeout, "idle-timeout", client.DefaultHTTPOptions.IdleConnTimeout, "Tiempo que se conserva una conexión ociosa")
+	fs.BoolVar(&f.fromStdin, "stdin", false, "Leer capturas de /stats desde la entrada estándar")
+	fs.StringVar(&f.fromFile, "from-file", "", "Leer capturas de /stats desde un archivo o directorio")
+	fs.BoolVar(&f.demo, "demo", false, "Usar un Filebeat simulado con datos de ejemplo")
+	fs.Int64Var(&f.demoSeed, "demo-seed", demo.DefaultSeed, "Semilla de los datos de -demo; la misma semilla repite los mismos datos")
+	fs.BoolVar(&f.system, "system", false, "Mostrar CPU, memoria y disco del host (filtop en la misma máquina que Filebeat)")
+	fs.IntVar(&f.pid, "pid", 0, "PID de Filebeat para -system (por defecto se busca el que escucha en -port)")
+	fs.StringVar(&f.registry, "registry", "", "Directorio data/registry de Filebeat, para seguir las rotaciones de los archivos")
+	if command != "serve" {
+		// serve las expone en -listen
+		fs.StringVar(&f.metricsListen, "metrics-listen", "", "Dirección de /metrics con las métricas propias de filtop (desactivado si está vacío)")
+	}
+	logDefault := "stderr"
+	if command == "" {
+		logDefault = "en el directorio de caché del usuario"
+	}
+	fs.StringVar(&f.logPath, "log-file", "", "Archivo de log (por defecto "+logDefault+")")
+	fs.StringVar(&f.level, "log-level", "info", "Nivel de log: debug, info, warn o error")
+}
+
+// parse lee los flags de args, prepara el log y recuerda los indicados
+func (f *beatFlags) parse(fs *flag.FlagSet, args []string) {
+	fs.Parse(args)
+	setLogLevel(f.level)
+	f.explicit = make(map[string]bool)
+	fs.Visit(func(fl *flag.Flag) { f.explicit[fl.Name] = true })
+}
+
+// session es lo que comparten los modos que consultan Filebeat: la
+// configuración, los beats con sus colectores y los destinos de las
+// alertas y las muestras. command es el subcomando, "" en la interfaz de
+// terminal.
+type session struct {
+	command string
+	flags   *beatFlags
+	// overrides son los flags que completan la configuración; setInterval
+	// cambia el intervalo
+	overrides cliFlags
+	cfg       *Config
+	logFile   *os.File
+
+	replay *offline.Transport
+	// Con el modo demo o las capturas el beat no sale de la configuración
+	fixedTarget bool
+	beats       []*beat
+	// El panel Host y Elasticsearch siguen al primero
+	primary     *beat
+	historySize int
+
+	alertLog *alerts.Log
+	// archive es el historial de métricas guardado en disco, nil si no se
+	// configuró; archiveMetrics son las rutas de /stats que se guardan
+	archive        *archive.Archive
+	archiveMetrics []string
+	notifier       *notify.Dispatcher
+	silences       *alerts.Silences
+	publisher      *remotewrite.Publisher
+	pluginSinks    []*plugins.Sink
+
+	// Los endpoints propios se siguen consultando por la red; los beats
+	// pueden pedir TLS y credenciales
+	httpOptions  client.HTTPOptions
+	endpointHTTP *http.Client
+	beatHTTP     *http.Client
+
+	// Las rotaciones se cuentan desde el arranque, también entre recargas;
+	// trackedRegistry es el registry del que son
+	tracker         *registry.Tracker
+	trackedRegistry string
+
+	// Ctrl-C cancela ctx: se abortan las consultas en curso, los colectores
+	// terminan y recién entonces se cierra el sink
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// Los colectores usan un contexto propio para poder reemplazarlos al
+	// recargar la configuración. reloadMu evita que una recarga los
+	// reinicie mientras la aplicación se apaga; también protege profile,
+	// el perfil en uso.
+	workers     sync.WaitGroup
+	stopWorkers context.CancelFunc
+	reloadMu    sync.Mutex
+	profile     string
+
+	notifierDone  chan struct{}
+	publisherDone chan struct{}
+	pluginsDone   sync.WaitGroup
+}
+
+// newSession carga la configuración y prepara los beats de command; los
+// colectores arrancan con startWorkers
+func newSession(command string, flags *beatFlags) *session {
+	s := &session{command: command, flags: flags, overrides: flags.cliFlags, profile: flags.profile}
+	cfg, err := loadConfig(flags.configPath, flags.explicit["config"], flags.profile)
+	if err != nil {
+		fatal("Error cargando la configuración", "err", err)
+	}
+	cfg.applyFlags(s.overrides)
+	s.cfg = cfg
+
+	// En modo terminal el log no puede ir a stderr sin romper la pantalla
+	if command == "" && flags.logPath == "" {
+		flags.logPath = defaultLogPath()
+	}
+	if flags.logPath != "" {
+		s.logFile, err = openLog(flags.logPath)
+		if err != nil {
+			fatal("Error abriendo el archivo de log", "path", flags.logPath, "err", err)
+		}
+		setLogOutput(s.logFile)
+	}
+
+	refresh = time.Duration(cfg.Interval) * time.Second
+	targets := cfg.beatTargets()
+	if flags.demo {
+		demoURL, err := demo.Start("127.0.0.1:0", demo.Options{Seed: flags.demoSeed, Step: refresh})
+		if err != nil {
+			fatal("Error iniciando el modo demo", "err", err)
+		}
+		targets = []beatTarget{{name: "demo", url: demoURL}}
+	}
+	switch {
+	case flags.fromStdin:
+		s.replay, err = offline.Read(os.Stdin)
+	case flags.fromFile != "":
+		s.replay, err = offline.Load(flags.fromFile)
+	}
+	if err != nil {
+		fatal("Error leyendo las capturas", "err", err)
+	}
+	if s.replay != nil {
+		targets = []beatTarget{{name: "offline", url: "http://offline"}}
+	}
+	s.fixedTarget = flags.demo || s.replay != nil
+
+	s.alertLog, err = alerts.NewLog(cfg.AlertHistory.size(), cfg.AlertHistory.maxAge(), os.ExpandEnv(cfg.AlertHistory.Path))
+	if err != nil {
+		fatal("Error abriendo el historial de alertas", "path", cfg.AlertHistory.Path, "err", err)
+	}
+	// Solo la interfaz de terminal y serve retienen el historial
+	if path := os.ExpandEnv(cfg.History.Path); path != "" && (command == "" || command == "serve") {
+		s.archive, err = archive.Open(path, cfg.History.options())
+		if err != nil {
+			fatal("Error abriendo el historial de métricas", "path", cfg.History.Path, "err", err)
+		}
+		s.archiveMetrics = cfg.History.metrics()
+	}
+	s.notifier = notify.NewDispatcher(s.alertLog)
+	s.silences = alerts.NewSilences()
+	s.silences.SetConfigured(cfg.silences(time.Now()))
+
+	s.httpOptions = client.HTTPOptions{
+		Timeout:             flags.timeout,
+		DisableKeepAlives:   !flags.keepAlive,
+		IdleConnTimeout:     flags.idleTimeout,
+		MaxIdleConnsPerHost: client.DefaultHTTPOptions.MaxIdleConnsPerHost,
+	}
+	s.endpointHTTP = client.NewHTTPClient(s.httpOptions)
+	beatOptions, err := cfg.beatHTTPOptions(s.httpOptions)
+	if err != nil {
+		fatal("Error configurando la conexión con Filebeat", "err", err)
+	}
+	s.beatHTTP = client.NewHTTPClient(beatOptions)
+	if s.replay != nil {
+		s.beatHTTP = &http.Client{Timeout: flags.timeout, Transport: s.replay}
+	}
+	// La página Charts puede mostrar todo el historial, también en terminal
+	s.historySize = int(flags.retention / refresh)
+	if s.historySize < historySize {
+		s.historySize = historySize
+	}
+	s.beats = s.newBeats(targets)
+	s.primary = s.beats[0]
+	s.notifier.SetChannels(cfg.notifyChannels(s.endpointHTTP), time.Now())
+	s.publisher = remotewrite.New(cfg.RemoteWrite.options(s.endpointHTTP))
+	s.pluginSinks = cfg.pluginSinks()
+	registerSelfMetrics(s.publisher, s.pluginSinks)
+
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+	setupSignalHandler(s.cancel)
+
+	if flags.metricsListen != "" {
+		lis, err := net.Listen("tcp", flags.metricsListen)
+		if err != nil {
+			fatal("Error escuchando", "addr", flags.metricsListen, "err", err)
+		}
+		slog.Info("Métricas de filtop escuchando", "addr", flags.metricsListen)
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", selfMetrics.Handler())
+		go http.Serve(lis, mux)
+	}
+	return s
+}
+
+// debugURLs son pprof y expvar del beat en beatURL, que lo siguen salvo que
+// se indiquen con flags
+func (s *session) debugURLs(beatURL string) (pprof, expvar string) {
+	pprof, expvar = s.flags.pprofURL, s.flags.expvarURL
+	if pprof == "" {
+		pprof = beatURL
+	}
+	if expvar == "" {
+		expvar = beatURL + "/debug/vars"
+	}
+	return pprof, expvar
+}
+
+func (s *session) newBeats(targets []beatTarget) []*beat {
+	beats := make([]*beat, len(targets))
+	for i, target := range targets {
+		b := &beat{index: i, name: target.name, url: target.url, group: target.group}
+		if len(targets) > 1 {
+			b.label = target.name
+		}
+		b.pprofURL, b.expvarURL = s.debugURLs(target.url)
+		b.source = client.New(target.url)
+		b.source.StateEnabled = s.flags.state
+		b.source.Retries = s.flags.retries
+		b.source.InputsInterval, b.source.StateInterval = s.cfg.clientIntervals()
+		b.source.HTTP = s.beatHTTP
+		b.history = metrics.NewHistory(s.historySize)
+		b.store = metrics.NewStore(b.history)
+		b.derived = newDerivedMetrics(s.cfg, b.history, b.label, s.alertLog, s.notifier, s.silences)
+		beats[i] = b
+	}
+	return beats
+}
+
+// startOutputs arranca el notificador, remote_write y los plugins. Al
+// apagar, el notificador envía lo que quedó demorado por el límite de
+// frecuencia, remote_write las muestras pendientes y los plugins reciben el
+// fin de su entrada y un momento para terminar.
+func (s *session) startOutputs() {
+	s.notifierDone = make(chan struct{})
+	go func() {
+		s.notifier.Run(s.ctx)
+		close(s.notifierDone)
+	}()
+	s.publisherDone = make(chan struct{})
+	go func() {
+		s.publisher.Run(s.ctx)
+		close(s.publisherDone)
+	}()
+	for _, plugin := range s.pluginSinks {
+		plugin := plugin
+		s.pluginsDone.Add(1)
+		go func() {
+			defer s.pluginsDone.Done()
+			plugin.Run(s.ctx)
+		}()
+	}
+}
+
+// startWorkers arranca un colector por beat y los de la configuración, que
+// envían lo que obtienen a out
+func (s *session) startWorkers(out sink) {
+	cfg, primary := s.cfg, s.primary
+	out = publishingSink{sink: out, publisher: s.publisher, plugins: s.pluginSinks, archive: s.archive, archiveMetrics: s.archiveMetrics, beats: s.beats}
+	var workersCtx context.Context
+	workersCtx, s.stopWorkers = context.WithCancel(s.ctx)
+	endpoints := cfg.Endpoints
+	s.workers.Add(len(s.beats) + len(endpoints))
+	for _, b := range s.beats {
+		b, expvar := b, b.expvarURL
+		go func() {
+			defer s.workers.Done()
+			dataWorker(workersCtx, b, out, expvar)
+		}()
+	}
+	for i, endpoint := range endpoints {
+		i, endpoint := i, endpoint
+		go func() {
+			defer s.workers.Done()
+			endpointWorker(workersCtx, i, endpoint, s.endpointHTTP, out)
+		}()
+	}
+	if cfg.System.Enabled {
+		collector := system.New(cfg.System.paths())
+		if port, ok := localBeatPort(primary.url); ok || cfg.System.PID != 0 {
+			collector.WatchProcess(int32(cfg.System.PID), port)
+		}
+		s.workers.Add(1)
+		go func() {
+			defer s.workers.Done()
+			systemWorker(workersCtx, collector, out)
+		}()
+	}
+	if path := cfg.Registry.Path; path != "" {
+		if s.tracker == nil || path != s.trackedRegistry {
+			s.tracker, s.trackedRegistry = registry.NewTracker(), path
+		}
+		tracker := s.tracker
+		interval := refresh
+		if cfg.Registry.Interval > 0 {
+			interval = time.Duration(cfg.Registry.Interval) * time.Second
+		}
+		s.workers.Add(1)
+		go func() {
+			defer s.workers.Done()
+			registryWorker(workersCtx, path, tracker, interval, primary.store, out)
+		}()
+	}
+	if cfg.Probe.Path != "" {
+		// La sonda consulta /inputs/ con su propio cliente, más seguido
+		// que el colector
+		source := client.New(primary.url)
+		source.HTTP = s.beatHTTP
+		var esClient *elastic.Client
+		if es := cfg.Elasticsearch; es.URL != "" {
+			// El error se informa en el panel Elasticsearch
+			esClient, _ = elastic.New(es.options(s.flags.timeout))
+		}
+		prober := probe.New(cfg.Probe.Path, cfg.Probe.Input != "", esClient != nil, cfg.Probe.timeout())
+		settings := cfg.Probe
+		indices := cfg.Elasticsearch.Indices
+		s.workers.Add(1)
+		go func() {
+			defer s.workers.Done()
+			probeWorker(workersCtx, prober, settings, source, esClient, indices, out)
+		}()
+	}
+	if k := cfg.Kafka; len(k.Brokers) > 0 {
+		opts, err := k.options(s.flags.timeout)
+		if err != nil {
+			slog.Error("Error configurando Kafka", "err", err)
+			out.Kafka(nil, err)
+		} else {
+			monitor := kafka.NewMonitor(kafka.New(opts), k.Topic, k.Group)
+			interval := refresh
+			if k.Interval > 0 {
+				interval = time.Duration(k.Interval) * time.Second
+			}
+			s.workers.Add(1)
+			go func() {
+				defer s.workers.Done()
+				kafkaWorker(workersCtx, monitor, interval, primary.history, out)
+			}()
+		}
+	}
+	if es := cfg.Elasticsearch; es.URL != "" {
+		esClient, err := elastic.New(es.options(s.flags.timeout))
+		if err != nil {
+			slog.Error("Error configurando Elasticsearch", "err", err)
+			out.Elastic(nil, err)
+			return
+		}
+		monitor := elastic.NewMonitor(esClient, es.Indices)
+		interval := refresh
+		if es.Interval > 0 {
+			interval = time.Duration(es.Interval) * time.Second
+		}
+		s.workers.Add(1)
+		go func() {
+			defer s.workers.Done()
+			elasticWorker(workersCtx, monitor, interval, primary.history, out)
+		}()
+	}
+}
+
+// reload vuelve a leer la configuración y reinicia los colectores con ella.
+// Si el archivo tiene errores se conserva la configuración actual. apply se
+// llama con los colectores detenidos, antes de reiniciarlos.
+func (s *session) reload(out sink, apply func(), failed func(error)) bool {
+	s.reloadMu.Lock()
+	defer s.reloadMu.Unlock()
+	if s.ctx.Err() != nil {
+		return false
+	}
+	newCfg, err := loadConfig(s.flags.configPath, s.flags.explicit["config"], s.profile)
+	var beatOptions client.HTTPOptions
+	if err == nil {
+		beatOptions, err = newCfg.beatHTTPOptions(s.httpOptions)
+	}
+	if err != nil {
+		slog.Error("Error recargando la configuración", "err", err)
+		failed(err)
+		return false
+	}
+	newCfg.applyFlags(s.overrides)
+
+	s.stopWorkers()
+	s.workers.Wait()
+
+	s.cfg = newCfg
+	refresh = time.Duration(s.cfg.Interval) * time.Second
+	if s.replay == nil {
+		s.beatHTTP.CloseIdleConnections()
+		s.beatHTTP = client.NewHTTPClient(beatOptions)
+	}
+	now := time.Now()
+	for _, b := range s.beats {
+		b.source.InputsInterval, b.source.StateInterval = s.cfg.clientIntervals()
+		b.source.HTTP = s.beatHTTP
+		b.derived.reconfigure(s.cfg, now)
+	}
+	s.notifier.SetChannels(s.cfg.notifyChannels(s.endpointHTTP), now)
+	s.silences.SetConfigured(s.cfg.silences(now))
+	s.publisher.SetOptions(s.cfg.RemoteWrite.options(s.endpointHTTP))
+	newTargets := s.cfg.beatTargets()
+	switch primary := s.primary; {
+	case s.fixedTarget:
+	case len(s.beats) == 1 && len(newTargets) == 1:
+		primary.name, primary.group = newTargets[0].name, newTargets[0].group
+		if beatURL := newTargets[0].url; beatURL != primary.url {
+			slog.Info("Cambio de Filebeat", "from", primary.url, "to", beatURL)
+			primary.url = beatURL
+			primary.pprofURL, primary.expvarURL = s.debugURLs(beatURL)
+			primary.source.BaseURL = beatURL
+			primary.source.Reset()
+			// Las tasas no se pueden calcular entre muestras de beats distintos
+			primary.store.Reset()
+			primary.derived.reset()
+		}
+	case targetsChanged(s.beats, newTargets):
+		// Cada beat empieza con su colector, historial y alertas
+		slog.Info("Cambio de targets", "targets", len(newTargets))
+		s.beats = s.newBeats(newTargets)
+		s.primary = s.beats[0]
+	}
+	apply()
+	s.startWorkers(out)
+	slog.Info("Configuración recargada", "path", s.flags.configPath, "profile", s.profile)
+	return true
+}
+
+// shutdown espera a que terminen los colectores, que se detienen al
+// cancelar ctx
+func (s *session) shutdown() {
+	s.reloadMu.Lock()
+	defer s.reloadMu.Unlock()
+	s.workers.Wait()
+}
+
+// finish espera a que el notificador, remote_write y los plugins terminen
+// de enviar y cierra los historiales
+func (s *session) finish() {
+	<-s.notifierDone
+	<-s.publisherDone
+	s.pluginsDone.Wait()
+	s.alertLog.Close()
+	if s.archive != nil {
+		s.archive.Close()
+	}
+}
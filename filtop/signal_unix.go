@@ -0,0 +1,36 @@
+//go:build !windows
+
+package main
+
+import (
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// setupSignalHandler llama a stop con la primera señal; una segunda señal
+// fuerza la salida si el apagado se demora.
+func setupSignalHandler(stop func()) {
+	c := make(chan os.Signal, 2)
+	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-c
+		slog.Info("Apagando la aplicación...")
+		stop()
+		<-c
+		os.Exit(1)
+	}()
+}
+
+// setupReloadHandler llama a reload con cada SIGHUP
+func setupReloadHandler(reload func()) {
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, syscall.SIGHUP)
+	go func() {
+		for range c {
+			slog.Info("SIGHUP recibido, recargando la configuración")
+			reload()
+		}
+	}()
+}
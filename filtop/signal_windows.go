@@ -0,0 +1,43 @@
+//go:build windows
+
+package main
+
+import (
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// En Windows Ctrl-C y Ctrl-Break llegan como os.Interrupt, y cerrar la
+// consola, cerrar la sesión o apagar el equipo como SIGTERM. Tras SIGTERM
+// el sistema termina el proceso a los pocos segundos, así que el apagado
+// tiene un plazo menor para cerrar el log y el historial de alertas antes.
+const consoleCloseGrace = 3 * time.Second
+
+// setupSignalHandler llama a stop con la primera señal; una segunda señal,
+// o el plazo tras cerrar la consola, fuerza la salida si el apagado se
+// demora.
+func setupSignalHandler(stop func()) {
+	c := make(chan os.Signal, 2)
+	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		sig := <-c
+		slog.Info("Apagando la aplicación...", "signal", sig)
+		stop()
+		var deadline <-chan time.Time
+		if sig == syscall.SIGTERM {
+			deadline = time.After(consoleCloseGrace)
+		}
+		select {
+		case <-c:
+		case <-deadline:
+		}
+		os.Exit(1)
+	}()
+}
+
+// setupReloadHandler no hace nada: Windows no tiene SIGHUP. La
+// configuración se recarga con la tecla r o reiniciando el servicio.
+func setupReloadHandler(func()) {}
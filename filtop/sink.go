@@ -0,0 +1,145 @@
+package main
+
+import (
+	"github.com/iTiagoCO/filtop/filtop/archive"
+	"github.com/iTiagoCO/filtop/filtop/elastic"
+	"github.com/iTiagoCO/filtop/filtop/kafka"
+	"github.com/iTiagoCO/filtop/filtop/metrics"
+	"github.com/iTiagoCO/filtop/filtop/plugins"
+	"github.com/iTiagoCO/filtop/filtop/probe"
+	"github.com/iTiagoCO/filtop/filtop/registry"
+	"github.com/iTiagoCO/filtop/filtop/remotewrite"
+	"github.com/iTiagoCO/filtop/filtop/server"
+	"github.com/iTiagoCO/filtop/filtop/system"
+	"github.com/iTiagoCO/filtop/filtop/ui"
+)
+
+// sink recibe lo que producen los colectores: la interfaz de terminal o,
+// en modo serve, la API HTTP. tab es el índice del beat en targets.
+type sink interface {
+	Sample(tab int, sample metrics.Sample, derived derivedValues)
+	StatsError(tab int, err error)
+	Expvar(tab int, doc map[string]interface{})
+	Endpoint(index int, values []interface{}, err error)
+	// System recibe las métricas del host; stats es nil si err no lo es
+	System(stats *system.Stats, err error)
+	// Elastic recibe el estado del clúster de Elasticsearch de destino;
+	// stats es nil si err no lo es
+	Elastic(stats *elastic.Stats, err error)
+	// Kafka recibe el estado del topic de Kafka de destino y del grupo que
+	// lo consume; stats es nil si err no lo es
+	Kafka(stats *kafka.Stats, err error)
+	// Registry recibe el historial de rotaciones de los archivos del
+	// registry de Filebeat y el error de la última lectura
+	Registry(files []registry.File, err error)
+	// Probe recibe las últimas marcas de la sonda de latencia y el error de
+	// la última consulta o escritura
+	Probe(results []probe.Result, err error)
+	// Close se llama una vez que los colectores terminaron
+	Close()
+}
+
+type tuiSink struct{}
+
+func (tuiSink) Sample(tab int, _ metrics.Sample, derived derivedValues) {
+	ui.UpdateCustom(tab, derived.computed, derived.alerts)
+	ui.UpdateAnomalies(tab, derived.anomalies)
+	if len(derived.panels) > 0 {
+		ui.UpdatePanels(tab, derived.panels)
+	}
+	ui.Update(tab)
+}
+
+// Los errores ya se registran en el log; la interfaz marca la pestaña y
+// pasa a expvar
+func (tuiSink) StatsError(tab int, err error) { ui.TargetError(tab, err) }
+
+func (tuiSink) Expvar(tab int, doc map[string]interface{}) { ui.ShowExpvar(tab, doc) }
+
+func (tuiSink) Endpoint(index int, values []interface{}, err error) {
+	ui.UpdateEndpoint(index, values, err)
+}
+
+func (tuiSink) System(stats *system.Stats, err error) { ui.UpdateHost(stats, err) }
+
+func (tuiSink) Elastic(stats *elastic.Stats, err error) { ui.UpdateElastic(stats, err) }
+
+func (tuiSink) Kafka(stats *kafka.Stats, err error) { ui.UpdateKafka(stats, err) }
+
+func (tuiSink) Registry(files []registry.File, err error) { ui.UpdateRegistry(files, err) }
+
+func (tuiSink) Probe(results []probe.Result, err error) { ui.UpdateProbe(results, err) }
+
+func (tuiSink) Close() {}
+
+type serverSink struct {
+	srv     *server.Server
+	session *session
+}
+
+// El servidor guarda cada beat por su nombre; tab es su posición en beats,
+// que solo cambia al recargar con los colectores detenidos
+func (s serverSink) Sample(tab int, sample metrics.Sample, derived derivedValues) {
+	name := s.session.beats[tab].name
+	s.srv.RecordSample(name, sample.Stats, sample.Info, sample.Schema, derived.computed, derived.alerts, derived.anomalies)
+	s.srv.RecordAlerts(name, derived.events)
+}
+
+func (s serverSink) StatsError(tab int, err error) {
+	s.srv.RecordError(s.session.beats[tab].name, err)
+}
+
+func (serverSink) Expvar(int, map[string]interface{}) {}
+
+func (s serverSink) Endpoint(index int, values []interface{}, err error) {
+	s.srv.RecordEndpoint(index, values, err)
+}
+
+func (s serverSink) System(stats *system.Stats, err error) { s.srv.RecordHost(stats, err) }
+
+func (s serverSink) Elastic(stats *elastic.Stats, err error) { s.srv.RecordElastic(stats, err) }
+
+func (s serverSink) Kafka(stats *kafka.Stats, err error) { s.srv.RecordKafka(stats, err) }
+
+func (s serverSink) Registry(files []registry.File, err error) { s.srv.RecordRegistry(files, err) }
+
+func (s serverSink) Probe(results []probe.Result, err error) { s.srv.RecordProbe(results, err) }
+
+func (s serverSink) Close() { s.srv.Close() }
+
+// publishingSink además publica cada muestra por remote_write, si está
+// configurado, la pasa a los plugins y la guarda en el historial de
+// métricas
+type publishingSink struct {
+	sink
+	publisher      *remotewrite.Publisher
+	plugins        []*plugins.Sink
+	archive        *archive.Archive
+	archiveMetrics []string
+	beats          []*beat
+}
+
+func (p publishingSink) Sample(tab int, sample metrics.Sample, derived derivedValues) {
+	b := p.beats[tab]
+	p.publisher.Add(b.name, sample.Stats, derived.computed, derived.alerts)
+	for _, plugin := range p.plugins {
+		plugin.Add(b.name, sample.Stats, derived.computed, derived.alerts)
+	}
+	if p.archive != nil {
+		values := make(map[string]float64, len(p.archiveMetrics)+len(derived.computed))
+		for _, path := range p.archiveMetrics {
+			if v, _, ok := b.history.Value(0, path); ok {
+				values[path] = v
+			}
+		}
+		for _, value := range derived.computed {
+			if value.Err == nil {
+				values[value.Name] = value.Value
+			}
+		}
+		if err := p.archive.Add(b.name, sample.Stats.Timestamp, values); err != nil {
+			targetLogger(b.label).Warn("Error guardando el historial de métricas", "err", err)
+		}
+	}
+	p.sink.Sample(tab, sample, derived)
+}
@@ -0,0 +1,77 @@
+package system
+
+import (
+	"io/fs"
+	"path/filepath"
+	"time"
+)
+
+// PathUsage es lo que ocupan los archivos de una ruta de logs (un archivo,
+// un directorio o un patrón como /var/log/nginx/*.log) y cuánto crecen.
+type PathUsage struct {
+	Path  string `json:"path"`
+	Files int    `json:"files"`
+	Bytes uint64 `json:"bytes"`
+	// GrowthPerSec es negativo si los archivos se rotaron o borraron
+	GrowthPerSec float64 `json:"growth_bytes_per_sec"`
+	Mountpoint   string  `json:"mountpoint,omitempty"`
+	// DiskUsedPercent es el uso del sistema de archivos que contiene la ruta
+	DiskUsedPercent float64 `json:"disk_used_percent"`
+	// FullIn estima cuándo se llena ese sistema de archivos al ritmo de
+	// crecimiento actual; 0 si no crece
+	FullIn time.Duration `json:"full_in,omitempty"`
+}
+
+// pathSize suma los archivos regulares que coinciden con pattern,
+// recorriendo los directorios. Los archivos ilegibles se omiten.
+func pathSize(pattern string) (files int, bytes uint64) {
+	matches, err := filepath.Glob(pattern)
+	if err != nil {
+		return 0, 0
+	}
+	for _, match := range matches {
+		filepath.WalkDir(match, func(_ string, entry fs.DirEntry, err error) error {
+			if err != nil || !entry.Type().IsRegular() {
+				return nil
+			}
+			if info, err := entry.Info(); err == nil {
+				files++
+				bytes += uint64(info.Size())
+			}
+			return nil
+		})
+	}
+	return files, bytes
+}
+
+// pathUsages mide cada ruta y la asocia al sistema de archivos que la
+// contiene para estimar cuándo se llena.
+func (c *Collector) pathUsages(filesystems []Filesystem, elapsed float64) []PathUsage {
+	byPath := make(map[string]*Filesystem)
+	for i := range filesystems {
+		for _, path := range filesystems[i].Paths {
+			byPath[path] = &filesystems[i]
+		}
+	}
+
+	sizes := make(map[string]uint64, len(c.paths))
+	usages := make([]PathUsage, 0, len(c.paths))
+	for _, path := range c.paths {
+		usage := PathUsage{Path: path}
+		usage.Files, usage.Bytes = pathSize(path)
+		sizes[path] = usage.Bytes
+		if prev, ok := c.prevSizes[path]; ok && elapsed > 0 {
+			usage.GrowthPerSec = (float64(usage.Bytes) - float64(prev)) / elapsed
+		}
+		if fs := byPath[path]; fs != nil {
+			usage.Mountpoint = fs.Mountpoint
+			usage.DiskUsedPercent = fs.UsedPercent
+			if usage.GrowthPerSec > 0 {
+				usage.FullIn = time.Duration(float64(fs.FreeBytes) / usage.GrowthPerSec * float64(time.Second))
+			}
+		}
+		usages = append(usages, usage)
+	}
+	c.prevSizes = sizes
+	return usages
+}
@@ -0,0 +1,150 @@
+package system
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shirou/gopsutil/v4/net"
+	"github.com/shirou/gopsutil/v4/process"
+)
+
+const beatProcessName = "filebeat"
+
+// ProcessStats son datos del proceso de Filebeat que /stats no incluye.
+// FDs e IO son nil si no se pudieron leer, lo habitual cuando Filebeat corre
+// como otro usuario.
+type ProcessStats struct {
+	PID     int32      `json:"pid"`
+	Threads int32      `json:"threads"`
+	FDs     *int32     `json:"fds,omitempty"`
+	IO      *ProcessIO `json:"io,omitempty"`
+	// Blocked es la cantidad de hilos en estado D (esperando E/S sin
+	// poder interrumpirse), típico de un disco o NFS que no responde
+	Blocked int `json:"blocked_threads"`
+}
+
+// ProcessIO son los bytes leídos y escritos en disco por segundo
+type ProcessIO struct {
+	ReadPerSec  float64 `json:"read_bytes_per_sec"`
+	WritePerSec float64 `json:"write_bytes_per_sec"`
+}
+
+// processMonitor sigue al proceso de Filebeat. Si no se indicó el PID se
+// busca el proceso que escucha en el puerto de la API o, si no se puede
+// saber, uno llamado filebeat; se vuelve a buscar si el proceso termina.
+type processMonitor struct {
+	pid  int32
+	port int
+
+	proc   *process.Process
+	prevIO *process.IOCountersStat
+	prevAt time.Time
+}
+
+func (m *processMonitor) collect(ctx context.Context) (*ProcessStats, error) {
+	if m.proc != nil {
+		if running, _ := m.proc.IsRunningWithContext(ctx); !running {
+			m.proc, m.prevIO = nil, nil
+		}
+	}
+	if m.proc == nil {
+		proc, err := m.find(ctx)
+		if err != nil {
+			return nil, err
+		}
+		m.proc = proc
+	}
+
+	now := time.Now()
+	stats := &ProcessStats{PID: m.proc.Pid}
+	threads, err := m.proc.NumThreadsWithContext(ctx)
+	if err != nil {
+		m.proc = nil
+		return nil, err
+	}
+	stats.Threads = threads
+	if fds, err := m.proc.NumFDsWithContext(ctx); err == nil {
+		stats.FDs = &fds
+	}
+
+	// /proc/<pid>/io solo lo puede leer el dueño del proceso o root
+	counters, _ := m.proc.IOCountersWithContext(ctx)
+	if counters != nil && m.prevIO != nil && counters.ReadBytes >= m.prevIO.ReadBytes && counters.WriteBytes >= m.prevIO.WriteBytes {
+		elapsed := now.Sub(m.prevAt).Seconds()
+		stats.IO = &ProcessIO{
+			ReadPerSec:  float64(counters.ReadBytes-m.prevIO.ReadBytes) / elapsed,
+			WritePerSec: float64(counters.WriteBytes-m.prevIO.WriteBytes) / elapsed,
+		}
+	}
+	m.prevIO, m.prevAt = counters, now
+
+	stats.Blocked = blockedThreads(ctx, m.proc)
+	return stats, nil
+}
+
+func (m *processMonitor) find(ctx context.Context) (*process.Process, error) {
+	if m.pid != 0 {
+		proc, err := process.NewProcessWithContext(ctx, m.pid)
+		if err != nil {
+			return nil, fmt.Errorf("PID %d: %w", m.pid, err)
+		}
+		return proc, nil
+	}
+
+	// Sin permisos para ver los sockets de otros usuarios no aparece el
+	// PID y se busca por nombre
+	if conns, err := net.ConnectionsWithContext(ctx, "tcp"); err == nil {
+		for _, conn := range conns {
+			if conn.Status == "LISTEN" && int(conn.Laddr.Port) == m.port && conn.Pid != 0 {
+				return process.NewProcessWithContext(ctx, conn.Pid)
+			}
+		}
+	}
+
+	procs, err := process.ProcessesWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, proc := range procs {
+		if name, err := proc.NameWithContext(ctx); err == nil && name == beatProcessName {
+			return proc, nil
+		}
+	}
+	return nil, errors.New("no se encontró el proceso de Filebeat")
+}
+
+// blockedThreads cuenta los hilos en estado D. En Linux se revisa cada hilo
+// en /proc, porque en un programa Go el hilo principal rara vez es el que
+// queda bloqueado; en otros sistemas solo se ve el estado del proceso.
+func blockedThreads(ctx context.Context, proc *process.Process) int {
+	dir := filepath.Join("/proc", strconv.Itoa(int(proc.Pid)), "task")
+	tasks, err := os.ReadDir(dir)
+	if err != nil {
+		status, err := proc.StatusWithContext(ctx)
+		if err == nil && len(status) > 0 && status[0] == process.Blocked {
+			return 1
+		}
+		return 0
+	}
+
+	blocked := 0
+	for _, task := range tasks {
+		stat, err := os.ReadFile(filepath.Join(dir, task.Name(), "stat"))
+		if err != nil {
+			continue
+		}
+		// El estado va después del nombre entre paréntesis, que puede
+		// contener espacios
+		fields := strings.Fields(string(stat[strings.LastIndexByte(string(stat), ')')+1:]))
+		if len(fields) > 0 && fields[0] == "D" {
+			blocked++
+		}
+	}
+	return blocked
+}
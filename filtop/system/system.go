@@ -0,0 +1,180 @@
+// Package system obtiene métricas del host donde corre filtop (CPU,
+// memoria y los sistemas de archivos de los logs), porque muchos problemas
+// de Filebeat son en realidad falta de recursos de la máquina.
+package system
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/shirou/gopsutil/v4/cpu"
+	"github.com/shirou/gopsutil/v4/disk"
+	"github.com/shirou/gopsutil/v4/mem"
+)
+
+// Stats es una muestra del host. Las tasas de disco valen 0 en la primera
+// muestra.
+type Stats struct {
+	Time        time.Time    `json:"time"`
+	CPUPercent  float64      `json:"cpu_percent"`
+	MemoryUsed  uint64       `json:"memory_used"`
+	MemoryTotal uint64       `json:"memory_total"`
+	Filesystems []Filesystem `json:"filesystems"`
+	Paths       []PathUsage  `json:"paths"`
+	// Process es el proceso de Filebeat si se sigue con WatchProcess;
+	// ProcessError indica por qué no se pudo leer
+	Process      *ProcessStats `json:"process,omitempty"`
+	ProcessError string        `json:"process_error,omitempty"`
+}
+
+// Filesystem es el sistema de archivos que contiene una o más de las rutas
+// monitoreadas. IOKnown es false si no se encontró el dispositivo (p. ej.
+// overlay en un contenedor).
+type Filesystem struct {
+	Paths             []string `json:"paths"`
+	Mountpoint        string   `json:"mountpoint"`
+	Device            string   `json:"device"`
+	UsedPercent       float64  `json:"used_percent"`
+	FreeBytes         uint64   `json:"free_bytes"`
+	InodesUsedPercent float64  `json:"inodes_used_percent"`
+	IOKnown           bool     `json:"io_known"`
+	ReadPerSec        float64  `json:"read_bytes_per_sec"`
+	WritePerSec       float64  `json:"write_bytes_per_sec"`
+}
+
+// Collector toma muestras del host. Recuerda los contadores de disco de la
+// muestra anterior para calcular las tasas; no es seguro usarlo desde
+// varias goroutines.
+type Collector struct {
+	paths  []string
+	prevIO map[string]disk.IOCountersStat
+	prevAt time.Time
+	// Tamaño de cada ruta en la muestra anterior
+	prevSizes map[string]uint64
+	beat      *processMonitor
+}
+
+// New crea un Collector para los sistemas de archivos que contienen paths
+func New(paths []string) *Collector {
+	return &Collector{paths: paths}
+}
+
+// WatchProcess agrega a cada muestra los datos del proceso de Filebeat: el
+// de pid o, si es 0, el que escucha en port.
+func (c *Collector) WatchProcess(pid int32, port int) {
+	c.beat = &processMonitor{pid: pid, port: port}
+}
+
+// Collect toma una muestra. Los errores de un sistema de archivos concreto
+// no invalidan la muestra: ese sistema de archivos se omite.
+func (c *Collector) Collect(ctx context.Context) (*Stats, error) {
+	now := time.Now()
+	stats := &Stats{Time: now}
+
+	percent, err := cpu.PercentWithContext(ctx, 0, false)
+	if err != nil {
+		return nil, err
+	}
+	if len(percent) > 0 {
+		stats.CPUPercent = percent[0]
+	}
+	vm, err := mem.VirtualMemoryWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	stats.MemoryUsed, stats.MemoryTotal = vm.Used, vm.Total
+
+	partitions, err := disk.PartitionsWithContext(ctx, true)
+	if err != nil {
+		return nil, err
+	}
+	stats.Filesystems = filesystems(c.paths, partitions)
+
+	var devices []string
+	for i := range stats.Filesystems {
+		fs := &stats.Filesystems[i]
+		if usage, err := disk.UsageWithContext(ctx, fs.Mountpoint); err == nil {
+			fs.UsedPercent = usage.UsedPercent
+			fs.FreeBytes = usage.Free
+			fs.InodesUsedPercent = usage.InodesUsedPercent
+		}
+		devices = append(devices, fs.Device)
+	}
+
+	// Sin contadores de E/S (p. ej. sin /proc/diskstats) solo faltan las tasas
+	counters, _ := disk.IOCountersWithContext(ctx, devices...)
+	elapsed := now.Sub(c.prevAt).Seconds()
+	for i := range stats.Filesystems {
+		fs := &stats.Filesystems[i]
+		curr, ok := counters[fs.Device]
+		fs.IOKnown = ok
+		prev, seen := c.prevIO[fs.Device]
+		// Un contador menor que el anterior indica que el dispositivo se reinició
+		if ok && seen && elapsed > 0 && curr.ReadBytes >= prev.ReadBytes && curr.WriteBytes >= prev.WriteBytes {
+			fs.ReadPerSec = float64(curr.ReadBytes-prev.ReadBytes) / elapsed
+			fs.WritePerSec = float64(curr.WriteBytes-prev.WriteBytes) / elapsed
+		}
+	}
+	stats.Paths = c.pathUsages(stats.Filesystems, elapsed)
+	c.prevIO, c.prevAt = counters, now
+
+	if c.beat != nil {
+		stats.Process, err = c.beat.collect(ctx)
+		if err != nil {
+			stats.ProcessError = err.Error()
+		}
+	}
+	return stats, nil
+}
+
+// filesystems agrupa paths según el punto de montaje más largo que los
+// contiene, en el orden de la configuración.
+func filesystems(paths []string, partitions []disk.PartitionStat) []Filesystem {
+	var result []Filesystem
+	index := make(map[string]int)
+	for _, path := range paths {
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			continue
+		}
+		var best *disk.PartitionStat
+		for i := range partitions {
+			p := &partitions[i]
+			if contains(p.Mountpoint, abs) && (best == nil || len(p.Mountpoint) > len(best.Mountpoint)) {
+				best = p
+			}
+		}
+		if best == nil {
+			continue
+		}
+		if i, ok := index[best.Mountpoint]; ok {
+			result[i].Paths = append(result[i].Paths, path)
+			continue
+		}
+		index[best.Mountpoint] = len(result)
+		result = append(result, Filesystem{
+			Paths:      []string{path},
+			Mountpoint: best.Mountpoint,
+			Device:     deviceName(best.Device),
+		})
+	}
+	return result
+}
+
+func contains(mountpoint, path string) bool {
+	if mountpoint == "/" || mountpoint == path {
+		return true
+	}
+	return strings.HasPrefix(path, mountpoint+"/")
+}
+
+// deviceName devuelve el nombre con el que el kernel lleva los contadores
+// de E/S: /dev/mapper/vg-root es en realidad dm-0.
+func deviceName(device string) string {
+	if resolved, err := filepath.EvalSymlinks(device); err == nil {
+		device = resolved
+	}
+	return filepath.Base(device)
+}
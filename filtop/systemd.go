@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"net"
+	"os"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/iTiagoCO/filtop/filtop/metrics"
+)
+
+// Con Type=notify en la unidad de systemd, filtop serve y filtop watch
+// avisan cuándo están listos (READY=1), cuándo recargan la configuración
+// (RELOADING=1) y cuándo se apagan (STOPPING=1). Si la unidad tiene
+// WatchdogSec= envían WATCHDOG=1 mientras el ciclo de consultas avance: si
+// se traba, systemd deja de recibirlo y reinicia el servicio. Fuera de
+// systemd (sin NOTIFY_SOCKET) no hacen nada.
+
+// sdNotify envía state al socket de notificación de systemd
+func sdNotify(state string) {
+	socket := os.Getenv("NOTIFY_SOCKET")
+	if socket == "" {
+		return
+	}
+	// Un socket abstracto se indica con @
+	if socket[0] == '@' {
+		socket = "\x00" + socket[1:]
+	}
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		slog.Warn("Error avisando a systemd", "state", state, "err", err)
+		return
+	}
+	defer conn.Close()
+	if _, err := conn.Write([]byte(state)); err != nil {
+		slog.Warn("Error avisando a systemd", "state", state, "err", err)
+	}
+}
+
+// watchdogInterval devuelve WatchdogSec= de la unidad; 0 si no tiene o el
+// watchdog es de otro proceso
+func watchdogInterval() time.Duration {
+	usec, err := strconv.ParseInt(os.Getenv("WATCHDOG_USEC"), 10, 64)
+	if err != nil || usec <= 0 {
+		return 0
+	}
+	if pid := os.Getenv("WATCHDOG_PID"); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		return 0
+	}
+	return time.Duration(usec) * time.Microsecond
+}
+
+// heartbeat registra cuándo terminó el último ciclo de consultas, con éxito
+// o con error
+type heartbeat struct {
+	last atomic.Int64
+}
+
+func newHeartbeat() *heartbeat {
+	h := &heartbeat{}
+	h.beat()
+	return h
+}
+
+func (h *heartbeat) beat() { h.last.Store(time.Now().UnixNano()) }
+
+func (h *heartbeat) since() time.Duration {
+	return time.Since(time.Unix(0, h.last.Load()))
+}
+
+// watchdogStall es cuánto puede tardar un ciclo sin considerarlo trabado:
+// el intervalo más, por cada beat que se consulta uno tras otro, detectar
+// su versión y consultarlo con todos los reintentos
+func watchdogStall(timeout time.Duration, retries, beats int) time.Duration {
+	return 2*refresh + 2*timeout*time.Duration((retries+1)*beats)
+}
+
+// runWatchdog envía WATCHDOG=1 a la mitad de WatchdogSec= mientras h haya
+// latido en los últimos stall, hasta que se cancela ctx
+func runWatchdog(ctx context.Context, h *heartbeat, stall time.Duration) {
+	interval := watchdogInterval()
+	if interval == 0 {
+		return
+	}
+	ticker := time.NewTicker(interval / 2)
+	defer ticker.Stop()
+	stalled := false
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if since := h.since(); since > stall {
+			if !stalled {
+				slog.Error("El ciclo de consultas no avanza: se deja de avisar al watchdog de systemd", "since", since.Round(time.Second))
+			}
+			stalled = true
+			continue
+		}
+		stalled = false
+		sdNotify("WATCHDOG=1")
+	}
+}
+
+// heartbeatSink además registra en heartbeat cada ciclo de consultas
+type heartbeatSink struct {
+	sink
+	heartbeat *heartbeat
+}
+
+func (h heartbeatSink) Sample(tab int, sample metrics.Sample, derived derivedValues) {
+	h.heartbeat.beat()
+	h.sink.Sample(tab, sample, derived)
+}
+
+func (h heartbeatSink) StatsError(tab int, err error) {
+	h.heartbeat.beat()
+	h.sink.StatsError(tab, err)
+}
@@ -0,0 +1,171 @@
+// Package telemetry cuenta las métricas propias de filtop (las consultas a
+// cada beat y su latencia, más las que se registren con CounterFunc y
+// GaugeFunc) y las expone con las del runtime de Go en /metrics, en el
+// formato de texto de Prometheus, para monitorear al propio monitor.
+package telemetry
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Límites de los buckets del histograma de latencia, en segundos
+var latencyBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// Registry acumula las métricas. Es seguro usarlo desde varias goroutines.
+type Registry struct {
+	start time.Time
+
+	mu      sync.Mutex
+	targets map[string]*targetStats
+	funcs   []metricFunc
+}
+
+// targetStats son las consultas a un beat
+type targetStats struct {
+	success, failure uint64
+	// buckets cuenta las consultas de cada límite de latencyBuckets, sin
+	// acumular; sum es la suma de sus latencias
+	buckets []uint64
+	sum     float64
+}
+
+// metricFunc es una métrica que se lee al servir /metrics
+type metricFunc struct {
+	name, help, kind string
+	value            func() float64
+}
+
+// New crea un Registry vacío
+func New() *Registry {
+	return &Registry{start: time.Now(), targets: make(map[string]*targetStats)}
+}
+
+// ObserveScrape registra una consulta a target que tardó duration; err es
+// su error o nil si respondió
+func (r *Registry) ObserveScrape(target string, duration time.Duration, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stats := r.targets[target]
+	if stats == nil {
+		stats = &targetStats{buckets: make([]uint64, len(latencyBuckets)+1)}
+		r.targets[target] = stats
+	}
+	if err != nil {
+		stats.failure++
+	} else {
+		stats.success++
+	}
+	seconds := duration.Seconds()
+	stats.buckets[sort.SearchFloat64s(latencyBuckets, seconds)]++
+	stats.sum += seconds
+}
+
+// CounterFunc registra un contador que se lee de value en cada consulta a
+// /metrics
+func (r *Registry) CounterFunc(name, help string, value func() float64) {
+	r.addFunc(metricFunc{name: name, help: help, kind: "counter", value: value})
+}
+
+// GaugeFunc registra un valor que se lee de value en cada consulta a
+// /metrics
+func (r *Registry) GaugeFunc(name, help string, value func() float64) {
+	r.addFunc(metricFunc{name: name, help: help, kind: "gauge", value: value})
+}
+
+func (r *Registry) addFunc(f metricFunc) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.funcs = append(r.funcs, f)
+}
+
+// Handler sirve /metrics
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		r.WriteTo(w)
+	})
+}
+
+// WriteTo escribe las métricas en el formato de texto de Prometheus
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	var b strings.Builder
+	r.mu.Lock()
+	names := make([]string, 0, len(r.targets))
+	for name := range r.targets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	header(&b, "filtop_scrapes_total", "counter", "Consultas a /stats de cada beat, por resultado")
+	for _, name := range names {
+		stats := r.targets[name]
+		fmt.Fprintf(&b, "filtop_scrapes_total{target=%s,result=\"success\"} %d\n", quote(name), stats.success)
+		fmt.Fprintf(&b, "filtop_scrapes_total{target=%s,result=\"failure\"} %d\n", quote(name), stats.failure)
+	}
+	header(&b, "filtop_scrape_duration_seconds", "histogram", "Latencia de las consultas a /stats de cada beat")
+	for _, name := range names {
+		stats := r.targets[name]
+		var cumulative uint64
+		for i, le := range latencyBuckets {
+			cumulative += stats.buckets[i]
+			fmt.Fprintf(&b, "filtop_scrape_duration_seconds_bucket{target=%s,le=\"%s\"} %d\n", quote(name), formatFloat(le), cumulative)
+		}
+		count := stats.success + stats.failure
+		fmt.Fprintf(&b, "filtop_scrape_duration_seconds_bucket{target=%s,le=\"+Inf\"} %d\n", quote(name), count)
+		fmt.Fprintf(&b, "filtop_scrape_duration_seconds_sum{target=%s} %s\n", quote(name), formatFloat(stats.sum))
+		fmt.Fprintf(&b, "filtop_scrape_duration_seconds_count{target=%s} %d\n", quote(name), count)
+	}
+	funcs := append([]metricFunc(nil), r.funcs...)
+	r.mu.Unlock()
+
+	// Fuera del lock: value puede tomar el de otro paquete
+	for _, f := range funcs {
+		header(&b, f.name, f.kind, f.help)
+		fmt.Fprintf(&b, "%s %s\n", f.name, formatFloat(f.value()))
+	}
+
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	gauge := func(name, help string, value float64) {
+		header(&b, name, "gauge", help)
+		fmt.Fprintf(&b, "%s %s\n", name, formatFloat(value))
+	}
+	gauge("filtop_start_time_seconds", "Inicio de filtop, en segundos desde la época Unix", float64(r.start.UnixNano())/1e9)
+	gauge("go_goroutines", "Goroutines en ejecución", float64(runtime.NumGoroutine()))
+	gauge("go_memstats_heap_alloc_bytes", "Bytes del heap en uso", float64(mem.HeapAlloc))
+	gauge("go_memstats_sys_bytes", "Bytes obtenidos del sistema operativo", float64(mem.Sys))
+	header(&b, "go_gc_cycles_total", "counter", "Ciclos completos del recolector de basura")
+	fmt.Fprintf(&b, "go_gc_cycles_total %d\n", mem.NumGC)
+
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
+
+func header(b *strings.Builder, name, kind, help string) {
+	fmt.Fprintf(b, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+// quote escapa un valor de etiqueta
+func quote(value string) string {
+	value = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`).Replace(value)
+	return `"` + value + `"`
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
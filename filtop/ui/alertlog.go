@@ -0,0 +1,141 @@
+package ui
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/iTiagoCO/filtop/filtop/alerts"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// Página Alerts: historial de las alertas que se activaron y resolvieron,
+// de la más reciente a la más antigua, para revisar después lo que pasó
+// (p. ej. si la cola se llenó durante la noche). La tecla e lo exporta a
+// CSV. Las transiciones silenciadas, que no se notificaron, se ven en gris.
+
+var (
+	alertsTable *tview.Table
+	alertsHelp  *tview.TextView
+	// alertsExport es el resultado de la última exportación
+	alertsExport string
+)
+
+func showAlertsPage() {
+	if options.AlertLog == nil {
+		return
+	}
+	alertsTable = tview.NewTable().SetFixed(1, 0).SetSelectable(true, false)
+	alertsTable.SetBorder(true)
+	alertsHelp = tview.NewTextView().SetDynamicColors(true)
+	page := tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(alertsHelp, 1, 0, false).
+		AddItem(alertsTable, 0, 1, true)
+	page.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() == tcell.KeyRune && event.Rune() == 'e' {
+			exportAlerts()
+			updateAlertsPage()
+			return nil
+		}
+		return event
+	})
+
+	alertsExport = ""
+	pages.AddPage("alerts", page, true, true)
+	pages.SwitchToPage("alerts")
+	updateAlertsPage()
+}
+
+func updateAlertsPage() {
+	if alertsTable == nil {
+		return
+	}
+	if name, _ := pages.GetFrontPage(); name != "alerts" {
+		return
+	}
+
+	headers := []string{"Hora", "Regla", "Severidad", "Evento", "Valor", "Duración"}
+	if multipleTabs() {
+		headers = append([]string{"Target"}, headers...)
+	}
+	for col, header := range headers {
+		setCell(alertsTable, 0, col, header, tcell.ColorYellow)
+	}
+
+	records := options.AlertLog.Records()
+	for i := range records {
+		// La más reciente arriba
+		record := records[len(records)-1-i]
+		cells := alertCells(record)
+		if multipleTabs() {
+			cells = append([]string{tview.Escape(record.Target)}, cells...)
+		}
+		color := tcell.GetColor(severityColor(record.Severity))
+		switch {
+		case record.Silenced:
+			color = tcell.ColorGray
+		case !record.Raised:
+			color = tcell.ColorGreen
+		}
+		for col, text := range cells {
+			setCell(alertsTable, i+1, col, text, color)
+		}
+	}
+	for row := alertsTable.GetRowCount() - 1; row > len(records); row-- {
+		alertsTable.RemoveRow(row)
+	}
+	alertsTable.SetTitle(fmt.Sprintf(" Historial de alertas (%d) ", len(records)))
+	alertsHelp.SetText(" [yellow]e[-]: exportar a CSV · [yellow]Esc[-]: volver" + alertsExport)
+}
+
+// alertCells son las columnas de una transición; la duración es la de la
+// alerta que se resolvió
+func alertCells(record alerts.Record) []string {
+	event, duration := "activada", "-"
+	if !record.Raised {
+		event, duration = "resuelta", formatAgo(record.At.Sub(record.Since))
+	}
+	if record.Silenced {
+		event += " (silenciada)"
+	}
+	value := "-"
+	if record.Value != nil {
+		value = formatComputed(*record.Value)
+	}
+	return []string{
+		record.At.Local().Format("01-02 15:04:05"),
+		tview.Escape(record.Rule),
+		record.Severity,
+		event,
+		value,
+		duration,
+	}
+}
+
+// exportAlerts escribe el historial en un CSV del directorio actual
+func exportAlerts() {
+	path, err := filepath.Abs(fmt.Sprintf("filtop-alerts-%s.csv", time.Now().Format("20060102-150405")))
+	if err == nil {
+		err = writeAlertsCSV(path)
+	}
+	if err != nil {
+		alertsExport = " · [red]error exportando: " + tview.Escape(err.Error()) + "[-]"
+		return
+	}
+	alertsExport = " · [green]exportado a " + tview.Escape(path) + "[-]"
+}
+
+func writeAlertsCSV(path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := alerts.WriteCSV(file, options.AlertLog.Records()); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
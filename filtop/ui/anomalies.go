@@ -0,0 +1,70 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/iTiagoCO/filtop/filtop/metrics"
+
+	"github.com/rivo/tview"
+)
+
+// Banner bajo la cabecera con los inputs cuyo ritmo de eventos se apartó de
+// su línea base. Solo ocupa lugar mientras haya alguno.
+
+// Inputs que se listan como mucho; el resto se resume
+const maxAnomalyLines = 3
+
+func createAnomalyBanner() *tview.TextView {
+	return tview.NewTextView().SetDynamicColors(true)
+}
+
+// UpdateAnomalies muestra las anomalías en curso de la pestaña tab
+func UpdateAnomalies(tab int, anomalies []metrics.Anomaly) {
+	queueUpdate(func() {
+		tabStates[tab].anomalies = anomalies
+		if tab == activeTab {
+			showAnomalies(anomalies)
+		}
+	})
+}
+
+func showAnomalies(anomalies []metrics.Anomaly) {
+	if layout.banner == nil {
+		return
+	}
+	lines := make([]string, 0, maxAnomalyLines)
+	for i, anomaly := range anomalies {
+		if i == maxAnomalyLines-1 && len(anomalies) > maxAnomalyLines {
+			lines = append(lines, fmt.Sprintf("[yellow]⚠ y %d inputs más[-]", len(anomalies)-i))
+			break
+		}
+		lines = append(lines, anomalyText(anomaly, time.Now()))
+	}
+	setText(layout.banner, strings.Join(lines, "\n"))
+	layout.root.ResizeItem(layout.banner, len(lines), 0)
+}
+
+func anomalyText(anomaly metrics.Anomaly, now time.Time) string {
+	ago := formatAgo(now.Sub(anomaly.Since))
+	input := tview.Escape(anomaly.Input)
+	switch anomaly.Kind {
+	case metrics.AnomalyStopped:
+		return fmt.Sprintf("[red]⚠ input %s dejó de producir eventos hace %s[-]", input, ago)
+	case metrics.AnomalyDrop:
+		return fmt.Sprintf("[yellow]⚠ input %s cayó a %.1f ev/s (línea base %.1f ev/s) hace %s[-]", input, anomaly.Rate, anomaly.Baseline, ago)
+	}
+	return fmt.Sprintf("[yellow]⚠ input %s subió a %.1f ev/s (línea base %.1f ev/s) hace %s[-]", input, anomaly.Rate, anomaly.Baseline, ago)
+}
+
+// formatAgo redondea una duración corta a algo legible ("40s", "4m", "2h")
+func formatAgo(d time.Duration) string {
+	switch {
+	case d < time.Minute:
+		return fmt.Sprintf("%ds", int(d.Seconds()))
+	case d < time.Hour:
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	}
+	return formatETA(d)
+}
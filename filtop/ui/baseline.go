@@ -0,0 +1,145 @@
+package ui
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+	"time"
+
+	"github.com/iTiagoCO/filtop/filtop/baseline"
+)
+
+// Modo comparación: con la tecla b se captura una línea base durante
+// baselineCapture y con c cada valor de los paneles se muestra junto con
+// su desviación respecto de ella.
+
+const baselineCapture = time.Minute
+
+// Desviación relativa a partir de la cual se resalta un valor
+const (
+	deviationNotice = 0.1
+	deviationLarge  = 0.5
+)
+
+var (
+	// liveValues son los valores mostrados en la última muestra, con las
+	// claves de la línea base
+	liveValues = make(map[string]float64)
+	// reference es la línea base con la que se compara si compareMode
+	reference   *baseline.Baseline
+	compareMode bool
+	// recorder no es nil mientras se captura una línea base
+	recorder     *baseline.Recorder
+	captureUntil time.Time
+	// baselineError es el último error al leer o guardar la línea base
+	baselineError string
+)
+
+// compared registra v como el valor actual de key y, en modo comparación,
+// devuelve la desviación respecto de la línea base para agregar al texto.
+func compared(key string, v float64) string {
+	liveValues[key] = v
+	if !compareMode || reference == nil {
+		return ""
+	}
+	base, ok := reference.Value(key)
+	if !ok || math.IsNaN(v) {
+		return ""
+	}
+	if base == 0 {
+		if v == 0 {
+			return " [gray](=)[-]"
+		}
+		return " [yellow](antes 0)[-]"
+	}
+	deviation := (v - base) / math.Abs(base)
+	color := "gray"
+	switch {
+	case math.Abs(deviation) >= deviationLarge:
+		color = "red"
+	case math.Abs(deviation) >= deviationNotice:
+		color = "yellow"
+	}
+	return fmt.Sprintf(" [%s](%+.0f%%)[-]", color, deviation*100)
+}
+
+// startCapture empieza a capturar una línea base con las próximas muestras
+func startCapture() {
+	if options.BaselinePath == "" {
+		baselineError = "no hay dónde guardar la línea base"
+		return
+	}
+	recorder = baseline.NewRecorder()
+	captureUntil = time.Now().Add(baselineCapture)
+	baselineError = ""
+	if current.Stats != nil {
+		updateHeader()
+	}
+}
+
+// recordBaseline suma la muestra mostrada a la captura en curso y, al
+// terminar, guarda la línea base y la usa para comparar.
+func recordBaseline() {
+	if recorder == nil {
+		return
+	}
+	recorder.Add(liveValues)
+	if time.Now().Before(captureUntil) {
+		return
+	}
+	captured := recorder.Baseline(beatVersion(), time.Now())
+	recorder = nil
+	// El log escribe en la página Logs a través de la cola de tview, que
+	// no se puede usar desde su propia goroutine
+	go saveBaseline(captured, options.BaselinePath)
+}
+
+func saveBaseline(captured *baseline.Baseline, path string) {
+	err := captured.Save(path)
+	if err != nil {
+		slog.Error("Error guardando la línea base", "path", path, "err", err)
+	} else {
+		slog.Info("Línea base guardada", "path", path, "samples", captured.Samples)
+	}
+	queueUpdate(func() {
+		if err != nil {
+			baselineError = err.Error()
+		} else {
+			reference = captured
+		}
+		if current.Stats != nil {
+			updateHeader()
+		}
+	})
+}
+
+// setCompare activa o desactiva el modo comparación, leyendo la línea base
+// del archivo si todavía no se capturó ninguna.
+func setCompare(enabled bool) {
+	compareMode = enabled
+	if !enabled || reference != nil {
+		return
+	}
+	loaded, err := baseline.Load(options.BaselinePath)
+	if err != nil {
+		baselineError = err.Error()
+		compareMode = false
+		return
+	}
+	reference, baselineError = loaded, ""
+}
+
+// baselineSummary es el estado de la captura o de la comparación para la
+// cabecera
+func baselineSummary() string {
+	switch {
+	case baselineError != "":
+		return " | [red]línea base: " + baselineError + "[-]"
+	case recorder != nil:
+		left := time.Until(captureUntil).Round(time.Second)
+		return fmt.Sprintf(" | [aqua]capturando línea base (%s)[-]", max(left, 0))
+	case compareMode && reference != nil:
+		return fmt.Sprintf(" | [aqua]vs. línea base %s (%s)[-]", reference.CapturedAt.Local().Format("01-02 15:04"), reference.Version)
+	}
+	return ""
+}
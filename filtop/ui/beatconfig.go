@@ -0,0 +1,471 @@
+package ui
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+	"gopkg.in/yaml.v3"
+)
+
+// Página filebeat.yml (tecla y): la configuración local de Filebeat, de solo
+// lectura y con colores, con notas junto a los valores que explican lo que
+// se observa, p. ej. el llenado de la cola junto a queue.mem.events o los
+// inputs filestream sin id, que releen los archivos. r vuelve a leer el
+// archivo.
+
+// Valores por defecto de Filebeat 8 que se usan si el archivo no los define
+const (
+	defaultQueueEvents  = 3200
+	defaultOutputWorker = 1
+)
+
+// defaultBulkMaxSize es el bulk_max_size por defecto de cada salida
+var defaultBulkMaxSize = map[string]int{"elasticsearch": 1600, "logstash": 2048, "redis": 2048}
+
+// Muestras con que se calculan las tasas y el tamaño de los lotes de las
+// notas
+const configRateSamples = 10
+
+var (
+	beatConfigTable *tview.Table
+	beatConfigHelp  *tview.TextView
+	beatConfigNotes *tview.TextView
+	beatConfigPage  *tview.Flex
+	// beatConfig es el archivo leído al abrir la página
+	beatConfig *filebeatConfig
+)
+
+// configSetting es una clave del archivo con su línea y, si es un escalar,
+// su valor
+type configSetting struct {
+	line  int
+	value string
+}
+
+// filebeatConfig es filebeat.yml con cada clave por su ruta con puntos, p.
+// ej. "queue.mem.events" o "filebeat.inputs[0].id", se escriba anidada o
+// con puntos
+type filebeatConfig struct {
+	lines    []string
+	settings map[string]configSetting
+	// err es el error al leer o interpretar el archivo
+	err error
+}
+
+func loadFilebeatConfig(path string) *filebeatConfig {
+	config := &filebeatConfig{settings: make(map[string]configSetting)}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		config.err = err
+		return config
+	}
+	text := strings.ReplaceAll(string(data), "\r\n", "\n")
+	config.lines = strings.Split(strings.TrimRight(text, "\n"), "\n")
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		config.err = err
+		return config
+	}
+	config.walk("", &root)
+	return config
+}
+
+func (c *filebeatConfig) walk(path string, node *yaml.Node) {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		for _, child := range node.Content {
+			c.walk(path, child)
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			child := key.Value
+			if path != "" {
+				child = path + "." + child
+			}
+			// Con claves con puntos (output.elasticsearch.hosts) también
+			// se registran las intermedias
+			for i := len(path) + 1; i < len(child); i++ {
+				if child[i] == '.' {
+					if _, ok := c.settings[child[:i]]; !ok {
+						c.settings[child[:i]] = configSetting{line: key.Line}
+					}
+				}
+			}
+			setting := configSetting{line: key.Line}
+			if value.Kind == yaml.ScalarNode {
+				setting.value = value.Value
+			}
+			c.settings[child] = setting
+			c.walk(child, value)
+		}
+	case yaml.SequenceNode:
+		for i, item := range node.Content {
+			child := fmt.Sprintf("%s[%d]", path, i)
+			c.settings[child] = configSetting{line: item.Line, value: item.Value}
+			c.walk(child, item)
+		}
+	}
+}
+
+// number es el valor entero de la clave path; ok es false si no está o no
+// es un número (p. ej. una variable ${...})
+func (c *filebeatConfig) number(path string) (n int, ok bool) {
+	setting, found := c.settings[path]
+	if !found {
+		return 0, false
+	}
+	n, err := strconv.Atoi(setting.value)
+	return n, err == nil
+}
+
+// output es el nombre de la salida configurada, p. ej. "elasticsearch"
+func (c *filebeatConfig) output() string {
+	for path := range c.settings {
+		name, ok := strings.CutPrefix(path, "output.")
+		if !ok || strings.Contains(name, ".") {
+			continue
+		}
+		if c.settings["output."+name+".enabled"].value != "false" {
+			return name
+		}
+	}
+	return ""
+}
+
+func showBeatConfigPage() {
+	beatConfigTable = tview.NewTable().SetSelectable(true, false)
+	beatConfigTable.SetBorder(true)
+	beatConfigHelp = tview.NewTextView().SetDynamicColors(true).
+		SetText(" [yellow]r[-]: volver a leer el archivo · [yellow]Esc[-]: volver")
+	beatConfigNotes = tview.NewTextView().SetDynamicColors(true)
+	beatConfigPage = tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(beatConfigHelp, 1, 0, false).
+		AddItem(beatConfigNotes, 0, 0, false).
+		AddItem(beatConfigTable, 0, 1, true)
+	beatConfigPage.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() == tcell.KeyRune && event.Rune() == 'r' {
+			readBeatConfig()
+			return nil
+		}
+		return event
+	})
+
+	pages.AddPage("beatconfig", beatConfigPage, true, true)
+	pages.SwitchToPage("beatconfig")
+	readBeatConfig()
+}
+
+// readBeatConfig lee el archivo y vuelve a mostrarlo
+func readBeatConfig() {
+	beatConfigTable.Clear()
+	beatConfig = nil
+	if options.FilebeatConfigPath != "" {
+		beatConfig = loadFilebeatConfig(options.FilebeatConfigPath)
+	}
+	updateBeatConfigPage()
+	beatConfigTable.Select(0, 0)
+}
+
+func updateBeatConfigPage() {
+	if beatConfigTable == nil {
+		return
+	}
+	if name, _ := pages.GetFrontPage(); name != "beatconfig" {
+		return
+	}
+	if beatConfig == nil {
+		beatConfigTable.SetTitle(" filebeat.yml ")
+		setCell(beatConfigTable, 0, 0, "Usa -filebeat-config con la ruta del filebeat.yml de Filebeat", tcell.ColorGray)
+		return
+	}
+
+	hints, notes := configHints(beatConfig)
+	for i, line := range beatConfig.lines {
+		text := highlightYAML(line)
+		if hint := hints[i+1]; len(hint) > 0 {
+			text += "  [gray]←[-] " + strings.Join(hint, " [gray]·[-] ")
+		}
+		setCell(beatConfigTable, i, 0, strconv.Itoa(i+1), tcell.ColorGray)
+		beatConfigTable.GetCell(i, 0).SetAlign(tview.AlignRight)
+		setCell(beatConfigTable, i, 1, text, tcell.ColorWhite)
+	}
+	if beatConfig.err != nil {
+		notes = append([]string{"[red]" + tview.Escape(beatConfig.err.Error()) + "[-]"}, notes...)
+	}
+	setText(beatConfigNotes, strings.Join(notes, "\n"))
+	beatConfigPage.ResizeItem(beatConfigNotes, len(notes), 0)
+	beatConfigTable.SetTitle(fmt.Sprintf(" filebeat.yml: %s (%d líneas) ", tview.Escape(options.FilebeatConfigPath), len(beatConfig.lines)))
+}
+
+// configHints son las notas de cada línea del archivo según las últimas
+// métricas, y las de lo que el archivo no define
+func configHints(config *filebeatConfig) (hints map[int][]string, notes []string) {
+	hints = make(map[int][]string)
+	add := func(path, hint string) {
+		if setting, ok := config.settings[path]; ok {
+			hints[setting.line] = append(hints[setting.line], hint)
+		}
+	}
+
+	switch setting, ok := config.settings["http.enabled"]; {
+	case !ok:
+		notes = append(notes, "[yellow]http.enabled no está: Filebeat no expone /stats salvo que se active por línea de comandos[-]")
+	case setting.value == "false":
+		add("http.enabled", "[red]sin el endpoint HTTP filtop no puede leer las métricas[-]")
+	}
+	if current.Stats == nil {
+		// Las notas de los inputs no dependen de las métricas
+		inputHints(config, add)
+		return hints, notes
+	}
+
+	queue := current.Stats.Libbeat.Pipeline.Queue
+	queueEvents, queueSet := config.number("queue.mem.events")
+	if queue.MaxEvents > 0 {
+		percent := float64(queue.Filled.Events) / float64(queue.MaxEvents) * 100
+		color := "green"
+		switch {
+		case percent >= 95:
+			color = "red"
+		case percent >= 80:
+			color = "yellow"
+		}
+		add("queue.mem.events", fmt.Sprintf("[%s]cola %d/%d (%.0f%%)[-]", color, queue.Filled.Events, queue.MaxEvents, percent))
+		if queueSet && uint64(queueEvents) != queue.MaxEvents {
+			add("queue.mem.events", fmt.Sprintf("[yellow]Filebeat usa %d: ¿es el archivo que cargó?[-]", queue.MaxEvents))
+		}
+		if _, ok := config.settings["queue.mem.events"]; !ok {
+			notes = append(notes, fmt.Sprintf("[gray]queue.mem.events no está: la cola es de %d eventos (%d%% llena)[-]", queue.MaxEvents, int(percent)))
+		}
+	}
+	if !queueSet {
+		queueEvents = defaultQueueEvents
+		if queue.MaxEvents > 0 {
+			queueEvents = int(queue.MaxEvents)
+		}
+	}
+
+	if output := config.output(); output != "" {
+		outputHints(config, output, queueEvents, add)
+	}
+	inputHints(config, add)
+	return hints, notes
+}
+
+// outputHints anota la salida con lo que confirma y lo que falla, y
+// bulk_max_size con el tamaño real de los lotes
+func outputHints(config *filebeatConfig, output string, queueEvents int, add func(path, hint string)) {
+	prefix := "output." + output
+	if acked, ok := recentRate("output.events.acked"); ok {
+		add(prefix, "confirma "+formatEventRate(acked))
+	}
+	if failed, ok := recentRate("output.events.failed"); ok && failed > 0 {
+		add(prefix, "[red]fallan "+formatEventRate(failed)+"[-]")
+	}
+
+	bulk, bulkSet := config.number(prefix + ".bulk_max_size")
+	if !bulkSet {
+		bulk = defaultBulkMaxSize[output]
+	}
+	if events, ok := recentIncrease("output.events.total"); ok {
+		if batches, ok := recentIncrease("output.events.batches"); ok && batches > 0 {
+			hint := fmt.Sprintf("lotes de ~%.0f eventos", events/batches)
+			if bulk > 0 && events/batches >= float64(bulk)*0.9 {
+				hint = "[yellow]" + hint + ": llenos, la salida va al límite[-]"
+			}
+			add(prefix+".bulk_max_size", hint)
+		}
+	}
+	worker, ok := config.number(prefix + ".worker")
+	if !ok {
+		worker, ok = config.number(prefix + ".workers")
+	}
+	if !ok {
+		worker = defaultOutputWorker
+	}
+	if bulk > 0 && queueEvents < bulk*worker {
+		add(prefix+".bulk_max_size", fmt.Sprintf("[yellow]la cola (%d) es menor que bulk_max_size × worker (%d): los lotes no se llenan[-]", queueEvents, bulk*worker))
+		add("queue.mem.events", fmt.Sprintf("[yellow]menor que bulk_max_size × worker (%d)[-]", bulk*worker))
+	}
+}
+
+// inputHints anota los inputs de filebeat.inputs: los obsoletos, los
+// filestream sin id o con el id repetido y, con /inputs/, lo que produce
+// cada uno
+func inputHints(config *filebeatConfig, add func(path, hint string)) {
+	ids := make(map[string]int)
+	for i := 0; ; i++ {
+		input := fmt.Sprintf("filebeat.inputs[%d]", i)
+		if _, ok := config.settings[input]; !ok {
+			break
+		}
+		kind := config.settings[input+".type"].value
+		if config.settings[input+".enabled"].value == "false" {
+			add(input+".enabled", "[gray]deshabilitado[-]")
+			continue
+		}
+		switch kind {
+		case "log":
+			add(input+".type", "[yellow]input log obsoleto: filestream lo reemplaza[-]")
+		case "filestream":
+			if _, ok := config.settings[input+".id"]; !ok {
+				add(input+".type", "[red]filestream sin id: puede releer los archivos y duplicar eventos[-]")
+			}
+		}
+		id, ok := config.settings[input+".id"]
+		if !ok {
+			continue
+		}
+		if line, repeated := ids[id.value]; repeated {
+			add(input+".id", fmt.Sprintf("[red]id repetido (línea %d)[-]", line))
+		} else {
+			ids[id.value] = id.line
+		}
+		if hint, ok := liveInputHint(id.value); ok {
+			add(input+".id", hint)
+		}
+	}
+}
+
+// liveInputHint es lo que produce el input id según /inputs/; ok es false
+// si no hay datos de inputs
+func liveInputHint(id string) (string, bool) {
+	if current.Stats == nil || len(current.Stats.Filebeat.Inputs) == 0 {
+		return "", false
+	}
+	for _, input := range current.Stats.Filebeat.Inputs {
+		if input.ID != id {
+			continue
+		}
+		rate, _ := inputEventRate(id)
+		text := fmt.Sprintf("%s, %d archivos", formatEventRate(rate), input.Files)
+		if input.Errors > 0 {
+			text += fmt.Sprintf(" [red]%d errores[-]", input.Errors)
+		}
+		return text, true
+	}
+	return "[gray]no aparece en /inputs/[-]", true
+}
+
+// recentIncrease es cuánto aumentó el contador path en las últimas
+// configRateSamples muestras
+func recentIncrease(path string) (float64, bool) {
+	increase, _, ok := recentChange(path)
+	return increase, ok
+}
+
+// recentRate es recentIncrease por segundo
+func recentRate(path string) (float64, bool) {
+	increase, elapsed, ok := recentChange(path)
+	if !ok || elapsed <= 0 {
+		return 0, false
+	}
+	return increase / elapsed, true
+}
+
+func recentChange(path string) (increase, seconds float64, ok bool) {
+	history := store.History()
+	back := min(history.Len()-1, configRateSamples)
+	if back < 1 {
+		return 0, 0, false
+	}
+	after, end, found := history.Value(0, path)
+	if !found {
+		return 0, 0, false
+	}
+	before, start, found := history.Value(back, path)
+	// Si el contador bajó, Filebeat se reinició
+	if !found || after < before {
+		return 0, 0, false
+	}
+	return after - before, end.Sub(start).Seconds(), true
+}
+
+// highlightYAML colorea una línea de YAML: comentarios en gris, claves en
+// amarillo y los valores según su tipo
+func highlightYAML(line string) string {
+	code, comment := splitComment(line)
+	var b strings.Builder
+	rest := strings.TrimLeft(code, " ")
+	b.WriteString(code[:len(code)-len(rest)])
+	if strings.TrimSpace(rest) == "---" {
+		b.WriteString("[gray]" + tview.Escape(rest) + "[-]")
+		rest = ""
+	}
+	for strings.HasPrefix(rest, "- ") {
+		b.WriteString("[gray]- [-]")
+		rest = rest[2:]
+	}
+	if i := keyEnd(rest); i >= 0 {
+		b.WriteString("[yellow]" + tview.Escape(rest[:i+1]) + "[-]")
+		rest = rest[i+1:]
+	}
+	if value := strings.TrimSpace(rest); value != "" {
+		b.WriteString(strings.Replace(rest, value, "["+yamlValueColor(value)+"]"+tview.Escape(value)+"[-]", 1))
+	} else {
+		b.WriteString(rest)
+	}
+	if comment != "" {
+		b.WriteString("[gray]" + tview.Escape(comment) + "[-]")
+	}
+	return b.String()
+}
+
+// splitComment separa el comentario de una línea: un # fuera de comillas al
+// principio o después de un espacio
+func splitComment(line string) (code, comment string) {
+	var quote rune
+	for i, r := range line {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == '#' && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t'):
+			return line[:i], line[i:]
+		}
+	}
+	return line, ""
+}
+
+// keyEnd es la posición de los dos puntos que terminan la clave, o -1 si la
+// línea no empieza con una clave
+func keyEnd(text string) int {
+	var quote rune
+	for i, r := range text {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case i == 0 && (r == '"' || r == '\''):
+			quote = r
+		case r == ':' && (i+1 == len(text) || text[i+1] == ' '):
+			return i
+		case r == ' ' && i+1 < len(text) && text[i+1] == '#':
+			return -1
+		}
+	}
+	return -1
+}
+
+func yamlValueColor(value string) string {
+	switch strings.ToLower(value) {
+	case "true", "false", "yes", "no", "on", "off", "null", "~":
+		return "fuchsia"
+	}
+	if _, err := strconv.ParseFloat(value, 64); err == nil {
+		return "aqua"
+	}
+	if strings.HasPrefix(value, "${") {
+		return "orange"
+	}
+	return "green"
+}
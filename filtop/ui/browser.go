@@ -0,0 +1,262 @@
+package ui
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// Página Métricas (tecla m): el documento /stats completo como un árbol
+// navegable, con el valor actual de cada hoja y, a la derecha, la historia
+// reciente de la seleccionada. w fija la hoja en el panel Watch y c y r la
+// agregan a la página Charts como valor o como tasa por segundo.
+
+var (
+	browserTree   *tview.TreeView
+	browserDetail *tview.TextView
+	browserHelp   *tview.TextView
+	// browserNodes son los nodos por ruta, para actualizarlos sin perder
+	// lo expandido
+	browserNodes map[string]*tview.TreeNode
+)
+
+// Puntos del sparkline del detalle
+const browserPoints = 60
+
+// chartedPath es una ruta que se grafica en la página Charts; rate la
+// muestra como incremento por segundo, para los contadores
+type chartedPath struct {
+	path string
+	rate bool
+}
+
+// chartedPaths son las rutas agregadas a Charts desde el navegador
+var chartedPaths []chartedPath
+
+func showBrowserPage() {
+	root := tview.NewTreeNode("stats").SetColor(tcell.ColorYellow)
+	browserTree = tview.NewTreeView().SetRoot(root).SetCurrentNode(root)
+	browserTree.SetBorder(true)
+	browserTree.SetSelectedFunc(func(node *tview.TreeNode) {
+		node.SetExpanded(!node.IsExpanded())
+	})
+	browserTree.SetChangedFunc(func(*tview.TreeNode) {
+		updateBrowserDetail()
+	})
+	browserNodes = make(map[string]*tview.TreeNode)
+	browserDetail = tview.NewTextView().SetDynamicColors(true)
+	browserDetail.SetBorder(true).SetTitle(" Detalle ")
+	browserHelp = tview.NewTextView().SetDynamicColors(true).
+		SetText(" [yellow]Enter[-]: expandir · [yellow]w[-]: fijar en Watch · [yellow]c[-]: graficar el valor · [yellow]r[-]: graficar la tasa/s · [yellow]Esc[-]: volver")
+
+	body := tview.NewFlex().
+		AddItem(browserTree, 0, 3, true).
+		AddItem(browserDetail, 0, 2, false)
+	page := tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(browserHelp, 1, 0, false).
+		AddItem(body, 0, 1, true)
+	page.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() != tcell.KeyRune {
+			return event
+		}
+		path, ok := selectedLeaf()
+		if !ok {
+			return event
+		}
+		switch event.Rune() {
+		case 'w':
+			if err := togglePin(path); err != nil {
+				setCommandResult("[red]" + tview.Escape(err.Error()) + "[-]")
+				break
+			}
+			rebuildMainPage()
+			updateUI()
+			updateBrowserPage()
+		case 'c':
+			toggleChart(path, false)
+		case 'r':
+			toggleChart(path, true)
+		default:
+			return event
+		}
+		return nil
+	})
+
+	pages.AddPage("browser", page, true, true)
+	pages.SwitchToPage("browser")
+	updateBrowserPage()
+	root.SetExpanded(true)
+}
+
+func updateBrowserPage() {
+	if browserTree == nil {
+		return
+	}
+	if name, _ := pages.GetFrontPage(); name != "browser" {
+		return
+	}
+	if current.Stats != nil && current.Stats.Raw != nil {
+		addBrowserNodes(browserTree.GetRoot(), "", current.Stats.Raw)
+	}
+	browserTree.SetTitle(fmt.Sprintf(" %sMétricas de /stats ", tabPrefix()))
+	updateBrowserDetail()
+}
+
+// addBrowserNodes agrega o actualiza los nodos de doc bajo parent. Las
+// rutas usan la sintaxis de client.Lookup, como el historial.
+func addBrowserNodes(parent *tview.TreeNode, prefix string, doc interface{}) {
+	type child struct {
+		key, path string
+		value     interface{}
+	}
+	var children []child
+	switch v := doc.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			path := key
+			if prefix != "" {
+				path = prefix + "." + key
+			}
+			children = append(children, child{key, path, v[key]})
+		}
+	case []interface{}:
+		for i, value := range v {
+			key := "[" + strconv.Itoa(i) + "]"
+			children = append(children, child{key, prefix + key, value})
+		}
+	}
+
+	for _, c := range children {
+		node, exists := browserNodes[c.path]
+		if !exists {
+			node = tview.NewTreeNode(c.key).SetReference(c.path)
+			parent.AddChild(node)
+			browserNodes[c.path] = node
+		}
+		switch value := c.value.(type) {
+		case map[string]interface{}, []interface{}:
+			if !exists {
+				node.SetExpanded(false)
+			}
+			node.SetText(c.key).SetColor(tcell.ColorYellow)
+			addBrowserNodes(node, c.path, value)
+		case float64, bool:
+			node.SetText(fmt.Sprintf("%s: %s%s", c.key, formatValue(value), leafMarks(c.path))).SetColor(tcell.ColorWhite)
+		default:
+			// Los textos no tienen historial: no se pueden fijar ni graficar
+			node.SetText(fmt.Sprintf("%s: %s", c.key, formatValue(value))).SetColor(tcell.ColorGray)
+		}
+	}
+}
+
+// leafMarks indica si la hoja está en Watch o en Charts
+func leafMarks(path string) string {
+	var marks []string
+	if containsPath(watchPaths(), path) {
+		marks = append(marks, "watch")
+	}
+	for _, charted := range chartedPaths {
+		if charted.path == path {
+			marks = append(marks, "chart")
+		}
+	}
+	if len(marks) == 0 {
+		return ""
+	}
+	return " [" + strings.Join(marks, ", ") + "]"
+}
+
+// selectedLeaf es la ruta de la hoja numérica seleccionada
+func selectedLeaf() (string, bool) {
+	node := browserTree.GetCurrentNode()
+	if node == nil || len(node.GetChildren()) > 0 {
+		return "", false
+	}
+	path, ok := node.GetReference().(string)
+	if !ok {
+		return "", false
+	}
+	_, _, found := store.History().Value(0, path)
+	return path, found
+}
+
+func updateBrowserDetail() {
+	path, ok := selectedLeaf()
+	if !ok {
+		browserDetail.SetText("[gray]Elegí una métrica numérica para ver su historia[-]")
+		return
+	}
+	history := store.History()
+	value, _, _ := history.Value(0, path)
+	var b strings.Builder
+	fmt.Fprintf(&b, "[yellow]Ruta:[-] %s\n", tview.Escape(path))
+	fmt.Fprintf(&b, "[yellow]Valor:[-] %s\n", formatComputed(value))
+	if prev, _, ok := history.Value(1, path); ok {
+		change, color := formatChange(value - prev)
+		fmt.Fprintf(&b, "[yellow]Cambio:[-] [%s]%s[-]\n", color.String(), change)
+	}
+	if rate, ok := history.RateOver(path, rateWindow); ok && isCounter(path) {
+		fmt.Fprintf(&b, "[yellow]Tasa:[-] %s/s%s\n", formatComputed(rate), smoothingSuffix())
+	}
+	points, _ := history.Series(path)
+	if len(points) > 1 {
+		lo, hi, sum := math.Inf(1), math.Inf(-1), 0.0
+		for _, p := range points {
+			lo, hi, sum = math.Min(lo, p.Value), math.Max(hi, p.Value), sum+p.Value
+		}
+		fmt.Fprintf(&b, "[yellow]Mín/prom/máx:[-] %s / %s / %s (%d muestras)\n", formatComputed(lo), formatComputed(sum/float64(len(points))), formatComputed(hi), len(points))
+		if len(points) > browserPoints {
+			points = points[len(points)-browserPoints:]
+		}
+		series := make([]float64, len(points))
+		for i, p := range points {
+			series[i] = p.Value
+		}
+		fmt.Fprintf(&b, "\n[green]%s[-]\n", sparkline(series))
+	}
+	b.WriteString("\n")
+	if marks := leafMarks(path); marks != "" {
+		fmt.Fprintf(&b, "[gray]En%s[-]\n", tview.Escape(marks))
+	}
+	browserDetail.SetText(b.String())
+}
+
+// isCounter indica si la ruta es entera, creció y nunca bajó en el
+// historial retenido, como un contador
+func isCounter(path string) bool {
+	points, _ := store.History().Series(path)
+	grew := false
+	for i := 1; i < len(points); i++ {
+		if points[i].Value < points[i-1].Value || points[i].Value != math.Trunc(points[i].Value) {
+			return false
+		}
+		grew = grew || points[i].Value > points[i-1].Value
+	}
+	return grew
+}
+
+// toggleChart agrega la ruta a la página Charts o la quita si ya estaba
+func toggleChart(path string, rate bool) {
+	for i, charted := range chartedPaths {
+		if charted.path == path {
+			chartedPaths = append(chartedPaths[:i:i], chartedPaths[i+1:]...)
+			if charted.rate == rate {
+				updateBrowserPage()
+				return
+			}
+			break
+		}
+	}
+	chartedPaths = append(chartedPaths, chartedPath{path: path, rate: rate})
+	updateBrowserPage()
+}
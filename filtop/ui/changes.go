@@ -0,0 +1,124 @@
+package ui
+
+import (
+	"math"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// Resaltado de cambios: los contadores de la página principal que cambiaron
+// desde la muestra anterior muestran el cambio (+N o -N) y, durante
+// changeHighlight, se resaltan en video inverso, para ver qué se mueve durante
+// un incidente sin comparar los valores a ojo. La tecla d lo activa y lo
+// desactiva.
+
+// changeHighlight es cuánto dura el resaltado después de cada muestra
+const changeHighlight = 2 * time.Second
+
+// changesMode muestra los cambios; está activo por defecto
+var changesMode = true
+
+// counterState es el último valor de un contador y su cambio respecto de la
+// muestra anterior
+type counterState struct {
+	value  float64
+	sample time.Time
+	delta  float64
+	at     time.Time
+}
+
+// counters son los contadores mostrados, por clave; se vacía al cambiar de
+// pestaña para no comparar con otro Filebeat
+var counters = make(map[string]*counterState)
+
+// counterChange registra v como el valor de key en la muestra actual y
+// devuelve su cambio desde la anterior y si todavía se resalta. Los
+// redibujados de una misma muestra no lo vuelven a calcular.
+func counterChange(key string, v float64) (delta float64, recent bool) {
+	sample := current.Stats.Timestamp
+	state, ok := counters[key]
+	switch {
+	case !ok:
+		counters[key] = &counterState{value: v, sample: sample}
+		return 0, false
+	case !state.sample.Equal(sample):
+		state.delta, state.value, state.sample = v-state.value, v, sample
+		if state.delta != 0 {
+			state.at = time.Now()
+		}
+	}
+	return state.delta, state.delta != 0 && time.Since(state.at) < changeHighlight
+}
+
+// changeText es el cambio para agregar al valor, p. ej. " +120" en verde;
+// format recibe el valor absoluto
+func changeText(delta float64, format func(float64) string) string {
+	switch {
+	case delta > 0:
+		return " [green]+" + format(delta) + "[-]"
+	case delta < 0:
+		return " [red]-" + format(-delta) + "[-]"
+	}
+	return ""
+}
+
+// setChangedCell es setCell para el contador key: agrega el cambio desde la
+// muestra anterior y lo resalta si es reciente
+func setChangedCell(table *tview.Table, row, col int, text string, color tcell.Color, key string, v float64, format func(float64) string) {
+	var attributes tcell.AttrMask
+	// Se registra aunque no se muestre, para que al activarlo el cambio sea
+	// desde la muestra anterior
+	if delta, recent := counterChange(key, v); changesMode {
+		text += changeText(delta, format)
+		if recent {
+			attributes = tcell.AttrReverse
+		}
+	}
+	setCell(table, row, col, text, color)
+	table.GetCell(row, col).SetAttributes(attributes)
+}
+
+// changedValue es como setChangedCell para el texto de un TextView
+func changedValue(text, key string, v float64, format func(float64) string) string {
+	delta, recent := counterChange(key, v)
+	if !changesMode {
+		return text
+	}
+	if recent {
+		text = "[::r]" + text + "[::-]"
+	}
+	return text + changeText(delta, format)
+}
+
+// formatCount formatea el cambio de un contador de eventos o archivos
+func formatCount(v float64) string {
+	return formatComputed(math.Round(v))
+}
+
+// formatByteCount formatea el cambio de un contador de bytes
+func formatByteCount(v float64) string {
+	return formatBytes(uint64(v))
+}
+
+// scheduleChangeClear quita el resaltado cuando vence, sin esperar a la
+// próxima muestra
+func scheduleChangeClear() {
+	if !changesMode {
+		return
+	}
+	time.AfterFunc(changeHighlight, func() {
+		queueUpdate(func() {
+			updateUI()
+		})
+	})
+}
+
+// setChanges activa o desactiva el resaltado de cambios
+func setChanges(enabled bool) {
+	changesMode = enabled
+	if current.Stats != nil {
+		updateUI()
+	}
+}
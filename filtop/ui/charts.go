@@ -0,0 +1,520 @@
+package ui
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/iTiagoCO/filtop/filtop/client"
+	"github.com/iTiagoCO/filtop/filtop/metrics"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// Página Charts (tecla g): la evolución de algunas tasas en el historial
+// retenido, para ver de un vistazo caídas bruscas como las que produce un
+// límite de red o un Elasticsearch que frena la ingesta.
+
+const outputBytesPath = "libbeat.output.write.bytes"
+
+// pipelineSeries son los contadores del desglose de eventos del pipeline,
+// apilados en este orden
+var pipelineSeries = []struct {
+	path  string
+	label string
+	color tcell.Color
+}{
+	{"libbeat.pipeline.events.published", "publicados", tcell.ColorGreen},
+	{"libbeat.pipeline.events.filtered", "filtrados", tcell.ColorAqua},
+	{"libbeat.pipeline.events.dropped", "descartados", tcell.ColorYellow},
+	{"libbeat.pipeline.events.failed", "fallidos", tcell.ColorRed},
+}
+
+// Colores de los rangos de tamaño de los eventos, del más chico al más
+// grande; uno por cada límite de metrics.EventSizeBounds y uno más
+var eventSizeColors = []tcell.Color{tcell.ColorGreen, tcell.ColorAqua, tcell.ColorBlue, tcell.ColorYellow, tcell.ColorRed}
+
+// Rangos de tiempo que se eligen con la tecla w; 0 es todo el historial
+// retenido
+var chartWindows = []time.Duration{time.Minute, 5 * time.Minute, 30 * time.Minute, 0}
+
+// Rango mínimo al acercar y fracción del rango que se desplaza con las
+// flechas
+const (
+	minChartSpan  = 10 * time.Second
+	chartPanShare = 4
+)
+
+var (
+	outputChart    *chart
+	pipelineChart  *chart
+	eventSizeChart *chart
+	// pathCharts[i] es el gráfico de chartedPaths[i], agregado desde la
+	// página Métricas
+	pathCharts []*chart
+	chartsHelp *tview.TextView
+	// chartWindow es el índice en chartWindows del último rango elegido con w
+	chartWindow = len(chartWindows) - 1
+	// chartSpan es la duración mostrada (0 es todo) y chartEnd el final del
+	// rango; con chartEnd en cero el rango sigue a la muestra más reciente
+	chartSpan time.Duration
+	chartEnd  time.Time
+)
+
+// chart dibuja una o más series apiladas como barras verticales, con la
+// muestra más reciente a la derecha. Si el rango tiene más muestras que
+// columnas, cada columna es el promedio de varias.
+type chart struct {
+	*tview.Box
+	series [][]metrics.Point
+	colors []tcell.Color
+	format func(float64) string
+}
+
+func newChart(format func(float64) string, colors ...tcell.Color) *chart {
+	c := &chart{Box: tview.NewBox(), format: format, colors: colors}
+	c.SetBorder(true)
+	return c
+}
+
+// setSeries asigna una serie por color. Las series se alinean por el final:
+// todas terminan en la muestra más reciente. Una serie vacía (un contador
+// que esta versión de Filebeat no tiene) cuenta como cero.
+func (c *chart) setSeries(series ...[]metrics.Point) {
+	var longest []metrics.Point
+	n := -1
+	for _, points := range series {
+		if len(points) == 0 {
+			continue
+		}
+		if n < 0 || len(points) < n {
+			n = len(points)
+		}
+		if len(points) > len(longest) {
+			longest = points
+		}
+	}
+	c.series = c.series[:0]
+	for _, points := range series {
+		if len(points) == 0 {
+			points = make([]metrics.Point, len(longest))
+			for i, p := range longest {
+				points[i].Time = p.Time
+			}
+		}
+		c.series = append(c.series, points[len(points)-max(n, 0):])
+	}
+}
+
+// visible devuelve el rango de muestras [from, to) que cae en la ventana
+func (c *chart) visible() (from, to int) {
+	points := c.series[0]
+	to = len(points)
+	if !chartEnd.IsZero() {
+		for to > 1 && points[to-1].Time.After(chartEnd) {
+			to--
+		}
+	}
+	if chartSpan == 0 {
+		return 0, to
+	}
+	since := points[to-1].Time.Add(-chartSpan)
+	for from < to-1 && points[from].Time.Before(since) {
+		from++
+	}
+	return from, to
+}
+
+// columns reparte las muestras [from, to) en como mucho width columnas y
+// devuelve el valor de cada serie en cada una
+func (c *chart) columns(from, to, width int) [][]float64 {
+	n := min(to-from, width)
+	columns := make([][]float64, n)
+	for col := range columns {
+		lo, hi := from+col*(to-from)/n, from+(col+1)*(to-from)/n
+		columns[col] = make([]float64, len(c.series))
+		for k, points := range c.series {
+			for _, p := range points[lo:hi] {
+				columns[col][k] += p.Value
+			}
+			columns[col][k] /= float64(hi - lo)
+		}
+	}
+	return columns
+}
+
+func (c *chart) Draw(screen tcell.Screen) {
+	c.Box.DrawForSubclass(screen, c)
+	x, y, width, height := c.GetInnerRect()
+	if width < 20 || height < 2 {
+		return
+	}
+	if len(c.series) == 0 || len(c.series[0]) == 0 {
+		tview.Print(screen, "Sin datos todavía", x, y, width, tview.AlignLeft, tcell.ColorGray)
+		return
+	}
+
+	from, to := c.visible()
+	lo, hi, avg := c.summary(from, to)
+	// El ancho de las etiquetas depende del máximo, que con varias muestras
+	// por columna es el del promedio: se calcula con el de las muestras
+	zeroLabel := c.format(0)
+	labelWidth := max(len(c.format(hi)), len(zeroLabel)) + 1
+	columns := c.columns(from, to, width-labelWidth)
+
+	// Escala desde cero: así una caída se ve como tal y no como ruido
+	top := 0.0
+	for _, values := range columns {
+		top = math.Max(top, sum(values))
+	}
+	topLabel := c.format(top)
+	labelWidth = max(labelWidth, len(topLabel)+1)
+	plotWidth, plotHeight := width-labelWidth, height-1
+	if len(columns) > plotWidth {
+		columns = columns[len(columns)-plotWidth:]
+	}
+
+	// Con un rango elegido, pocas muestras se ensanchan para llenar el
+	// gráfico; así acercar permite ver un pico de cerca
+	barWidth := 1
+	if chartSpan > 0 {
+		barWidth = max(plotWidth/len(columns), 1)
+	}
+	left := x + labelWidth + plotWidth - len(columns)*barWidth
+	tops := make([]int, len(c.series))
+	for i, values := range columns {
+		// Altura acumulada de cada serie en octavos de celda; un valor
+		// distinto de cero ocupa al menos un octavo para que se vea
+		stack := 0
+		for k, v := range values {
+			if top > 0 && v > 0 {
+				stack += max(int(math.Round(v/top*float64(plotHeight*8))), 1)
+			}
+			tops[k] = stack
+		}
+		for row := 0; row < plotHeight; row++ {
+			block, style, ok := c.cell(tops, row*8)
+			if !ok {
+				break
+			}
+			for col := left + i*barWidth; col < left+(i+1)*barWidth; col++ {
+				screen.SetContent(col, y+plotHeight-1-row, block, nil, style)
+			}
+		}
+	}
+
+	tview.Print(screen, topLabel, x, y, labelWidth-1, tview.AlignRight, tcell.ColorGray)
+	tview.Print(screen, zeroLabel, x, y+plotHeight-1, labelWidth-1, tview.AlignRight, tcell.ColorGray)
+	points := c.series[0]
+	since, until := points[from].Time.Local().Format(time.TimeOnly), points[to-1].Time.Local().Format(time.TimeOnly)
+	tview.Print(screen, until, x+labelWidth, y+plotHeight, plotWidth, tview.AlignRight, tcell.ColorGray)
+	// El resumen va a la izquierda y el inicio del rango bajo la primera
+	// columna, si no se superponen
+	stats := fmt.Sprintf("mín %s · prom %s · máx %s", c.format(lo), c.format(avg), c.format(hi))
+	statsEnd := x + labelWidth
+	if plotWidth > tview.TaggedStringWidth(stats)+len(until)+2 {
+		tview.Print(screen, stats, x+labelWidth, y+plotHeight, plotWidth, tview.AlignLeft, tcell.ColorWhite)
+		statsEnd += tview.TaggedStringWidth(stats) + 2
+	}
+	if left >= statsEnd && len(columns)*barWidth > len(since)+len(until) {
+		tview.Print(screen, since, left, y+plotHeight, plotWidth, tview.AlignLeft, tcell.ColorGray)
+	}
+}
+
+// summary calcula el mínimo, el máximo y el promedio de la suma de las
+// series en las muestras [from, to)
+func (c *chart) summary(from, to int) (lo, hi, avg float64) {
+	lo, hi = math.Inf(1), math.Inf(-1)
+	for i := from; i < to; i++ {
+		v := c.stacked(i)
+		lo, hi = math.Min(lo, v), math.Max(hi, v)
+		avg += v
+	}
+	return lo, hi, avg / float64(to-from)
+}
+
+func sum(values []float64) float64 {
+	total := 0.0
+	for _, v := range values {
+		total += v
+	}
+	return total
+}
+
+// stacked es la suma de las series en la columna i
+func (c *chart) stacked(i int) float64 {
+	sum := 0.0
+	for _, points := range c.series {
+		sum += points[i].Value
+	}
+	return sum
+}
+
+// cell elige el carácter de la celda que empieza en el octavo lo de una
+// columna cuyas series terminan en tops. Si una serie termina dentro de la
+// celda, el bloque parcial lleva su color y el fondo el de la siguiente.
+func (c *chart) cell(tops []int, lo int) (rune, tcell.Style, bool) {
+	for k, end := range tops {
+		if end <= lo {
+			continue
+		}
+		style := tcell.StyleDefault.Foreground(c.colors[k])
+		if end >= lo+8 {
+			return '█', style, true
+		}
+		for j := k + 1; j < len(tops); j++ {
+			if tops[j] > end {
+				style = style.Background(c.colors[j])
+				break
+			}
+		}
+		return sparkBlocks[end-lo-1], style, true
+	}
+	return 0, tcell.StyleDefault, false
+}
+
+func showChartsPage() {
+	outputChart = newChart(formatByteRate, tcell.ColorGreen)
+	colors := make([]tcell.Color, len(pipelineSeries))
+	for i, series := range pipelineSeries {
+		colors[i] = series.color
+	}
+	pipelineChart = newChart(formatEventRate, colors...)
+	eventSizeChart = newChart(formatEventRate, eventSizeColors...)
+	chartsHelp = tview.NewTextView().SetDynamicColors(true)
+	page := tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(chartsHelp, 1, 0, false).
+		AddItem(outputChart, 0, 1, false).
+		AddItem(pipelineChart, 0, 1, false).
+		AddItem(eventSizeChart, 0, 1, false)
+	pathCharts = pathCharts[:0]
+	for range chartedPaths {
+		c := newChart(formatComputed, tcell.ColorFuchsia)
+		pathCharts = append(pathCharts, c)
+		page.AddItem(c, 0, 1, false)
+	}
+	page.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch {
+		case event.Key() == tcell.KeyLeft:
+			panCharts(-1)
+		case event.Key() == tcell.KeyRight:
+			panCharts(1)
+		case event.Key() == tcell.KeyEnd:
+			chartEnd = time.Time{}
+		case event.Key() != tcell.KeyRune:
+			return event
+		case event.Rune() == 'w':
+			chartWindow = (chartWindow + 1) % len(chartWindows)
+			chartSpan, chartEnd = chartWindows[chartWindow], time.Time{}
+		case event.Rune() == '+' || event.Rune() == '=':
+			zoomCharts(0.5)
+		case event.Rune() == '-':
+			zoomCharts(2)
+		default:
+			return event
+		}
+		updateCharts()
+		return nil
+	})
+
+	pages.AddPage("charts", page, true, true)
+	pages.SwitchToPage("charts")
+	updateCharts()
+}
+
+// updateCharts refresca la página si está a la vista
+func updateCharts() {
+	if outputChart == nil {
+		return
+	}
+	if name, _ := pages.GetFrontPage(); name != "charts" {
+		return
+	}
+
+	points, found := store.History().RateSeriesOver(outputBytesPath, rateWindow)
+	outputChart.setSeries(points)
+	title := fmt.Sprintf(" Output %s: bytes escritos/s ", outputType())
+	switch {
+	case !found:
+		title = fmt.Sprintf(" Output %s: sin %s ", outputType(), outputBytesPath)
+	case len(points) > 0:
+		title = fmt.Sprintf(" Output %s: bytes escritos/s · actual %s ", outputType(), formatByteRate(points[len(points)-1].Value))
+	}
+	outputChart.SetTitle(title)
+	updatePipelineChart()
+	updateEventSizeChart()
+	updatePathCharts()
+
+	window := "todo el historial"
+	switch {
+	case !chartEnd.IsZero():
+		window = fmt.Sprintf("%s hasta las %s", formatSpan(chartSpan), chartEnd.Local().Format(time.TimeOnly))
+	case chartSpan > 0:
+		window = "últimos " + formatSpan(chartSpan)
+	}
+	chartsHelp.SetText(fmt.Sprintf(" %sRango: [aqua]%s[-] · [yellow]w[-]: rangos · [yellow]+/-[-]: acercar/alejar · [yellow]←/→[-]: desplazar · [yellow]End[-]: en vivo · [yellow]Esc[-]: volver", tabPrefix(), window))
+}
+
+// updatePipelineChart muestra los eventos publicados, filtrados por los
+// processors (drop_event, include_lines) y descartados o fallidos en el
+// output, para distinguir unos de otros
+func updatePipelineChart() {
+	series := make([][]metrics.Point, len(pipelineSeries))
+	var legend []string
+	for i, s := range pipelineSeries {
+		series[i], _ = store.History().RateSeriesOver(s.path, rateWindow)
+		text := s.label
+		if n := len(series[i]); n > 0 {
+			text += " " + formatEventRate(series[i][n-1].Value)
+		}
+		legend = append(legend, fmt.Sprintf("[%s]%s[-]", s.color.String(), text))
+	}
+	pipelineChart.setSeries(series...)
+	pipelineChart.SetTitle(" Eventos del pipeline: " + strings.Join(legend, " · ") + " ")
+}
+
+// updateEventSizeChart reparte los eventos/s de los inputs según el tamaño
+// promedio de sus eventos, para ver cuándo un input empieza a enviar eventos
+// más grandes. Los rangos sin eventos en el último intervalo no muestran su
+// tasa. No usa el suavizado de las tasas: cada barra es un intervalo.
+func updateEventSizeChart() {
+	bounds := metrics.EventSizeBounds
+	series, sizes := store.History().EventSizeSeries(bounds)
+	var legend []string
+	for i, points := range series {
+		text := eventSizeRange(bounds, i)
+		if n := len(points); n > 0 && points[n-1].Value > 0 {
+			text += " " + formatEventRate(points[n-1].Value)
+		}
+		legend = append(legend, fmt.Sprintf("[%s]%s[-]", eventSizeColors[i].String(), text))
+	}
+	eventSizeChart.setSeries(series...)
+	title := " Eventos por tamaño: " + strings.Join(legend, " · ")
+	if n := len(sizes); n > 0 && sizes[n-1].Value > 0 {
+		title += " · promedio " + formatEventSize(sizes[n-1].Value)
+	}
+	eventSizeChart.SetTitle(title + " ")
+}
+
+// eventSizeRange describe el rango i de tamaños, p. ej. 256 B-1 KiB
+func eventSizeRange(bounds []float64, i int) string {
+	format := func(bytes float64) string {
+		return strings.Replace(formatBytes(uint64(bytes)), ".0 ", " ", 1)
+	}
+	switch {
+	case i == 0:
+		return "<" + format(bounds[0])
+	case i == len(bounds):
+		return "≥" + format(bounds[i-1])
+	}
+	return format(bounds[i-1]) + "-" + format(bounds[i])
+}
+
+// updatePathCharts muestra las rutas agregadas desde la página Métricas,
+// como valor o como tasa por segundo
+func updatePathCharts() {
+	for i, charted := range chartedPaths {
+		if i >= len(pathCharts) {
+			return
+		}
+		points, found := store.History().Series(charted.path)
+		if charted.rate {
+			points, found = store.History().RateSeriesOver(charted.path, rateWindow)
+		}
+		pathCharts[i].setSeries(points)
+		title := " " + tview.Escape(charted.path)
+		if charted.rate {
+			title += "/s"
+		}
+		switch {
+		case !found:
+			title += ": sin datos "
+		case len(points) > 0:
+			title += " · actual " + formatComputed(points[len(points)-1].Value) + " "
+		default:
+			title += " "
+		}
+		pathCharts[i].SetTitle(title)
+	}
+}
+
+// formatSpan formatea la duración de un rango sin las unidades en cero,
+// p. ej. 5m o 2m30s
+func formatSpan(d time.Duration) string {
+	text := d.Round(time.Second).String()
+	if strings.HasSuffix(text, "m0s") {
+		text = strings.TrimSuffix(text, "0s")
+	}
+	if strings.HasSuffix(text, "h0m") {
+		text = strings.TrimSuffix(text, "0m")
+	}
+	return text
+}
+
+// chartsRetained devuelve el instante de la primera y la última muestra de
+// los gráficos
+func chartsRetained() (first, last time.Time, ok bool) {
+	if outputChart == nil || len(outputChart.series) == 0 || len(outputChart.series[0]) == 0 {
+		return time.Time{}, time.Time{}, false
+	}
+	points := outputChart.series[0]
+	return points[0].Time, points[len(points)-1].Time, true
+}
+
+// zoomCharts multiplica el rango mostrado por factor, manteniendo su final.
+// Un rango que abarca todo lo retenido pasa a ser "todo el historial".
+func zoomCharts(factor float64) {
+	first, last, ok := chartsRetained()
+	if !ok {
+		return
+	}
+	retained := last.Sub(first)
+	span := chartSpan
+	if span == 0 {
+		span = retained
+	}
+	span = max(time.Duration(float64(span)*factor).Round(time.Second), minChartSpan)
+	if span >= retained {
+		chartSpan, chartEnd = 0, time.Time{}
+		return
+	}
+	chartSpan = span
+}
+
+// panCharts desplaza el rango una fracción de su duración hacia atrás
+// (direction -1) o hacia adelante (1). Al llegar a la última muestra el
+// rango vuelve a seguirla.
+func panCharts(direction int) {
+	first, last, ok := chartsRetained()
+	if !ok || chartSpan == 0 {
+		return
+	}
+	end := chartEnd
+	if end.IsZero() {
+		end = last
+	}
+	end = end.Add(time.Duration(direction) * chartSpan / chartPanShare)
+	switch {
+	case !end.Before(last):
+		chartEnd = time.Time{}
+	case end.Before(first.Add(chartSpan)):
+		chartEnd = first.Add(chartSpan)
+	default:
+		chartEnd = end
+	}
+}
+
+// outputType devuelve el tipo de output de Filebeat, p. ej. elasticsearch
+func outputType() string {
+	if current.Stats != nil {
+		if name, ok := client.Lookup(current.Stats.Raw, "libbeat.output.type"); ok {
+			return fmt.Sprint(name)
+		}
+	}
+	if state := current.State; state != nil && state.Output.Name != "" {
+		return state.Output.Name
+	}
+	return "?"
+}
@@ -0,0 +1,90 @@
+package ui
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/iTiagoCO/filtop/filtop/alerts"
+	"github.com/iTiagoCO/filtop/filtop/metrics"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+var activeAlerts []alerts.Alert
+
+func createCustomPanel(names []string) *tview.Table {
+	table := tview.NewTable().SetBorders(false)
+	table.SetTitle(" Custom ").SetBorder(true)
+	for row, name := range names {
+		addMetricRow(table, row, name+":", "-", tcell.ColorAqua)
+	}
+	return table
+}
+
+// UpdateCustom muestra las métricas calculadas y las alertas activas de la
+// pestaña tab
+func UpdateCustom(tab int, values []metrics.ComputedValue, active []alerts.Alert) {
+	queueUpdate(func() {
+		tabStates[tab].computed, tabStates[tab].alerts = values, active
+		if tab != activeTab {
+			return
+		}
+		activeAlerts = active
+		if current.Stats != nil {
+			updateHeader()
+		}
+		showCustom(values)
+	})
+}
+
+func showCustom(values []metrics.ComputedValue) {
+	if layout.custom == nil {
+		return
+	}
+	for row, value := range values {
+		switch {
+		case value.Err != nil:
+			setCell(layout.custom, row, 1, "error: "+value.Err.Error(), tcell.ColorRed)
+		case math.IsNaN(value.Value):
+			setCell(layout.custom, row, 1, "-", tcell.ColorGray)
+		default:
+			setCell(layout.custom, row, 1, formatComputed(value.Value)+compared("computed."+value.Name, value.Value), tcell.ColorAqua)
+		}
+	}
+}
+
+func formatComputed(v float64) string {
+	if v == math.Trunc(v) && math.Abs(v) < 1e15 {
+		return fmt.Sprintf("%.0f", v)
+	}
+	return fmt.Sprintf("%.4g", v)
+}
+
+// alertSummary es el texto de las alertas activas para la cabecera
+func alertSummary() string {
+	if len(activeAlerts) == 0 {
+		return ""
+	}
+	parts := make([]string, len(activeAlerts))
+	for i, alert := range activeAlerts {
+		color := severityColor(alert.Severity)
+		if alert.Silenced {
+			color = "gray"
+		}
+		parts[i] = fmt.Sprintf("[%s]%s=%s[-]", color, alert.Rule, formatComputed(alert.Value))
+	}
+	return " | [red::b]ALERTAS[-::-] " + strings.Join(parts, " ")
+}
+
+// severityColor es el color de una alerta según su severidad
+func severityColor(severity string) string {
+	switch severity {
+	case "critical":
+		return "red"
+	case "info":
+		return "aqua"
+	}
+	return "yellow"
+}
@@ -0,0 +1,56 @@
+package ui
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// Las actualizaciones de un mismo ciclo (muestra, métricas calculadas,
+// paneles, endpoints) llegan por separado; se aplican de inmediato pero se
+// dibujan juntas, como mucho una vez por drawInterval.
+const drawInterval = 100 * time.Millisecond
+
+var (
+	drawPending atomic.Bool
+	// Último texto asignado a cada TextView, para no volver a procesarlo
+	shownText = make(map[*tview.TextView]string)
+)
+
+// queueUpdate ejecuta f en la goroutine de tview y agenda un redibujado
+func queueUpdate(f func()) {
+	app.QueueUpdate(f)
+	if drawPending.Swap(true) {
+		return
+	}
+	time.AfterFunc(drawInterval, func() {
+		// QueueUpdateDraw respeta el orden de la cola: se dibuja después de
+		// las actualizaciones pendientes
+		app.QueueUpdateDraw(func() { drawPending.Store(false) })
+	})
+}
+
+// setText cambia el texto de view solo si es distinto del actual
+func setText(view *tview.TextView, text string) {
+	if shownText[view] == text {
+		return
+	}
+	shownText[view] = text
+	view.SetText(text)
+}
+
+// setCell actualiza el texto y el color de una celda, o la crea si no
+// existe (GetCell devuelve entonces una celda vacía sin estilo).
+func setCell(table *tview.Table, row, col int, text string, color tcell.Color) {
+	cell := table.GetCell(row, col)
+	if cell.Style == tcell.StyleDefault {
+		table.SetCell(row, col, tview.NewTableCell(text).SetTextColor(color))
+		return
+	}
+	if cell.Text != text {
+		cell.SetText(text)
+	}
+	cell.SetTextColor(color)
+}
@@ -0,0 +1,141 @@
+package ui
+
+import (
+	"fmt"
+
+	"github.com/iTiagoCO/filtop/filtop/elastic"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// Panel Elasticsearch: estado del clúster de destino y eventos que
+// Filebeat envió contra documentos que el clúster indexó, para detectar
+// pérdidas silenciosas o pipelines de ingesta que fallan.
+
+// Diferencia entre enviados e indexados, en porcentaje de los enviados, a
+// partir de la cual se resalta. Un poco de diferencia es normal porque los
+// dos contadores no se leen en el mismo instante.
+const (
+	lossWarnPercent     = 1
+	lossCriticalPercent = 5
+)
+
+// Filas del panel
+const (
+	esRowCluster = iota
+	esRowWrite
+	esRowILM
+	esRowSent
+	esRowIndexed
+	esRowDiff
+	esRows
+)
+
+func createElasticPanel() *tview.Table {
+	table := tview.NewTable().SetBorders(false)
+	table.SetTitle(" Elasticsearch ").SetBorder(true)
+	addMetricRow(table, esRowCluster, "Clúster:", "-", tcell.ColorWhite)
+	addMetricRow(table, esRowWrite, "Escritura:", "-", tcell.ColorWhite)
+	addMetricRow(table, esRowILM, "ILM:", "-", tcell.ColorWhite)
+	addMetricRow(table, esRowSent, "Enviados:", "-", tcell.ColorGreen)
+	addMetricRow(table, esRowIndexed, "Indexados:", "-", tcell.ColorGreen)
+	addMetricRow(table, esRowDiff, "Diferencia:", "-", tcell.ColorWhite)
+	return table
+}
+
+// UpdateElastic muestra el estado del clúster y la comparación entre lo
+// enviado y lo indexado
+func UpdateElastic(stats *elastic.Stats, err error) {
+	queueUpdate(func() {
+		table := layout.elastic
+		if table == nil {
+			return
+		}
+		if err != nil {
+			setCell(table, esRowCluster, 1, "error: "+err.Error(), tcell.ColorRed)
+			for row := esRowWrite; row < esRows; row++ {
+				setCell(table, row, 1, "-", tcell.ColorGray)
+			}
+			return
+		}
+		updateClusterHealth(table, stats)
+
+		ingestion := stats.Ingestion
+		if ingestion == nil {
+			return
+		}
+		setCell(table, esRowIndexed, 1, fmt.Sprintf("%.1f/s", ingestion.Indexed)+compared("es.indexed_per_sec", ingestion.Indexed), tcell.ColorGreen)
+		if ingestion.Sent == nil {
+			setCell(table, esRowSent, 1, "-", tcell.ColorGreen)
+			setCell(table, esRowDiff, 1, "-", tcell.ColorWhite)
+			return
+		}
+		sent := *ingestion.Sent
+		setCell(table, esRowSent, 1, fmt.Sprintf("%.1f/s", sent)+compared("es.sent_per_sec", sent), tcell.ColorGreen)
+		text, color := lossText(sent, ingestion.Indexed)
+		setCell(table, esRowDiff, 1, text, color)
+	})
+}
+
+func updateClusterHealth(table *tview.Table, stats *elastic.Stats) {
+	health := stats.Health
+	if health == nil {
+		setCell(table, esRowCluster, 1, stats.HealthError, tcell.ColorGray)
+		setCell(table, esRowWrite, 1, "-", tcell.ColorGray)
+		setCell(table, esRowILM, 1, "-", tcell.ColorGray)
+		return
+	}
+
+	text := fmt.Sprintf("%s · %d nodos", health.Status, health.Nodes)
+	if health.UnassignedShards > 0 {
+		text += fmt.Sprintf(" · %d shards sin asignar", health.UnassignedShards)
+	}
+	setCell(table, esRowCluster, 1, text, statusColor(health.Status))
+
+	text = fmt.Sprintf("cola %d · %.1f rechazos/s", health.WriteQueue, health.RejectedPerSec)
+	color := tcell.ColorAqua
+	if health.RejectedPerSec > 0 {
+		color = tcell.ColorRed
+	}
+	setCell(table, esRowWrite, 1, text, color)
+
+	switch len(health.ILMErrors) {
+	case 0:
+		setCell(table, esRowILM, 1, "sin errores", tcell.ColorAqua)
+	case 1:
+		ilm := health.ILMErrors[0]
+		setCell(table, esRowILM, 1, fmt.Sprintf("%s: %s", ilm.Index, ilm.FailedStep), tcell.ColorRed)
+	default:
+		setCell(table, esRowILM, 1, fmt.Sprintf("%d índices con errores", len(health.ILMErrors)), tcell.ColorRed)
+	}
+}
+
+func statusColor(status string) tcell.Color {
+	switch status {
+	case "green":
+		return tcell.ColorGreen
+	case "yellow":
+		return tcell.ColorYellow
+	}
+	return tcell.ColorRed
+}
+
+// lossText describe cuánto menos se indexó de lo que se envió. Más
+// indexados que enviados indica que otros clientes escriben en los mismos
+// índices y la comparación no es fiable.
+func lossText(sent, indexed float64) (string, tcell.Color) {
+	diff := sent - indexed
+	if sent <= 0 {
+		return fmt.Sprintf("%+.1f/s", -diff), tcell.ColorWhite
+	}
+	percent := diff / sent * 100
+	text := fmt.Sprintf("%+.1f/s (%+.1f%%)", -diff, -percent)
+	switch {
+	case percent >= lossCriticalPercent:
+		return text, tcell.ColorRed
+	case percent >= lossWarnPercent:
+		return text, tcell.ColorYellow
+	}
+	return text, tcell.ColorWhite
+}
@@ -0,0 +1,42 @@
+package ui
+
+import (
+	"fmt"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// EndpointPanel describe un panel clave/valor alimentado por un endpoint
+// JSON declarado en la configuración.
+type EndpointPanel struct {
+	Name   string
+	Labels []string
+}
+
+func createEndpointPanel(endpoint EndpointPanel) *tview.Table {
+	table := tview.NewTable().SetBorders(false)
+	table.SetTitle(fmt.Sprintf(" %s ", endpoint.Name)).SetBorder(true)
+	for row, label := range endpoint.Labels {
+		addMetricRow(table, row, label+":", "-", tcell.ColorAqua)
+	}
+	return table
+}
+
+// UpdateEndpoint muestra los valores del endpoint index, en el orden de sus
+// etiquetas. Un valor nil indica que la ruta no existe en el documento.
+func UpdateEndpoint(index int, values []interface{}, err error) {
+	queueUpdate(func() {
+		table := layout.endpoints[index]
+		for row := range options.Endpoints[index].Labels {
+			switch {
+			case err != nil:
+				setCell(table, row, 1, "error", tcell.ColorRed)
+			case row >= len(values) || values[row] == nil:
+				setCell(table, row, 1, "-", tcell.ColorGray)
+			default:
+				setCell(table, row, 1, formatValue(values[row]), tcell.ColorAqua)
+			}
+		}
+	})
+}
@@ -0,0 +1,94 @@
+package ui
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// Modo de respaldo: cuando /stats no responde se consume la salida expvar
+// (/debug/vars) del proceso y se muestra como un árbol genérico de métricas.
+
+var (
+	expvarDoc      map[string]interface{}
+	expvarNodes    = make(map[string]*tview.TreeNode)
+	expvarFallback bool
+)
+
+func createExpvarPage() *tview.TreeView {
+	root := tview.NewTreeNode("expvar").SetColor(tcell.ColorYellow)
+	tree := tview.NewTreeView().SetRoot(root).SetCurrentNode(root)
+	tree.SetTitle(" Métricas expvar (/debug/vars) ").SetBorder(true)
+	tree.SetSelectedFunc(func(node *tview.TreeNode) {
+		node.SetExpanded(!node.IsExpanded())
+	})
+	return tree
+}
+
+// ShowExpvar muestra el documento expvar de la pestaña tab si es la activa
+// y, la primera vez, cambia a la página de respaldo si el usuario estaba en
+// el panel principal.
+func ShowExpvar(tab int, doc map[string]interface{}) {
+	queueUpdate(func() {
+		if tab != activeTab {
+			return
+		}
+		expvarDoc = doc
+		updateExpvar()
+		if !expvarFallback {
+			expvarFallback = true
+			if name, _ := pages.GetFrontPage(); name == "main" {
+				pages.SwitchToPage("expvar")
+			}
+		}
+	})
+}
+
+// leaveExpvarFallback vuelve al panel principal cuando /stats se recupera
+func leaveExpvarFallback() {
+	if !expvarFallback {
+		return
+	}
+	expvarFallback = false
+	if name, _ := pages.GetFrontPage(); name == "expvar" {
+		pages.SwitchToPage("main")
+	}
+}
+
+func updateExpvar() {
+	tree, ok := getPrimitiveFromPage("expvar").(*tview.TreeView)
+	if !ok || expvarDoc == nil {
+		return
+	}
+	addExpvarNodes(tree.GetRoot(), "", expvarDoc)
+}
+
+func addExpvarNodes(parent *tview.TreeNode, prefix string, doc map[string]interface{}) {
+	keys := make([]string, 0, len(doc))
+	for key := range doc {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		path := prefix + "/" + key
+		node, exists := expvarNodes[path]
+		if !exists {
+			node = tview.NewTreeNode(key).SetReference(path)
+			parent.AddChild(node)
+			expvarNodes[path] = node
+		}
+
+		if child, ok := doc[key].(map[string]interface{}); ok {
+			if !exists {
+				node.SetExpanded(false)
+			}
+			node.SetText(fmt.Sprintf("%s (%d)", key, len(child))).SetColor(tcell.ColorYellow)
+			addExpvarNodes(node, path, child)
+			continue
+		}
+		node.SetText(fmt.Sprintf("%s: %s", key, formatValue(doc[key]))).SetColor(tcell.ColorWhite)
+	}
+}
@@ -0,0 +1,247 @@
+package ui
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/iTiagoCO/filtop/filtop/metrics"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// Página Fleet: todos los beats de targets agrupados por host, con su
+// estado y lo esencial de cada uno, para ver de un vistazo la flota sin
+// recorrer las pestañas. La columna Salud es el puntaje de cada beat
+// (metrics.ScoreHealth) con lo que más le resta; con o los hosts y sus
+// beats se ordenan de peor a mejor, así la peor instancia queda arriba.
+// Enter abre la pestaña del beat seleccionado.
+
+var (
+	fleetTable *tview.Table
+	fleetHelp  *tview.TextView
+	// fleetRows[i] es la pestaña de la fila i; -1 en las filas de host
+	fleetRows []int
+	// fleetByHealth ordena por salud en lugar del orden de targets
+	fleetByHealth bool
+)
+
+func showFleetPage() {
+	if !multipleTabs() {
+		return
+	}
+	fleetTable = tview.NewTable().SetFixed(1, 0).SetSelectable(true, false)
+	fleetTable.SetBorder(true).SetTitle(" Flota ")
+	fleetTable.SetSelectedFunc(func(row, _ int) {
+		if row < len(fleetRows) && fleetRows[row] >= 0 {
+			pages.SwitchToPage("main")
+			switchTab(fleetRows[row])
+		}
+	})
+	fleetHelp = tview.NewTextView().SetDynamicColors(true)
+	page := tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(fleetHelp, 1, 0, false).
+		AddItem(fleetTable, 0, 1, true)
+	page.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() == tcell.KeyRune && event.Rune() == 'o' {
+			fleetByHealth = !fleetByHealth
+			updateFleetPage()
+			// La peor instancia, o la primera, queda seleccionada
+			fleetTable.Select(2, 0)
+			return nil
+		}
+		return event
+	})
+
+	pages.AddPage("fleet", page, true, true)
+	pages.SwitchToPage("fleet")
+	updateFleetPage()
+	// La primera fila es la del host del primer beat
+	fleetTable.Select(2, 0)
+}
+
+// fleetGroups son las pestañas de cada host, en el orden en que aparecen
+// o, con fleetByHealth, de peor a mejor salud
+func fleetGroups(scores []float64) (names []string, groups map[string][]int) {
+	groups = make(map[string][]int)
+	for i, tab := range options.Tabs {
+		if _, ok := groups[tab.Group]; !ok {
+			names = append(names, tab.Group)
+		}
+		groups[tab.Group] = append(groups[tab.Group], i)
+	}
+	if fleetByHealth {
+		for _, tabs := range groups {
+			sort.SliceStable(tabs, func(a, b int) bool { return scores[tabs[a]] < scores[tabs[b]] })
+		}
+		// Ya ordenadas, la primera pestaña de cada host es la peor
+		sort.SliceStable(names, func(a, b int) bool { return scores[groups[names[a]][0]] < scores[groups[names[b]][0]] })
+	}
+	return names, groups
+}
+
+// fleetHealth es la salud de cada pestaña. Las que todavía no tienen
+// datos no tienen puntaje (ok false).
+func fleetHealth() (healths []metrics.Health, ok []bool) {
+	now := time.Now()
+	healths, ok = make([]metrics.Health, len(options.Tabs)), make([]bool, len(options.Tabs))
+	for i, tab := range options.Tabs {
+		state, session := tabStates[i], tab.Store.Session(now)
+		// Un beat que nunca respondió también está caído
+		if state.err != "" {
+			session.Down = true
+		}
+		if !state.sampled && !session.Down {
+			continue
+		}
+		healths[i], ok[i] = metrics.ScoreHealth(tab.Store.Latest().Stats, tab.Store.History(), session), true
+	}
+	return healths, ok
+}
+
+func updateFleetPage() {
+	if fleetTable == nil {
+		return
+	}
+	if name, _ := pages.GetFrontPage(); name != "fleet" {
+		return
+	}
+
+	order := "como en targets"
+	if fleetByHealth {
+		order = "por salud, de peor a mejor"
+	}
+	fleetHelp.SetText(" [yellow]Enter[-]: abrir la pestaña · [yellow]o[-]: orden (" + order + ") · [yellow]Esc[-]: volver")
+
+	headers := []string{"#", "Beat", "Tipo", "Versión", "Estado", "Salud", "Eventos/s", "Descartados", "Cola"}
+	for col, header := range headers {
+		setCell(fleetTable, 0, col, header, tcell.ColorYellow)
+	}
+	healths, scored := fleetHealth()
+	// Sin puntaje van al final
+	scores := make([]float64, len(healths))
+	for i, health := range healths {
+		scores[i] = health.Score
+		if !scored[i] {
+			scores[i] = math.Inf(1)
+		}
+	}
+	names, groups := fleetGroups(scores)
+	fleetRows = fleetRows[:0]
+	fleetRows = append(fleetRows, -1)
+	row := 1
+	for _, name := range names {
+		tabs := groups[name]
+		up, worst := 0, math.Inf(1)
+		for _, i := range tabs {
+			if tabStates[i].sampled && tabStates[i].err == "" {
+				up++
+			}
+			worst = math.Min(worst, scores[i])
+		}
+		color := tcell.ColorGreen
+		if up < len(tabs) {
+			color = tcell.ColorRed
+		}
+		cells := []string{"", tview.Escape(name), "", "", fmt.Sprintf("%d/%d ok", up, len(tabs)), "", "", "", ""}
+		for col, text := range cells {
+			setCell(fleetTable, row, col, text, color)
+		}
+		if !math.IsInf(worst, 1) {
+			setCell(fleetTable, row, 5, fmt.Sprintf("peor %.0f", worst), healthColor(worst))
+		}
+		fleetTable.GetCell(row, 1).SetAttributes(tcell.AttrBold)
+		fleetRows = append(fleetRows, -1)
+		row++
+		for _, i := range tabs {
+			for col, text := range fleetCells(i) {
+				setCell(fleetTable, row, col, text, tabColor(tabStates[i]))
+			}
+			if scored[i] {
+				setCell(fleetTable, row, 5, healthText(healths[i]), healthColor(healths[i].Score))
+			}
+			// Los errores de conexión pueden ser largos
+			fleetTable.GetCell(row, 4).SetMaxWidth(40)
+			fleetRows = append(fleetRows, i)
+			row++
+		}
+	}
+	for r := fleetTable.GetRowCount() - 1; r >= row; r-- {
+		fleetTable.RemoveRow(r)
+	}
+}
+
+// fleetCells son las columnas de la pestaña i
+func fleetCells(i int) []string {
+	tab, state := options.Tabs[i], tabStates[i]
+	cells := []string{fmt.Sprint(i + 1), "  " + tview.Escape(tabShortName(tab)), "-", "-", "sin datos", "-", "-", "-", "-"}
+	switch {
+	case state.err != "":
+		cells[4] = "✗ " + tview.Escape(state.err)
+	case state.sampled:
+		cells[4] = "● ok"
+	}
+	sample := tab.Store.Latest()
+	if sample.Info != nil {
+		cells[2], cells[3] = sample.Info.Beat, sample.Info.Version
+	}
+	if sample.Stats == nil {
+		return cells
+	}
+	if rate, ok := eventRate(tab.Store.History()); ok {
+		cells[6] = formatEventRate(rate)
+	}
+	pipeline := sample.Stats.Libbeat.Pipeline
+	cells[7] = fmt.Sprint(pipeline.Events.Dropped)
+	if pipeline.Queue.MaxEvents > 0 {
+		cells[8] = fmt.Sprintf("%.0f%%", float64(pipeline.Queue.Filled.Events)/float64(pipeline.Queue.MaxEvents)*100)
+	}
+	return cells
+}
+
+// eventRate son los eventos/s del pipeline, suavizados según rateWindow
+func eventRate(history *metrics.History) (float64, bool) {
+	return history.RateOver("libbeat.pipeline.events.total", rateWindow)
+}
+
+// healthText es el puntaje con lo que más le resta, p. ej. 62 · cola 91%
+func healthText(h metrics.Health) string {
+	text := fmt.Sprintf("%.0f", h.Score)
+	switch h.Worst() {
+	case "descartes":
+		text += fmt.Sprintf(" · descartes %.1f%%", h.DropRatio*100)
+	case "cola":
+		text += fmt.Sprintf(" · cola %.0f%%", h.QueueFill*100)
+	case "conexión":
+		text += " · conexión"
+	case "retraso":
+		if math.IsInf(h.LagSeconds, 1) {
+			text += " · salida detenida"
+		} else {
+			text += " · retraso " + formatAgo(time.Duration(h.LagSeconds*float64(time.Second)))
+		}
+	}
+	return text
+}
+
+func healthColor(score float64) tcell.Color {
+	switch {
+	case score >= 80:
+		return tcell.ColorGreen
+	case score >= 50:
+		return tcell.ColorYellow
+	}
+	return tcell.ColorRed
+}
+
+func tabColor(state tabState) tcell.Color {
+	switch {
+	case state.err != "":
+		return tcell.ColorRed
+	case state.sampled:
+		return tcell.ColorWhite
+	}
+	return tcell.ColorGray
+}
@@ -0,0 +1,153 @@
+package ui
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/iTiagoCO/filtop/filtop/system"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// Panel Host: CPU y memoria de la máquina y los sistemas de archivos de los
+// logs, para distinguir un problema de Filebeat de uno de recursos. El
+// panel Rutas muestra cuánto ocupa y crece cada ruta de logs.
+
+// Uso de disco o inodos a partir del cual se resalta un sistema de archivos
+const (
+	diskWarnPercent     = 80
+	diskCriticalPercent = 90
+)
+
+// Si al ritmo actual el disco se llena antes de esto, la ruta se resalta
+const fullSoon = 6 * time.Hour
+
+func createHostPanel() *tview.Table {
+	table := tview.NewTable().SetBorders(false)
+	table.SetTitle(" Host ").SetBorder(true)
+	addMetricRow(table, 0, "CPU:", "-", tcell.ColorOrange)
+	addMetricRow(table, 1, "Memoria:", "-", tcell.ColorGreen)
+	return table
+}
+
+func createPathsPanel(paths []string) *tview.Table {
+	table := tview.NewTable().SetBorders(false)
+	table.SetTitle(" Rutas ").SetBorder(true)
+	for row, path := range paths {
+		addMetricRow(table, row, path+":", "-", tcell.ColorAqua)
+	}
+	return table
+}
+
+// UpdateHost muestra las métricas del host, con una fila por sistema de
+// archivos, y el uso de cada ruta de logs.
+func UpdateHost(stats *system.Stats, err error) {
+	queueUpdate(func() {
+		table := layout.host
+		if table == nil {
+			return
+		}
+		if err != nil {
+			setCell(table, 0, 1, "error: "+err.Error(), tcell.ColorRed)
+			return
+		}
+		updatePaths(stats.Paths)
+
+		setCell(table, 0, 1, fmt.Sprintf("%.1f%%", stats.CPUPercent)+compared("host.cpu_percent", stats.CPUPercent), tcell.ColorOrange)
+		memory := "-"
+		if stats.MemoryTotal > 0 {
+			percent := float64(stats.MemoryUsed) / float64(stats.MemoryTotal) * 100
+			memory = fmt.Sprintf("%s / %s (%.0f%%)", formatBytes(stats.MemoryUsed), formatBytes(stats.MemoryTotal), percent) +
+				compared("host.memory_percent", percent)
+		}
+		setCell(table, 1, 1, memory, tcell.ColorGreen)
+
+		rows := 2
+		if stats.Process != nil || stats.ProcessError != "" {
+			text, color := processText(stats)
+			setCell(table, rows, 0, "Filebeat:", tcell.ColorWhite)
+			setCell(table, rows, 1, text, color)
+			rows++
+		}
+		for _, fs := range stats.Filesystems {
+			text := fmt.Sprintf("disco %.0f%% · inodos %.0f%%", fs.UsedPercent, fs.InodesUsedPercent)
+			if fs.IOKnown {
+				text += fmt.Sprintf(" · L %s/s E %s/s", formatBytes(uint64(fs.ReadPerSec)), formatBytes(uint64(fs.WritePerSec)))
+			}
+			setCell(table, rows, 0, fs.Mountpoint+":", tcell.ColorWhite)
+			setCell(table, rows, 1, text, usageColor(max(fs.UsedPercent, fs.InodesUsedPercent)))
+			rows++
+		}
+		for row := table.GetRowCount() - 1; row >= rows; row-- {
+			table.RemoveRow(row)
+		}
+	})
+}
+
+func updatePaths(paths []system.PathUsage) {
+	for row, usage := range paths {
+		// El panel es angosto: el punto de montaje ya está en el panel Host
+		text := fmt.Sprintf("%s (%d)", formatBytes(usage.Bytes), usage.Files)
+		if usage.GrowthPerSec != 0 {
+			sign := "+"
+			if usage.GrowthPerSec < 0 {
+				sign = "-"
+			}
+			text += fmt.Sprintf(" %s%s/s", sign, formatBytes(uint64(math.Abs(usage.GrowthPerSec))))
+		}
+		if usage.Mountpoint != "" {
+			text += fmt.Sprintf(" · disco %.0f%%", usage.DiskUsedPercent)
+		}
+		color := usageColor(usage.DiskUsedPercent)
+		if usage.FullIn > 0 {
+			text += " · lleno en " + formatETA(usage.FullIn)
+			if usage.FullIn < fullSoon {
+				color = tcell.ColorRed
+			}
+		}
+		setCell(layout.paths, row, 1, text, color)
+	}
+}
+
+// formatETA redondea una duración larga a algo legible ("3h", "2d")
+func formatETA(d time.Duration) string {
+	switch {
+	case d >= 48*time.Hour:
+		return fmt.Sprintf("%dd", int(d.Hours()/24))
+	case d >= time.Hour:
+		return fmt.Sprintf("%dh", int(d.Hours()))
+	}
+	return fmt.Sprintf("%dm", int(d.Minutes())+1)
+}
+
+// processText resume el proceso de Filebeat. Los hilos en estado D se
+// resaltan: suelen indicar un disco o un montaje de red que no responde.
+func processText(stats *system.Stats) (string, tcell.Color) {
+	proc := stats.Process
+	if proc == nil {
+		return stats.ProcessError, tcell.ColorGray
+	}
+	text := fmt.Sprintf("PID %d · %d hilos", proc.PID, proc.Threads)
+	if proc.FDs != nil {
+		text += fmt.Sprintf(" · %d fds", *proc.FDs)
+	}
+	if proc.IO != nil {
+		text += fmt.Sprintf(" · L %s/s E %s/s", formatBytes(uint64(proc.IO.ReadPerSec)), formatBytes(uint64(proc.IO.WritePerSec)))
+	}
+	if proc.Blocked > 0 {
+		return text + fmt.Sprintf(" · %d en estado D", proc.Blocked), tcell.ColorRed
+	}
+	return text, tcell.ColorAqua
+}
+
+func usageColor(percent float64) tcell.Color {
+	switch {
+	case percent >= diskCriticalPercent:
+		return tcell.ColorRed
+	case percent >= diskWarnPercent:
+		return tcell.ColorYellow
+	}
+	return tcell.ColorAqua
+}
@@ -0,0 +1,123 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/iTiagoCO/filtop/filtop/kafka"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// Panel Kafka: eventos que Filebeat envió contra mensajes que llegaron al
+// topic y cuánto le falta leer al grupo de consumidores, para saber si los
+// logs efectivamente llegaron más allá de Kafka.
+
+// Filas del panel
+const (
+	kafkaRowTopic = iota
+	kafkaRowSent
+	kafkaRowWritten
+	kafkaRowDiff
+	kafkaRowLag
+	kafkaRowConsumed
+	kafkaRows
+)
+
+func createKafkaPanel() *tview.Table {
+	table := tview.NewTable().SetBorders(false)
+	table.SetTitle(" Kafka: " + tview.Escape(options.KafkaTopic) + " ").SetBorder(true)
+	addMetricRow(table, kafkaRowTopic, "Topic:", "-", tcell.ColorWhite)
+	addMetricRow(table, kafkaRowSent, "Enviados:", "-", tcell.ColorGreen)
+	addMetricRow(table, kafkaRowWritten, "Escritos:", "-", tcell.ColorGreen)
+	addMetricRow(table, kafkaRowDiff, "Diferencia:", "-", tcell.ColorWhite)
+	addMetricRow(table, kafkaRowLag, "Lag:", "-", tcell.ColorWhite)
+	addMetricRow(table, kafkaRowConsumed, "Consumidos:", "-", tcell.ColorGreen)
+	return table
+}
+
+// UpdateKafka muestra la comparación entre lo enviado y lo escrito en el
+// topic y el progreso del grupo que lo consume
+func UpdateKafka(stats *kafka.Stats, err error) {
+	queueUpdate(func() {
+		table := layout.kafka
+		if table == nil {
+			return
+		}
+		if err != nil {
+			setCell(table, kafkaRowTopic, 1, "error: "+err.Error(), tcell.ColorRed)
+			for row := kafkaRowSent; row < kafkaRows; row++ {
+				setCell(table, row, 1, "-", tcell.ColorGray)
+			}
+			return
+		}
+
+		text := fmt.Sprintf("%d particiones · %d brokers", stats.Partitions, stats.Brokers)
+		if stats.Brokers == 1 {
+			text = strings.TrimSuffix(text, "s")
+		}
+		color := tcell.ColorAqua
+		if stats.Leaderless > 0 {
+			text += fmt.Sprintf(" · %d sin líder", stats.Leaderless)
+			color = tcell.ColorRed
+		}
+		setCell(table, kafkaRowTopic, 1, text, color)
+
+		production := stats.Production
+		if production != nil {
+			setCell(table, kafkaRowWritten, 1, fmt.Sprintf("%.1f/s", production.Written)+compared("kafka.written_per_sec", production.Written), tcell.ColorGreen)
+			if production.Sent != nil {
+				sent := *production.Sent
+				setCell(table, kafkaRowSent, 1, fmt.Sprintf("%.1f/s", sent)+compared("kafka.sent_per_sec", sent), tcell.ColorGreen)
+				text, color := lossText(sent, production.Written)
+				setCell(table, kafkaRowDiff, 1, text, color)
+			} else {
+				setCell(table, kafkaRowSent, 1, "-", tcell.ColorGreen)
+				setCell(table, kafkaRowDiff, 1, "-", tcell.ColorWhite)
+			}
+		}
+		updateConsumerLag(table, stats)
+	})
+}
+
+// updateConsumerLag muestra el lag del grupo en mensajes y cuánto crece o,
+// si se achica, cuándo se pondría al día. Las particiones en las que el
+// grupo nunca confirmó un offset se indican aparte.
+func updateConsumerLag(table *tview.Table, stats *kafka.Stats) {
+	consumer := stats.Consumer
+	if consumer == nil {
+		text := "sin grupo configurado"
+		if stats.ConsumerError != "" {
+			text = stats.ConsumerError
+		}
+		setCell(table, kafkaRowLag, 1, text, tcell.ColorGray)
+		setCell(table, kafkaRowConsumed, 1, "-", tcell.ColorGray)
+		return
+	}
+
+	text := formatComputed(float64(consumer.Lag)) + compared("kafka.lag", float64(consumer.Lag))
+	color := tcell.ColorWhite
+	if consumer.Consumed != nil && stats.Production != nil && consumer.Lag > 0 {
+		switch growth := stats.Production.Written - *consumer.Consumed; {
+		case growth > 0:
+			text += fmt.Sprintf(" (+%.1f/s)", growth)
+			color = tcell.ColorYellow
+		case growth < 0:
+			catchUp := time.Duration(float64(consumer.Lag) / -growth * float64(time.Second))
+			text += " · al día en ~" + formatETA(catchUp)
+		}
+	}
+	if consumer.Uncommitted > 0 {
+		text += fmt.Sprintf(" · %d sin offset", consumer.Uncommitted)
+	}
+	setCell(table, kafkaRowLag, 1, text, color)
+
+	if consumer.Consumed == nil {
+		setCell(table, kafkaRowConsumed, 1, "-", tcell.ColorGreen)
+		return
+	}
+	consumed := *consumer.Consumed
+	setCell(table, kafkaRowConsumed, 1, fmt.Sprintf("%.1f/s · %s", consumed, tview.Escape(consumer.Group))+compared("kafka.consumed_per_sec", consumed), tcell.ColorGreen)
+}
@@ -0,0 +1,71 @@
+package ui
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/rivo/tview"
+)
+
+// Página Logs: en modo terminal el log de filtop va a un archivo para no
+// romper la pantalla, y aquí se muestran sus últimas líneas.
+
+const logLines = 500
+
+var (
+	logMu     sync.Mutex
+	logBuffer []string
+	logView   *tview.TextView
+)
+
+type logWriter struct{}
+
+// LogWriter conserva las últimas líneas escritas para la página Logs. Se
+// combina con el archivo de log mediante io.MultiWriter.
+func LogWriter() io.Writer { return logWriter{} }
+
+func (logWriter) Write(p []byte) (int, error) {
+	logMu.Lock()
+	for _, line := range strings.Split(strings.TrimRight(string(p), "\n"), "\n") {
+		logBuffer = append(logBuffer, line)
+	}
+	if len(logBuffer) > logLines {
+		logBuffer = append([]string(nil), logBuffer[len(logBuffer)-logLines:]...)
+	}
+	logMu.Unlock()
+
+	if app != nil {
+		queueUpdate(updateLogs)
+	}
+	return len(p), nil
+}
+
+func showLogsPage() {
+	logView = tview.NewTextView().SetScrollable(true)
+	title := " Logs de filtop "
+	if options.LogPath != "" {
+		title = fmt.Sprintf(" Logs de filtop (%s) ", options.LogPath)
+	}
+	logView.SetTitle(title).SetBorder(true)
+
+	pages.AddPage("logs", logView, true, true)
+	pages.SwitchToPage("logs")
+	updateLogs()
+}
+
+// updateLogs refresca la página si está a la vista
+func updateLogs() {
+	if logView == nil {
+		return
+	}
+	if name, _ := pages.GetFrontPage(); name != "logs" {
+		return
+	}
+	logMu.Lock()
+	text := strings.Join(logBuffer, "\n")
+	logMu.Unlock()
+	setText(logView, text)
+	logView.ScrollToEnd()
+}
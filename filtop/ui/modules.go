@@ -0,0 +1,173 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/iTiagoCO/filtop/filtop/client"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// moduleInputs devuelve los inputs generados por los filesets de un módulo.
+// Filebeat nombra esos inputs con el prefijo "<módulo>-<fileset>" o
+// "<módulo>.<fileset>", que es lo único que permite atribuirlos.
+func moduleInputs(module string, inputs []client.Input) []client.Input {
+	var result []client.Input
+	for _, input := range inputs {
+		if filesetName(module, input) != "" {
+			result = append(result, input)
+		}
+	}
+	return result
+}
+
+// moduleEventRate suma los eventos/s de los inputs de un módulo
+func moduleEventRate(module string) (rate float64, inputs int) {
+	for _, input := range moduleInputs(module, current.Stats.Filebeat.Inputs) {
+		inputRate, _ := inputEventRate(input.ID)
+		rate += inputRate
+		inputs++
+	}
+	return rate, inputs
+}
+
+func filesetName(module string, input client.Input) string {
+	id := strings.ToLower(input.ID)
+	module = strings.ToLower(module)
+	for _, sep := range []string{"-", "."} {
+		if rest, ok := strings.CutPrefix(id, module+sep); ok {
+			fileset, _, _ := strings.Cut(rest, "-")
+			return fileset
+		}
+	}
+	return ""
+}
+
+func findModule(name string) (client.Module, bool) {
+	if current.Stats != nil {
+		for _, module := range current.Stats.Filebeat.Modules.List {
+			if module.Name == name {
+				return module, true
+			}
+		}
+	}
+	return client.Module{}, false
+}
+
+func showModuleDetails(name string) {
+	module, ok := findModule(name)
+	if !ok {
+		return
+	}
+
+	summary := tview.NewTextView().SetDynamicColors(true)
+	summary.SetTitle(fmt.Sprintf(" Módulo: %s ", module.Name)).SetBorder(true)
+
+	table := tview.NewTable().SetBorders(false).SetFixed(1, 0)
+	table.SetTitle(" Filesets ").SetBorder(true)
+	headers := []string{"Fileset", "Input", "Events", "Events/s", "Bytes", "Errors", "Last Event"}
+	for col, h := range headers {
+		table.SetCell(0, col, tview.NewTableCell(h).SetTextColor(tcell.ColorYellow).SetAlign(tview.AlignCenter))
+	}
+
+	inputs := moduleInputs(module.Name, current.Stats.Filebeat.Inputs)
+	var totalEvents, totalErrors uint64
+	var totalRate float64
+	for i, input := range inputs {
+		rate, _ := inputEventRate(input.ID)
+		totalEvents += input.Events
+		totalErrors += input.Errors
+		totalRate += rate
+
+		errColor := tcell.ColorWhite
+		if input.Errors > 0 {
+			errColor = tcell.ColorRed
+		}
+		table.SetCell(i+1, 0, tview.NewTableCell(filesetName(module.Name, input)).SetTextColor(tcell.ColorWhite))
+		table.SetCell(i+1, 1, tview.NewTableCell(input.ID).SetTextColor(tcell.ColorWhite))
+		table.SetCell(i+1, 2, tview.NewTableCell(fmt.Sprintf("%d", input.Events)).SetTextColor(tcell.ColorWhite))
+		table.SetCell(i+1, 3, tview.NewTableCell(fmt.Sprintf("%.1f", rate)+compared("input."+input.ID, rate)).SetTextColor(tcell.ColorWhite))
+		table.SetCell(i+1, 4, tview.NewTableCell(formatBytes(input.Bytes)).SetTextColor(tcell.ColorWhite))
+		table.SetCell(i+1, 5, tview.NewTableCell(fmt.Sprintf("%d", input.Errors)).SetTextColor(errColor))
+		lastEvent := "-"
+		if input.State != nil && !input.State.LastPublished.IsZero() {
+			lastEvent = input.State.LastPublished.Local().Format(time.TimeOnly)
+		}
+		table.SetCell(i+1, 6, tview.NewTableCell(lastEvent).SetTextColor(tcell.ColorWhite))
+	}
+
+	status := "[red]deshabilitado[-]"
+	if module.Enabled {
+		status = "[green]habilitado[-]"
+	}
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "[yellow]Estado:[-] %s\n", status)
+	fmt.Fprintf(&builder, "[yellow]Errores del módulo:[-] %d\n", module.Errors)
+	fmt.Fprintf(&builder, "[yellow]Filesets con input:[-] %d\n", len(inputs))
+	fmt.Fprintf(&builder, "[yellow]Eventos:[-] %d (%.1f/s) | [yellow]Errores de inputs:[-] %d", totalEvents, totalRate, totalErrors)
+	if len(inputs) == 0 {
+		builder.WriteString("\n[gray]No hay inputs atribuibles a este módulo")
+	}
+	summary.SetText(builder.String())
+
+	errorsView := tview.NewTextView().SetDynamicColors(true).SetScrollable(true)
+	errorsView.SetTitle(" Errores ").SetBorder(true)
+	errorsView.SetText(moduleErrorReport(module, inputs))
+
+	layout := tview.NewFlex().SetDirection(tview.FlexRow)
+	layout.AddItem(summary, 6, 1, false)
+	layout.AddItem(table, 0, 1, true)
+	layout.AddItem(errorsView, 0, 1, false)
+
+	pages.AddPage("module_details", layout, true, true)
+	pages.SwitchToPage("module_details")
+}
+
+// moduleErrorReport detalla los errores de un módulo: contadores de error de
+// sus inputs y, si se configuró -filebeat-log, las líneas de error recientes
+// del log de Filebeat que mencionan el módulo o alguno de sus inputs.
+func moduleErrorReport(module client.Module, inputs []client.Input) string {
+	var builder strings.Builder
+
+	builder.WriteString("[yellow]Contadores de error por input:[-]\n")
+	var withErrors int
+	for _, input := range inputs {
+		if input.Errors > 0 {
+			fmt.Fprintf(&builder, "  [red]%d[-] %s\n", input.Errors, input.ID)
+			withErrors++
+		}
+	}
+	if withErrors == 0 {
+		builder.WriteString("  [gray]Ningún input reporta errores de procesamiento[-]\n")
+	}
+
+	builder.WriteString("\n[yellow]Log de Filebeat:[-]\n")
+	if options.FilebeatLogPath == "" {
+		builder.WriteString("  [gray]Usa -filebeat-log para correlacionar con el log de Filebeat[-]\n")
+		return builder.String()
+	}
+
+	keywords := []string{module.Name}
+	for _, input := range inputs {
+		keywords = append(keywords, input.ID)
+	}
+	entries, err := client.TailErrorLines(options.FilebeatLogPath, keywords, 50)
+	if err != nil {
+		fmt.Fprintf(&builder, "  [red]No se pudo leer %s: %v[-]\n", options.FilebeatLogPath, err)
+		return builder.String()
+	}
+	if len(entries) == 0 {
+		builder.WriteString("  [gray]Sin errores recientes para este módulo[-]\n")
+	}
+	for _, entry := range entries {
+		color := "yellow"
+		if entry.Level == "error" {
+			color = "red"
+		}
+		fmt.Fprintf(&builder, "  [gray]%s[-] [%s]%s[-] %s\n", entry.Time, color, strings.ToUpper(entry.Level), tview.Escape(entry.Message))
+	}
+	return builder.String()
+}
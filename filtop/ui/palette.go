@@ -0,0 +1,371 @@
+package ui
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/iTiagoCO/filtop/filtop/client"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// Paleta de comandos: la tecla : abre, como en k9s, una línea donde se
+// escribe el comando con coincidencia aproximada (p. ej. "intv 10" es
+// "interval 10"), para usar las funciones avanzadas sin memorizar una tecla
+// para cada una. ↑ y ↓ eligen entre los que coinciden y Enter ejecuta.
+
+// command es una acción de la paleta. Si arg no está vacío el comando
+// recibe el resto de la línea, p. ej. los segundos de interval.
+type command struct {
+	name string
+	arg  string
+	help string
+	run  func(arg string) error
+}
+
+// Resultado del último comando, que se muestra en la cabecera un rato
+var (
+	commandResult   string
+	commandResultAt time.Time
+)
+
+const commandResultFor = 10 * time.Second
+
+// Paneles que se pueden ocultar con toggle; los que reciben el foco con
+// Tab no se ocultan
+var togglePanels = []string{"queue", "harvesters", "host", "elasticsearch", "kafka", "probe", "endpoints", "custom", "watch", "panels"}
+
+// hiddenPanels son los paneles ocultos de la página principal
+var hiddenPanels = make(map[string]bool)
+
+// inputFilter muestra solo los inputs cuyo id o tipo lo contiene
+var inputFilter string
+
+func commands() []command {
+	var list []command
+	if multipleTabs() {
+		for i, tab := range options.Tabs {
+			i := i
+			list = append(list, command{name: "tab " + tab.Name, help: "ir a la pestaña", run: func(string) error {
+				pages.SwitchToPage("main")
+				switchTab(i)
+				return nil
+			}})
+		}
+	}
+	list = append(list,
+		command{name: "interval", arg: "<segundos>", help: "cambiar el intervalo de refresco", run: setInterval},
+		command{name: "filter", arg: "<texto>", help: "filtrar los inputs por id o tipo; vacío quita el filtro", run: setInputFilter},
+		command{name: "export", help: "guardar la última muestra de /stats en un JSON", run: exportSnapshot},
+		command{name: "watch", arg: "<ruta>", help: "fijar o quitar una ruta de /stats en el panel Watch", run: watchPath},
+		command{name: "smoothing", arg: "<instant|1m|5m>", help: "suavizar las tasas con un promedio", run: setSmoothingCommand},
+	)
+	if options.Silences != nil {
+		list = append(list,
+			command{name: "silence", arg: "<duración> [regla]", help: "no notificar las alertas de la pestaña, p. ej. silence 30m", run: silence},
+			command{name: "unsilence", help: "quitar los silencios de la pestaña", run: func(string) error {
+				unsilence()
+				return nil
+			}},
+		)
+	}
+	for _, panel := range togglePanels {
+		panel := panel
+		list = append(list, command{name: "toggle " + panel, help: "mostrar u ocultar el panel", run: func(string) error {
+			hiddenPanels[panel] = !hiddenPanels[panel]
+			pages.SwitchToPage("main")
+			rebuildMainPage()
+			updateUI()
+			return nil
+		}})
+	}
+	pageCommands := []struct {
+		name, help string
+		show       func()
+	}{
+		{"fleet", "página Flota", showFleetPage},
+		{"top", "inputs con más eventos/s", showTopPage},
+		{"timeline", "ciclo de vida de los inputs", showTimelinePage},
+		{"metrics", "navegar el documento /stats", showBrowserPage},
+		{"registry", "rotaciones de los archivos", showRegistryPage},
+		{"filebeat.yml", "configuración de Filebeat con notas", showBeatConfigPage},
+		{"session", "resumen de la sesión", showSessionPage},
+		{"alerts", "historial de alertas", showAlertsPage},
+		{"charts", "gráficos", showChartsPage},
+		{"logs", "log de filtop", showLogsPage},
+		{"pprof", "perfiles de Filebeat", showPprofPage},
+	}
+	for _, page := range pageCommands {
+		show := page.show
+		list = append(list, command{name: page.name, help: page.help, run: func(string) error {
+			pages.SwitchToPage("main")
+			show()
+			return nil
+		}})
+	}
+	if options.SelectProfile != nil {
+		for _, name := range options.Profiles {
+			name := name
+			list = append(list, command{name: "profile " + name, help: "recargar con el perfil", run: func(string) error {
+				pages.SwitchToPage("main")
+				go options.SelectProfile(name)
+				return nil
+			}})
+		}
+	}
+	if options.Reload != nil {
+		list = append(list, command{name: "reload", help: "volver a leer la configuración", run: func(string) error {
+			pages.SwitchToPage("main")
+			go options.Reload()
+			return nil
+		}})
+	}
+	list = append(list,
+		command{name: "baseline", help: "capturar una línea base", run: func(string) error {
+			pages.SwitchToPage("main")
+			startCapture()
+			return nil
+		}},
+		command{name: "compare", help: "activar o desactivar el modo comparación", run: func(string) error {
+			pages.SwitchToPage("main")
+			setCompare(!compareMode)
+			updateUI()
+			return nil
+		}},
+		command{name: "changes", help: "resaltar o no los contadores que cambiaron", run: func(string) error {
+			pages.SwitchToPage("main")
+			setChanges(!changesMode)
+			return nil
+		}},
+		command{name: "quit", help: "salir de filtop", run: func(string) error {
+			app.Stop()
+			return nil
+		}},
+	)
+	return list
+}
+
+// match es un comando que coincide con lo escrito, con su argumento
+type match struct {
+	command
+	arg   string
+	score int
+}
+
+// matchCommands ordena los comandos que coinciden con line, de mejor a peor
+func matchCommands(list []command, line string) []match {
+	line = strings.TrimLeft(line, " ")
+	var matches []match
+	for _, cmd := range list {
+		query, arg := line, ""
+		if cmd.arg != "" {
+			// El nombre es la primera palabra y el resto el argumento
+			if i := strings.IndexByte(line, ' '); i >= 0 {
+				query, arg = line[:i], strings.TrimSpace(line[i+1:])
+			}
+		}
+		score, ok := fuzzyScore(cmd.name, strings.TrimSpace(query))
+		if !ok {
+			continue
+		}
+		matches = append(matches, match{command: cmd, arg: arg, score: score})
+	}
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].score > matches[j].score })
+	return matches
+}
+
+// fuzzyScore indica si las letras de query aparecen en orden en name, sin
+// distinguir mayúsculas. Puntúan más las seguidas y las que empiezan una
+// palabra, así "tw1" prefiere "tab web-1".
+func fuzzyScore(name, query string) (int, bool) {
+	name, query = strings.ToLower(name), strings.ToLower(query)
+	score, last := 0, -2
+	runes := []rune(name)
+	i := 0
+	for _, q := range query {
+		if q == ' ' {
+			continue
+		}
+		for i < len(runes) && runes[i] != q {
+			i++
+		}
+		if i == len(runes) {
+			return 0, false
+		}
+		score++
+		if i == last+1 {
+			score += 3
+		}
+		if i == 0 || !unicode.IsLetter(runes[i-1]) && !unicode.IsDigit(runes[i-1]) {
+			score += 2
+		}
+		last = i
+		i++
+	}
+	return score, true
+}
+
+func showPalette() {
+	list := commands()
+	input := tview.NewInputField().SetLabel(":").SetFieldBackgroundColor(tcell.ColorDefault)
+	results := tview.NewList().ShowSecondaryText(false).SetHighlightFullLine(true)
+	status := tview.NewTextView().SetDynamicColors(true)
+	var matches []match
+
+	refresh := func(line string) {
+		matches = matchCommands(list, line)
+		results.Clear()
+		for _, m := range matches {
+			label := tview.Escape(m.name)
+			if m.arg != "" {
+				label += " [yellow]" + tview.Escape(m.arg) + "[-]"
+			} else if m.command.arg != "" {
+				label += " [gray]" + m.command.arg + "[-]"
+			}
+			results.AddItem(label+"  [gray]"+tview.Escape(m.help)+"[-]", "", 0, nil)
+		}
+		status.SetText("")
+	}
+	hide := func() {
+		pages.RemovePage("palette")
+		if front, _ := pages.GetFrontPage(); front == "main" {
+			app.SetFocus(getFocusableComponent(currentFocus))
+		}
+	}
+	input.SetChangedFunc(refresh)
+	input.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch event.Key() {
+		case tcell.KeyUp:
+			results.SetCurrentItem((results.GetCurrentItem() - 1 + max(1, results.GetItemCount())) % max(1, results.GetItemCount()))
+			return nil
+		case tcell.KeyDown:
+			results.SetCurrentItem((results.GetCurrentItem() + 1) % max(1, results.GetItemCount()))
+			return nil
+		case tcell.KeyEsc:
+			hide()
+			return nil
+		case tcell.KeyEnter:
+			if len(matches) == 0 {
+				return nil
+			}
+			// Si falla la paleta sigue abierta para corregirlo
+			m := matches[results.GetCurrentItem()]
+			if err := m.run(m.arg); err != nil {
+				status.SetText("[red]" + tview.Escape(err.Error()) + "[-]")
+				return nil
+			}
+			hide()
+			return nil
+		}
+		return event
+	})
+	refresh("")
+
+	box := tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(input, 1, 0, true).
+		AddItem(results, 0, 1, false).
+		AddItem(status, 1, 0, false)
+	box.SetBorder(true).SetTitle(" Comandos ")
+	modal := tview.NewFlex().
+		AddItem(nil, 0, 1, false).
+		AddItem(tview.NewFlex().SetDirection(tview.FlexRow).
+			AddItem(nil, 0, 1, false).
+			AddItem(box, min(len(list), 12)+4, 0, true).
+			AddItem(nil, 0, 1, false), 70, 0, true).
+		AddItem(nil, 0, 1, false)
+	// Encima de la página actual
+	pages.AddPage("palette", modal, true, true)
+}
+
+// setCommandResult muestra en la cabecera el resultado de un comando
+func setCommandResult(text string) {
+	commandResult, commandResultAt = text, time.Now()
+	if current.Stats != nil {
+		updateHeader()
+	}
+}
+
+// commandSummary es el resultado del último comando para la cabecera,
+// mientras sea reciente
+func commandSummary() string {
+	if commandResult == "" || time.Since(commandResultAt) > commandResultFor {
+		return ""
+	}
+	return " | " + commandResult
+}
+
+func setInterval(arg string) error {
+	if options.SetInterval == nil {
+		return errors.New("no disponible")
+	}
+	seconds, err := strconv.Atoi(arg)
+	if err != nil || seconds <= 0 {
+		return errors.New("el intervalo debe ser una cantidad de segundos mayor que 0")
+	}
+	// La recarga espera a los colectores: no puede bloquear la interfaz
+	go options.SetInterval(seconds)
+	setCommandResult(fmt.Sprintf("[green]intervalo: %ds[-]", seconds))
+	return nil
+}
+
+func setInputFilter(arg string) error {
+	inputFilter = arg
+	pages.SwitchToPage("main")
+	if current.Stats != nil {
+		updateInputs()
+	}
+	updateTopPage()
+	updateTimelinePage()
+	return nil
+}
+
+// filterInputs son los inputs cuyo id o tipo contiene inputFilter
+func filterInputs(inputs []client.Input) []client.Input {
+	if inputFilter == "" {
+		return inputs
+	}
+	var filtered []client.Input
+	for _, input := range inputs {
+		if matchesInputFilter(input.ID, input.Type) {
+			filtered = append(filtered, input)
+		}
+	}
+	return filtered
+}
+
+// matchesInputFilter indica si el filtro de inputs incluye al input con ese
+// id y tipo
+func matchesInputFilter(id, inputType string) bool {
+	filter := strings.ToLower(inputFilter)
+	return strings.Contains(strings.ToLower(id), filter) || strings.Contains(strings.ToLower(inputType), filter)
+}
+
+// exportSnapshot escribe la última respuesta de /stats en un JSON del
+// directorio actual, que se puede volver a abrir con -from-file
+func exportSnapshot(string) error {
+	if current.Stats == nil || current.Stats.Raw == nil {
+		return errors.New("todavía no hay muestras")
+	}
+	path, err := filepath.Abs(fmt.Sprintf("filtop-snapshot-%s.json", current.Stats.Timestamp.Format("20060102-150405")))
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(current.Stats.Raw, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		return err
+	}
+	setCommandResult("[green]exportado a " + tview.Escape(path) + "[-]")
+	return nil
+}
@@ -0,0 +1,194 @@
+package ui
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/iTiagoCO/filtop/filtop/metrics"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// Tipos de panel definibles en la configuración
+const (
+	PanelTable     = "table"
+	PanelGauge     = "gauge"
+	PanelSparkline = "sparkline"
+)
+
+// Ancho de las barras de gauge y cantidad de puntos de un sparkline
+const (
+	gaugeWidth      = 20
+	sparklinePoints = 30
+)
+
+var sparkBlocks = []rune("▁▂▃▄▅▆▇█")
+
+// Panel describe un panel definido por el usuario. Max es el 100% de los
+// gauges; Right lo coloca en el panel derecho en lugar del izquierdo.
+type Panel struct {
+	Title  string
+	Type   string
+	Labels []string
+	Max    float64
+	Right  bool
+}
+
+func createUserPanel(panel Panel) tview.Primitive {
+	if panel.Type == PanelTable {
+		table := tview.NewTable().SetBorders(false)
+		table.SetTitle(fmt.Sprintf(" %s ", panel.Title)).SetBorder(true)
+		for row, label := range panel.Labels {
+			addMetricRow(table, row, label+":", "-", tcell.ColorAqua)
+		}
+		return table
+	}
+
+	view := tview.NewTextView().SetDynamicColors(true)
+	view.SetTitle(fmt.Sprintf(" %s ", panel.Title)).SetBorder(true)
+	view.SetText("Cargando...")
+	return view
+}
+
+// UpdatePanels muestra los valores de los paneles definidos por el usuario
+// para la pestaña tab, en el orden de la configuración.
+func UpdatePanels(tab int, values [][]metrics.ComputedValue) {
+	queueUpdate(func() {
+		state := &tabStates[tab]
+		state.panels = values
+		state.series = appendSeries(state.series, values)
+		if tab == activeTab {
+			showPanels(values)
+		}
+	})
+}
+
+func showPanels(values [][]metrics.ComputedValue) {
+	series := tabStates[activeTab].series
+	for i, panelValues := range values {
+		if i >= len(layout.panels) {
+			return
+		}
+		panel := options.Panels[i]
+		switch view := layout.panels[i].(type) {
+		case *tview.Table:
+			for row, value := range panelValues {
+				switch {
+				case value.Err != nil:
+					setCell(view, row, 1, "error", tcell.ColorRed)
+				case math.IsNaN(value.Value):
+					setCell(view, row, 1, "-", tcell.ColorGray)
+				default:
+					setCell(view, row, 1, formatComputed(value.Value)+compared(panelKey(panel, row), value.Value), tcell.ColorAqua)
+				}
+			}
+		case *tview.TextView:
+			setText(view, renderPanel(panel, panelValues, series[i]))
+		}
+	}
+}
+
+// appendSeries agrega los valores de una muestra a los recientes de cada
+// métrica de cada panel, que se muestran como sparklines
+func appendSeries(series [][][]float64, values [][]metrics.ComputedValue) [][][]float64 {
+	for len(series) < len(values) {
+		series = append(series, nil)
+	}
+	for i, panelValues := range values {
+		for len(series[i]) < len(panelValues) {
+			series[i] = append(series[i], nil)
+		}
+		for j, value := range panelValues {
+			points := append(series[i][j], value.Value)
+			if len(points) > sparklinePoints {
+				points = points[len(points)-sparklinePoints:]
+			}
+			series[i][j] = points
+		}
+	}
+	return series
+}
+
+func renderPanel(panel Panel, values []metrics.ComputedValue, series [][]float64) string {
+	width := 0
+	for _, label := range panel.Labels {
+		if len(label) > width {
+			width = len(label)
+		}
+	}
+
+	var builder strings.Builder
+	for i, value := range values {
+		fmt.Fprintf(&builder, "%-*s ", width, panel.Labels[i])
+		if value.Err != nil {
+			builder.WriteString("[red]error[-]\n")
+			continue
+		}
+		if panel.Type == PanelGauge {
+			builder.WriteString(gauge(value.Value, panel.Max))
+		} else {
+			fmt.Fprintf(&builder, "[green]%s[-]", sparkline(series[i]))
+		}
+		if math.IsNaN(value.Value) {
+			builder.WriteString(" [gray]-[-]\n")
+		} else {
+			fmt.Fprintf(&builder, " %s%s\n", formatComputed(value.Value), compared(panelKey(panel, i), value.Value))
+		}
+	}
+	return builder.String()
+}
+
+// panelKey es la clave de la línea base de la fila row de un panel
+func panelKey(panel Panel, row int) string {
+	return "panel." + panel.Title + "." + panel.Labels[row]
+}
+
+func gauge(value, max float64) string {
+	if max <= 0 {
+		max = 100
+	}
+	ratio := value / max
+	if math.IsNaN(ratio) || ratio < 0 {
+		ratio = 0
+	}
+	if ratio > 1 {
+		ratio = 1
+	}
+
+	color := "green"
+	switch {
+	case ratio >= 0.9:
+		color = "red"
+	case ratio >= 0.7:
+		color = "yellow"
+	}
+	filled := int(ratio * gaugeWidth)
+	return fmt.Sprintf("[%s]%s[gray]%s[-] %3.0f%%", color, strings.Repeat("█", filled), strings.Repeat(".", gaugeWidth-filled), ratio*100)
+}
+
+// sparkline escala la serie entre su mínimo y su máximo; los NaN se dejan
+// en blanco.
+func sparkline(series []float64) string {
+	lo, hi := math.Inf(1), math.Inf(-1)
+	for _, v := range series {
+		if !math.IsNaN(v) {
+			lo = math.Min(lo, v)
+			hi = math.Max(hi, v)
+		}
+	}
+
+	out := make([]rune, len(series))
+	for i, v := range series {
+		switch {
+		case math.IsNaN(v):
+			out[i] = ' '
+		case hi == lo:
+			out[i] = sparkBlocks[0]
+		default:
+			out[i] = sparkBlocks[int((v-lo)/(hi-lo)*float64(len(sparkBlocks)-1))]
+		}
+	}
+	return string(out)
+}
@@ -0,0 +1,153 @@
+package ui
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/iTiagoCO/filtop/filtop/client"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// Página pprof: resume /debug/pprof/heap y /debug/pprof/goroutine (formato
+// de texto debug=1) para diagnosticar fugas sin instalar go tool pprof.
+
+const pprofTop = 15
+
+func showPprofPage() {
+	heapView := tview.NewTextView().SetDynamicColors(true).SetScrollable(true)
+	heapView.SetTitle(" Heap: top asignaciones en uso ").SetBorder(true)
+	goroutineView := tview.NewTextView().SetDynamicColors(true).SetScrollable(true)
+	goroutineView.SetTitle(" Goroutines: top stacks ").SetBorder(true)
+
+	layout := tview.NewFlex().
+		AddItem(heapView, 0, 1, true).
+		AddItem(goroutineView, 0, 1, false)
+	layout.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() == tcell.KeyRune && event.Rune() == 'r' {
+			go loadPprof(options.Tabs[activeTab].PprofURL, options.Transport, heapView, goroutineView)
+			return nil
+		}
+		return event
+	})
+
+	pages.AddPage("pprof", layout, true, true)
+	pages.SwitchToPage("pprof")
+	go loadPprof(options.Tabs[activeTab].PprofURL, options.Transport, heapView, goroutineView)
+}
+
+func loadPprof(pprofURL string, transport http.RoundTripper, heapView, goroutineView *tview.TextView) {
+	app.QueueUpdateDraw(func() {
+		heapView.SetText("[gray]Cargando...")
+		goroutineView.SetText("[gray]Cargando...")
+	})
+
+	httpClient := &http.Client{Timeout: 30 * time.Second, Transport: transport}
+	heap, heapErr := client.FetchPprof(httpClient, pprofURL+"/debug/pprof/heap?debug=1")
+	goroutines, goroutineErr := client.FetchPprof(httpClient, pprofURL+"/debug/pprof/goroutine?debug=1")
+
+	app.QueueUpdateDraw(func() {
+		if heapErr != nil {
+			heapView.SetText(pprofError(heapErr))
+		} else {
+			heapView.SetText(formatHeapTop(heap))
+		}
+		if goroutineErr != nil {
+			goroutineView.SetText(pprofError(goroutineErr))
+		} else {
+			goroutineView.SetText(formatGoroutineTop(goroutines))
+		}
+	})
+}
+
+func pprofError(err error) string {
+	return fmt.Sprintf("[red]%v[-]\n\n[gray]Inicia Filebeat con http.pprof.enabled: true\nr: reintentar", tview.Escape(err.Error()))
+}
+
+// formatHeapTop agrupa las asignaciones en uso por la primera función fuera
+// del runtime y las ordena por bytes.
+func formatHeapTop(entries []client.PprofEntry) string {
+	type site struct {
+		fn    string
+		bytes int64
+		count int64
+	}
+	sites := make(map[string]*site)
+	var total int64
+	for _, entry := range entries {
+		fn := firstUserFrame(entry.Stack)
+		s, ok := sites[fn]
+		if !ok {
+			s = &site{fn: fn}
+			sites[fn] = s
+		}
+		s.bytes += entry.Bytes
+		s.count += entry.Count
+		total += entry.Bytes
+	}
+
+	sorted := make([]*site, 0, len(sites))
+	for _, s := range sites {
+		sorted = append(sorted, s)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].bytes > sorted[j].bytes })
+
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "[yellow]En uso (muestreado):[-] %s\n\n", formatBytes(uint64(total)))
+	for i, s := range sorted {
+		if i == pprofTop {
+			break
+		}
+		percent := 0.0
+		if total > 0 {
+			percent = float64(s.bytes) / float64(total) * 100
+		}
+		fmt.Fprintf(&builder, "[green]%10s[-] %5.1f%% %6d obj  %s\n", formatBytes(uint64(s.bytes)), percent, s.count, tview.Escape(s.fn))
+	}
+	builder.WriteString("\n[gray]r: actualizar | Esc: regresar")
+	return builder.String()
+}
+
+func formatGoroutineTop(entries []client.PprofEntry) string {
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Count > entries[j].Count })
+
+	var total int64
+	for _, entry := range entries {
+		total += entry.Count
+	}
+
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "[yellow]Goroutines:[-] %d en %d stacks distintos\n\n", total, len(entries))
+	for i, entry := range entries {
+		if i == pprofTop {
+			break
+		}
+		fmt.Fprintf(&builder, "[green]%d[-] goroutines\n", entry.Count)
+		for j, fn := range entry.Stack {
+			if j == 5 {
+				fmt.Fprintf(&builder, "    [gray]... %d frames más[-]\n", len(entry.Stack)-j)
+				break
+			}
+			fmt.Fprintf(&builder, "    %s\n", tview.Escape(fn))
+		}
+		builder.WriteString("\n")
+	}
+	builder.WriteString("[gray]r: actualizar | Esc: regresar")
+	return builder.String()
+}
+
+func firstUserFrame(stack []string) string {
+	for _, fn := range stack {
+		if !strings.HasPrefix(fn, "runtime.") {
+			return fn
+		}
+	}
+	if len(stack) > 0 {
+		return stack[0]
+	}
+	return "?"
+}
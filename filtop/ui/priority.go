@@ -0,0 +1,288 @@
+package ui
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/iTiagoCO/filtop/filtop/metrics"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// Paneles de las alertas: mientras una alerta está activa, el panel de la
+// página principal que muestra su métrica se enmarca con el color de su
+// severidad y los demás se atenúan, para que la atención vaya a lo que
+// importa durante un incidente. Entre muestras, la cola y los inputs de una
+// alerta se actualizan varias veces por intervalo con una estimación a
+// partir de la última tasa, marcada con ~. Las alertas silenciadas no
+// cuentan.
+
+// MainPanels son los paneles de la página principal a los que se puede
+// asignar una alerta, además de los títulos de los paneles propios
+var MainPanels = []string{"system", "queue", "harvesters", "host", "elasticsearch", "kafka", "probe", "inputs", "modules", "custom", "watch", "endpoints"}
+
+// AlertRule indica en qué panel se ve una alerta: Panel si se configuró o,
+// si no, los que muestran las métricas de su condición
+type AlertRule struct {
+	Name    string
+	Metrics []string
+	Panel   string
+}
+
+// metricPanels relaciona prefijos de las rutas de /stats, sin libbeat.,
+// filebeat., beat. ni system., con el panel que las muestra
+var metricPanels = []struct{ prefix, panel string }{
+	{"pipeline.queue", "queue"},
+	{"pipeline.events.active", "queue"},
+	{"harvester", "harvesters"},
+	{"cpu", "system"},
+	{"memstats", "system"},
+	{"info.uptime", "system"},
+	{"load", "system"},
+	{"input", "inputs"},
+	{"events", "inputs"},
+	{"output", "elasticsearch"},
+}
+
+// Actualizaciones estimadas de los paneles de una alerta por intervalo, y
+// el tiempo mínimo entre dos
+const (
+	priorityUpdates = 4
+	minPriorityTick = 250 * time.Millisecond
+)
+
+// alertPanels son los paneles de la alerta rule
+func alertPanels(rule string) []string {
+	for _, r := range options.AlertRules {
+		if r.Name != rule {
+			continue
+		}
+		if r.Panel != "" {
+			return []string{r.Panel}
+		}
+		var panels []string
+		for _, metric := range r.Metrics {
+			if panel := metricPanel(metric); panel != "" && !containsPath(panels, panel) {
+				panels = append(panels, panel)
+			}
+		}
+		return panels
+	}
+	return nil
+}
+
+// metricPanel es el panel que muestra la métrica path, o "" si ninguno
+func metricPanel(path string) string {
+	switch {
+	case containsPath(options.Computed, path):
+		return "custom"
+	case containsPath(watchPaths(), path):
+		return "watch"
+	case path == metrics.QueueFullIn:
+		return "queue"
+	}
+	for _, prefix := range []string{"libbeat.", "filebeat.", "beat.", "system."} {
+		if trimmed, ok := strings.CutPrefix(path, prefix); ok {
+			path = trimmed
+			break
+		}
+	}
+	for _, m := range metricPanels {
+		if path == m.prefix || strings.HasPrefix(path, m.prefix+".") {
+			// Sin Elasticsearch, las métricas de salida se ven junto a Kafka
+			if m.panel == "elasticsearch" && !options.Elasticsearch && options.KafkaTopic != "" {
+				return "kafka"
+			}
+			return m.panel
+		}
+	}
+	return ""
+}
+
+// priorityPanels son los paneles de las alertas activas de la pestaña, con
+// el color de la más severa de cada uno
+func priorityPanels() map[string]tcell.Color {
+	panels := make(map[string]tcell.Color)
+	ranks := make(map[string]int)
+	for _, alert := range activeAlerts {
+		if alert.Silenced {
+			continue
+		}
+		rank := severityRank(alert.Severity)
+		for _, panel := range alertPanels(alert.Rule) {
+			if rank > ranks[panel] {
+				ranks[panel] = rank
+				panels[panel] = tcell.GetColor(severityColor(alert.Severity))
+			}
+		}
+	}
+	return panels
+}
+
+func severityRank(severity string) int {
+	switch severity {
+	case "critical":
+		return 3
+	case "info":
+		return 1
+	}
+	return 2
+}
+
+// drawPriority enmarca los paneles de las alertas activas y atenúa los
+// demás, sobre la página principal ya dibujada. Si ningún panel de las
+// alertas está a la vista no cambia nada.
+func drawPriority(screen tcell.Screen) {
+	if front, _ := pages.GetFrontPage(); front != "main" {
+		return
+	}
+	framed := make(map[tview.Primitive]tcell.Color)
+	for name, color := range priorityPanels() {
+		for _, item := range layout.named[name] {
+			framed[item] = color
+		}
+	}
+	if len(framed) == 0 {
+		return
+	}
+	for _, items := range layout.named {
+		for _, item := range items {
+			x, y, width, height := item.GetRect()
+			if color, ok := framed[item]; ok {
+				frameRect(screen, x, y, width, height, color)
+			} else {
+				dimRect(screen, x, y, width, height)
+			}
+		}
+	}
+}
+
+// frameRect pinta con color el borde y el título de un panel
+func frameRect(screen tcell.Screen, x, y, width, height int, color tcell.Color) {
+	for col := x; col < x+width; col++ {
+		for _, row := range []int{y, y + height - 1} {
+			restyle(screen, col, row, func(style tcell.Style) tcell.Style { return style.Foreground(color).Bold(true) })
+		}
+	}
+	for row := y + 1; row < y+height-1; row++ {
+		for _, col := range []int{x, x + width - 1} {
+			restyle(screen, col, row, func(style tcell.Style) tcell.Style { return style.Foreground(color).Bold(true) })
+		}
+	}
+}
+
+// dimRect atenúa todo el contenido de un panel
+func dimRect(screen tcell.Screen, x, y, width, height int) {
+	for row := y; row < y+height; row++ {
+		for col := x; col < x+width; col++ {
+			restyle(screen, col, row, func(style tcell.Style) tcell.Style { return style.Dim(true) })
+		}
+	}
+}
+
+func restyle(screen tcell.Screen, x, y int, change func(tcell.Style) tcell.Style) {
+	mainc, combc, style, _ := screen.GetContent(x, y)
+	screen.SetContent(x, y, mainc, combc, change(style))
+}
+
+// schedulePriorityUpdates agenda las estimaciones de los paneles de las
+// alertas hasta la próxima muestra; se descartan si llega antes
+func schedulePriorityUpdates() {
+	panels := priorityPanels()
+	_, queue := panels["queue"]
+	_, inputs := panels["inputs"]
+	if !queue && !inputs {
+		return
+	}
+	interval, ok := sampleInterval()
+	step := interval / priorityUpdates
+	if !ok || step < minPriorityTick {
+		return
+	}
+	sample := current.Stats
+	for k := 1; k < priorityUpdates; k++ {
+		elapsed := step * time.Duration(k)
+		time.AfterFunc(elapsed, func() {
+			queueUpdate(func() {
+				if current.Stats != sample {
+					return
+				}
+				panels := priorityPanels()
+				if _, ok := panels["queue"]; ok {
+					estimateQueue(elapsed)
+				}
+				if _, ok := panels["inputs"]; ok {
+					estimateInputs(elapsed)
+				}
+			})
+		})
+	}
+}
+
+// sampleInterval es el tiempo entre las dos últimas muestras
+func sampleInterval() (time.Duration, bool) {
+	_, last, ok := store.History().Value(0, "beat.info.uptime.ms")
+	_, previous, found := store.History().Value(1, "beat.info.uptime.ms")
+	if !ok || !found || !last.After(previous) {
+		return 0, false
+	}
+	return last.Sub(previous), true
+}
+
+// lastSlope es cuánto por segundo cambió path entre las dos últimas
+// muestras
+func lastSlope(path string) (float64, bool) {
+	last, lastAt, ok := store.History().Value(0, path)
+	previous, previousAt, found := store.History().Value(1, path)
+	if !ok || !found || !lastAt.After(previousAt) {
+		return 0, false
+	}
+	return (last - previous) / lastAt.Sub(previousAt).Seconds(), true
+}
+
+// estimateQueue muestra el llenado de la cola elapsed después de la
+// muestra, si siguiera cambiando como entre las dos últimas
+func estimateQueue(elapsed time.Duration) {
+	queue := current.Stats.Libbeat.Pipeline.Queue
+	slope, ok := lastSlope("pipeline.queue.filled.events")
+	if !ok {
+		return
+	}
+	filled := math.Max(float64(queue.Filled.Events)+slope*elapsed.Seconds(), 0)
+	if queue.MaxEvents > 0 {
+		filled = math.Min(filled, float64(queue.MaxEvents))
+	}
+	events := uint64(math.Round(filled))
+	setText(layout.queue, queueText(events, fmt.Sprintf("~%d/%d", events, queue.MaxEvents)))
+}
+
+// estimateInputs muestra los eventos y bytes de cada input elapsed después
+// de la muestra, a la última tasa de cada uno
+func estimateInputs(elapsed time.Duration) {
+	if current.Stats.InputsError != "" {
+		return
+	}
+	table := layout.inputs
+	inputs := filterInputs(current.Stats.Filebeat.Inputs)
+	var totalEvents, totalBytes float64
+	for i, input := range inputs {
+		events, bytes := float64(input.Events), float64(input.Bytes)
+		if rate, ok := store.History().InputRate(input.ID); ok {
+			events += rate * elapsed.Seconds()
+		}
+		if rate, ok := store.History().InputByteRate(input.ID); ok {
+			bytes += rate * elapsed.Seconds()
+		}
+		table.GetCell(i+1, 2).SetText(fmt.Sprintf("~%.0f", events))
+		table.GetCell(i+1, 4).SetText("~" + formatBytes(uint64(bytes)))
+		totalEvents += events
+		totalBytes += bytes
+	}
+	if row := len(inputs) + 1; len(inputs) > 1 {
+		table.GetCell(row, 2).SetText(fmt.Sprintf("~%.0f", totalEvents))
+		table.GetCell(row, 4).SetText("~" + formatBytes(uint64(totalBytes)))
+	}
+}
@@ -0,0 +1,119 @@
+package ui
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/iTiagoCO/filtop/filtop/metrics"
+	"github.com/iTiagoCO/filtop/filtop/probe"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// Panel Latencia: la sonda escribe una marca en un log de prueba y mide
+// cuánto tarda en reflejarse en el contador del input y en poder buscarse
+// en Elasticsearch, la latencia de ingesta real de punta a punta. Muestra
+// la última marca y la mediana y el máximo de las recientes.
+
+// Filas del panel
+const (
+	probeRowMarker = iota
+	probeRowFilebeat
+	probeRowElastic
+	probeRows
+)
+
+var (
+	// probeResults son las últimas marcas y probeError el error de la
+	// última consulta o escritura de la sonda
+	probeResults []probe.Result
+	probeError   string
+)
+
+func createProbePanel() *tview.Table {
+	table := tview.NewTable().SetBorders(false)
+	table.SetTitle(" Latencia ").SetBorder(true)
+	addMetricRow(table, probeRowMarker, "Marca:", "-", tcell.ColorGray)
+	addMetricRow(table, probeRowFilebeat, "Filebeat:", "-", tcell.ColorGray)
+	addMetricRow(table, probeRowElastic, "Elasticsearch:", "-", tcell.ColorGray)
+	return table
+}
+
+// UpdateProbe recibe las últimas marcas de la sonda de latencia
+func UpdateProbe(results []probe.Result, err error) {
+	queueUpdate(func() {
+		probeResults, probeError = results, ""
+		if err != nil {
+			probeError = err.Error()
+		}
+		updateProbePanel()
+	})
+}
+
+func updateProbePanel() {
+	table := layout.probe
+	if table == nil {
+		return
+	}
+	now := time.Now()
+	switch last := len(probeResults) - 1; {
+	case probeError != "":
+		setCell(table, probeRowMarker, 1, "error: "+probeError, tcell.ColorRed)
+	case last < 0:
+		setCell(table, probeRowMarker, 1, "esperando la primera", tcell.ColorGray)
+	case !probeResults[last].Done:
+		setCell(table, probeRowMarker, 1, fmt.Sprintf("enviada hace %s, esperando", formatAgo(now.Sub(probeResults[last].Sent))), tcell.ColorYellow)
+	default:
+		setCell(table, probeRowMarker, 1, fmt.Sprintf("última hace %s (%d medidas)", formatAgo(now.Sub(probeResults[last].Sent)), len(probeResults)), tcell.ColorWhite)
+	}
+
+	if options.ProbeInput == "" {
+		setCell(table, probeRowFilebeat, 1, "no se mide (sin input)", tcell.ColorGray)
+	} else {
+		text, color := probeStageText(func(r probe.Result) time.Duration { return r.Filebeat })
+		setCell(table, probeRowFilebeat, 1, text, color)
+	}
+	if !options.Elasticsearch {
+		setCell(table, probeRowElastic, 1, "no se mide", tcell.ColorGray)
+	} else {
+		text, color := probeStageText(func(r probe.Result) time.Duration { return r.Elasticsearch })
+		setCell(table, probeRowElastic, 1, text, color)
+	}
+}
+
+// probeStageText describe una etapa en las marcas terminadas: la última
+// latencia, o que no llegó, con la mediana y el máximo de las recientes
+func probeStageText(stage func(probe.Result) time.Duration) (string, tcell.Color) {
+	var latencies []float64
+	var last *probe.Result
+	for i, result := range probeResults {
+		if !result.Done {
+			continue
+		}
+		last = &probeResults[i]
+		if d := stage(result); d > 0 {
+			latencies = append(latencies, d.Seconds())
+		}
+	}
+	if last == nil {
+		return "-", tcell.ColorGray
+	}
+	text, color := "no llegó", tcell.ColorRed
+	if d := stage(*last); d > 0 {
+		text, color = formatLatency(d.Seconds()), tcell.ColorAqua
+	}
+	if len(latencies) > 1 {
+		p := metrics.NewPercentiles(latencies)
+		text += fmt.Sprintf(" · mediana %s · máx %s", formatLatency(p.P50), formatLatency(p.Max))
+	}
+	return text, color
+}
+
+// formatLatency formatea segundos, p. ej. 850ms o 2.3s
+func formatLatency(seconds float64) string {
+	if seconds < 1 {
+		return fmt.Sprintf("%.0fms", seconds*1000)
+	}
+	return fmt.Sprintf("%.1fs", seconds)
+}
@@ -0,0 +1,51 @@
+package ui
+
+import (
+	"github.com/rivo/tview"
+)
+
+// Selector de perfiles: la tecla P lista los perfiles de conexión de la
+// configuración y Enter vuelve a cargarla con el elegido, como si filtop se
+// hubiera iniciado con -profile.
+
+func showProfilePicker() {
+	if len(options.Profiles) == 0 || options.SelectProfile == nil {
+		return
+	}
+	list := tview.NewList().ShowSecondaryText(false).SetHighlightFullLine(true)
+	list.SetBorder(true).SetTitle(" Perfiles ")
+	choose := func(name string) func() {
+		return func() {
+			pages.SwitchToPage("main")
+			// La recarga espera a los colectores: no puede bloquear la
+			// interfaz
+			go options.SelectProfile(name)
+		}
+	}
+	for i, name := range options.Profiles {
+		label := tview.Escape(name)
+		if name == options.Profile {
+			label += " [gray](en uso)[-]"
+			list.SetCurrentItem(i)
+		}
+		list.AddItem(label, "", 0, choose(name))
+	}
+	if options.Profile != "" {
+		list.AddItem("[gray]sin perfil[-]", "", 0, choose(""))
+	}
+
+	width := 30
+	for _, name := range options.Profiles {
+		width = max(width, len(name)+14)
+	}
+	height := list.GetItemCount() + 2
+	modal := tview.NewFlex().
+		AddItem(nil, 0, 1, false).
+		AddItem(tview.NewFlex().SetDirection(tview.FlexRow).
+			AddItem(nil, 0, 1, false).
+			AddItem(list, height, 0, true).
+			AddItem(nil, 0, 1, false), width, 0, true).
+		AddItem(nil, 0, 1, false)
+	// Encima de la página actual
+	pages.AddPage("profiles", modal, true, true)
+}
@@ -0,0 +1,162 @@
+package ui
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/iTiagoCO/filtop/filtop/registry"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// Página Rotaciones (tecla R): cada archivo del registry de Filebeat con su
+// inode, su offset y cuántas veces rotó o se truncó desde que filtop
+// empezó, porque una rotación mal configurada es una causa frecuente de
+// logs duplicados o perdidos. Las relecturas, que duplican eventos, se
+// avisan además en la cabecera durante rereadNotice.
+
+const rereadNotice = 15 * time.Minute
+
+var (
+	registryTable *tview.Table
+	registryHelp  *tview.TextView
+	// registryFiles es el último historial recibido y registryError el
+	// error de la última lectura
+	registryFiles []registry.File
+	registryError string
+	registryRead  bool
+)
+
+// UpdateRegistry recibe el historial de rotaciones del registry
+func UpdateRegistry(files []registry.File, err error) {
+	queueUpdate(func() {
+		registryFiles, registryError, registryRead = files, "", true
+		if err != nil {
+			registryError = err.Error()
+		}
+		updateRegistryPage()
+		if current.Stats != nil {
+			updateHeader()
+		}
+	})
+}
+
+func showRegistryPage() {
+	registryTable = tview.NewTable().SetFixed(1, 0).SetSelectable(true, false)
+	registryTable.SetBorder(true)
+	registryHelp = tview.NewTextView().SetDynamicColors(true).
+		SetText(" [yellow]Esc[-]: volver")
+	page := tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(registryHelp, 1, 0, false).
+		AddItem(registryTable, 0, 1, true)
+
+	pages.AddPage("registry", page, true, true)
+	pages.SwitchToPage("registry")
+	updateRegistryPage()
+	registryTable.Select(1, 0)
+}
+
+func updateRegistryPage() {
+	if registryTable == nil {
+		return
+	}
+	if name, _ := pages.GetFrontPage(); name != "registry" {
+		return
+	}
+
+	switch {
+	case options.RegistryPath == "":
+		registryTable.Clear()
+		registryTable.SetTitle(" Rotaciones ")
+		setCell(registryTable, 0, 0, "Usa -registry o registry.path con el directorio data/registry de Filebeat", tcell.ColorGray)
+		return
+	case !registryRead:
+		registryTable.Clear()
+		registryTable.SetTitle(" Rotaciones: " + tview.Escape(options.RegistryPath) + " ")
+		setCell(registryTable, 0, 0, "Leyendo el registry...", tcell.ColorGray)
+		return
+	}
+
+	headers := []string{"Archivo", "Inode", "Offset", "Rotaciones", "Truncados", "Relecturas", "Última"}
+	for col, header := range headers {
+		setCell(registryTable, 0, col, header, tcell.ColorYellow)
+	}
+	now := time.Now()
+	rotated := 0
+	for i, file := range registryFiles {
+		row := i + 1
+		color := tcell.ColorWhite
+		switch {
+		case !file.Present:
+			color = tcell.ColorGray
+		case file.Rereads > 0:
+			color = tcell.ColorRed
+		case file.Truncations > 0:
+			color = tcell.ColorOrange
+		case file.Rotations > 0:
+			color = tcell.ColorAqua
+		}
+		last := "-"
+		if changed := file.LastChange(); !changed.IsZero() {
+			last = fmt.Sprintf("hace %s (%s)", formatAgo(now.Sub(changed)), formatClock(changed))
+			rotated++
+		}
+		rereads := "0"
+		if file.Rereads > 0 {
+			rereads = fmt.Sprintf("%d (%s)", file.Rereads, duplicatedText(file.DuplicatedBytes))
+		}
+		path := tview.Escape(file.Path)
+		if !file.Present {
+			path += " (ya no está)"
+		}
+		cells := []string{path, tview.Escape(file.Identity), formatBytes(uint64(max(file.Offset, 0))), fmt.Sprint(file.Rotations), fmt.Sprint(file.Truncations), rereads, last}
+		for col, text := range cells {
+			setCell(registryTable, row, col, text, color)
+		}
+		registryTable.GetCell(row, 0).SetMaxWidth(70)
+	}
+	for row := registryTable.GetRowCount() - 1; row > len(registryFiles); row-- {
+		registryTable.RemoveRow(row)
+	}
+
+	title := fmt.Sprintf(" Rotaciones: %s (%d archivos, %d cambiaron) ", tview.Escape(options.RegistryPath), len(registryFiles), rotated)
+	if registryError != "" {
+		title += "[red]" + tview.Escape(registryError) + "[-] "
+	}
+	registryTable.SetTitle(title)
+}
+
+// duplicatedText describe lo que se volvió a leer, con los eventos que
+// representa según el tamaño promedio de los eventos del primer beat, p. ej.
+// "1.2 MiB, ~4100 eventos"
+func duplicatedText(bytes int64) string {
+	text := formatBytes(uint64(max(bytes, 0)))
+	if size, ok := options.Tabs[0].Store.EventSize(); ok {
+		text += fmt.Sprintf(", ~%.0f eventos", float64(bytes)/size)
+	}
+	return text
+}
+
+// registrySummary avisa en la cabecera de las relecturas recientes, que
+// probablemente duplicaron eventos
+func registrySummary() string {
+	var latest *registry.File
+	count := 0
+	for i, file := range registryFiles {
+		if file.Rereads == 0 || time.Since(file.LastReread) > rereadNotice {
+			continue
+		}
+		count++
+		if latest == nil || file.LastReread.After(latest.LastReread) {
+			latest = &registryFiles[i]
+		}
+	}
+	switch count {
+	case 0:
+		return ""
+	case 1:
+		return fmt.Sprintf(" | [red]⚠ relectura de %s: posibles duplicados (%s)[-]", tview.Escape(latest.Path), duplicatedText(latest.DuplicatedBytes))
+	}
+	return fmt.Sprintf(" | [red]⚠ %d archivos releídos: posibles duplicados (R)[-]", count)
+}
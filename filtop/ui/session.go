@@ -0,0 +1,231 @@
+package ui
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/iTiagoCO/filtop/filtop/metrics"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// Página Session: todo lo ocurrido desde que filtop empezó a monitorear
+// cada Filebeat (eventos enviados y descartados, promedios, picos, cortes),
+// como resumen para un cambio de turno. Con varias pestañas cada beat es
+// una columna y la última suma los totales. La tecla e lo exporta a texto.
+
+var (
+	sessionTable *tview.Table
+	sessionHelp  *tview.TextView
+	// sessionExport es el resultado de la última exportación
+	sessionExport string
+)
+
+func showSessionPage() {
+	sessionTable = tview.NewTable().SetFixed(1, 1)
+	sessionTable.SetBorder(true)
+	sessionHelp = tview.NewTextView().SetDynamicColors(true)
+	page := tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(sessionHelp, 1, 0, false).
+		AddItem(sessionTable, 0, 1, true)
+	page.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() == tcell.KeyRune && event.Rune() == 'e' {
+			exportSession()
+			updateSessionPage()
+			return nil
+		}
+		return event
+	})
+
+	sessionExport = ""
+	pages.AddPage("session", page, true, true)
+	pages.SwitchToPage("session")
+	updateSessionPage()
+}
+
+func updateSessionPage() {
+	if sessionTable == nil {
+		return
+	}
+	if name, _ := pages.GetFrontPage(); name != "session" {
+		return
+	}
+
+	rows := sessionRows(time.Now())
+	for row, cells := range rows {
+		for col, text := range cells {
+			color := tcell.ColorWhite
+			switch {
+			case row == 0 || col == 0:
+				color = tcell.ColorYellow
+			case multipleTabs() && col == len(cells)-1:
+				color = tcell.ColorGreen
+			}
+			setCell(sessionTable, row, col, tview.Escape(text), color)
+		}
+	}
+	sessionTable.SetTitle(" Sesión ")
+	sessionHelp.SetText(" [yellow]e[-]: exportar a texto · [yellow]Esc[-]: volver" + sessionExport)
+}
+
+// sessionRows arma la tabla de la página: la primera fila son los nombres
+// de los beats y la primera columna el de cada dato
+func sessionRows(now time.Time) [][]string {
+	summaries := make([]metrics.SessionSummary, len(options.Tabs))
+	for i, tab := range options.Tabs {
+		summaries[i] = tab.Store.Session(now)
+	}
+	header := []string{""}
+	for _, tab := range options.Tabs {
+		header = append(header, tab.Name)
+	}
+	if multipleTabs() {
+		header = append(header, "Total")
+		summaries = append(summaries, sessionTotal(summaries))
+	}
+
+	labels := []string{
+		"Primera muestra", "Duración", "Muestras", "Eventos enviados", "Eventos fallidos",
+		"Eventos descartados", "Bytes enviados", "Eventos/s promedio", "Bytes/s promedio",
+		"Pico de eventos/s", "Pico de la cola", "Desconexiones", "Tiempo sin conexión",
+		"Reinicios de Filebeat",
+	}
+	rows := [][]string{header}
+	for _, label := range labels {
+		rows = append(rows, []string{label})
+	}
+	for _, summary := range summaries {
+		for i, text := range sessionCells(summary) {
+			rows[i+1] = append(rows[i+1], text)
+		}
+	}
+	return rows
+}
+
+// sessionCells son los datos de un beat, en el orden de sessionRows
+func sessionCells(s metrics.SessionSummary) []string {
+	if s.Samples == 0 {
+		cells := make([]string, 14)
+		for i := range cells {
+			cells[i] = "-"
+		}
+		cells[2] = "0"
+		return cells
+	}
+	eventRate, byteRate, ok := s.Rates()
+	average := []string{"-", "-"}
+	if ok {
+		average = []string{formatEventRate(eventRate), formatByteRate(byteRate)}
+	}
+	peakRate, peakQueue := "-", "-"
+	if !s.PeakRateAt.IsZero() {
+		peakRate = fmt.Sprintf("%s (%s)", formatEventRate(s.PeakRate), formatClock(s.PeakRateAt))
+	}
+	if !s.PeakQueueAt.IsZero() {
+		peakQueue = fmt.Sprint(s.PeakQueue)
+		if s.QueueMax > 0 {
+			peakQueue = fmt.Sprintf("%d/%d (%.0f%%)", s.PeakQueue, s.QueueMax, float64(s.PeakQueue)/float64(s.QueueMax)*100)
+		}
+		peakQueue += " (" + formatClock(s.PeakQueueAt) + ")"
+	}
+	downtime := formatAgo(s.Downtime)
+	if s.Down {
+		downtime += " (sin conexión)"
+	}
+	return []string{
+		formatClock(s.First),
+		formatAgo(s.Last.Sub(s.First)),
+		fmt.Sprint(s.Samples),
+		fmt.Sprint(s.Acked),
+		fmt.Sprint(s.Failed),
+		fmt.Sprint(s.Dropped),
+		formatBytes(s.Bytes),
+		average[0],
+		average[1],
+		peakRate,
+		peakQueue,
+		fmt.Sprint(s.Disconnects),
+		downtime,
+		fmt.Sprint(s.Restarts),
+	}
+}
+
+// sessionTotal suma los beats; los picos son los de cada uno y no se suman,
+// así que se deja el mayor
+func sessionTotal(summaries []metrics.SessionSummary) metrics.SessionSummary {
+	var total metrics.SessionSummary
+	for _, s := range summaries {
+		if s.Samples == 0 {
+			continue
+		}
+		if total.Samples == 0 || s.First.Before(total.First) {
+			total.First = s.First
+		}
+		if s.Last.After(total.Last) {
+			total.Last = s.Last
+		}
+		total.Samples += s.Samples
+		total.Acked += s.Acked
+		total.Failed += s.Failed
+		total.Dropped += s.Dropped
+		total.Bytes += s.Bytes
+		if s.PeakRate > total.PeakRate {
+			total.PeakRate, total.PeakRateAt = s.PeakRate, s.PeakRateAt
+		}
+		total.Disconnects += s.Disconnects
+		total.Downtime += s.Downtime
+		total.Down = total.Down || s.Down
+		total.Restarts += s.Restarts
+	}
+	return total
+}
+
+// formatClock es la hora de t, con la fecha si no es de hoy
+func formatClock(t time.Time) string {
+	t, now := t.Local(), time.Now()
+	if t.YearDay() != now.YearDay() || t.Year() != now.Year() {
+		return t.Format("01-02 15:04:05")
+	}
+	return t.Format("15:04:05")
+}
+
+// exportSession escribe el resumen en un archivo de texto del directorio
+// actual, con las columnas alineadas
+func exportSession() {
+	now := time.Now()
+	path, err := filepath.Abs(fmt.Sprintf("filtop-session-%s.txt", now.Format("20060102-150405")))
+	if err == nil {
+		err = os.WriteFile(path, []byte(sessionText(now)), 0o644)
+	}
+	if err != nil {
+		sessionExport = " · [red]error exportando: " + tview.Escape(err.Error()) + "[-]"
+		return
+	}
+	sessionExport = " · [green]exportado a " + tview.Escape(path) + "[-]"
+}
+
+func sessionText(now time.Time) string {
+	rows := sessionRows(now)
+	widths := make([]int, len(rows[0]))
+	for _, cells := range rows {
+		for col, text := range cells {
+			widths[col] = max(widths[col], len([]rune(text)))
+		}
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "Sesión de filtop al %s\n\n", now.Format(time.RFC3339))
+	for _, cells := range rows {
+		for col, text := range cells {
+			b.WriteString(text)
+			if col < len(cells)-1 {
+				b.WriteString(strings.Repeat(" ", widths[col]-len([]rune(text))+2))
+			}
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
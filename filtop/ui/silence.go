@@ -0,0 +1,124 @@
+package ui
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/iTiagoCO/filtop/filtop/alerts"
+
+	"github.com/rivo/tview"
+)
+
+// Silencios: la tecla z silencia durante defaultSilence las alertas de la
+// pestaña, o quita sus silencios si ya tenía, p. ej. antes de reiniciar el
+// Filebeat. Las alertas se siguen mostrando, en gris, y quedan en el
+// historial, pero no se notifican. silence en la paleta elige la duración
+// y la regla.
+
+const defaultSilence = time.Hour
+
+// silenceTarget es el beat de la pestaña activa como lo nombran los
+// silencios: vacío si hay uno solo
+func silenceTarget() string {
+	if !multipleTabs() {
+		return ""
+	}
+	return options.Tabs[activeTab].Name
+}
+
+func toggleSilence() {
+	if options.Silences == nil {
+		return
+	}
+	if len(tabSilences()) > 0 {
+		unsilence()
+		return
+	}
+	addSilence(defaultSilence, "")
+}
+
+// silence es el comando silence <duración> [regla]
+func silence(arg string) error {
+	fields := strings.Fields(arg)
+	if len(fields) == 0 || len(fields) > 2 {
+		return errors.New("uso: silence <duración> [regla], p. ej. silence 30m")
+	}
+	duration, err := time.ParseDuration(fields[0])
+	if err != nil || duration <= 0 {
+		return errors.New("la duración debe ser positiva, p. ej. 30m o 2h")
+	}
+	rule := ""
+	if len(fields) == 2 {
+		rule = fields[1]
+	}
+	pages.SwitchToPage("main")
+	addSilence(duration, rule)
+	return nil
+}
+
+func addSilence(duration time.Duration, rule string) {
+	now := time.Now()
+	options.Silences.Add(alerts.Silence{
+		Target: silenceTarget(),
+		Rule:   rule,
+		Start:  now,
+		End:    now.Add(duration),
+		Reason: "desde la interfaz",
+	})
+	what := "alertas silenciadas"
+	if rule != "" {
+		what = tview.Escape(rule) + " silenciada"
+	}
+	setCommandResult(fmt.Sprintf("[gray]%s hasta las %s[-]", what, now.Add(duration).Format("15:04")))
+}
+
+func unsilence() {
+	pages.SwitchToPage("main")
+	if n := options.Silences.Remove(silenceTarget(), time.Now()); n > 0 {
+		setCommandResult(fmt.Sprintf("[green]silencios quitados: %d[-]", n))
+		return
+	}
+	setCommandResult("[gray]la pestaña no tenía silencios[-]")
+}
+
+// tabSilences son los silencios vigentes o programados de la pestaña
+// activa, sin los de todos los beats
+func tabSilences() []alerts.Silence {
+	var list []alerts.Silence
+	target := silenceTarget()
+	for _, silence := range options.Silences.Active(time.Now()) {
+		if silence.Target == target {
+			list = append(list, silence)
+		}
+	}
+	return list
+}
+
+// silenceSummary es el texto de los silencios vigentes de la pestaña para
+// la cabecera, incluidos los de todos los beats
+func silenceSummary() string {
+	if options.Silences == nil {
+		return ""
+	}
+	now, target := time.Now(), silenceTarget()
+	var until time.Time
+	count := 0
+	for _, silence := range options.Silences.Active(now) {
+		if silence.Start.After(now) || silence.Target != "" && silence.Target != target {
+			continue
+		}
+		if count == 0 {
+			until = silence.End
+		}
+		count++
+	}
+	switch count {
+	case 0:
+		return ""
+	case 1:
+		return " | [gray]silencio hasta las " + until.Format("15:04") + "[-]"
+	}
+	return fmt.Sprintf(" | [gray]%d silencios, el primero hasta las %s[-]", count, until.Format("15:04"))
+}
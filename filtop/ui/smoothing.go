@@ -0,0 +1,96 @@
+package ui
+
+import (
+	"errors"
+	"time"
+)
+
+// Suavizado de las tasas: por defecto los eventos/s y bytes/s se calculan
+// entre las dos últimas muestras, lo que en inputs con ráfagas salta de un
+// extremo al otro. La tecla v alterna entre la tasa instantánea y los
+// promedios de 1 y 5 minutos, en todas las páginas y los gráficos; las
+// alertas siguen usando la instantánea.
+
+// rateWindows son los suavizados que se pueden elegir; 0 es la tasa
+// instantánea
+var rateWindows = []time.Duration{0, time.Minute, 5 * time.Minute}
+
+// rateWindow es el suavizado actual
+var rateWindow time.Duration
+
+// configuredSmoothing es el de la configuración, para no pisar el elegido
+// con v si una recarga no lo cambia
+var configuredSmoothing time.Duration
+
+// setConfiguredSmoothing aplica el suavizado de la configuración si cambió
+func setConfiguredSmoothing(window time.Duration) {
+	if window != configuredSmoothing {
+		configuredSmoothing, rateWindow = window, window
+	}
+}
+
+func cycleSmoothing() {
+	next := rateWindows[0]
+	for i, window := range rateWindows {
+		if window == rateWindow && i+1 < len(rateWindows) {
+			next = rateWindows[i+1]
+		}
+	}
+	setSmoothing(next)
+}
+
+func setSmoothing(window time.Duration) {
+	rateWindow = window
+	setCommandResult("[green]tasas: " + smoothingName(window) + "[-]")
+	updateUI()
+	updateCharts()
+	updateTopPage()
+	updateFleetPage()
+	updateBrowserPage()
+	updateBeatConfigPage()
+}
+
+// setSmoothingCommand es el comando smoothing <instant|1m|5m>
+func setSmoothingCommand(arg string) error {
+	for _, window := range rateWindows {
+		if arg == smoothingName(window) {
+			setSmoothing(window)
+			return nil
+		}
+	}
+	return errors.New("el suavizado debe ser instant, 1m o 5m")
+}
+
+func smoothingName(window time.Duration) string {
+	if window == 0 {
+		return "instant"
+	}
+	return formatSpan(window)
+}
+
+// smoothingSuffix indica junto a una tasa que es un promedio
+func smoothingSuffix() string {
+	if rateWindow == 0 {
+		return ""
+	}
+	return " (prom. " + formatSpan(rateWindow) + ")"
+}
+
+// smoothingSummary es el suavizado para la cabecera, si no es el
+// instantáneo
+func smoothingSummary() string {
+	if rateWindow == 0 {
+		return ""
+	}
+	return " | tasas: prom. " + formatSpan(rateWindow)
+}
+
+// inputEventRate son los eventos/s de un input de la pestaña activa
+func inputEventRate(id string) (float64, bool) {
+	return store.History().InputRateOver(id, rateWindow)
+}
+
+// inputByteRate son los bytes/s de un input de la pestaña activa
+func inputByteRate(id string) (float64, bool) {
+	return store.History().InputByteRateOver(id, rateWindow)
+}
@@ -0,0 +1,198 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/iTiagoCO/filtop/filtop/alerts"
+	"github.com/iTiagoCO/filtop/filtop/metrics"
+
+	"github.com/rivo/tview"
+)
+
+// Pestañas: con varios Filebeats en targets cada uno tiene su propio
+// tablero. La barra superior los numera y muestra el estado de su conexión,
+// con los beats de un mismo host juntos detrás de su nombre; las teclas 1 a
+// 9 cambian de pestaña. Con un solo Filebeat la barra no se muestra.
+
+// Tab es un Filebeat monitoreado
+type Tab struct {
+	Name string
+	// Group es el host del beat; los beats de un host se muestran juntos
+	Group string
+	// Store es el que alimenta su colector
+	Store *metrics.Store
+	// PprofURL es donde expone /debug/pprof (http.pprof.enabled)
+	PprofURL string
+}
+
+// tabState es lo último que llegó de cada pestaña, para mostrarlo al volver
+// a ella
+type tabState struct {
+	computed  []metrics.ComputedValue
+	alerts    []alerts.Alert
+	anomalies []metrics.Anomaly
+	panels    [][]metrics.ComputedValue
+	// series son los valores recientes de cada métrica de cada panel
+	series [][][]float64
+	// err es el último error de conexión; vacío si la última consulta
+	// funcionó
+	err     string
+	sampled bool
+}
+
+var (
+	// tabStates[i] corresponde a options.Tabs[i]
+	tabStates []tabState
+	activeTab int
+)
+
+// TargetError marca la pestaña como sin conexión hasta la próxima muestra
+func TargetError(tab int, err error) {
+	queueUpdate(func() {
+		tabStates[tab].err = err.Error()
+		updateTabBar()
+		updateFleetPage()
+		updateSessionPage()
+	})
+}
+
+func multipleTabs() bool {
+	return len(options.Tabs) > 1
+}
+
+func createTabBar() *tview.TextView {
+	return tview.NewTextView().SetDynamicColors(true)
+}
+
+func updateTabBar() {
+	if layout.tabs == nil {
+		return
+	}
+	var parts []string
+	tabs := options.Tabs
+	for i := 0; i < len(tabs); {
+		// Pestañas seguidas del mismo host
+		j := i + 1
+		for j < len(tabs) && tabs[j].Group == tabs[i].Group {
+			j++
+		}
+		if j-i == 1 {
+			parts = append(parts, tabLabel(i, tabs[i].Name, tabStates[i]))
+			i = j
+			continue
+		}
+		labels := make([]string, 0, j-i)
+		for ; i < j; i++ {
+			name := tabShortName(tabs[i])
+			if info := tabs[i].Store.Latest().Info; info != nil {
+				name += " " + info.Beat
+			}
+			labels = append(labels, tabLabel(i, name, tabStates[i]))
+		}
+		parts = append(parts, "[gray]"+tview.Escape(tabs[j-1].Group)+"[-] "+strings.Join(labels, " "))
+	}
+	text := strings.Join(parts, "  ")
+	if err := tabStates[activeTab].err; err != "" {
+		text += "  [red]" + tview.Escape(err) + "[-]"
+	}
+	setText(layout.tabs, text)
+}
+
+// tabLabel es el número, el nombre y el estado de una pestaña: ● recibe
+// muestras, ✗ falló la última consulta y ○ todavía no respondió
+func tabLabel(i int, name string, state tabState) string {
+	symbol, color := "○", "gray"
+	switch {
+	case state.err != "":
+		symbol, color = "✗", "red"
+	case state.sampled:
+		symbol, color = "●", "green"
+	}
+	background := "-"
+	if i == activeTab {
+		background = "blue"
+	}
+	return fmt.Sprintf("[white:%s] %d %s [%s:%s]%s[white:%s] [-:-]", background, i+1, tview.Escape(name), color, background, symbol, background)
+}
+
+// tabShortName es el nombre de la pestaña sin el de su host, p. ej. :5067,
+// para mostrarla junto a los demás beats del host
+func tabShortName(tab Tab) string {
+	if short := strings.TrimPrefix(tab.Name, tab.Group); short != tab.Name && short != "" {
+		return short
+	}
+	return tab.Name
+}
+
+// tabPrefix es el nombre de la pestaña activa para los títulos de otras
+// páginas; vacío con un solo Filebeat
+func tabPrefix() string {
+	if !multipleTabs() {
+		return ""
+	}
+	return "[::b]" + tview.Escape(options.Tabs[activeTab].Name) + "[::-] · "
+}
+
+// tabsChanged indica si tabs ya no son los Filebeats de before, p. ej. al
+// cambiar de perfil
+func tabsChanged(before, tabs []Tab) bool {
+	if len(before) != len(tabs) {
+		return true
+	}
+	for i := range tabs {
+		if before[i].Store != tabs[i].Store {
+			return true
+		}
+	}
+	return false
+}
+
+// resetTabs empieza de nuevo por la primera pestaña con los Filebeats de
+// options
+func resetTabs() {
+	tabStates = make([]tabState, len(options.Tabs))
+	activeTab = 0
+	store = options.Tabs[0].Store
+	current = store.Latest()
+	activeAlerts = nil
+	recorder = nil
+	liveValues = make(map[string]float64)
+	counters = make(map[string]*counterState)
+	expvarFallback = false
+	// Las demás páginas son de los Filebeats anteriores
+	pages.SwitchToPage("main")
+}
+
+// switchTab muestra el tablero del Filebeat de la pestaña i con lo último
+// que llegó de él
+func switchTab(i int) {
+	if i >= len(options.Tabs) || i == activeTab {
+		return
+	}
+	activeTab = i
+	store = options.Tabs[i].Store
+	current = store.Latest()
+	state := tabStates[i]
+	activeAlerts = state.alerts
+	// La línea base es de un solo Filebeat: no se mezclan las muestras
+	recorder = nil
+	liveValues = make(map[string]float64)
+	counters = make(map[string]*counterState)
+
+	// Los detalles de inputs y módulos, pprof y expvar son del anterior
+	expvarFallback = false
+	switch front, _ := pages.GetFrontPage(); front {
+	case "charts", "logs", "top", "timeline":
+	default:
+		pages.SwitchToPage("main")
+	}
+	rebuildMainPage()
+	showCustom(state.computed)
+	showAnomalies(state.anomalies)
+	showPanels(state.panels)
+	updateUI()
+	updateCharts()
+	updateTopPage()
+	updateTimelinePage()
+}
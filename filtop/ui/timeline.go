@@ -0,0 +1,219 @@
+package ui
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/iTiagoCO/filtop/filtop/metrics"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// Página Ciclo de vida (tecla i): cuándo aparecieron, desaparecieron o
+// cambiaron de estado los inputs del Filebeat de la pestaña activa, algo
+// esencial cuando autodiscover o las recargas de la configuración crean y
+// destruyen inputs todo el tiempo. Arriba cada input es una línea de tiempo
+// y abajo están los cambios, del más reciente al más antiguo.
+
+var (
+	timelinePage  *tview.Flex
+	timelineLanes *lanes
+	timelineTable *tview.Table
+	timelineHelp  *tview.TextView
+)
+
+// Etiqueta y color de cada cambio en la tabla
+var changeLabels = map[metrics.InputChangeKind]struct {
+	text  string
+	color tcell.Color
+}{
+	metrics.InputPresent:     {"ya estaba", tcell.ColorGray},
+	metrics.InputStarted:     {"iniciado", tcell.ColorGreen},
+	metrics.InputStopped:     {"detenido", tcell.ColorRed},
+	metrics.InputRestarted:   {"reiniciado", tcell.ColorAqua},
+	metrics.InputActivated:   {"activo", tcell.ColorGreen},
+	metrics.InputDeactivated: {"inactivo", tcell.ColorYellow},
+}
+
+// Ancho máximo de los ids de los inputs junto a sus líneas de tiempo, y
+// cuántas líneas de tiempo se muestran como mucho
+const (
+	laneLabelWidth = 30
+	maxLanes       = 20
+)
+
+// lanes dibuja una línea de tiempo por input: ━ mientras está activo, ╌
+// mientras está inactivo y nada mientras no existe, con ▶ donde empezó, ↻
+// donde se reinició y ■ donde se detuvo
+type lanes struct {
+	*tview.Box
+	ids     []string
+	changes []metrics.InputChange
+	// from y to son el rango de tiempo mostrado
+	from, to time.Time
+}
+
+func (l *lanes) Draw(screen tcell.Screen) {
+	l.Box.DrawForSubclass(screen, l)
+	x, y, width, height := l.GetInnerRect()
+	if len(l.ids) == 0 {
+		tview.Print(screen, "Sin inputs todavía", x, y, width, tview.AlignLeft, tcell.ColorGray)
+		return
+	}
+	labelWidth := 0
+	for _, id := range l.ids {
+		labelWidth = max(labelWidth, min(len(id), laneLabelWidth))
+	}
+	plotX, plotWidth := x+labelWidth+1, width-labelWidth-1
+	if plotWidth < 10 || height < 2 {
+		return
+	}
+	span := l.to.Sub(l.from)
+	column := func(at time.Time) int {
+		if span <= 0 {
+			return plotWidth - 1
+		}
+		return min(int(float64(at.Sub(l.from))/float64(span)*float64(plotWidth)), plotWidth-1)
+	}
+
+	for row, id := range l.ids {
+		if row >= min(height-1, maxLanes) {
+			break
+		}
+		tview.Print(screen, tview.Escape(id), x, y+row, labelWidth, tview.AlignLeft, tcell.ColorWhite)
+		// Estado de cada columna: se recorre el tiempo con los cambios del
+		// input en orden
+		present, active := false, false
+		next := 0
+		var own []metrics.InputChange
+		for _, change := range l.changes {
+			if change.ID == id {
+				own = append(own, change)
+			}
+		}
+		for col := 0; col < plotWidth; col++ {
+			marker := rune(0)
+			markerColor := tcell.ColorWhite
+			for next < len(own) && column(own[next].At) <= col {
+				switch change := own[next]; change.Kind {
+				case metrics.InputPresent, metrics.InputStarted:
+					present, active = true, true
+					if change.Kind == metrics.InputStarted {
+						marker, markerColor = '▶', tcell.ColorGreen
+					}
+				case metrics.InputRestarted:
+					present, active = true, true
+					marker, markerColor = '↻', tcell.ColorAqua
+				case metrics.InputStopped:
+					present = false
+					marker, markerColor = '■', tcell.ColorRed
+				case metrics.InputActivated:
+					active = true
+				case metrics.InputDeactivated:
+					active = false
+				}
+				next++
+			}
+			switch {
+			case marker != 0:
+				screen.SetContent(plotX+col, y+row, marker, nil, tcell.StyleDefault.Foreground(markerColor))
+			case present && active:
+				screen.SetContent(plotX+col, y+row, '━', nil, tcell.StyleDefault.Foreground(tcell.ColorGreen))
+			case present:
+				screen.SetContent(plotX+col, y+row, '╌', nil, tcell.StyleDefault.Foreground(tcell.ColorYellow))
+			}
+		}
+	}
+	axis := y + min(len(l.ids), height-1, maxLanes)
+	tview.Print(screen, l.from.Local().Format(time.TimeOnly), plotX, axis, plotWidth, tview.AlignLeft, tcell.ColorGray)
+	tview.Print(screen, l.to.Local().Format(time.TimeOnly), plotX, axis, plotWidth, tview.AlignRight, tcell.ColorGray)
+}
+
+func showTimelinePage() {
+	timelineLanes = &lanes{Box: tview.NewBox()}
+	timelineLanes.SetBorder(true)
+	timelineTable = tview.NewTable().SetFixed(1, 0).SetSelectable(true, false)
+	timelineTable.SetBorder(true).SetTitle(" Cambios ")
+	timelineHelp = tview.NewTextView().SetDynamicColors(true)
+	timelinePage = tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(timelineHelp, 1, 0, false).
+		AddItem(timelineLanes, 3, 0, false).
+		AddItem(timelineTable, 0, 1, true)
+
+	pages.AddPage("timeline", timelinePage, true, true)
+	pages.SwitchToPage("timeline")
+	updateTimelinePage()
+}
+
+func updateTimelinePage() {
+	if timelineTable == nil {
+		return
+	}
+	if name, _ := pages.GetFrontPage(); name != "timeline" {
+		return
+	}
+
+	var changes []metrics.InputChange
+	for _, change := range store.InputChanges() {
+		if matchesInputFilter(change.ID, change.Type) {
+			changes = append(changes, change)
+		}
+	}
+
+	// Las líneas de tiempo van en el orden en que se vio cada input
+	var ids []string
+	seen := make(map[string]bool)
+	started, stopped := 0, 0
+	for _, change := range changes {
+		if !seen[change.ID] {
+			seen[change.ID] = true
+			ids = append(ids, change.ID)
+		}
+		switch change.Kind {
+		case metrics.InputStarted:
+			started++
+		case metrics.InputStopped:
+			stopped++
+		}
+	}
+	timelineLanes.ids, timelineLanes.changes = ids, changes
+	// Una línea por input, más el eje y el borde
+	timelinePage.ResizeItem(timelineLanes, min(len(ids), maxLanes)+3, 0)
+	timelineLanes.to = time.Now()
+	if current.Stats != nil {
+		timelineLanes.to = current.Stats.Timestamp
+	}
+	timelineLanes.from = timelineLanes.to
+	if len(changes) > 0 {
+		timelineLanes.from = changes[0].At
+	}
+	title := fmt.Sprintf(" %sCiclo de vida de los inputs: %d iniciados, %d detenidos ", tabPrefix(), started, stopped)
+	if len(changes) > 0 {
+		title += "desde las " + changes[0].At.Local().Format(time.TimeOnly) + " "
+	}
+	if inputFilter != "" {
+		title += "(filtro: " + tview.Escape(inputFilter) + ") "
+	}
+	if len(ids) > maxLanes {
+		title += fmt.Sprintf("(primeros %d de %d) ", maxLanes, len(ids))
+	}
+	timelineLanes.SetTitle(title)
+
+	for col, header := range []string{"Hora", "Input", "Tipo", "Cambio"} {
+		setCell(timelineTable, 0, col, header, tcell.ColorYellow)
+	}
+	for i := range changes {
+		change := changes[len(changes)-1-i]
+		label := changeLabels[change.Kind]
+		row := i + 1
+		setCell(timelineTable, row, 0, change.At.Local().Format(time.DateTime), tcell.ColorWhite)
+		setCell(timelineTable, row, 1, tview.Escape(change.ID), tcell.ColorWhite)
+		setCell(timelineTable, row, 2, change.Type, tcell.ColorWhite)
+		setCell(timelineTable, row, 3, label.text, label.color)
+	}
+	for row := timelineTable.GetRowCount() - 1; row > len(changes); row-- {
+		timelineTable.RemoveRow(row)
+	}
+	timelineHelp.SetText(" [green]▶[-] iniciado · [aqua]↻[-] reiniciado · [red]■[-] detenido · [green]━[-] activo · [yellow]╌[-] inactivo · [yellow]Esc[-]: volver")
+}
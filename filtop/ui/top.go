@@ -0,0 +1,207 @@
+package ui
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/iTiagoCO/filtop/filtop/client"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// Página Top: los inputs del Filebeat de la pestaña activa ordenados por
+// eventos/s o bytes/s, como el orden por defecto de top, para ver qué está
+// inundando el pipeline. Se actualiza en su lugar con cada muestra y
+// conserva el input seleccionado; o cambia el orden y Enter abre sus
+// métricas.
+
+var (
+	topTable *tview.Table
+	topHelp  *tview.TextView
+	// topByBytes ordena por bytes/s en lugar de eventos/s
+	topByBytes bool
+	// topInputs[i] es el input de la fila i+1
+	topInputs []client.Input
+)
+
+// Ancho de la barra de la columna Parte
+const topBarWidth = 20
+
+// Un input cuyos eventos recientes miden al menos esto por su tamaño
+// promedio histórico se marca en la columna Bytes/ev
+const eventSizeGrowth = 3
+
+func showTopPage() {
+	topTable = tview.NewTable().SetFixed(1, 0).SetSelectable(true, false)
+	topTable.SetBorder(true)
+	topTable.SetSelectedFunc(func(row, _ int) {
+		if row > 0 && row <= len(topInputs) {
+			showInputMetrics(topInputs[row-1])
+		}
+	})
+	topHelp = tview.NewTextView().SetDynamicColors(true)
+	page := tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(topHelp, 1, 0, false).
+		AddItem(topTable, 0, 1, true)
+	page.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() == tcell.KeyRune && event.Rune() == 'o' {
+			topByBytes = !topByBytes
+			updateTopPage()
+			return nil
+		}
+		return event
+	})
+
+	topInputs = nil
+	pages.AddPage("top", page, true, true)
+	pages.SwitchToPage("top")
+	updateTopPage()
+	topTable.Select(1, 0)
+}
+
+// topEntry es un input con sus tasas actuales
+type topEntry struct {
+	input               client.Input
+	eventRate, byteRate float64
+	ok                  bool
+}
+
+// topEventSize es el tamaño de los eventos recientes del input o, si no
+// leyó eventos en ese tiempo, el promedio histórico; grew indica que los
+// recientes son mucho más grandes que el promedio
+func topEventSize(input client.Input) (text string, grew bool) {
+	if input.Events == 0 {
+		return "-", false
+	}
+	average := float64(input.Bytes) / float64(input.Events)
+	size, ok := store.History().InputEventSize(input.ID, rateWindow)
+	if !ok {
+		return formatEventSize(average), false
+	}
+	if average > 0 && size >= eventSizeGrowth*average {
+		return "▲ " + formatEventSize(size), true
+	}
+	return formatEventSize(size), false
+}
+
+func updateTopPage() {
+	if topTable == nil {
+		return
+	}
+	if name, _ := pages.GetFrontPage(); name != "top" {
+		return
+	}
+
+	var inputs []client.Input
+	if current.Stats != nil {
+		inputs = filterInputs(current.Stats.Filebeat.Inputs)
+	}
+	entries := make([]topEntry, len(inputs))
+	var totalEvents, totalBytes float64
+	for i, input := range inputs {
+		entries[i].input = input
+		entries[i].eventRate, entries[i].ok = inputEventRate(input.ID)
+		entries[i].byteRate, _ = inputByteRate(input.ID)
+		totalEvents += entries[i].eventRate
+		totalBytes += entries[i].byteRate
+	}
+	// Los que todavía no tienen tasa van al final
+	sort.SliceStable(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		if a.ok != b.ok {
+			return a.ok
+		}
+		if topByBytes {
+			return a.byteRate > b.byteRate
+		}
+		return a.eventRate > b.eventRate
+	})
+
+	// Se mantiene seleccionado el mismo input aunque cambie de puesto
+	selected := ""
+	if row, _ := topTable.GetSelection(); row > 0 && row <= len(topInputs) {
+		selected = topInputs[row-1].ID
+	}
+
+	headers := []string{"#", "Input", "Tipo", "Eventos/s", "Bytes/s", "Bytes/ev", "Parte", "Eventos", "Archivos"}
+	sortCol := 3
+	if topByBytes {
+		sortCol = 4
+	}
+	for col, header := range headers {
+		if col == sortCol {
+			header += " ▼"
+		}
+		setCell(topTable, 0, col, header, tcell.ColorYellow)
+	}
+	topInputs = topInputs[:0]
+	for i, entry := range entries {
+		row := i + 1
+		rate, total := entry.eventRate, totalEvents
+		if topByBytes {
+			rate, total = entry.byteRate, totalBytes
+		}
+		eventRate, byteRate, share := "-", "-", ""
+		if entry.ok {
+			eventRate, byteRate = formatEventRate(entry.eventRate), formatByteRate(entry.byteRate)
+			share = topShare(rate, total)
+		}
+		size, grew := topEventSize(entry.input)
+		cells := []string{
+			fmt.Sprint(row),
+			tview.Escape(entry.input.ID),
+			entry.input.Type,
+			eventRate,
+			byteRate,
+			size,
+			share,
+			fmt.Sprint(entry.input.Events),
+			fmt.Sprint(entry.input.Files),
+		}
+		color := tcell.ColorWhite
+		if !entry.ok || rate == 0 {
+			color = tcell.ColorGray
+		}
+		for col, text := range cells {
+			setCell(topTable, row, col, text, color)
+		}
+		topTable.GetCell(row, 1).SetMaxWidth(50)
+		if grew {
+			topTable.GetCell(row, 5).SetTextColor(tcell.ColorYellow)
+		}
+		topInputs = append(topInputs, entry.input)
+		if entry.input.ID == selected {
+			topTable.Select(row, 0)
+		}
+	}
+	for row := topTable.GetRowCount() - 1; row > len(entries); row-- {
+		topTable.RemoveRow(row)
+	}
+
+	order := "eventos/s"
+	if topByBytes {
+		order = "bytes/s"
+	}
+	filter := ""
+	if inputFilter != "" {
+		filter = ", filtro: " + tview.Escape(inputFilter)
+	}
+	title := fmt.Sprintf(" %sTop inputs por %s (%d%s) ", tabPrefix(), order, len(entries), filter)
+	if size, ok := store.EventSize(); ok {
+		title += "· promedio " + formatEventSize(size) + " "
+	}
+	topTable.SetTitle(title)
+	topHelp.SetText(" [yellow]o[-]: ordenar por eventos/s o bytes/s · [yellow]Enter[-]: métricas del input · [yellow]Esc[-]: volver")
+}
+
+// topShare es la parte del total de un input, con una barra
+func topShare(rate, total float64) string {
+	if total <= 0 {
+		return strings.Repeat("░", topBarWidth) + "   0%"
+	}
+	share := rate / total
+	filled := int(share*topBarWidth + 0.5)
+	return strings.Repeat("█", filled) + strings.Repeat("░", topBarWidth-filled) + fmt.Sprintf(" %3.0f%%", share*100)
+}
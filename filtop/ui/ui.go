@@ -0,0 +1,983 @@
+// Package ui implementa la interfaz de terminal de filtop sobre tview.
+package ui
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/iTiagoCO/filtop/filtop/alerts"
+	"github.com/iTiagoCO/filtop/filtop/client"
+	"github.com/iTiagoCO/filtop/filtop/expr"
+	"github.com/iTiagoCO/filtop/filtop/metrics"
+	"github.com/iTiagoCO/filtop/filtop/mirror"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+const focusableCount = 3
+
+const headerTitle = "[::b]FILTOP[::-] v2.0"
+
+// Options configura las páginas opcionales de la interfaz
+type Options struct {
+	// Tabs son los Filebeats monitoreados, uno por pestaña
+	Tabs []Tab
+	// FilebeatLogPath permite correlacionar errores de módulos con el log
+	FilebeatLogPath string
+	// FilebeatConfigPath es el filebeat.yml de la página filebeat.yml;
+	// vacío si no se indicó
+	FilebeatConfigPath string
+	// RegistryPath es el registry de Filebeat de la página Rotaciones;
+	// vacío si no se configuró
+	RegistryPath string
+	// Endpoints son paneles clave/valor alimentados por endpoints JSON propios
+	Endpoints []EndpointPanel
+	// Computed son los nombres de las métricas calculadas del panel Custom
+	Computed []string
+	// Panels son los paneles definidos por el usuario en la configuración
+	Panels []Panel
+	// Watch son las rutas de /stats del panel Watch
+	Watch []string
+	// SystemPaths son las rutas de logs del panel Host; nil lo oculta
+	SystemPaths []string
+	// AlertLog es el historial de la página Alerts
+	AlertLog *alerts.Log
+	// AlertRules indican qué panel destacar mientras cada alerta está activa
+	AlertRules []AlertRule
+	// Silences son los silencios de las alertas; nil no permite silenciarlas
+	Silences *alerts.Silences
+	// RateSmoothing es el suavizado inicial de las tasas: 0 (instantáneas), 1m o 5m
+	RateSmoothing time.Duration
+	// Elasticsearch muestra el panel que compara lo enviado con lo indexado
+	Elasticsearch bool
+	// KafkaTopic muestra el panel Kafka de ese topic, que compara lo enviado
+	// con lo escrito y sigue el lag de quien lo consume
+	KafkaTopic string
+	// Probe muestra el panel Latencia de la sonda; ProbeInput es el id del
+	// input que lee su log de prueba, vacío si solo se mide Elasticsearch
+	Probe      bool
+	ProbeInput string
+	// LastEventColumn agrega al panel Inputs la columna Last Event;
+	// QuietAfter es el tiempo sin eventos para resaltar un input
+	LastEventColumn bool
+	QuietAfter      time.Duration
+	// InputRules colorean las filas del panel Inputs; InputWindow es la
+	// ventana de avg_rate e increase() en sus condiciones
+	InputRules  []InputRule
+	InputWindow time.Duration
+	// LogPath es el archivo donde se escribe el log de filtop
+	LogPath string
+	// Reload se llama con la tecla r para volver a leer la configuración
+	Reload func()
+	// SetInterval recarga la configuración con otro intervalo de refresco,
+	// en segundos
+	SetInterval func(seconds int)
+	// Profiles son los perfiles de conexión que ofrece la tecla P; Profile
+	// es el que está en uso y SelectProfile recarga la configuración con
+	// otro
+	Profiles      []string
+	Profile       string
+	SelectProfile func(name string)
+	// Transport es el de las consultas a los beats, con su TLS y
+	// credenciales; nil usa el de Go
+	Transport http.RoundTripper
+	// Mirror recibe cada pantalla dibujada para filtop attach; nil si no se
+	// comparte
+	Mirror *mirror.Server
+	// BaselinePath es donde la tecla b guarda la línea base; Compare
+	// arranca comparando con ella
+	BaselinePath string
+	Compare      bool
+}
+
+// mainLayout guarda los paneles de la página principal para actualizarlos
+// sin recorrer el árbol de Flex en cada refresco.
+type mainLayout struct {
+	root       *tview.Flex
+	tabs       *tview.TextView
+	header     *tview.TextView
+	banner     *tview.TextView
+	system     *tview.Table
+	queue      *tview.TextView
+	harvesters *tview.TextView
+	host       *tview.Table
+	paths      *tview.Table
+	elastic    *tview.Table
+	kafka      *tview.Table
+	probe      *tview.Table
+	inputs     *tview.Table
+	modules    *tview.List
+	custom     *tview.Table
+	watch      *tview.Table
+	endpoints  []*tview.Table
+	panels     []tview.Primitive
+	// Módulos que muestra la lista, en orden; nil hasta la primera muestra
+	moduleNames []string
+	// named son los paneles a la vista por nombre, para destacar los de las
+	// alertas activas
+	named map[string][]tview.Primitive
+}
+
+var (
+	layout mainLayout
+
+	app     *tview.Application
+	pages   *tview.Pages
+	pageMap map[string]tview.Primitive
+	store   *metrics.Store
+	options Options
+	// current es la muestra que se está mostrando. Solo se lee y escribe
+	// desde la goroutine de tview; el colector publica en store.
+	current metrics.Sample
+	// configError es el último error al recargar la configuración
+	configError string
+
+	currentFocus int
+)
+
+// Init construye la interfaz. Empieza mostrando la primera pestaña.
+func Init(opts Options) {
+	options = opts
+	setConfiguredSmoothing(opts.RateSmoothing)
+	store = opts.Tabs[0].Store
+	tabStates = make([]tabState, len(opts.Tabs))
+
+	app = tview.NewApplication()
+	pages = tview.NewPages()
+	pageMap = make(map[string]tview.Primitive)
+
+	initUI()
+	setCompare(opts.Compare)
+}
+
+// Run bloquea hasta que se cierra la interfaz
+func Run() error {
+	return app.Run()
+}
+
+// Stop cierra la interfaz y restaura la terminal
+func Stop() {
+	app.Stop()
+}
+
+// Update redibuja los paneles con la última muestra del Store de la pestaña
+// tab si es la que se está mostrando
+func Update(tab int) {
+	queueUpdate(func() {
+		tabStates[tab].sampled, tabStates[tab].err = true, ""
+		updateTabBar()
+		// El historial, la flota y la sesión son de todas las pestañas
+		updateAlertsPage()
+		updateFleetPage()
+		updateSessionPage()
+		if tab != activeTab {
+			return
+		}
+		current = store.Latest()
+		leaveExpvarFallback()
+		updateUI()
+		updateCharts()
+		updateTopPage()
+		updateTimelinePage()
+		updateBrowserPage()
+		updateBeatConfigPage()
+		recordBaseline()
+		scheduleChangeClear()
+		schedulePriorityUpdates()
+	})
+}
+
+// Reconfigure reconstruye la página principal con los paneles de una
+// configuración recargada.
+func Reconfigure(opts Options) {
+	queueUpdate(func() {
+		changed := tabsChanged(options.Tabs, opts.Tabs)
+		options = opts
+		setConfiguredSmoothing(opts.RateSmoothing)
+		configError = ""
+		if changed {
+			resetTabs()
+		}
+		// Los paneles pueden ser otros
+		for i := range tabStates {
+			tabStates[i].series = nil
+		}
+		rebuildMainPage()
+		updateUI()
+	})
+}
+
+// rebuildMainPage reemplaza la página principal por una nueva, sin valores
+func rebuildMainPage() {
+	// Los TextView anteriores dejan de usarse
+	shownText = make(map[*tview.TextView]string)
+
+	front, _ := pages.GetFrontPage()
+	mainFlex := createMainPage()
+	pages.AddPage("main", mainFlex, true, front == "main")
+	pageMap["main"] = mainFlex
+	if front == "main" {
+		app.SetFocus(getFocusableComponent(currentFocus))
+	}
+}
+
+// ConfigError muestra en la cabecera que la recarga falló; se mantiene la
+// configuración anterior.
+func ConfigError(err error) {
+	queueUpdate(func() {
+		configError = err.Error()
+		if current.Stats != nil {
+			updateHeader()
+		}
+	})
+}
+
+func initUI() {
+	mainFlex := createMainPage()
+	pages.AddPage("main", mainFlex, true, true)
+	pageMap["main"] = mainFlex
+
+	expvarPage := createExpvarPage()
+	pages.AddPage("expvar", expvarPage, true, false)
+	pageMap["expvar"] = expvarPage
+	app.SetRoot(pages, true)
+	app.SetAfterDrawFunc(func(screen tcell.Screen) {
+		drawPriority(screen)
+		if options.Mirror != nil {
+			options.Mirror.Publish(mirror.Capture(screen))
+		}
+	})
+
+	app.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		// Lo que se escribe en la paleta no son atajos
+		if front, _ := pages.GetFrontPage(); front == "palette" {
+			return event
+		}
+		switch event.Key() {
+		case tcell.KeyEsc:
+			pages.SwitchToPage("main")
+		case tcell.KeyTab:
+			currentFocus = (currentFocus + 1) % focusableCount
+			app.SetFocus(getFocusableComponent(currentFocus))
+		case tcell.KeyBacktab:
+			currentFocus = (currentFocus - 1 + focusableCount) % focusableCount
+			app.SetFocus(getFocusableComponent(currentFocus))
+		case tcell.KeyEnter:
+			if currentFocus == 1 {
+				showInputDetails()
+			}
+		case tcell.KeyRune:
+			switch event.Rune() {
+			case 'p':
+				showPprofPage()
+			case 'l':
+				showLogsPage()
+			case 'g':
+				showChartsPage()
+			case 'a':
+				showAlertsPage()
+			case 'P':
+				showProfilePicker()
+			case 'f':
+				showFleetPage()
+			case 's':
+				showSessionPage()
+			case 't':
+				showTopPage()
+			case 'i':
+				showTimelinePage()
+			case 'm':
+				showBrowserPage()
+			case 'R':
+				showRegistryPage()
+			case 'y':
+				showBeatConfigPage()
+			case ':':
+				showPalette()
+				return nil
+			case 'r':
+				// En la página pprof r vuelve a cargar los perfiles
+				if front, _ := pages.GetFrontPage(); front == "main" && options.Reload != nil {
+					go options.Reload()
+				}
+			case 'b':
+				if front, _ := pages.GetFrontPage(); front == "main" {
+					startCapture()
+				}
+			case 'c':
+				if front, _ := pages.GetFrontPage(); front == "main" {
+					setCompare(!compareMode)
+					updateUI()
+				}
+			case 'd':
+				if front, _ := pages.GetFrontPage(); front == "main" {
+					setChanges(!changesMode)
+				}
+			case 'z':
+				if front, _ := pages.GetFrontPage(); front == "main" {
+					toggleSilence()
+				}
+			case 'v':
+				cycleSmoothing()
+			case '1', '2', '3', '4', '5', '6', '7', '8', '9':
+				if multipleTabs() {
+					switchTab(int(event.Rune() - '1'))
+				}
+			}
+		}
+		return event
+	})
+}
+
+// createMainPage construye la página principal según options
+func createMainPage() *tview.Flex {
+	mainFlex := tview.NewFlex().SetDirection(tview.FlexRow)
+	layout = mainLayout{named: make(map[string][]tview.Primitive)}
+
+	layout.header = tview.NewTextView().
+		SetDynamicColors(true).
+		SetTextAlign(tview.AlignCenter).
+		SetText(headerTitle)
+	layout.banner = createAnomalyBanner()
+	layout.system = createSystemPanel()
+	layout.queue = createQueuePanel()
+	layout.harvesters = createHarvesterChart()
+	layout.inputs = createInputsTable()
+	layout.modules = createModulesWidget()
+
+	body := tview.NewFlex()
+	leftPanel := tview.NewFlex().SetDirection(tview.FlexRow)
+	rightPanel := tview.NewFlex().SetDirection(tview.FlexRow)
+
+	// Los paneles ocultos con toggle se crean igual, para actualizarlos sin
+	// preguntar en cada refresco
+	add := func(flex *tview.Flex, name string, item tview.Primitive, size int) {
+		if !hiddenPanels[name] {
+			flex.AddItem(item, size, 1, false)
+			layout.named[name] = append(layout.named[name], item)
+		}
+	}
+	add(leftPanel, "system", layout.system, 8)
+	add(leftPanel, "queue", layout.queue, 6)
+	add(leftPanel, "harvesters", layout.harvesters, 8)
+	if options.SystemPaths != nil {
+		layout.host = createHostPanel()
+		// CPU, memoria, proceso y un sistema de archivos por ruta como mucho
+		add(leftPanel, "host", layout.host, len(options.SystemPaths)+5)
+		layout.paths = createPathsPanel(options.SystemPaths)
+		add(leftPanel, "host", layout.paths, len(options.SystemPaths)+2)
+	}
+	if options.Elasticsearch {
+		layout.elastic = createElasticPanel()
+		add(leftPanel, "elasticsearch", layout.elastic, esRows+2)
+	}
+	if options.KafkaTopic != "" {
+		layout.kafka = createKafkaPanel()
+		add(leftPanel, "kafka", layout.kafka, kafkaRows+2)
+	}
+	if options.Probe {
+		layout.probe = createProbePanel()
+		add(leftPanel, "probe", layout.probe, probeRows+2)
+	}
+	for _, endpoint := range options.Endpoints {
+		table := createEndpointPanel(endpoint)
+		layout.endpoints = append(layout.endpoints, table)
+		add(leftPanel, "endpoints", table, len(endpoint.Labels)+2)
+	}
+
+	rightPanel.AddItem(layout.inputs, 0, 2, false)
+	rightPanel.AddItem(layout.modules, 0, 1, false)
+	layout.named["inputs"] = []tview.Primitive{layout.inputs}
+	layout.named["modules"] = []tview.Primitive{layout.modules}
+	if len(options.Computed) > 0 {
+		layout.custom = createCustomPanel(options.Computed)
+		add(rightPanel, "custom", layout.custom, len(options.Computed)+2)
+	}
+	if paths := watchPaths(); len(paths) > 0 {
+		layout.watch = createWatchPanel(paths)
+		add(rightPanel, "watch", layout.watch, len(paths)+2)
+	}
+
+	layout.panels = make([]tview.Primitive, len(options.Panels))
+	for i, panel := range options.Panels {
+		layout.panels[i] = createUserPanel(panel)
+		target := leftPanel
+		if panel.Right {
+			target = rightPanel
+		}
+		add(target, "panels", layout.panels[i], len(panel.Labels)+2)
+		if !hiddenPanels["panels"] {
+			layout.named[panel.Title] = append(layout.named[panel.Title], layout.panels[i])
+		}
+	}
+
+	body.AddItem(leftPanel, 0, 1, false)
+	body.AddItem(rightPanel, 0, 2, false)
+
+	if multipleTabs() {
+		layout.tabs = createTabBar()
+		mainFlex.AddItem(layout.tabs, 1, 1, false)
+		updateTabBar()
+	}
+	mainFlex.AddItem(layout.header, 1, 1, false)
+	mainFlex.AddItem(layout.banner, 0, 0, false)
+	mainFlex.AddItem(body, 0, 1, false)
+	layout.root = mainFlex
+	return mainFlex
+}
+
+func getFocusableComponent(index int) tview.Primitive {
+	switch index {
+	case 0:
+		return layout.system
+	case 1:
+		return layout.inputs
+	case 2:
+		return layout.modules
+	}
+	return nil
+}
+
+func showInputDetails() {
+	if current.Stats == nil || len(current.Stats.Filebeat.Inputs) == 0 {
+		return
+	}
+
+	list := tview.NewList().ShowSecondaryText(false)
+	list.SetTitle(" Detalles de Inputs ").SetBorder(true)
+
+	for _, input := range current.Stats.Filebeat.Inputs {
+		list.AddItem(fmt.Sprintf("%s (%s)", input.Type, input.Device), "", 0, func() {
+			showInputMetrics(input)
+		})
+	}
+
+	list.AddItem("Regresar", "", 'b', func() {
+		pages.SwitchToPage("main")
+	})
+
+	pages.AddPage("input_details", list, true, true)
+	pages.SwitchToPage("input_details")
+}
+
+func showInputMetrics(input client.Input) {
+	textView := tview.NewTextView().SetDynamicColors(true)
+	textView.SetBorder(true).SetTitle(fmt.Sprintf(" Métricas: %s ", input.ID))
+
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "[yellow]Tipo:[-] %s\n", input.Type)
+	fmt.Fprintf(&builder, "[yellow]Dispositivo:[-] %s\n", input.Device)
+	fmt.Fprintf(&builder, "[yellow]Paquetes:[-] %d\n", input.Packets)
+	fmt.Fprintf(&builder, "[yellow]Bytes:[-] %s\n", formatBytes(input.Bytes))
+	fmt.Fprintf(&builder, "[yellow]Eventos:[-] %d\n", input.Events)
+	if input.Events > 0 {
+		fmt.Fprintf(&builder, "[yellow]Tamaño promedio de evento:[-] %s", formatEventSize(float64(input.Bytes)/float64(input.Events)))
+		if size, ok := store.History().InputEventSize(input.ID, 0); ok {
+			fmt.Fprintf(&builder, " (último intervalo: %s)", formatEventSize(size))
+		}
+		builder.WriteString("\n")
+	}
+	fmt.Fprintf(&builder, "[yellow]Activo:[-] %t\n", input.Active)
+	if activity, seen := store.History().InputActivity(input.ID); seen {
+		now := current.Stats.Timestamp
+		fmt.Fprintf(&builder, "[yellow]Último evento:[-] %s\n", lastEventText(activity, seen, now))
+		if inputQuiet(activity, now) {
+			fmt.Fprintf(&builder, "[orange]Sin eventos más tiempo del habitual (su mayor pausa fue de %s)[-]\n", formatAgo(activity.LongestGap))
+		}
+	}
+	if input.State != nil {
+		fmt.Fprintf(&builder, "\n[yellow]Estado:[-]\n")
+		if input.State.Cursor != "" {
+			fmt.Fprintf(&builder, "Cursor: %s\n", input.State.Cursor)
+		}
+		fmt.Fprintf(&builder, "Offset: %d\n", input.State.Offset)
+		if !input.State.LastPublished.IsZero() {
+			fmt.Fprintf(&builder, "Último evento publicado: %s\n", input.State.LastPublished.Local().Format(time.DateTime))
+		}
+	}
+	fmt.Fprintf(&builder, "\n[yellow]Histogramas:[-]\n")
+	fmt.Fprintf(&builder, "Arrival Period:\n%s", formatHistogram(input.ArrivalPeriod.Histogram))
+	fmt.Fprintf(&builder, "\nProcessing Time:\n%s", formatHistogram(input.ProcessingTime.Histogram))
+
+	textView.SetText(builder.String())
+
+	modal := tview.NewModal().
+		SetText(textView.GetText(true)).
+		AddButtons([]string{"Regresar"}).
+		SetDoneFunc(func(_ int, _ string) {
+			pages.SwitchToPage("input_details")
+		})
+
+	pages.AddPage("input_metrics", modal, true, true)
+	pages.SwitchToPage("input_metrics")
+}
+
+func formatHistogram(histo map[string]interface{}) string {
+	var builder strings.Builder
+	for k, v := range histo {
+		if val, ok := v.(float64); ok {
+			fmt.Fprintf(&builder, "  %-15s: %.2f\n", k, val)
+		}
+	}
+	return builder.String()
+}
+
+// formatEventRate formatea eventos/s, p. ej. 12.5 ev/s o 3.2k ev/s
+func formatEventRate(rate float64) string {
+	switch {
+	case rate >= 1e6:
+		return fmt.Sprintf("%.1fM ev/s", rate/1e6)
+	case rate >= 1e3:
+		return fmt.Sprintf("%.1fk ev/s", rate/1e3)
+	}
+	return fmt.Sprintf("%.1f ev/s", rate)
+}
+
+// formatByteRate formatea bytes/s, p. ej. 1.5 MiB/s
+func formatByteRate(rate float64) string {
+	return formatBytes(uint64(rate)) + "/s"
+}
+
+// formatEventSize formatea el tamaño promedio de un evento, p. ej. 812 B/ev
+func formatEventSize(size float64) string {
+	return formatBytes(uint64(math.Round(size))) + "/ev"
+}
+
+func formatBytes(bytes uint64) string {
+	const unit = 1024
+	if bytes < unit {
+		return fmt.Sprintf("%d B", bytes)
+	}
+	div, exp := int64(unit), 0
+	for n := bytes / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(bytes)/float64(div), "KMGTPE"[exp])
+}
+
+// formatValue formatea un valor JSON decodificado para mostrarlo en una celda
+func formatValue(value interface{}) string {
+	switch v := value.(type) {
+	case float64:
+		if v == math.Trunc(v) && math.Abs(v) < 1e15 {
+			return fmt.Sprintf("%.0f", v)
+		}
+		return fmt.Sprintf("%.3f", v)
+	case []interface{}:
+		return fmt.Sprintf("[%d elementos]", len(v))
+	case nil:
+		return "null"
+	default:
+		return tview.Escape(fmt.Sprint(v))
+	}
+}
+
+func createSystemPanel() *tview.Table {
+	table := tview.NewTable().SetBorders(false)
+	table.SetTitle(" Sistema ").SetBorder(true)
+	addMetricRow(table, 0, "CPU Total:", "0.0%", tcell.ColorOrange)
+	addMetricRow(table, 1, "Memoria RSS:", "0.0 MB", tcell.ColorGreen)
+	addMetricRow(table, 2, "Uptime:", "0h 0m", tcell.ColorBlue)
+	addMetricRow(table, 3, "Load Avg:", "0.00 0.00 0.00", tcell.ColorYellow)
+	return table
+}
+
+func updateUI() {
+	if current.Stats == nil {
+		return
+	}
+	updateHeader()
+	updateSystemMetrics()
+	updateQueue()
+	updateHarvesters()
+	updateInputs()
+	updateModules()
+	updateWatch()
+	updateProbePanel()
+}
+
+func addMetricRow(table *tview.Table, row int, label, value string, color tcell.Color) {
+	table.SetCell(row, 0, tview.NewTableCell(label).SetTextColor(tcell.ColorWhite))
+	table.SetCell(row, 1, tview.NewTableCell(value).SetTextColor(color))
+}
+
+func createQueuePanel() *tview.TextView {
+	view := tview.NewTextView().SetDynamicColors(true)
+	view.SetTitle(" Pipeline Queue ").SetBorder(true)
+	view.SetText("[green]0/0 [white]| [gray]....................")
+	return view
+}
+
+func createHarvesterChart() *tview.TextView {
+	view := tview.NewTextView().SetDynamicColors(true)
+	view.SetTitle(" Harvesters ").SetBorder(true)
+	view.SetText("Active: 0 | Open Files: 0")
+	return view
+}
+func createInputsTable() *tview.Table {
+	table := tview.NewTable().SetBorders(true)
+	table.SetTitle(" Inputs ").SetBorder(true)
+	headers := []string{"Type", "Active", "Events", "Events/s", "Bytes", "Bytes/s", "Files"}
+	if options.LastEventColumn {
+		headers = append(headers, "Last Event")
+	}
+	for col, h := range headers {
+		table.SetCell(0, col, tview.NewTableCell(h).SetTextColor(tcell.ColorYellow).SetAlign(tview.AlignCenter))
+	}
+	return table
+}
+
+func createModulesWidget() *tview.List {
+	list := tview.NewList().ShowSecondaryText(false)
+	list.SetTitle(" Modules ").SetBorder(true)
+	list.AddItem("Loading...", "", 0, nil)
+	return list
+}
+
+func getPrimitiveFromPage(pageName string) tview.Primitive {
+	if primitive, exists := pageMap[pageName]; exists {
+		return primitive
+	}
+	return nil
+}
+
+// mirrorSummary indica en la cabecera cuántos siguen la pantalla con
+// filtop attach
+func mirrorSummary() string {
+	if options.Mirror == nil {
+		return ""
+	}
+	if clients := options.Mirror.Clients(); clients > 0 {
+		return fmt.Sprintf(" | [aqua]compartida: %d viendo[-]", clients)
+	}
+	return ""
+}
+
+// beatVersion devuelve la versión de Filebeat de la muestra actual
+func beatVersion() string {
+	if info := current.Info; info != nil && info.Version != "" {
+		return info.Version
+	}
+	return current.Stats.Beat.Info.Version
+}
+
+func updateHeader() {
+	version := beatVersion()
+	if version == "" {
+		version = "?"
+	}
+	text := fmt.Sprintf("%s | Filebeat %s (schema %s)", headerTitle, version, current.Schema)
+	if state := current.State; state != nil {
+		text += fmt.Sprintf(" | output: %s | queue: %s", state.Output.Name, state.Queue.Name)
+	}
+	if current.Stats.FetchDuration > 0 {
+		text += fmt.Sprintf(" | fetch: %s", current.Stats.FetchDuration.Round(time.Millisecond))
+	}
+	if options.Profile != "" {
+		text += " | perfil: " + tview.Escape(options.Profile)
+	}
+	text += alertSummary()
+	text += silenceSummary()
+	text += smoothingSummary()
+	text += mirrorSummary()
+	text += baselineSummary()
+	text += registrySummary()
+	text += commandSummary()
+	if configError != "" {
+		text += " | [red]config: " + tview.Escape(configError) + "[-]"
+	}
+	setText(layout.header, text)
+}
+
+func updateSystemMetrics() {
+	stats := current.Stats
+
+	// CPU
+	totalMs := stats.Beat.CPU.Total.Time.MS
+	cpuPercent := float64(totalMs) / float64(stats.Beat.Info.Uptime.MS) * 100
+
+	// Memoria
+	rssMB := float64(stats.Beat.Memstats.RSS) / 1024 / 1024
+
+	// Uptime
+	uptime := time.Duration(stats.Beat.Info.Uptime.MS) * time.Millisecond
+
+	// Load Average
+	load := stats.System.Load.Norm
+
+	panel := layout.system
+	setCell(panel, 0, 1, fmt.Sprintf("%.1f%%", cpuPercent)+compared("cpu_percent", cpuPercent), tcell.ColorOrange)
+	setCell(panel, 1, 1, fmt.Sprintf("%.1f MB", rssMB)+compared("rss_mb", rssMB), tcell.ColorGreen)
+	setCell(panel, 2, 1, fmt.Sprintf("%v", uptime.Truncate(time.Minute)), tcell.ColorBlue)
+	setCell(panel, 3, 1, fmt.Sprintf("%.2f %.2f %.2f", load.Load1, load.Load5, load.Load15)+compared("load1", load.Load1), tcell.ColorYellow)
+}
+
+func updateHarvesters() {
+	harvester := current.Stats.Filebeat.Harvester
+	running := changedValue(fmt.Sprint(harvester.Running), "harvesters.running", float64(harvester.Running), formatCount)
+	open := changedValue(fmt.Sprint(harvester.Open), "harvesters.open", float64(harvester.Open), formatCount)
+	setText(layout.harvesters, fmt.Sprintf("Active: %s%s | Open Files: %s%s",
+		running, compared("harvesters_running", float64(harvester.Running)),
+		open, compared("harvesters_open", float64(harvester.Open))))
+}
+
+func updateQueue() {
+	queue := current.Stats.Libbeat.Pipeline.Queue
+	filled := changedValue(fmt.Sprintf("%d/%d", queue.Filled.Events, queue.MaxEvents), "queue.filled", float64(queue.Filled.Events), formatCount)
+	setText(layout.queue, queueText(queue.Filled.Events, filled))
+}
+
+// queueText es el texto del panel Pipeline Queue con filled eventos en la
+// cola, que se muestran como label
+func queueText(filled uint64, label string) string {
+	queue := current.Stats.Libbeat.Pipeline.Queue
+	percent := 0.0
+	if queue.MaxEvents > 0 {
+		percent = float64(filled) / float64(queue.MaxEvents) * 100
+	}
+
+	bars := int(percent / 5)
+	if bars < 0 {
+		bars = 0
+	}
+	text := fmt.Sprintf("[green]%s%s [white]| %s", label,
+		compared("queue_filled", float64(filled)), strings.Repeat("█", bars))
+	if forecast, ok := store.History().ForecastQueue(queue.MaxEvents, metrics.ForecastWindow); ok && forecast.FullIn <= forecastHorizon {
+		text += "\n" + forecastText(forecast)
+	}
+	return text
+}
+
+// La proyección de la cola se muestra si se llenaría dentro de
+// forecastHorizon, y en rojo dentro de forecastUrgent
+const (
+	forecastHorizon = time.Hour
+	forecastUrgent  = 5 * time.Minute
+)
+
+// forecastText avisa cuándo se llenaría la cola, p. ej. "llena en ~4m al
+// ritmo actual", y debajo cuánto crece y lo que confirma la salida
+func forecastText(forecast metrics.QueueForecast) string {
+	color := "yellow"
+	if forecast.FullIn <= forecastUrgent {
+		color = "red"
+	}
+	detail := "crece " + formatEventRate(forecast.Growth)
+	if forecast.AckedOK {
+		detail += " · salida " + formatEventRate(forecast.Acked)
+	}
+	return fmt.Sprintf("[%s]⚠ llena en ~%s al ritmo actual[-]\n[gray]%s[-]", color, formatAgo(forecast.FullIn), detail)
+}
+
+func updateInputs() {
+	table := layout.inputs
+	rows := 1
+	if current.Stats.InputsError != "" {
+		table.SetTitle(" Inputs (no disponible) ")
+		setCell(table, 1, 0, tview.Escape(current.Stats.InputsError), tcell.ColorGray)
+		for col := 1; col < table.GetColumnCount(); col++ {
+			setCell(table, 1, col, "", tcell.ColorGray)
+		}
+		rows = 2
+	} else {
+		table.SetTitle(" Inputs ")
+		inputs := filterInputs(current.Stats.Filebeat.Inputs)
+		if inputFilter != "" {
+			table.SetTitle(fmt.Sprintf(" Inputs (filtro: %s, %d de %d) ", tview.Escape(inputFilter), len(inputs), len(current.Stats.Filebeat.Inputs)))
+		}
+		var total inputsTotal
+		now := current.Stats.Timestamp
+		for i, input := range inputs {
+			row := i + 1
+			eventRate, eventsOk := inputEventRate(input.ID)
+			byteRate, bytesOk := inputByteRate(input.ID)
+			activity, seen := store.History().InputActivity(input.ID)
+			color := inputColor(input.ID)
+			if color == tcell.ColorWhite && seen && inputQuiet(activity, now) {
+				color = quietColor
+			}
+			setCell(table, row, 0, input.Type, color)
+			setCell(table, row, 1, fmt.Sprintf("%t", input.Active), color)
+			setChangedCell(table, row, 2, fmt.Sprintf("%d", input.Events), color, "input."+input.ID+".events", float64(input.Events), formatCount)
+			setCell(table, row, 3, rateText(formatEventRate, eventRate, eventsOk, "input."+input.ID), color)
+			setChangedCell(table, row, 4, formatBytes(input.Bytes), color, "input."+input.ID+".bytes", float64(input.Bytes), formatByteCount)
+			setCell(table, row, 5, rateText(formatByteRate, byteRate, bytesOk, "input."+input.ID+".bytes_per_sec"), color)
+			setChangedCell(table, row, 6, fmt.Sprintf("%d", input.Files), color, "input."+input.ID+".files", float64(input.Files), formatCount)
+			if options.LastEventColumn {
+				setCell(table, row, 7, lastEventText(activity, seen, now), color)
+			}
+			total.add(input, eventRate, byteRate)
+			if activity.LastEvent.After(total.lastEvent) {
+				total.lastEvent = activity.LastEvent
+			}
+		}
+		rows += len(inputs)
+		if len(inputs) > 1 {
+			updateInputsTotal(table, rows, total, len(inputs))
+			rows++
+		}
+	}
+
+	// Quita las filas que sobran de la muestra anterior
+	for row := table.GetRowCount() - 1; row >= rows; row-- {
+		table.RemoveRow(row)
+	}
+}
+
+// inputsTotal es la suma de todos los inputs, para la fila Total
+type inputsTotal struct {
+	active    int
+	events    uint64
+	eventRate float64
+	bytes     uint64
+	byteRate  float64
+	files     uint64
+	lastEvent time.Time
+}
+
+func (t *inputsTotal) add(input client.Input, eventRate, byteRate float64) {
+	if input.Active {
+		t.active++
+	}
+	t.events += input.Events
+	t.eventRate += eventRate
+	t.bytes += input.Bytes
+	t.byteRate += byteRate
+	t.files += input.Files
+}
+
+func updateInputsTotal(table *tview.Table, row int, total inputsTotal, count int) {
+	setCell(table, row, 0, "Total", tcell.ColorYellow)
+	setCell(table, row, 1, fmt.Sprintf("%d/%d", total.active, count), tcell.ColorYellow)
+	setChangedCell(table, row, 2, fmt.Sprintf("%d", total.events), tcell.ColorYellow, "inputs.events", float64(total.events), formatCount)
+	setCell(table, row, 3, rateText(formatEventRate, total.eventRate, true, "inputs.events_per_sec"), tcell.ColorYellow)
+	setChangedCell(table, row, 4, formatBytes(total.bytes), tcell.ColorYellow, "inputs.bytes", float64(total.bytes), formatByteCount)
+	setCell(table, row, 5, rateText(formatByteRate, total.byteRate, true, "inputs.bytes_per_sec"), tcell.ColorYellow)
+	setChangedCell(table, row, 6, fmt.Sprintf("%d", total.files), tcell.ColorYellow, "inputs.files", float64(total.files), formatCount)
+	if options.LastEventColumn {
+		activity := metrics.InputActivity{LastEvent: total.lastEvent}
+		setCell(table, row, 7, lastEventText(activity, !total.lastEvent.IsZero(), current.Stats.Timestamp), tcell.ColorYellow)
+	}
+}
+
+// InputRule es una regla de color del panel Inputs: la fila toma Color
+// (red, orange, yellow, green, blue o gray) si se cumple When
+type InputRule struct {
+	Color string
+	When  *expr.Expr
+}
+
+// inputColor es el color de la primera regla que cumple el input, o blanco.
+// Una condición que todavía no se puede evaluar, p. ej. avg_rate con una
+// sola muestra, no se cumple.
+func inputColor(id string) tcell.Color {
+	env := store.History().InputEnv(id, options.InputWindow)
+	for _, rule := range options.InputRules {
+		if ok, err := rule.When.Bool(env); err == nil && ok {
+			return tcell.GetColor(rule.Color)
+		}
+	}
+	return tcell.ColorWhite
+}
+
+// Un input se resalta como silencioso si lleva más de options.QuietAfter sin
+// eventos y más de quietGapFactor veces su mayor pausa observada, para no
+// marcar los que producen eventos de vez en cuando
+const quietGapFactor = 3
+
+var quietColor = tcell.ColorOrange
+
+func inputQuiet(activity metrics.InputActivity, now time.Time) bool {
+	if activity.LastEvent.IsZero() {
+		return false
+	}
+	silence := now.Sub(activity.LastEvent)
+	return silence >= options.QuietAfter && silence > quietGapFactor*activity.LongestGap
+}
+
+// lastEventText describe cuándo aumentaron por última vez los eventos de un
+// input, p. ej. "hace 12m"
+func lastEventText(activity metrics.InputActivity, seen bool, now time.Time) string {
+	switch {
+	case !seen:
+		return "-"
+	case activity.LastEvent.IsZero():
+		return "sin eventos en " + formatAgo(now.Sub(activity.FirstSeen))
+	}
+	return "hace " + formatAgo(now.Sub(activity.LastEvent))
+}
+
+// rateText formatea una tasa con su desviación de la línea base, o "-" si
+// todavía no hay dos muestras para calcularla
+func rateText(format func(float64) string, rate float64, ok bool, key string) string {
+	if !ok {
+		return "-"
+	}
+	return format(rate) + compared(key, rate)
+}
+
+func updateModules() {
+	list := layout.modules
+	modules := current.Stats.Filebeat.Modules.List
+	var totalRate float64
+	for _, input := range current.Stats.Filebeat.Inputs {
+		rate, _ := inputEventRate(input.ID)
+		totalRate += rate
+	}
+
+	// Con los mismos módulos solo cambian los textos; así se conserva la
+	// selección sin reconstruir la lista
+	same := layout.moduleNames != nil && len(layout.moduleNames) == len(modules)
+	for i := 0; same && i < len(modules); i++ {
+		same = layout.moduleNames[i] == modules[i].Name
+	}
+	if !same {
+		selected := list.GetCurrentItem()
+		list.Clear()
+		layout.moduleNames = make([]string, 0, len(modules))
+		for _, module := range modules {
+			name := module.Name
+			list.AddItem(moduleItemText(module, totalRate), "", 0, func() {
+				showModuleDetails(name)
+			})
+			layout.moduleNames = append(layout.moduleNames, name)
+		}
+		list.SetCurrentItem(selected)
+		return
+	}
+
+	for i, module := range modules {
+		text := moduleItemText(module, totalRate)
+		if main, _ := list.GetItemText(i); main != text {
+			list.SetItemText(i, text, "")
+		}
+	}
+}
+
+// moduleItemText describe un módulo y, si está habilitado, los eventos/s
+// de sus filesets y qué parte son del total de los inputs
+func moduleItemText(module client.Module, totalRate float64) string {
+	color, status := "[red]", "✗"
+	if module.Enabled {
+		color, status = "[green]", "✓"
+	}
+	moduleErrors := changedValue(fmt.Sprint(module.Errors), "module."+module.Name+".errors", float64(module.Errors), formatCount)
+	text := fmt.Sprintf("%s%s %s (%s%s errors)", color, status, module.Name, moduleErrors, color)
+	if !module.Enabled {
+		return text
+	}
+	rate, inputs := moduleEventRate(module.Name)
+	if inputs == 0 {
+		return text + " [gray]sin inputs atribuibles[-]"
+	}
+	text += " [aqua]" + formatEventRate(rate)
+	if totalRate > 0 {
+		text += fmt.Sprintf(" (%.0f%%)", rate/totalRate*100)
+	}
+	return text + "[-]" + compared("module."+module.Name, rate)
+}
@@ -0,0 +1,132 @@
+package ui
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// Panel Watch: rutas de /stats fijadas por el usuario (watch en la
+// configuración o el comando watch de la paleta) con su valor, su cambio
+// desde la muestra anterior y un sparkline, para seguir métricas que filtop
+// no muestra por su cuenta.
+
+// pinnedPaths son las rutas agregadas con la paleta; se conservan al
+// recargar la configuración
+var pinnedPaths []string
+
+// watchPaths son las rutas del panel: las de la configuración y después
+// las fijadas, sin repetir
+func watchPaths() []string {
+	paths := append([]string(nil), options.Watch...)
+	for _, path := range pinnedPaths {
+		if !containsPath(paths, path) {
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
+
+func containsPath(paths []string, path string) bool {
+	for _, p := range paths {
+		if p == path {
+			return true
+		}
+	}
+	return false
+}
+
+func createWatchPanel(paths []string) *tview.Table {
+	table := tview.NewTable().SetBorders(false)
+	table.SetTitle(" Watch ").SetBorder(true)
+	for row, path := range paths {
+		setCell(table, row, 0, tview.Escape(path), tcell.ColorWhite)
+		table.GetCell(row, 0).SetMaxWidth(40)
+		setCell(table, row, 1, "-", tcell.ColorGray)
+	}
+	return table
+}
+
+func updateWatch() {
+	if layout.watch == nil {
+		return
+	}
+	history := store.History()
+	for row, path := range watchPaths() {
+		value, _, ok := history.Value(0, path)
+		if !ok {
+			setCell(layout.watch, row, 1, "-", tcell.ColorGray)
+			setCell(layout.watch, row, 2, "", tcell.ColorGray)
+			setCell(layout.watch, row, 3, "", tcell.ColorGray)
+			continue
+		}
+		setCell(layout.watch, row, 1, formatComputed(value), tcell.ColorAqua)
+		delta, color := "", tcell.ColorGray
+		if prev, _, ok := history.Value(1, path); ok {
+			delta, color = formatChange(value - prev)
+		}
+		setCell(layout.watch, row, 2, delta, color)
+		points, _ := history.Series(path)
+		if len(points) > sparklinePoints {
+			points = points[len(points)-sparklinePoints:]
+		}
+		series := make([]float64, len(points))
+		for i, point := range points {
+			series[i] = point.Value
+		}
+		setCell(layout.watch, row, 3, sparkline(series), tcell.ColorGreen)
+	}
+}
+
+// formatChange formatea el cambio desde la muestra anterior: verde si
+// subió, rojo si bajó e = si no cambió
+func formatChange(change float64) (string, tcell.Color) {
+	switch {
+	case change > 0:
+		return "+" + formatComputed(change), tcell.ColorGreen
+	case change < 0:
+		return formatComputed(change), tcell.ColorRed
+	}
+	return "=", tcell.ColorGray
+}
+
+// watchPath fija una ruta en el panel Watch, o la quita si ya estaba
+// fijada
+func watchPath(path string) error {
+	if path == "" {
+		return errors.New("falta la ruta, p. ej. libbeat.pipeline.events.failed")
+	}
+	if err := togglePin(path); err != nil {
+		return err
+	}
+	pages.SwitchToPage("main")
+	rebuildMainPage()
+	updateUI()
+	return nil
+}
+
+// togglePin agrega la ruta a pinnedPaths o la quita si ya estaba; el
+// panel Watch cambia de tamaño, así que después hay que reconstruir la
+// página principal
+func togglePin(path string) error {
+	if containsPath(options.Watch, path) {
+		return fmt.Errorf("%s está en watch de la configuración", path)
+	}
+	if containsPath(pinnedPaths, path) {
+		remaining := pinnedPaths[:0:0]
+		for _, p := range pinnedPaths {
+			if p != path {
+				remaining = append(remaining, p)
+			}
+		}
+		pinnedPaths = remaining
+		return nil
+	}
+	if _, _, ok := store.History().Value(0, path); !ok {
+		setCommandResult("[yellow]" + tview.Escape(path) + " todavía no aparece en /stats[-]")
+	}
+	pinnedPaths = append(pinnedPaths, path)
+	return nil
+}
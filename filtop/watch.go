@@ -0,0 +1,232 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/iTiagoCO/filtop/filtop/client"
+)
+
+// filtop watch comprueba en cada ciclo que cada Filebeat responda, tenga
+// harvesters activos si lee archivos y no descarte eventos, y solo informa cuando eso
+// cambia: una línea en stdout y, si se pide, un POST a un webhook o la
+// salida con código 1 al primer fallo. Es una alternativa liviana a un
+// sistema de alertas completo, p. ej. para un cron o un ExecCondition.
+
+// watchOptions son los flags de filtop watch
+type watchOptions struct {
+	webhook       string
+	exitOnFailure bool
+	http          *http.Client
+	// heartbeat registra cada ronda de comprobaciones para el watchdog de
+	// systemd
+	heartbeat *heartbeat
+}
+
+// watchChange es lo que se envía al webhook con cada cambio de estado
+type watchChange struct {
+	Target   string    `json:"target"`
+	URL      string    `json:"url"`
+	OK       bool      `json:"ok"`
+	Failures []string  `json:"failures"`
+	At       time.Time `json:"at"`
+}
+
+// watchFailure es una comprobación que falla. El estado se compara por
+// check, para no informar cada ciclo solo porque cambió la cantidad de
+// descartados.
+type watchFailure struct {
+	check  string
+	detail string
+}
+
+// watcher recuerda lo necesario para comparar cada muestra de un beat con
+// la anterior
+type watcher struct {
+	beat    *beat
+	dropped uint64
+	// sampled es falso hasta la primera muestra y después de un fallo de
+	// conexión, cuando no hay con qué comparar los descartados
+	sampled bool
+	// reported son las comprobaciones que fallaban en el último estado
+	// informado
+	reported string
+	informed bool
+}
+
+// runWatch ejecuta filtop watch y termina con su código de salida
+func runWatch(args []string) {
+	fs := newFlagSet("watch", "filtop watch [flags]")
+	flags := &beatFlags{}
+	flags.register(fs, "watch")
+	webhook := fs.String("webhook", "", "URL a la que se envía cada cambio de estado (POST JSON)")
+	exitOnFailure := fs.Bool("exit-on-failure", false, "Terminar con código 1 al primer fallo")
+	flags.parse(fs, args)
+
+	s := newSession("watch", flags)
+	heartbeat := newHeartbeat()
+	go runWatchdog(s.ctx, heartbeat, watchdogStall(flags.timeout, flags.retries, len(s.beats)))
+	code := watchBeats(s.ctx, s.beats, watchOptions{webhook: *webhook, exitOnFailure: *exitOnFailure, http: s.endpointHTTP, heartbeat: heartbeat})
+	sdNotify("STOPPING=1")
+	os.Exit(code)
+}
+
+// watchBeats vigila los beats hasta que se cancela ctx y devuelve el código
+// de salida del programa.
+func watchBeats(ctx context.Context, beats []*beat, opts watchOptions) int {
+	watchers := make([]*watcher, len(beats))
+	for i, b := range beats {
+		watchers[i] = &watcher{beat: b}
+	}
+	ticker := time.NewTicker(refresh)
+	defer ticker.Stop()
+
+	// status es el último estado informado a systemd
+	status := ""
+	for {
+		for _, w := range watchers {
+			failures := w.check(ctx)
+			if ctx.Err() != nil {
+				return 0
+			}
+			checks := make([]string, len(failures))
+			change := watchChange{Target: w.beat.name, URL: w.beat.url, OK: len(failures) == 0, Failures: []string{}, At: time.Now()}
+			for i, failure := range failures {
+				checks[i] = failure.check
+				change.Failures = append(change.Failures, failure.detail)
+			}
+			state := strings.Join(checks, ",")
+			if w.informed && state == w.reported {
+				continue
+			}
+			w.reported, w.informed = state, true
+			report(ctx, change, opts)
+			if !change.OK && opts.exitOnFailure {
+				return 1
+			}
+		}
+		opts.heartbeat.beat()
+		if current := watchStatus(watchers); current != status {
+			// Listo tras la primera ronda, con el estado de cada beat ya
+			// informado
+			if status == "" {
+				sdNotify("READY=1")
+			}
+			sdNotify("STATUS=" + current)
+			status = current
+		}
+		select {
+		case <-ctx.Done():
+			return 0
+		case <-ticker.C:
+		}
+	}
+}
+
+// watchStatus resume el estado de los beats para systemctl status
+func watchStatus(watchers []*watcher) string {
+	ok := 0
+	for _, w := range watchers {
+		if w.reported == "" {
+			ok++
+		}
+	}
+	return fmt.Sprintf("%d de %d beats OK", ok, len(watchers))
+}
+
+// check consulta el beat y devuelve lo que falla; vacío si todo está bien
+func (w *watcher) check(ctx context.Context) []watchFailure {
+	source := w.beat.source
+	start := time.Now()
+	if source.Info() == nil {
+		if err := source.DetectVersion(ctx); err != nil {
+			if ctx.Err() == nil {
+				selfMetrics.ObserveScrape(w.beat.name, time.Since(start), err)
+			}
+			w.sampled = false
+			return []watchFailure{{"up", "sin respuesta: " + err.Error()}}
+		}
+	}
+	stats, _, err := source.Fetch(ctx)
+	if ctx.Err() == nil {
+		selfMetrics.ObserveScrape(w.beat.name, time.Since(start), err)
+	}
+	if err != nil {
+		// Filebeat pudo reiniciarse con otra versión: se vuelve a detectar
+		source.Reset()
+		w.sampled = false
+		return []watchFailure{{"up", "sin respuesta: " + err.Error()}}
+	}
+	source.Normalize(stats)
+
+	var failures []watchFailure
+	if readsFiles(stats) && stats.Filebeat.Harvester.Running == 0 {
+		failures = append(failures, watchFailure{"harvesting", "sin harvesters activos"})
+	}
+	// Si el contador bajó, Filebeat se reinició y no hay con qué comparar
+	dropped := stats.Libbeat.Pipeline.Events.Dropped
+	if w.sampled && dropped > w.dropped {
+		failures = append(failures, watchFailure{"dropping", fmt.Sprintf("%d eventos descartados en el último ciclo", dropped-w.dropped)})
+	}
+	w.dropped, w.sampled = dropped, true
+	return failures
+}
+
+// fileInputs son los tipos de input que leen archivos con harvesters
+var fileInputs = map[string]bool{"filestream": true, "log": true, "container": true}
+
+// readsFiles indica si el beat lee archivos, para exigirle harvesters
+// activos: los inputs de red (tcp, udp, syslog, kafka...) no los usan y
+// fallarían siempre. Sin /inputs/ se considera que lee archivos si alguna
+// vez abrió uno.
+func readsFiles(stats *client.FilebeatStats) bool {
+	for _, input := range stats.Filebeat.Inputs {
+		if fileInputs[input.Type] {
+			return true
+		}
+	}
+	return stats.Filebeat.Harvester.Started > 0
+}
+
+// report informa un cambio de estado en stdout y en el webhook
+func report(ctx context.Context, change watchChange, opts watchOptions) {
+	state := "OK"
+	if !change.OK {
+		state = "FALLA: " + strings.Join(change.Failures, "; ")
+	}
+	fmt.Printf("%s %s %s\n", change.At.Format(time.RFC3339), change.Target, state)
+	if opts.webhook == "" {
+		return
+	}
+	if err := postChange(ctx, opts.http, opts.webhook, change); err != nil {
+		slog.Warn("Error enviando el cambio de estado al webhook", "url", opts.webhook, "err", err)
+	}
+}
+
+func postChange(ctx context.Context, httpClient *http.Client, url string, change watchChange) error {
+	body, err := json.Marshal(change)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("el webhook respondió %s", resp.Status)
+	}
+	return nil
+}